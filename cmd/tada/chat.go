@@ -2,18 +2,25 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 	"github.com/Lin-Jiong-HDU/tada/internal/ai/glm"
 	"github.com/Lin-Jiong-HDU/tada/internal/ai/openai"
 	"github.com/Lin-Jiong-HDU/tada/internal/conversation"
+	"github.com/Lin-Jiong-HDU/tada/internal/core"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
 	"github.com/Lin-Jiong-HDU/tada/internal/memory"
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 	"github.com/Lin-Jiong-HDU/tada/internal/storage"
 	"github.com/Lin-Jiong-HDU/tada/internal/terminal"
 	"github.com/spf13/cobra"
@@ -30,6 +37,22 @@ var (
 	chatNoHistory  bool
 	chatNoStream   bool
 	chatNoRender   bool
+	chatSearch     string
+	chatSince      string
+	chatUntil      string
+	chatLimit      int
+	chatResume     bool
+	chatReindex    bool
+	chatExportID   string
+	chatFormat     string
+	chatOutput     string
+	chatImport     string
+	chatBranch     int
+	chatTag        string
+	chatAll        bool
+	chatArchived   bool
+	chatArchiveID  string
+	chatUnarchive  string
 )
 
 func getChatCommand() *cobra.Command {
@@ -44,6 +67,13 @@ func getChatCommand() *cobra.Command {
   - 临时模式: 使用 --no-history 不保存历史
   - 流式输出: 实时显示 AI 响应
   - Markdown 渲染: 美化输出格式
+  - 执行命令: /run <请求> 或 !<命令>，输出会作为上下文加入对话
+  - 长对话: 超出上下文窗口时自动摘要较早的对话，/compact 手动压缩
+  - 搜索历史: --search "关键词"，--resume 直接恢复最匹配的对话
+  - 导出/导入: --export <ID> --format md|json|html|txt，--import <文件>
+  - 分支: /retry、/edit、/undo 生成新的分支，/fork 复制为新对话
+  - 整理: 自动生成标题，/rename 和 /tag 修改，--archive 归档，--list --tag 过滤
+  - 附件: /file <路径>、/attach <通配符> 或在消息中写 @路径，管道输入: cat log | tada chat "问题"
 `,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			_, err := storage.InitConfig()
@@ -54,6 +84,7 @@ func getChatCommand() *cobra.Command {
 
 	cmd.Flags().StringVarP(&chatPromptName, "prompt", "p", "default", "Prompt 模板名称")
 	cmd.Flags().StringVarP(&chatContinueID, "continue", "c", "", "恢复对话 ID")
+	cmd.Flags().IntVar(&chatBranch, "branch", 0, "恢复对话时切换到指定分支（见 /branches）")
 	cmd.Flags().BoolVarP(&chatList, "list", "l", false, "列出所有对话")
 	cmd.Flags().BoolVar(&chatToday, "today", false, "仅列出今天的对话")
 	cmd.Flags().StringVarP(&chatShowID, "show", "s", "", "显示对话详情")
@@ -62,6 +93,21 @@ func getChatCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&chatNoHistory, "no-history", false, "不保存历史")
 	cmd.Flags().BoolVar(&chatNoStream, "no-stream", false, "禁用流式输出")
 	cmd.Flags().BoolVar(&chatNoRender, "no-render", false, "禁用 markdown 渲染")
+	cmd.Flags().StringVar(&chatTag, "tag", "", "按标签过滤列表和搜索结果")
+	cmd.Flags().BoolVar(&chatAll, "all", false, "列表和搜索结果包含已归档的对话")
+	cmd.Flags().BoolVar(&chatArchived, "archived", false, "只列出和搜索已归档的对话")
+	cmd.Flags().StringVar(&chatArchiveID, "archive", "", "归档对话")
+	cmd.Flags().StringVar(&chatUnarchive, "unarchive", "", "取消归档对话")
+	cmd.Flags().StringVar(&chatSearch, "search", "", "全文搜索对话历史（可配合 --prompt 过滤）")
+	cmd.Flags().StringVar(&chatSince, "since", "", "搜索起始日期 (YYYY-MM-DD 或 7d)")
+	cmd.Flags().StringVar(&chatUntil, "until", "", "搜索截止日期 (YYYY-MM-DD 或 7d)")
+	cmd.Flags().IntVar(&chatLimit, "limit", 10, "最多显示的搜索结果数")
+	cmd.Flags().BoolVar(&chatResume, "resume", false, "恢复排名第一的搜索结果")
+	cmd.Flags().BoolVar(&chatReindex, "reindex", false, "重建搜索索引")
+	cmd.Flags().StringVar(&chatExportID, "export", "", "导出对话")
+	cmd.Flags().StringVar(&chatFormat, "format", conversation.FormatMarkdown, "导出格式 (md|json|html|txt)")
+	cmd.Flags().StringVarP(&chatOutput, "output", "o", "", "导出到文件（默认输出到终端）")
+	cmd.Flags().StringVar(&chatImport, "import", "", "从 JSON 文件导入对话（- 表示标准输入）")
 
 	return cmd
 }
@@ -70,24 +116,30 @@ func runChat(cmd *cobra.Command, args []string) error {
 	cfg := storage.GetConfig()
 
 	// 验证 API key
-	if cfg.AI.APIKey == "" {
-		return fmt.Errorf("AI API key 未配置，请在 ~/.tada/config.yaml 中设置")
+	apiKey, err := cfg.AI.ResolveAPIKey()
+	if err != nil {
+		return err
+	}
+	if apiKey == "" {
+		return fmt.Errorf("AI API key 未配置，请在 ~/.tada/config.yaml 中设置 api_key、api_key_env 或 api_key_command")
 	}
 
 	// 创建 AI provider
 	var aiProvider ai.AIProvider
 	switch cfg.AI.Provider {
 	case "openai":
-		aiProvider = openai.NewClient(cfg.AI.APIKey, cfg.AI.Model, cfg.AI.BaseURL)
+		aiProvider = openai.NewClient(apiKey, cfg.AI.Model, cfg.AI.BaseURL)
 	case "glm", "zhipu":
-		aiProvider = glm.NewClient(cfg.AI.APIKey, cfg.AI.Model, cfg.AI.BaseURL)
+		aiProvider = glm.NewClient(apiKey, cfg.AI.Model, cfg.AI.BaseURL)
 	default:
 		return fmt.Errorf("不支持的 provider: %s", cfg.AI.Provider)
 	}
+	// 发送给 AI 之前脱敏
+	aiProvider = privacy.WrapProvider(aiProvider, privacy.Default())
 
 	// 初始化存储
 	configDir, _ := storage.GetConfigDir()
-	conversationsDir := filepath.Join(configDir, "conversations")
+	conversationsDir := filepath.Join(configDir, storage.ConversationsDirName)
 	promptsDir := filepath.Join(configDir, "prompts")
 	memoryPromptsDir := filepath.Join(configDir, "prompts", "memory")
 
@@ -107,19 +159,28 @@ func runChat(cmd *cobra.Command, args []string) error {
 
 	// Initialize memory manager if enabled
 	if cfg.Memory.Enabled {
-		memConfig := &memory.Config{
-			Enabled:            true,
-			ShortTermMaxTokens: cfg.Memory.ShortTermMaxTokens,
-			EntityThreshold:    cfg.Memory.EntityThreshold,
-			StoragePath:        cfg.Memory.StoragePath,
-		}
 		memoryPromptLoader := memory.NewPromptLoader(memoryPromptsDir)
-		memMgr, err := memory.NewManager(memConfig, aiProvider, memoryPromptLoader)
+		memMgr, err := memory.NewManager(memoryConfig(cfg.Memory), aiProvider, memoryPromptLoader)
 		if err == nil && memMgr != nil {
+			// 按当前所在的 git 仓库（或工作目录）区分项目记忆
+			if wd, err := os.Getwd(); err == nil {
+				memMgr.SetProject(memory.DetectProject(wd))
+			}
 			manager.SetMemoryManager(memMgr)
 		}
 	}
 
+	// 控制发送给 AI 的历史长度，超出时压缩较早的对话
+	contextBuilder := conversation.NewContextBuilder(cfg.AI.Model, cfg.Chat.ContextTokens, cfg.AI.MaxTokens, cfg.Chat.MaxHistory)
+	if cfg.Chat.SummarizeHistory {
+		contextBuilder.Summarize = conversation.NewAISummarizer(aiProvider)
+	}
+	manager.SetContextBuilder(contextBuilder)
+
+	if cfg.Chat.AutoTitle {
+		manager.SetTitleGenerator(conversation.NewAITitleGenerator(aiProvider))
+	}
+
 	// 处理子命令
 	if chatList {
 		return runListConversations(manager)
@@ -133,15 +194,59 @@ func runChat(cmd *cobra.Command, args []string) error {
 		return runDeleteConversation(manager, chatDeleteID)
 	}
 
+	if chatArchiveID != "" {
+		return runArchiveConversation(manager, chatArchiveID, true)
+	}
+
+	if chatUnarchive != "" {
+		return runArchiveConversation(manager, chatUnarchive, false)
+	}
+
+	if chatExportID != "" {
+		return runExportConversation(manager, chatExportID, chatFormat, chatOutput)
+	}
+
+	if chatImport != "" {
+		return runImportConversations(manager, chatImport)
+	}
+
+	if chatReindex {
+		if err := convStorage.Reindex(); err != nil {
+			return fmt.Errorf("重建索引失败: %w", err)
+		}
+		fmt.Println("✓ 搜索索引已重建")
+		if chatSearch == "" {
+			return nil
+		}
+	}
+
+	if chatSearch != "" {
+		opts, err := searchOptions(cmd)
+		if err != nil {
+			return err
+		}
+		results, err := runSearchConversations(manager, opts)
+		if err != nil || !chatResume || len(results) == 0 {
+			return err
+		}
+		chatContinueID = results[0].Conversation.ID
+		fmt.Println()
+	}
+
 	// 创建或恢复对话
 	var conv *conversation.Conversation
-	var err error
 
 	if chatContinueID != "" {
 		conv, err = manager.Get(chatContinueID)
 		if err != nil {
 			return fmt.Errorf("对话不存在: %s", chatContinueID)
 		}
+		if chatBranch > 0 {
+			if err := manager.SwitchBranch(conv.ID, chatBranch); err != nil {
+				return fmt.Errorf("切换分支失败: %w", err)
+			}
+			fmt.Printf("🌿 已切换到分支 %d\n", chatBranch)
+		}
 		fmt.Printf("📂 恢复对话: %s (%s)\n", conv.ID, conv.PromptName)
 	} else if chatNoHistory {
 		// 使用临时对话，不保存历史
@@ -173,15 +278,69 @@ func runChat(cmd *cobra.Command, args []string) error {
 	// 运行 REPL
 	repl := terminal.NewREPL(manager, conv, !chatNoStream, maxDisplayLines)
 	repl.SetRenderer(renderer)
+	repl.SetShowReasoning(cfg.Chat.ShowReasoning)
+
+	securityPolicy := &cfg.Security
+	if securityPolicy.CommandLevel == "" {
+		securityPolicy = security.DefaultPolicy()
+	}
+
+	// 附加的文件先经过受限路径检查
+	attacher := terminal.NewAttacher(security.NewPathAccessChecker(securityPolicy))
+	repl.SetAttacher(attacher)
+
+	// 管道输入（cat log | tada chat "问题"）作为附件发送，回复后退出
+	if stdinPiped() {
+		if err := runPipedChat(repl, attacher, strings.Join(args, " ")); err != nil {
+			return err
+		}
+		return finishChatSession(manager, repl.Conversation())
+	}
 
-	fmt.Println("💬 输入消息，/help 查看命令，/exit 退出")
+	// REPL 和命令确认共用同一个输入 reader，避免缓冲的输入丢失
+	reader := bufio.NewReader(os.Stdin)
+
+	// 对话中执行命令，复用单次命令模式的安全检查
+	if cfg.Chat.AllowCommands {
+		engine := core.NewEngine(aiProvider, core.NewExecutor(30*time.Second), securityPolicy)
+		if memMgr := manager.GetMemoryManager(); memMgr != nil {
+			engine.SetMemory(memMgr, !chatNoHistory)
+		}
+		repl.SetCommandRunner(core.NewChatRunner(engine, reader))
+		repl.SetProposeCommands(cfg.Chat.ProposeCommands)
+	}
+
+	// 输入历史保存在配置目录中，临时对话只保留在内存中
+	historyPath := ""
+	if !chatNoHistory && cfg.Chat.InputHistory > 0 {
+		historyPath = filepath.Join(configDir, storage.HistoryFileName)
+	}
+	history, err := terminal.LoadHistory(historyPath, cfg.Chat.InputHistory)
+	if err != nil {
+		log.Printf("Warning: failed to load input history: %v", err)
+	}
+	editor := terminal.NewLineEditor(reader, os.Stdin, os.Stdout, history)
+	editor.SetCompleter(repl.Complete)
+
+	// 生成回复时按任意键中断
+	repl.SetKeyInterrupt(true)
+
+	fmt.Println("💬 输入消息，/help 查看命令，/exit 或 Ctrl-D 退出")
 	fmt.Println()
 
-	err = runREPLLoop(repl)
+	err = runREPLLoop(repl, editor)
 	if err != nil {
 		return err
 	}
 
+	return finishChatSession(manager, repl.Conversation())
+}
+
+// finishChatSession 结束对话，为保存的对话生成记忆
+func finishChatSession(manager *conversation.Manager, conv *conversation.Conversation) error {
+	// Let a title still being generated in the background be saved
+	manager.Wait()
+
 	// Trigger memory processing for non-ephemeral conversations
 	if !chatNoHistory {
 		fmt.Println("\n💾 正在保存对话记忆...")
@@ -198,16 +357,48 @@ func runChat(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// runREPLLoop 运行 REPL 交互循环
-func runREPLLoop(repl *terminal.REPL) error {
-	reader := bufio.NewReader(os.Stdin)
+// stdinPiped 判断标准输入是否来自管道或文件
+func stdinPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
 
-	for {
-		// 显示提示符
-		fmt.Print("👉 ")
+// runPipedChat 将标准输入作为附件和问题一起发送，显示回复后结束
+func runPipedChat(repl *terminal.REPL, attacher *terminal.Attacher, question string) error {
+	att, err := attacher.Read(terminal.StdinName, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("读取标准输入失败: %w", err)
+	}
+	if att.Content == "" && question == "" {
+		return fmt.Errorf("标准输入为空，请提供内容或问题")
+	}
+	if att.Content != "" {
+		if err := repl.Attach(att); err != nil {
+			return err
+		}
+	}
+	if question == "" {
+		question = "请阅读以下内容，总结要点并指出其中的问题。"
+	}
+	return repl.ProcessInput(question)
+}
 
+// runREPLLoop 运行 REPL 交互循环
+func runREPLLoop(repl *terminal.REPL, editor *terminal.LineEditor) error {
+	for {
 		// 读取输入
-		input, err := reader.ReadString('\n')
+		input, err := editor.ReadLine()
+		if errors.Is(err, terminal.ErrInterrupt) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			repl.DisplayExitSummary()
+			return nil
+		}
 		if err != nil {
 			return fmt.Errorf("读取输入失败: %w", err)
 		}
@@ -246,10 +437,22 @@ func runListConversations(manager *conversation.Manager) error {
 		return err
 	}
 
+	total := len(convs)
+	convs = conversation.FilterConversations(convs, conversation.ListOptions{
+		IncludeArchived: chatAll,
+		ArchivedOnly:    chatArchived,
+		Tag:             chatTag,
+	})
+
 	if len(convs) == 0 {
-		if chatToday {
+		switch {
+		case chatTag != "":
+			fmt.Printf("💬 没有带标签 #%s 的对话\n", conversation.NormalizeTag(chatTag))
+		case chatArchived:
+			fmt.Println("💬 没有已归档的对话")
+		case chatToday:
 			fmt.Println("💬 今天没有对话记录")
-		} else {
+		default:
 			fmt.Println("💬 没有对话记录")
 		}
 		return nil
@@ -259,17 +462,135 @@ func runListConversations(manager *conversation.Manager) error {
 	fmt.Println()
 
 	for _, conv := range convs {
-		fmt.Printf("  %s  [%s]  %d 条消息  %s\n",
-			conv.ID[:12],
-			conv.PromptName,
-			len(conv.Messages),
-			conv.UpdatedAt.Format("2006-01-02 15:04"),
-		)
+		fmt.Println(formatConversationLine(conv))
+	}
+
+	if hidden := total - len(convs); hidden > 0 && !chatAll && !chatArchived && chatTag == "" {
+		fmt.Printf("\n（%d 个已归档的对话未显示，使用 --all 查看）\n", hidden)
+	}
+
+	return nil
+}
+
+// formatConversationLine 格式化列表中的一行：短 ID、标题、标签和元信息
+func formatConversationLine(conv *conversation.Conversation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %s  ", shortID(conv.ID))
+	if conv.IsArchived() {
+		b.WriteString("📦 ")
+	}
+
+	title := conv.Title()
+	if title == "" {
+		title = "(空对话)"
+	}
+	b.WriteString(title)
+
+	for _, tag := range conv.Tags {
+		fmt.Fprintf(&b, " #%s", tag)
+	}
+
+	fmt.Fprintf(&b, "\n      [%s]  %d 条消息  %s",
+		conv.PromptName,
+		len(conv.ActiveMessages()),
+		conv.UpdatedAt.Format("2006-01-02 15:04"),
+	)
+	return b.String()
+}
+
+func runArchiveConversation(manager *conversation.Manager, id string, archived bool) error {
+	conv, err := manager.SetArchived(id, archived)
+	if err != nil {
+		return fmt.Errorf("对话不存在: %w", err)
 	}
 
+	if archived {
+		fmt.Printf("📦 对话已归档: %s %s\n", shortID(conv.ID), conv.Title())
+	} else {
+		fmt.Printf("✓ 对话已取消归档: %s %s\n", shortID(conv.ID), conv.Title())
+	}
 	return nil
 }
 
+// searchOptions 根据命令行参数构建搜索条件
+func searchOptions(cmd *cobra.Command) (conversation.SearchOptions, error) {
+	opts := conversation.SearchOptions{
+		Query:           chatSearch,
+		Limit:           chatLimit,
+		Tag:             chatTag,
+		IncludeArchived: chatAll,
+		ArchivedOnly:    chatArchived,
+	}
+
+	// --prompt 默认值用于新对话，只有显式指定时才作为过滤条件
+	if cmd.Flags().Changed("prompt") {
+		opts.PromptName = chatPromptName
+	}
+
+	var err error
+	if chatSince != "" {
+		if opts.Since, err = parseSearchDate(chatSince, time.Now()); err != nil {
+			return opts, err
+		}
+	}
+	if chatUntil != "" {
+		if opts.Until, err = parseSearchDate(chatUntil, time.Now()); err != nil {
+			return opts, err
+		}
+		// 包含截止日期当天
+		opts.Until = opts.Until.Add(24*time.Hour - time.Nanosecond)
+	}
+	return opts, nil
+}
+
+// parseSearchDate 解析 YYYY-MM-DD 或相对天数（如 7d）
+func parseSearchDate(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			return today.AddDate(0, 0, -n), nil
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的日期 %q，请使用 YYYY-MM-DD 或 7d", value)
+	}
+	return t, nil
+}
+
+// runSearchConversations 搜索并显示对话
+func runSearchConversations(manager *conversation.Manager, opts conversation.SearchOptions) ([]conversation.SearchResult, error) {
+	results, err := manager.Search(opts)
+	if err != nil {
+		return nil, fmt.Errorf("搜索失败: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Printf("🔍 没有找到匹配 %q 的对话\n", opts.Query)
+		return nil, nil
+	}
+
+	fmt.Printf("🔍 找到 %d 个匹配 %q 的对话:\n\n", len(results), opts.Query)
+	for i, r := range results {
+		conv := r.Conversation
+		fmt.Printf("%2d. %s  %s  [%s]  %s\n", i+1, shortID(conv.ID), conv.Title(), conv.PromptName, conv.UpdatedAt.Format("2006-01-02 15:04"))
+		if r.Snippet != "" {
+			role := conv.Messages[r.MessageIndex].Role
+			fmt.Printf("    %s: %s\n", role, conversation.Highlight(r.Snippet, r.Matches, "\033[1;33m", "\033[0m"))
+		}
+	}
+	fmt.Printf("\n恢复对话: tada chat --continue <ID>\n")
+	return results, nil
+}
+
+// shortID 返回 --list 和搜索结果中显示的短 ID
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
 func runShowConversation(manager *conversation.Manager, id string) error {
 	conv, err := manager.Get(id)
 	if err != nil {
@@ -277,19 +598,89 @@ func runShowConversation(manager *conversation.Manager, id string) error {
 	}
 
 	fmt.Printf("对话: %s\n", conv.ID)
+	if title := conv.Title(); title != "" {
+		fmt.Printf("标题: %s\n", title)
+	}
 	fmt.Printf("Prompt: %s\n", conv.PromptName)
+	if len(conv.Tags) > 0 {
+		fmt.Printf("标签: %s\n", strings.Join(conv.Tags, ", "))
+	}
+	if conv.IsArchived() {
+		fmt.Println("状态: 已归档")
+	}
 	fmt.Printf("创建时间: %s\n", conv.CreatedAt.Format("2006-01-02 15:04:05"))
-	fmt.Printf("消息数: %d\n", len(conv.Messages))
+	messages := conv.ActiveMessages()
+	fmt.Printf("消息数: %d\n", len(messages))
+	if branches := conv.Branches(); len(branches) > 1 {
+		fmt.Printf("分支数: %d（显示当前分支，--continue %s --branch <n> 切换）\n", len(branches), shortID(conv.ID))
+	}
 	fmt.Println("\n消息:")
 	fmt.Println()
 
-	for _, msg := range conv.Messages {
+	for _, msg := range messages {
+		if label := msg.IncompleteLabel(); label != "" {
+			fmt.Printf("[%s] (%s): %s\n\n", msg.Role, label, msg.Content)
+			continue
+		}
 		fmt.Printf("[%s]: %s\n\n", msg.Role, msg.Content)
 	}
 
 	return nil
 }
 
+func runExportConversation(manager *conversation.Manager, id, format, output string) error {
+	conv, err := manager.Get(id)
+	if err != nil {
+		return fmt.Errorf("对话不存在: %w", err)
+	}
+
+	// 先在内存中生成，格式错误时不会留下空文件
+	var buf bytes.Buffer
+	if err := conversation.Export(&buf, conv, format); err != nil {
+		return fmt.Errorf("导出失败: %w", err)
+	}
+
+	if output == "" || output == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+
+	if err := os.WriteFile(output, buf.Bytes(), privacy.FileMode); err != nil {
+		return fmt.Errorf("写入导出文件失败: %w", err)
+	}
+
+	fmt.Printf("✓ 对话已导出: %s\n", output)
+	return nil
+}
+
+func runImportConversations(manager *conversation.Manager, path string) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("读取导入文件失败: %w", err)
+	}
+
+	convs, err := manager.Import(data)
+	if err != nil {
+		return fmt.Errorf("导入失败: %w", err)
+	}
+
+	fmt.Printf("✓ 已导入 %d 个对话:\n", len(convs))
+	for _, conv := range convs {
+		name := conv.Name
+		if name == "" {
+			name = conv.PromptName
+		}
+		fmt.Printf("  %s  %s  %d 条消息\n", shortID(conv.ID), name, len(conv.Messages))
+	}
+	return nil
+}
+
 func runDeleteConversation(manager *conversation.Manager, id string) error {
 	err := manager.Delete(id)
 	if err != nil {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 )
 
 func TestGetChatCommand_Exists(t *testing.T) {
@@ -18,7 +19,7 @@ func TestGetChatCommand_Exists(t *testing.T) {
 func TestGetChatCommand_HasFlags(t *testing.T) {
 	cmd := getChatCommand()
 
-	flags := []string{"prompt", "continue", "list", "delete"}
+	flags := []string{"prompt", "continue", "list", "delete", "search", "since", "until", "resume", "reindex", "export", "format", "output", "import", "branch", "tag", "all", "archived", "archive", "unarchive"}
 	for _, flag := range flags {
 		if cmd.Flags().Lookup(flag) == nil {
 			t.Errorf("Expected flag '%s' to exist", flag)
@@ -50,3 +51,27 @@ func TestGetChatCommand_HasRequiredFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSearchDate(t *testing.T) {
+	now := time.Date(2026, 3, 15, 18, 30, 0, 0, time.Local)
+
+	got, err := parseSearchDate("2026-03-01", now)
+	if err != nil {
+		t.Fatalf("parseSearchDate failed: %v", err)
+	}
+	if !got.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Unexpected date: %v", got)
+	}
+
+	got, err = parseSearchDate("7d", now)
+	if err != nil {
+		t.Fatalf("parseSearchDate failed: %v", err)
+	}
+	if !got.Equal(time.Date(2026, 3, 8, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Expected start of day 7 days ago, got %v", got)
+	}
+
+	if _, err := parseSearchDate("last week", now); err == nil {
+		t.Error("Expected error for invalid date")
+	}
+}
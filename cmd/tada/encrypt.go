@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+	"github.com/Lin-Jiong-HDU/tada/internal/storage"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var encryptDecrypt bool
+
+// getEncryptCommand returns the encrypt command
+func getEncryptCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "encrypt",
+		Short: "加密已保存的对话、记忆和任务",
+		Long: `用 config.yaml 中 encryption 配置的密钥加密已保存的数据。
+
+启用加密后新写入的文件会自动加密，这个命令把之前保存的明文文件
+（对话和搜索索引、记忆、会话和任务队列、输入历史）一次性转换。
+关闭加密前先运行 tada encrypt --decrypt 把数据还原为明文。
+
+  encryption:
+    enabled: true
+    keyfile: ~/.tada.key         # 不存在时自动生成，请妥善备份
+    # 或者不设置 keyfile，使用口令（环境变量 TADA_PASSPHRASE，或在终端中输入）
+
+密钥的盐保存在 ~/.tada/encryption.json，丢失口令或密钥文件后数据无法恢复。`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, err := storage.InitConfig()
+			return err
+		},
+		RunE: runEncrypt,
+	}
+
+	secretCmd := &cobra.Command{
+		Use:   "secret",
+		Short: "加密一个配置值（例如 API key）",
+		Long: `从终端或标准输入读取一个值，输出加密后的 enc:... 字符串，
+可以替换 config.yaml 中明文的 ai.api_key。`,
+		Args: cobra.NoArgs,
+		RunE: runEncryptSecret,
+	}
+
+	cmd.Flags().BoolVar(&encryptDecrypt, "decrypt", false, "解密为明文（关闭加密前使用）")
+	cmd.AddCommand(secretCmd)
+
+	return cmd
+}
+
+func runEncrypt(cmd *cobra.Command, args []string) error {
+	c := privacy.DefaultCipher()
+	if c == nil {
+		return fmt.Errorf("加密未启用，请先在 ~/.tada/config.yaml 中设置 encryption.enabled: true")
+	}
+
+	configDir, err := storage.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+	cfg := storage.GetConfig()
+
+	infoPath := filepath.Join(configDir, storage.KeyInfoName)
+
+	if encryptDecrypt {
+		// Plain text files are allowed again before the first one is written
+		if err := privacy.MarkConverted(infoPath, false); err != nil {
+			return err
+		}
+		privacy.SetRequireEncrypted(false)
+
+		count, err := storage.ConvertStores(configDir, cfg.Memory.StoragePath, nil)
+		if err != nil {
+			return fmt.Errorf("解密失败（已解密 %d 个文件）: %w", count, err)
+		}
+		fmt.Printf("✓ 已解密 %d 个文件，现在可以关闭 encryption.enabled\n", count)
+		if privacy.IsEncryptedString(cfg.AI.APIKey) {
+			fmt.Println("⚠️  config.yaml 中的 api_key 仍是加密的，关闭加密前请换回明文或改用 api_key_env")
+		}
+		return nil
+	}
+
+	count, err := storage.ConvertStores(configDir, cfg.Memory.StoragePath, c)
+	if err != nil {
+		return fmt.Errorf("加密失败（已加密 %d 个文件）: %w", count, err)
+	}
+	// Every stored file is encrypted now, plain text files found later are refused
+	if err := privacy.MarkConverted(infoPath, true); err != nil {
+		return err
+	}
+	privacy.SetRequireEncrypted(true)
+	fmt.Printf("✓ 已加密 %d 个文件，之后不再读取明文文件\n", count)
+	if cfg.AI.APIKey != "" && !privacy.IsEncryptedString(cfg.AI.APIKey) {
+		fmt.Println("⚠️  config.yaml 中的 api_key 仍是明文，可以用 tada encrypt secret 加密，或改用 api_key_env / api_key_command")
+	}
+	return nil
+}
+
+func runEncryptSecret(cmd *cobra.Command, args []string) error {
+	c := privacy.DefaultCipher()
+	if c == nil {
+		return fmt.Errorf("加密未启用，请先在 ~/.tada/config.yaml 中设置 encryption.enabled: true")
+	}
+
+	value, err := readSecret()
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("没有输入内容")
+	}
+
+	encrypted, err := c.EncryptString(value)
+	if err != nil {
+		return err
+	}
+	fmt.Println(encrypted)
+	return nil
+}
+
+// readSecret reads a value without echoing it on a terminal, or from stdin
+func readSecret() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "要加密的值: ")
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(value)), err
+	}
+	value, err := io.ReadAll(os.Stdin)
+	return strings.TrimSpace(string(value)), err
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEncryptCommand_Exists(t *testing.T) {
+	cmd := getEncryptCommand()
+	if cmd.Use != "encrypt" {
+		t.Errorf("Expected command name 'encrypt', got '%s'", cmd.Use)
+	}
+	if cmd.Flags().Lookup("decrypt") == nil {
+		t.Error("Expected --decrypt flag")
+	}
+
+	found := false
+	for _, sub := range cmd.Commands() {
+		if sub.Name() == "secret" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected secret subcommand")
+	}
+}
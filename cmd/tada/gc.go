@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+	"github.com/Lin-Jiong-HDU/tada/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var gcDryRun bool
+
+// getGCCommand returns the gc command
+func getGCCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "清理历史任务和过期会话",
+		Long: `按照配置中的 retention 策略清理任务队列。
+
+每个会话只保留最近的若干个已结束任务，超长的输出会被截断，
+空的会话目录会被删除，长期未活动且没有待处理任务的会话会被打包到
+~/.tada/archive/<会话ID>.tar.gz。`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, err := storage.InitConfig()
+			return err
+		},
+		RunE: runGC,
+	}
+
+	cmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "只显示将要清理的内容，不做修改")
+
+	return cmd
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	configDir, err := storage.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	cfg := storage.GetConfig()
+	var pruned queue.PruneResult
+	report, err := storage.CollectGarbage(
+		filepath.Join(configDir, storage.SessionDirName),
+		filepath.Join(configDir, storage.ArchiveDirName),
+		cfg.Retention, gcDryRun, time.Now(), pruneQueue(cfg.Retention, &pruned),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to collect garbage: %w", err)
+	}
+
+	prefix := ""
+	if gcDryRun {
+		prefix = "[dry-run] "
+	}
+
+	fmt.Printf("%s清理任务: %d 个\n", prefix, pruned.Removed)
+	fmt.Printf("%s截断任务输出: %d 个\n", prefix, pruned.Truncated)
+	fmt.Printf("%s删除空会话: %d 个\n", prefix, len(report.RemovedSessions))
+	fmt.Printf("%s归档会话: %d 个\n", prefix, len(report.ArchivedSessions))
+	for _, id := range report.ArchivedSessions {
+		fmt.Printf("  → %s\n", id)
+	}
+
+	return nil
+}
+
+// retentionConfig loads the retention settings for commands that do not
+// initialize the config themselves, falling back to the defaults
+func retentionConfig() storage.RetentionConfig {
+	if _, err := storage.InitConfig(); err != nil {
+		return storage.DefaultRetentionConfig()
+	}
+	return storage.GetConfig().Retention
+}
+
+// autoPrune runs the daily automatic garbage collection. Failures are reported
+// but never stop the calling command.
+func autoPrune(cfg storage.RetentionConfig) {
+	var pruned queue.PruneResult
+	if _, err := storage.AutoCollectGarbage(cfg, pruneQueue(cfg, &pruned)); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  自动清理失败: %v\n", err)
+	}
+}
+
+// pruneQueue returns the garbage collection step for the task queue of a
+// session: finished tasks over the per-session limit are dropped and long
+// outputs truncated, both counted in pruned, and sessions with tasks still
+// waiting or running are kept.
+func pruneQueue(cfg storage.RetentionConfig, pruned *queue.PruneResult) storage.SessionFunc {
+	policy := queue.RetentionPolicy{
+		MaxCompletedTasks: cfg.MaxCompletedTasks,
+		MaxOutputBytes:    cfg.MaxOutputBytes,
+	}
+	return func(sessionID, sessionDir string, dryRun bool) (bool, error) {
+		queueFile := filepath.Join(sessionDir, "queue.json")
+		if _, err := os.Stat(queueFile); err != nil {
+			return false, nil
+		}
+
+		q := queue.NewQueue(queueFile, sessionID)
+		var result queue.PruneResult
+		if dryRun {
+			result = q.CountPrunable(policy)
+		} else {
+			var err error
+			if result, err = q.Prune(policy); err != nil {
+				return false, fmt.Errorf("failed to prune session %s: %w", sessionID, err)
+			}
+		}
+		pruned.Removed += result.Removed
+		pruned.Truncated += result.Truncated
+		return q.HasActiveTasks(), nil
+	}
+}
+
+// applyOutputLimit caps the output stored for newly finished tasks
+func applyOutputLimit(queues map[string]*queue.Manager, cfg storage.RetentionConfig) {
+	for _, q := range queues {
+		q.SetOutputLimit(cfg.MaxOutputBytes)
+	}
+}
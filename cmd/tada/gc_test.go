@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+	"github.com/Lin-Jiong-HDU/tada/internal/storage"
+)
+
+func TestGCCommand_Exists(t *testing.T) {
+	cmd := getGCCommand()
+	if cmd == nil {
+		t.Fatal("Expected gc command to exist")
+	}
+
+	if cmd.Use != "gc" {
+		t.Errorf("Expected command name 'gc', got '%s'", cmd.Use)
+	}
+}
+
+func TestGCCommand_HasDryRunFlag(t *testing.T) {
+	cmd := getGCCommand()
+	if cmd.Flags().Lookup("dry-run") == nil {
+		t.Error("Expected --dry-run flag")
+	}
+}
+
+func TestPruneQueue(t *testing.T) {
+	root := t.TempDir()
+	sessionsDir := filepath.Join(root, "sessions")
+	archiveDir := filepath.Join(root, "archive")
+	now := time.Now()
+	old := now.Add(-60 * 24 * time.Hour)
+
+	writeQueue := func(sessionID string, modTime time.Time, tasks ...*queue.Task) string {
+		dir := filepath.Join(sessionsDir, sessionID)
+		os.MkdirAll(dir, 0755)
+		data, _ := json.Marshal(queue.QueueFile{Tasks: tasks})
+		path := filepath.Join(dir, "queue.json")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, modTime, modTime)
+		return dir
+	}
+
+	// Old session with only finished tasks -> archived
+	oldDir := writeQueue("old", old, &queue.Task{ID: "t1", SessionID: "old", Status: queue.TaskStatusCompleted, UpdatedAt: old})
+	// Old session with a pending task -> kept
+	busyDir := writeQueue("busy", old, &queue.Task{ID: "t2", SessionID: "busy", Status: queue.TaskStatusPending, UpdatedAt: old})
+	// Recent session over the completed limit -> pruned
+	writeQueue("recent", now,
+		&queue.Task{ID: "t3", SessionID: "recent", Status: queue.TaskStatusCompleted, UpdatedAt: now.Add(-time.Minute)},
+		&queue.Task{ID: "t4", SessionID: "recent", Status: queue.TaskStatusFailed, UpdatedAt: now,
+			Result: &queue.ExecutionResult{Output: strings.Repeat("x", 100)}},
+	)
+
+	cfg := storage.RetentionConfig{MaxAgeDays: 30, MaxCompletedTasks: 1, MaxOutputBytes: 10}
+
+	var pruned queue.PruneResult
+	if _, err := storage.CollectGarbage(sessionsDir, archiveDir, cfg, true, now, pruneQueue(cfg, &pruned)); err != nil {
+		t.Fatalf("CollectGarbage dry run failed: %v", err)
+	}
+	if want := (queue.PruneResult{Removed: 1, Truncated: 1}); pruned != want {
+		t.Errorf("Expected %+v in dry run, got %+v", want, pruned)
+	}
+	if tasks := queue.NewQueue(filepath.Join(sessionsDir, "recent", "queue.json"), "recent").GetAllTasks(); len(tasks) != 2 {
+		t.Errorf("Expected the dry run to leave the queue alone, got %d tasks", len(tasks))
+	}
+
+	pruned = queue.PruneResult{}
+	report, err := storage.CollectGarbage(sessionsDir, archiveDir, cfg, false, now, pruneQueue(cfg, &pruned))
+	if err != nil {
+		t.Fatalf("CollectGarbage failed: %v", err)
+	}
+	if want := (queue.PruneResult{Removed: 1, Truncated: 1}); pruned != want {
+		t.Errorf("Expected %+v pruned, got %+v", want, pruned)
+	}
+	if len(report.ArchivedSessions) != 1 || report.ArchivedSessions[0] != "old" {
+		t.Errorf("Expected the old session to be archived, got %v", report.ArchivedSessions)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Error("Expected archived session directory to be removed")
+	}
+	if _, err := os.Stat(busyDir); err != nil {
+		t.Error("Expected session with pending task to be kept")
+	}
+
+	tasks := queue.NewQueue(filepath.Join(sessionsDir, "recent", "queue.json"), "recent").GetAllTasks()
+	if len(tasks) != 1 || tasks[0].ID != "t4" {
+		t.Fatalf("Expected only newest task to remain, got %d tasks", len(tasks))
+	}
+	if output := tasks[0].Result.Output; !strings.HasPrefix(output, strings.Repeat("x", 10)) || len(output) >= 100 {
+		t.Errorf("Expected the output to be truncated, got %q", output)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Lin-Jiong-HDU/tada/internal/core"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 	"github.com/Lin-Jiong-HDU/tada/internal/storage"
 	"github.com/spf13/cobra"
 )
@@ -48,9 +49,14 @@ var quickCmd = &cobra.Command{
 		input := args[0]
 
 		// Validate config
-		if cfg.AI.APIKey == "" {
+		apiKey, err := cfg.AI.ResolveAPIKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		if apiKey == "" {
 			fmt.Fprintf(os.Stderr, "❌ Error: AI API key not configured. Please set it in ~/.tada/config.yaml\n")
-			fmt.Fprintf(os.Stderr, "Example:\n  ai:\n    api_key: sk-xxx\n")
+			fmt.Fprintf(os.Stderr, "Example:\n  ai:\n    api_key: sk-xxx          # or api_key_env: OPENAI_API_KEY\n")
 			os.Exit(1)
 		}
 
@@ -58,13 +64,15 @@ var quickCmd = &cobra.Command{
 		var aiProvider ai.AIProvider
 		switch cfg.AI.Provider {
 		case "openai":
-			aiProvider = openai.NewClient(cfg.AI.APIKey, cfg.AI.Model, cfg.AI.BaseURL)
+			aiProvider = openai.NewClient(apiKey, cfg.AI.Model, cfg.AI.BaseURL)
 		case "glm", "zhipu":
-			aiProvider = glm.NewClient(cfg.AI.APIKey, cfg.AI.Model, cfg.AI.BaseURL)
+			aiProvider = glm.NewClient(apiKey, cfg.AI.Model, cfg.AI.BaseURL)
 		default:
 			fmt.Fprintf(os.Stderr, "❌ Error: unsupported provider '%s' (supported: openai, glm)\n", cfg.AI.Provider)
 			os.Exit(1)
 		}
+		// Secrets are redacted before anything is sent to the provider
+		aiProvider = privacy.WrapProvider(aiProvider, privacy.Default())
 
 		executor := core.NewExecutor(30 * time.Second)
 
@@ -76,6 +84,14 @@ var quickCmd = &cobra.Command{
 
 		engine := core.NewEngine(aiProvider, executor, securityPolicy)
 
+		// Memory tells the engine about the user's environment and learns the
+		// commands they run (read only in incognito mode)
+		if cfg.Memory.Enabled {
+			if memMgr, err := openMemory(); err == nil && memMgr != nil {
+				engine.SetMemory(memMgr, !incognito)
+			}
+		}
+
 		// Initialize queue with current session
 		if !incognito {
 			session := storage.GetCurrentSession()
@@ -83,6 +99,8 @@ var quickCmd = &cobra.Command{
 				configDir, _ := storage.GetConfigDir()
 				queueFile := filepath.Join(configDir, storage.SessionDirName, session.ID, "queue.json")
 				q := queue.NewQueue(queueFile, session.ID)
+				q.SetOutputLimit(cfg.Retention.MaxOutputBytes)
+				q.SetApprovalPolicy(&cfg.Approval)
 				engine.SetQueue(q)
 			}
 		}
@@ -100,6 +118,9 @@ func init() {
 	rootCmd.AddCommand(getChatCommand())
 	rootCmd.AddCommand(getTasksCommand())
 	rootCmd.AddCommand(getRunCommand())
+	rootCmd.AddCommand(getGCCommand())
+	rootCmd.AddCommand(getMemoryCommand())
+	rootCmd.AddCommand(getEncryptCommand())
 	rootCmd.AddCommand(quickCmd) // Hidden command for backward compatibility
 
 	quickCmd.PersistentFlags().BoolVarP(&incognito, "incognito", "i", false, "Run in incognito mode (don't save history)")
@@ -121,7 +142,7 @@ func main() {
 	if len(os.Args) > 1 {
 		arg := os.Args[1]
 		// Only treat as command if it's an exact match without path separators and not a flag
-		if arg != "chat" && arg != "tasks" && arg != "run" && arg != "help" &&
+		if arg != "chat" && arg != "tasks" && arg != "run" && arg != "gc" && arg != "memory" && arg != "encrypt" && arg != "help" &&
 			!containsPathSeparator(arg) && !isFlag(arg) {
 			// Use quick command for single-shot command execution
 			args := append([]string{"quick"}, os.Args[1:]...)
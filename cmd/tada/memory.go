@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/memory"
+	"github.com/Lin-Jiong-HDU/tada/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	memoryClearShort bool
+	memoryClearLong  bool
+	memoryYes        bool
+	memoryOutput     string
+	memoryMerge      bool
+	memoryProject    bool
+	memoryCategory   string
+)
+
+// getMemoryCommand returns the memory command
+func getMemoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "memory",
+		Short: "查看和编辑记忆",
+		Long: `查看、修改和删除 tada 在对话中记住的内容。
+
+记忆保存在 memory.storage_path 下：
+  profile.json     用户画像的事实，带来源对话、时间和置信度（长期记忆）
+  profile_history.json  用户画像的历史版本，可用 rollback 回滚
+  user_profile.md  用户画像的 Markdown 版本
+  notes.json       用 add 或对话中 /remember 记住的事实（长期记忆）
+  entities.json    提到过的技术、工具等实体，按别名归一，热度随时间衰减（长期记忆）
+  summaries.json   最近对话的摘要（短期记忆）
+  summaries_archive.json  超出短期记忆的旧摘要，按相关性检索
+  projects/        每个项目（git 仓库或工作目录）的笔记
+
+加上 --project 时 show、add、edit、forget、clear 操作当前目录所属项目的笔记。`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, err := storage.InitConfig()
+			return err
+		},
+	}
+	cmd.PersistentFlags().BoolVarP(&memoryProject, "project", "p", false, "操作当前项目的笔记")
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "显示用户画像、对话摘要和实体",
+		Args:  cobra.NoArgs,
+		RunE:  runMemoryShow,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <内容>",
+		Short: "记住一条事实（--project 时记入项目笔记）",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runMemoryAdd,
+	}
+
+	editCmd := &cobra.Command{
+		Use:   "edit",
+		Short: "用 $EDITOR 编辑用户画像（--project 时编辑项目笔记）",
+		Args:  cobra.NoArgs,
+		RunE:  runMemoryEdit,
+	}
+
+	forgetCmd := &cobra.Command{
+		Use:   "forget <实体|摘要ID|事实ID>",
+		Short: "删除一个实体、一条对话摘要或记住的事实（--project 时删除项目笔记）",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runMemoryForget,
+	}
+
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "清除记忆（默认清除全部）",
+		Args:  cobra.NoArgs,
+		RunE:  runMemoryClear,
+	}
+	clearCmd.Flags().BoolVar(&memoryClearShort, "short", false, "只清除短期记忆（对话摘要）")
+	clearCmd.Flags().BoolVar(&memoryClearLong, "long", false, "只清除长期记忆（用户画像、实体和记住的事实）")
+	clearCmd.Flags().BoolVarP(&memoryYes, "yes", "y", false, "不询问直接清除")
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "导出全部记忆为 JSON",
+		Args:  cobra.NoArgs,
+		RunE:  runMemoryExport,
+	}
+	exportCmd.Flags().StringVarP(&memoryOutput, "output", "o", "", "导出到文件（默认输出到终端）")
+
+	importCmd := &cobra.Command{
+		Use:   "import <文件>",
+		Short: "从 JSON 导入记忆（- 表示标准输入）",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runMemoryImport,
+	}
+	importCmd.Flags().BoolVar(&memoryMerge, "merge", false, "与现有记忆合并，而不是替换")
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "显示用户画像的修改历史",
+		Args:  cobra.NoArgs,
+		RunE:  runMemoryHistory,
+	}
+
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback <版本>",
+		Short: "把用户画像回滚到历史版本",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runMemoryRollback,
+	}
+
+	mergeCmd := &cobra.Command{
+		Use:   "merge <实体> <别名>...",
+		Short: "把别名合并到一个实体，之后提到别名时计入该实体",
+		Args:  cobra.MinimumNArgs(2),
+		RunE:  runMemoryMerge,
+	}
+	mergeCmd.Flags().StringVar(&memoryCategory, "category", "", "实体类别（language、framework、tool、project、person、topic）")
+
+	cmd.AddCommand(showCmd, addCmd, editCmd, forgetCmd, clearCmd, exportCmd, importCmd, historyCmd, rollbackCmd, mergeCmd)
+	return cmd
+}
+
+// memoryConfig converts the memory section of the config
+func memoryConfig(cfg storage.MemoryConfig) *memory.Config {
+	return &memory.Config{
+		Enabled:            true,
+		ShortTermMaxTokens: cfg.ShortTermMaxTokens,
+		EntityThreshold:    cfg.EntityThreshold,
+		StoragePath:        cfg.StoragePath,
+		RetrievalTopK:      cfg.RetrievalTopK,
+		RetrievalTokens:    cfg.RetrievalTokens,
+		CheckpointTurns:    cfg.CheckpointTurns,
+		EntityHalfLifeDays: cfg.EntityHalfLifeDays,
+	}
+}
+
+// openMemory opens the memory store from the config, scoped to the project of
+// the working directory. Memory can be inspected and edited even when it is
+// disabled for chat.
+func openMemory() (*memory.Manager, error) {
+	mgr, err := memory.NewManager(memoryConfig(storage.GetConfig().Memory), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if wd, err := os.Getwd(); err == nil {
+		mgr.SetProject(memory.DetectProject(wd))
+	}
+	return mgr, nil
+}
+
+// projectNotes returns the notes of the current project for --project
+func projectNotes(mgr *memory.Manager) (*memory.NoteStore, error) {
+	if mgr.ProjectNotes() == nil {
+		return nil, fmt.Errorf("无法确定当前项目")
+	}
+	return mgr.ProjectNotes(), nil
+}
+
+func runMemoryShow(cmd *cobra.Command, args []string) error {
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	if memoryProject {
+		return printProjectNotes(os.Stdout, mgr)
+	}
+	printMemory(os.Stdout, mgr)
+	return nil
+}
+
+// printProjectNotes 显示当前项目的笔记
+func printProjectNotes(w io.Writer, mgr *memory.Manager) error {
+	notes, err := projectNotes(mgr)
+	if err != nil {
+		return err
+	}
+	project := mgr.Project()
+	list := notes.GetNotes()
+	fmt.Fprintf(w, "📁 项目: %s (%s)\n", project.Name, project.Root)
+	fmt.Fprintf(w, "📌 项目笔记: %d 条\n", len(list))
+	for _, note := range list {
+		fmt.Fprintf(w, "  %s  %s  %s\n", shortID(note.ID), note.CreatedAt.Format("2006-01-02 15:04"), note.Text)
+	}
+	return nil
+}
+
+// printMemory 显示用户画像、对话摘要和实体
+func printMemory(w io.Writer, mgr *memory.Manager) {
+	if version := mgr.LongTerm().ProfileVersion(); version > 0 {
+		fmt.Fprintf(w, "📝 用户画像（版本 %d）:\n", version)
+	} else {
+		fmt.Fprintln(w, "📝 用户画像:")
+	}
+	if profile := mgr.LongTerm().GetProfileMarkdown(); profile != "" {
+		fmt.Fprintln(w, profile)
+	} else {
+		fmt.Fprintln(w, "  (空)")
+	}
+
+	if facts := mgr.Notes().GetNotes(); len(facts) > 0 {
+		fmt.Fprintf(w, "\n📌 记住的事实: %d 条\n", len(facts))
+		for _, note := range facts {
+			fmt.Fprintf(w, "  %s  %s  %s\n", shortID(note.ID), note.CreatedAt.Format("2006-01-02 15:04"), note.Text)
+		}
+	}
+
+	summaries := mgr.ShortTerm().GetSummaries()
+	tokens := 0
+	for _, s := range summaries {
+		tokens += s.Tokens
+	}
+	fmt.Fprintf(w, "\n🕑 对话摘要: %d 条（约 %d tokens）\n", len(summaries), tokens)
+	for _, s := range summaries {
+		fmt.Fprintf(w, "  %s  %s  %s\n", shortID(s.ID), s.Timestamp.Format("2006-01-02 15:04"), s.Summary)
+	}
+	if archived := mgr.ShortTerm().GetArchive(); len(archived) > 0 {
+		fmt.Fprintf(w, "\n🗄  已归档的摘要: %d 条（只在与当前问题相关时使用）\n", len(archived))
+		for _, s := range archived {
+			fmt.Fprintf(w, "  %s  %s  %s\n", shortID(s.ID), s.Timestamp.Format("2006-01-02 15:04"), s.Summary)
+		}
+	}
+
+	if notes := mgr.ProjectNotes(); notes != nil {
+		if count := len(notes.GetNotes()); count > 0 {
+			fmt.Fprintf(w, "\n📁 当前项目 %s: %d 条笔记（tada memory show --project 查看）\n", mgr.Project().Name, count)
+		}
+	}
+
+	entities := mgr.LongTerm().GetEntities()
+	fmt.Fprintf(w, "\n🏷  实体: %d 个\n", len(entities))
+	for _, name := range mgr.LongTerm().EntityNames() {
+		entity := entities[name]
+		runs := ""
+		if entity.Runs > 0 {
+			runs = fmt.Sprintf("  执行 %d 次", entity.Runs)
+			if entity.Failures > 0 {
+				runs += fmt.Sprintf("（失败 %d 次）", entity.Failures)
+			}
+		}
+		var tags []string
+		if entity.Category != "" {
+			tags = append(tags, entity.Category)
+		}
+		if entity.Promoted {
+			tags = append(tags, "已加入画像")
+		}
+		tag := ""
+		if len(tags) > 0 {
+			tag = "  [" + strings.Join(tags, "，") + "]"
+		}
+		fmt.Fprintf(w, "  %-20s %3d 次  热度 %5.1f  最近 %s%s%s\n", name, entity.Count, mgr.LongTerm().EntityScore(name), entity.LastSeen.Format("2006-01-02"), runs, tag)
+	}
+}
+
+func runMemoryAdd(cmd *cobra.Command, args []string) error {
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	text := strings.Join(args, " ")
+
+	if memoryProject {
+		notes, err := projectNotes(mgr)
+		if err != nil {
+			return err
+		}
+		note, err := notes.AddNote(text)
+		if err != nil {
+			return fmt.Errorf("保存项目笔记失败: %w", err)
+		}
+		fmt.Printf("✓ 已记入项目 %s: %s\n", mgr.Project().Name, note.Text)
+		return nil
+	}
+
+	note, err := mgr.Remember(memory.ScopeGlobal, "", text)
+	if err != nil {
+		return fmt.Errorf("保存失败: %w", err)
+	}
+	fmt.Printf("✓ 已记住: %s\n", note.Text)
+	return nil
+}
+
+func runMemoryEdit(cmd *cobra.Command, args []string) error {
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	if memoryProject {
+		return editProjectNotes(mgr)
+	}
+
+	before := mgr.LongTerm().GetProfileMarkdown()
+	after, err := editText(before, "user_profile-*.md")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(after) == strings.TrimSpace(before) {
+		fmt.Println("用户画像未修改")
+		return nil
+	}
+
+	if err := mgr.LongTerm().SetProfileMarkdown(after); err != nil {
+		return fmt.Errorf("保存用户画像失败: %w", err)
+	}
+	fmt.Println("✓ 用户画像已更新")
+	return nil
+}
+
+// editProjectNotes 用编辑器编辑项目笔记，每行一条
+func editProjectNotes(mgr *memory.Manager) error {
+	notes, err := projectNotes(mgr)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 项目 %s 的笔记，每行一条，以 # 开头的行会被忽略\n", mgr.Project().Root)
+	var before []string
+	for _, note := range notes.GetNotes() {
+		before = append(before, note.Text)
+		b.WriteString(note.Text + "\n")
+	}
+
+	edited, err := editText(b.String(), "project_notes-*.md")
+	if err != nil {
+		return err
+	}
+	after := parseNoteLines(edited)
+	if strings.Join(after, "\n") == strings.Join(before, "\n") {
+		fmt.Println("项目笔记未修改")
+		return nil
+	}
+
+	if err := notes.SetNotes(after); err != nil {
+		return fmt.Errorf("保存项目笔记失败: %w", err)
+	}
+	fmt.Printf("✓ 项目笔记已更新: %d 条\n", len(after))
+	return nil
+}
+
+// parseNoteLines splits edited notes into one note per line, skipping
+// comments and blank lines and dropping list markers
+func parseNoteLines(text string) []string {
+	var notes []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, marker := range []string{"-", "*"} {
+			if line == marker || strings.HasPrefix(line, marker+" ") {
+				line = strings.TrimSpace(line[len(marker):])
+			}
+		}
+		if line != "" {
+			notes = append(notes, line)
+		}
+	}
+	return notes
+}
+
+// editText opens text in $VISUAL or $EDITOR and returns the edited content
+func editText(text, pattern string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// $EDITOR may contain arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	editCmd := exec.Command(parts[0], append(parts[1:], f.Name())...)
+	editCmd.Stdin = os.Stdin
+	editCmd.Stdout = os.Stdout
+	editCmd.Stderr = os.Stderr
+	if err := editCmd.Run(); err != nil {
+		return "", fmt.Errorf("编辑器退出异常: %w", err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func runMemoryForget(cmd *cobra.Command, args []string) error {
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	if memoryProject {
+		notes, err := projectNotes(mgr)
+		if err != nil {
+			return err
+		}
+		note, err := notes.RemoveNote(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已删除项目笔记: %s\n", note.Text)
+		return nil
+	}
+	removed, err := mgr.Forget(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已删除 %s\n", removed)
+	return nil
+}
+
+func runMemoryClear(cmd *cobra.Command, args []string) error {
+	if memoryProject {
+		return clearProjectNotes()
+	}
+
+	short, long := memoryClearShort, memoryClearLong
+	if !short && !long {
+		short, long = true, true
+	}
+
+	var what []string
+	if short {
+		what = append(what, "短期记忆（对话摘要）")
+	}
+	if long {
+		what = append(what, "长期记忆（用户画像、实体和记住的事实）")
+	}
+	if !memoryYes && !confirm(fmt.Sprintf("确定清除%s？[y/N] ", strings.Join(what, "和"))) {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	if err := mgr.Clear(short, long); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已清除%s\n", strings.Join(what, "和"))
+	return nil
+}
+
+// clearProjectNotes 清除当前项目的笔记
+func clearProjectNotes() error {
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	notes, err := projectNotes(mgr)
+	if err != nil {
+		return err
+	}
+	if !memoryYes && !confirm(fmt.Sprintf("确定清除项目 %s 的笔记？[y/N] ", mgr.Project().Name)) {
+		fmt.Println("已取消")
+		return nil
+	}
+	if err := notes.Clear(); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已清除项目 %s 的笔记\n", mgr.Project().Name)
+	return nil
+}
+
+func runMemoryHistory(cmd *cobra.Command, args []string) error {
+	if memoryProject {
+		return fmt.Errorf("history 不支持 --project")
+	}
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	printProfileHistory(os.Stdout, mgr)
+	return nil
+}
+
+// printProfileHistory 显示用户画像的各个版本及其修改
+func printProfileHistory(w io.Writer, mgr *memory.Manager) {
+	history := mgr.LongTerm().ProfileHistory()
+	if len(history) == 0 {
+		fmt.Fprintln(w, "用户画像还没有历史版本")
+		return
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		version := history[i]
+		note := ""
+		if version.Note != "" {
+			note = "  " + version.Note
+		}
+		fmt.Fprintf(w, "v%d  %s  来源 %s%s\n", version.Version, version.Time.Format("2006-01-02 15:04"), version.Source, note)
+		for _, change := range version.Changes {
+			switch change.Op {
+			case memory.OpAdd:
+				fmt.Fprintf(w, "  + [%s] %s\n", change.Category, change.Value)
+			case memory.OpRemove:
+				fmt.Fprintf(w, "  - [%s] %s\n", change.Category, change.Value)
+			case memory.OpUpdate:
+				fmt.Fprintf(w, "  ~ [%s] %s → %s\n", change.Category, change.OldValue, change.Value)
+			}
+		}
+	}
+}
+
+func runMemoryRollback(cmd *cobra.Command, args []string) error {
+	if memoryProject {
+		return fmt.Errorf("rollback 不支持 --project")
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(args[0], "v"))
+	if err != nil {
+		return fmt.Errorf("无效的版本: %s", args[0])
+	}
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	if err := mgr.LongTerm().RollbackProfile(version); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 用户画像已回滚到版本 %d（当前版本 %d）\n", version, mgr.LongTerm().ProfileVersion())
+	return nil
+}
+
+func runMemoryMerge(cmd *cobra.Command, args []string) error {
+	if memoryProject {
+		return fmt.Errorf("merge 不支持 --project")
+	}
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	if err := mgr.LongTerm().MergeEntities(args[0], args[1:], memoryCategory); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已把 %s 合并到 %s\n", strings.Join(args[1:], "、"), args[0])
+	return nil
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(question string) bool {
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func runMemoryExport(cmd *cobra.Command, args []string) error {
+	if memoryProject {
+		return fmt.Errorf("export 不支持 --project")
+	}
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(mgr.Export(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("导出失败: %w", err)
+	}
+	data = append(data, '\n')
+
+	if memoryOutput == "" || memoryOutput == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(memoryOutput, data, 0600); err != nil {
+		return fmt.Errorf("写入导出文件失败: %w", err)
+	}
+	fmt.Printf("✓ 记忆已导出: %s\n", memoryOutput)
+	return nil
+}
+
+func runMemoryImport(cmd *cobra.Command, args []string) error {
+	if memoryProject {
+		return fmt.Errorf("import 不支持 --project")
+	}
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("读取导入文件失败: %w", err)
+	}
+
+	snapshot, err := memory.ParseSnapshot(data)
+	if err != nil {
+		return fmt.Errorf("导入失败: %w", err)
+	}
+
+	mgr, err := openMemory()
+	if err != nil {
+		return err
+	}
+	if err := mgr.Import(snapshot, memoryMerge); err != nil {
+		return fmt.Errorf("导入失败: %w", err)
+	}
+
+	action := "替换"
+	if memoryMerge {
+		action = "合并"
+	}
+	fmt.Printf("✓ 已%s导入记忆: %d 条摘要，%d 个实体，%d 条事实\n", action, len(snapshot.Summaries), len(snapshot.Entities), len(snapshot.Notes))
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/memory"
+)
+
+func TestMemoryCommand_Subcommands(t *testing.T) {
+	cmd := getMemoryCommand()
+	if cmd.Use != "memory" {
+		t.Errorf("Expected command name 'memory', got '%s'", cmd.Use)
+	}
+
+	for _, name := range []string{"show", "add", "edit", "forget", "clear", "export", "import", "history", "rollback", "merge"} {
+		sub, _, err := cmd.Find([]string{name})
+		if err != nil || sub == cmd {
+			t.Errorf("Expected subcommand %s", name)
+		}
+	}
+
+	if cmd.PersistentFlags().Lookup("project") == nil {
+		t.Error("Expected memory --project flag")
+	}
+
+	clearCmd, _, _ := cmd.Find([]string{"clear"})
+	for _, flag := range []string{"short", "long", "yes"} {
+		if clearCmd.Flags().Lookup(flag) == nil {
+			t.Errorf("Expected clear --%s flag", flag)
+		}
+	}
+}
+
+func TestPrintMemory(t *testing.T) {
+	config := memory.DefaultConfig()
+	config.StoragePath = t.TempDir()
+	mgr, err := memory.NewManager(config, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.LongTerm().SetProfileMarkdown("- Prefers Go")
+	mgr.LongTerm().UpdateEntity("Go")
+	mgr.LongTerm().UpdateEntity("Go")
+	mgr.ShortTerm().AddSummary(&memory.Summary{ID: "0123456789abcdef", Summary: "Discussed Go", Tokens: 4})
+
+	var buf bytes.Buffer
+	printMemory(&buf, mgr)
+	out := buf.String()
+	for _, want := range []string{"- Prefers Go", "对话摘要: 1 条", "0123456789ab", "Discussed Go", "实体: 1 个", "2 次"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPrintProfileHistory(t *testing.T) {
+	config := memory.DefaultConfig()
+	config.StoragePath = t.TempDir()
+	mgr, err := memory.NewManager(config, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	printProfileHistory(&buf, mgr)
+	if !strings.Contains(buf.String(), "还没有历史版本") {
+		t.Errorf("unexpected output for empty history:\n%s", buf.String())
+	}
+
+	mgr.LongTerm().SetProfileMarkdown("## Shell\n- bash")
+	mgr.LongTerm().SetProfileMarkdown("## Shell\n- zsh")
+	buf.Reset()
+	printProfileHistory(&buf, mgr)
+	out := buf.String()
+	for _, want := range []string{"v2", "v1", "+ [shell] zsh", "- [shell] bash", "来源 user"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "v2") > strings.Index(out, "v1") {
+		t.Errorf("expected newest version first:\n%s", out)
+	}
+}
+
+func TestParseNoteLines(t *testing.T) {
+	text := "# 项目笔记\n- Uses Go 1.25\n\n* Run make lint before pushing\nDeploy with Helm\n-  \n"
+	got := parseNoteLines(text)
+	want := []string{"Uses Go 1.25", "Run make lint before pushing", "Deploy with Helm"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("parseNoteLines = %q, want %q", got, want)
+	}
+}
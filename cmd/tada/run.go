@@ -34,11 +34,16 @@ func runApprovedTasks(cmd *cobra.Command, args []string) error {
 
 	sessionsDir := filepath.Join(configDir, storage.SessionDirName)
 
+	// Apply retention before loading so old history does not pile up
+	retention := retentionConfig()
+	autoPrune(retention)
+
 	// Load all queues
 	queues, _, err := loadAllQueues(sessionsDir)
 	if err != nil {
 		return fmt.Errorf("failed to load queues: %w", err)
 	}
+	applyOutputLimit(queues, retention)
 
 	if len(queues) == 0 {
 		fmt.Println("没有找到任务队列")
@@ -47,6 +52,7 @@ func runApprovedTasks(cmd *cobra.Command, args []string) error {
 
 	// Create executor
 	executor := core.NewExecutor(30 * time.Second)
+	notifier := taskNotifier()
 	ctx := context.Background()
 
 	totalExecuted := 0
@@ -70,6 +76,7 @@ func runApprovedTasks(cmd *cobra.Command, args []string) error {
 		fmt.Printf("会话 %s: 执行 %d 个已批准任务...\n", sessionID, approvedCount)
 
 		taskExecutor := execution.NewTaskExecutor(q, executor)
+		taskExecutor.SetNotifier(notifier)
 		results, err := taskExecutor.ExecuteAllApproved(ctx)
 
 		executed := len(results)
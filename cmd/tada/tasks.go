@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/core"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/execution"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/notify"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/tui"
 	"github.com/Lin-Jiong-HDU/tada/internal/storage"
@@ -23,7 +25,8 @@ func getTasksCommand() *cobra.Command {
 		Short: "管理待授权命令队列",
 		Long: `打开 TUI 界面管理需要授权的命令。
 
-查看、授权或拒绝待授权的异步命令。`,
+查看、授权或拒绝待授权的异步命令，浏览历史任务的输出、错误和退出码，
+并按状态、会话或关键字筛选。`,
 		RunE: runTasks,
 	}
 }
@@ -37,19 +40,19 @@ func runTasks(cmd *cobra.Command, args []string) error {
 
 	sessionsDir := filepath.Join(configDir, storage.SessionDirName)
 
+	// Apply retention before loading so old history does not pile up
+	retention := retentionConfig()
+	autoPrune(retention)
+
 	// Load all queues and tasks
 	queues, allTasks, err := loadAllQueues(sessionsDir)
 	if err != nil {
 		return fmt.Errorf("failed to load tasks: %w", err)
 	}
+	applyOutputLimit(queues, retention)
 
-	// Filter pending tasks
-	var pendingTasks []*queue.Task
-	for _, task := range allTasks {
-		if task.Status == queue.TaskStatusPending {
-			pendingTasks = append(pendingTasks, task)
-		}
-	}
+	// Tasks started from the TUI keep running in the background, so tell the user when they finish
+	notifier := taskNotifier()
 
 	// Create a cancellable context tied to the TUI lifetime
 	ctx, cancel := context.WithCancel(context.Background())
@@ -79,14 +82,19 @@ func runTasks(cmd *cobra.Command, args []string) error {
 				return tui.AuthorizeResultMsg{TaskID: taskID, Success: false}
 			}
 
-			// Approve the task
-			if err := q.ApproveTask(taskID); err != nil {
+			// Record the approval; tasks under a multi-party rule may need more
+			task, err := q.ApproveTaskAs(taskID, queue.CurrentApprover())
+			if err != nil {
 				return tui.AuthorizeResultMsg{TaskID: taskID, Success: false}
 			}
+			if task.Status != queue.TaskStatusApproved {
+				return tui.AuthorizeResultMsg{TaskID: taskID, Success: true, AwaitingApprovals: true}
+			}
 
 			// Execute the task immediately using the shared cancellable context
 			executor := core.NewExecutor(30 * time.Second)
 			taskExecutor := execution.NewTaskExecutor(q, executor)
+			taskExecutor.SetNotifier(notifier)
 
 			go func() {
 				if err := taskExecutor.ExecuteTask(ctx, taskID); err != nil {
@@ -110,8 +118,12 @@ func runTasks(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Create TUI model with persistence handlers
-	model := tui.NewModelWithOptions(pendingTasks, onAuthorize, onReject, taskReloadFunc)
+	// Follow task progress and output through queue events
+	events := subscribeAll(ctx, queues)
+
+	// Create TUI model with persistence handlers; the TUI filters by status itself
+	// so that completed and failed tasks from earlier runs can be browsed too
+	model := tui.NewModelWithEvents(allTasks, onAuthorize, onReject, taskReloadFunc, events)
 
 	// Run TUI
 	p := tea.NewProgram(model, tea.WithAltScreen())
@@ -122,6 +134,14 @@ func runTasks(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// taskNotifier builds the notifier for finished tasks from the config
+func taskNotifier() *notify.Notifier {
+	if _, err := storage.InitConfig(); err != nil {
+		return notify.New(notify.DefaultConfig())
+	}
+	return notify.New(storage.GetConfig().Notify)
+}
+
 // loadAllQueues loads all queue managers and their tasks
 func loadAllQueues(sessionsDir string) (map[string]*queue.Manager, []*queue.Task, error) {
 	queues := make(map[string]*queue.Manager)
@@ -141,6 +161,11 @@ func loadAllQueues(sessionsDir string) (map[string]*queue.Manager, []*queue.Task
 			sessionDir := filepath.Join(sessionsDir, entry.Name())
 			queueFile := filepath.Join(sessionDir, "queue.json")
 
+			// Sessions without a queue have no tasks to show
+			if _, err := os.Stat(queueFile); err != nil {
+				continue
+			}
+
 			// Create queue manager
 			q := queue.NewQueue(queueFile, entry.Name())
 			tasks := q.GetAllTasks()
@@ -153,6 +178,44 @@ func loadAllQueues(sessionsDir string) (map[string]*queue.Manager, []*queue.Task
 	return queues, allTasks, nil
 }
 
+// subscribeAll merges the event streams of all queues into one channel.
+// The subscriptions end and the channel closes when ctx is cancelled.
+func subscribeAll(ctx context.Context, queues map[string]*queue.Manager) <-chan queue.Event {
+	merged := make(chan queue.Event, 64)
+
+	var wg sync.WaitGroup
+	for _, q := range queues {
+		events, unsubscribe := q.Subscribe()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer unsubscribe()
+			for {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						return
+					}
+					select {
+					case merged <- event:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	return merged
+}
+
 // findQueueForTask finds the queue manager that contains the given task
 func findQueueForTask(queues map[string]*queue.Manager, taskID string) *queue.Manager {
 	for _, q := range queues {
@@ -14,23 +14,6 @@ import (
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 )
 
-const (
-	defaultSystemPrompt = `You are tada, a terminal AI assistant. Your job is to understand user requests and convert them into shell commands.
-
-Rules:
-1. Return ONLY valid JSON
-2. For simple requests, return a single command
-3. Explain your reasoning in the "reason" field
-4. Mark dangerous commands (rm, chmod, etc.) with needs_confirm: true
-
-Response format:
-{
-  "commands": [{"cmd": "command", "args": ["arg1", "arg2"]}],
-  "reason": "explanation",
-  "needs_confirm": false
-}`
-)
-
 // Client implements AIProvider for OpenAI
 type Client struct {
 	apiKey  string
@@ -50,7 +33,7 @@ func NewClient(apiKey, model, baseURL string) *Client {
 // ParseIntent parses user input and returns intent
 func (c *Client) ParseIntent(ctx context.Context, input string, systemPrompt string) (*ai.Intent, error) {
 	if systemPrompt == "" {
-		systemPrompt = defaultSystemPrompt
+		systemPrompt = ai.DefaultIntentPrompt
 	}
 
 	prompt := fmt.Sprintf("User request: %s\n\nConvert this to shell commands. Return JSON only.", input)
@@ -147,11 +130,13 @@ func (c *Client) parseIntentResponse(response string) (*ai.Intent, error) {
 }
 
 // ChatStream 流式对话
-func (c *Client) ChatStream(ctx context.Context, messages []ai.Message) (<-chan string, error) {
+func (c *Client) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
 	reqBody := map[string]interface{}{
 		"model":    c.model,
 		"messages": messages,
 		"stream":   true, // 启用流式
+		// 在最后一块中返回令牌用量
+		"stream_options": map[string]bool{"include_usage": true},
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -180,13 +165,25 @@ func (c *Client) ChatStream(ctx context.Context, messages []ai.Message) (<-chan
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	ch := make(chan string)
+	ch := make(chan ai.StreamEvent)
 
 	go func() {
 		defer resp.Body.Close()
 		defer close(ch)
 
+		// 调用方取消后不再阻塞发送，返回 false 时关闭连接并退出
+		send := func(event ai.StreamEvent) bool {
+			select {
+			case ch <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		finished := false
 		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 		for scanner.Scan() {
 			line := scanner.Text()
 
@@ -197,6 +194,7 @@ func (c *Client) ChatStream(ctx context.Context, messages []ai.Message) (<-chan
 
 			data := strings.TrimPrefix(line, "data: ")
 			if data == "[DONE]" {
+				finished = true
 				break
 			}
 
@@ -204,18 +202,53 @@ func (c *Client) ChatStream(ctx context.Context, messages []ai.Message) (<-chan
 				Choices []struct {
 					Delta struct {
 						Content string `json:"content"`
+						// 推理模型的思考过程
+						ReasoningContent string `json:"reasoning_content"`
 					} `json:"delta"`
+					FinishReason string `json:"finish_reason"`
 				} `json:"choices"`
+				Usage *ai.Usage `json:"usage"`
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
 			}
 
 			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-				continue
+				send(ai.StreamEvent{Err: fmt.Errorf("failed to decode stream chunk: %w", err)})
+				return
+			}
+			if chunk.Error != nil {
+				send(ai.StreamEvent{Err: fmt.Errorf("API error: %s", chunk.Error.Message)})
+				return
 			}
 
+			event := ai.StreamEvent{Usage: chunk.Usage}
 			if len(chunk.Choices) > 0 {
-				content := chunk.Choices[0].Delta.Content
-				ch <- content
+				event.Content = chunk.Choices[0].Delta.Content
+				event.Reasoning = chunk.Choices[0].Delta.ReasoningContent
+				event.FinishReason = chunk.Choices[0].FinishReason
+			}
+			if event.FinishReason != "" {
+				finished = true
 			}
+			if event == (ai.StreamEvent{}) {
+				continue
+			}
+			if !send(event) {
+				return
+			}
+		}
+
+		// 取消导致的读取错误不需要报告
+		if ctx.Err() != nil {
+			return
+		}
+		if err := scanner.Err(); err != nil {
+			send(ai.StreamEvent{Err: fmt.Errorf("stream read failed: %w", err)})
+			return
+		}
+		if !finished {
+			send(ai.StreamEvent{Err: ai.ErrStreamIncomplete})
 		}
 	}()
 
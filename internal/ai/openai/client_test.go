@@ -2,9 +2,14 @@ package openai
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 )
@@ -118,8 +123,11 @@ func TestIntegration_ChatStream(t *testing.T) {
 	}
 
 	var response strings.Builder
-	for chunk := range stream {
-		response.WriteString(chunk)
+	for event := range stream {
+		if event.Err != nil {
+			t.Fatalf("stream error: %v", event.Err)
+		}
+		response.WriteString(event.Content)
 	}
 
 	if response.String() == "" {
@@ -128,3 +136,149 @@ func TestIntegration_ChatStream(t *testing.T) {
 
 	t.Logf("Response: %s", response.String())
 }
+
+func TestChatStream_StopsWhenCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			default:
+			}
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"chunk\"}}]}\n\n")
+			flusher.Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", "test-model", server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.ChatStream(ctx, []ai.Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if event := <-stream; event.Content != "chunk" {
+		t.Fatalf("expected first chunk, got %+v", event)
+	}
+
+	// 等 goroutine 读到下一块并阻塞在发送上，取消后即使没有人读取也应该退出并关闭 channel
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case _, ok := <-stream:
+		if ok {
+			t.Error("stream still sending after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream not closed after cancel")
+	}
+}
+
+// sseServer 返回依次发送 lines 后关闭连接的 SSE 服务
+func sseServer(lines ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, line := range lines {
+			fmt.Fprintf(w, "data: %s\n\n", line)
+		}
+	}))
+}
+
+// collectStream 读取全部事件，返回内容、结束原因、用量和错误
+func collectStream(t *testing.T, client *Client) (string, string, *ai.Usage, error) {
+	t.Helper()
+	stream, err := client.ChatStream(context.Background(), []ai.Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	var content strings.Builder
+	var finishReason string
+	var usage *ai.Usage
+	var streamErr error
+	for event := range stream {
+		content.WriteString(event.Content)
+		if event.FinishReason != "" {
+			finishReason = event.FinishReason
+		}
+		if event.Usage != nil {
+			usage = event.Usage
+		}
+		if event.Err != nil {
+			streamErr = event.Err
+		}
+	}
+	return content.String(), finishReason, usage, streamErr
+}
+
+func TestChatStream_FinishReasonAndUsage(t *testing.T) {
+	server := sseServer(
+		`{"choices":[{"delta":{"content":"Hello"}}]}`,
+		`{"choices":[{"delta":{"content":" World"},"finish_reason":"length"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+
+	content, finishReason, usage, err := collectStream(t, NewClient("test-key", "test-model", server.URL))
+	if err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	if content != "Hello World" || finishReason != ai.FinishLength {
+		t.Errorf("got %q, finish reason %q", content, finishReason)
+	}
+	if usage == nil || usage.TotalTokens != 5 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestChatStream_ReportsErrors(t *testing.T) {
+	// 连接在结束之前断开
+	server := sseServer(`{"choices":[{"delta":{"content":"Hel"}}]}`)
+	content, _, _, err := collectStream(t, NewClient("test-key", "test-model", server.URL))
+	server.Close()
+	if content != "Hel" || !errors.Is(err, ai.ErrStreamIncomplete) {
+		t.Errorf("expected incomplete stream error, got %q, %v", content, err)
+	}
+
+	// 无法解析的数据
+	server = sseServer(`{"choices":[{"delta":{"content":"Hel"}}]}`, `{not json`)
+	_, _, _, err = collectStream(t, NewClient("test-key", "test-model", server.URL))
+	server.Close()
+	if err == nil || !strings.Contains(err.Error(), "decode") {
+		t.Errorf("expected decode error, got %v", err)
+	}
+
+	// 流中返回的 API 错误
+	server = sseServer(`{"error":{"message":"rate limited"}}`)
+	_, _, _, err = collectStream(t, NewClient("test-key", "test-model", server.URL))
+	server.Close()
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("expected API error, got %v", err)
+	}
+}
+
+func TestChatStream_Reasoning(t *testing.T) {
+	server := sseServer(
+		`{"choices":[{"delta":{"reasoning_content":"Think "}}]}`,
+		`{"choices":[{"delta":{"reasoning_content":"first."}}]}`,
+		`{"choices":[{"delta":{"content":"Answer"},"finish_reason":"stop"}]}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+
+	client := NewClient("test-key", "test-model", server.URL)
+	stream, err := client.ChatStream(context.Background(), []ai.Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	var reasoning, content strings.Builder
+	for event := range stream {
+		reasoning.WriteString(event.Reasoning)
+		content.WriteString(event.Content)
+	}
+	if reasoning.String() != "Think first." || content.String() != "Answer" {
+		t.Errorf("reasoning = %q, content = %q", reasoning.String(), content.String())
+	}
+}
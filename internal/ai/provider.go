@@ -1,6 +1,9 @@
 package ai
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 // Message represents a chat message
 type Message struct {
@@ -22,12 +25,56 @@ type Command struct {
 	IsAsync bool     `json:"is_async"` // Indicates async execution requiring queue authorization
 }
 
+// DefaultIntentPrompt is the system prompt ParseIntent uses when none is given
+const DefaultIntentPrompt = `You are tada, a terminal AI assistant. Your job is to understand user requests and convert them into shell commands.
+
+Rules:
+1. Return ONLY valid JSON
+2. For simple requests, return a single command
+3. Explain your reasoning in the "reason" field
+4. Mark dangerous commands (rm, chmod, etc.) with needs_confirm: true
+
+Response format:
+{
+  "commands": [{"cmd": "command", "args": ["arg1", "arg2"]}],
+  "reason": "explanation",
+  "needs_confirm": false
+}`
+
 // AIProvider defines the interface for AI backends
 type AIProvider interface {
 	ParseIntent(ctx context.Context, input string, systemPrompt string) (*Intent, error)
 	AnalyzeOutput(ctx context.Context, cmd string, output string) (string, error)
 	Chat(ctx context.Context, messages []Message) (string, error)
 
-	// ChatStream 流式对话
-	ChatStream(ctx context.Context, messages []Message) (<-chan string, error)
+	// ChatStream 流式对话，channel 在回复结束、出错或 ctx 取消后关闭
+	ChatStream(ctx context.Context, messages []Message) (<-chan StreamEvent, error)
 }
+
+// Finish reasons reported by the API when a reply ends
+const (
+	FinishStop   = "stop"
+	FinishLength = "length" // 达到 max_tokens，回复不完整
+)
+
+// StreamEvent 流式回复中的一个事件
+//
+// 一个事件可以同时包含多个字段，例如最后一块内容和结束原因。
+// Err 不为 nil 时流在此之后关闭，之前收到的内容不完整。
+type StreamEvent struct {
+	Content      string // 回复内容增量
+	Reasoning    string // 推理内容增量
+	Usage        *Usage // 令牌用量，通常在最后一个事件中
+	FinishReason string // 结束原因，如 FinishStop、FinishLength
+	Err          error
+}
+
+// Usage token usage of a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ErrStreamIncomplete 连接在回复结束之前断开
+var ErrStreamIncomplete = errors.New("stream ended before the reply was complete")
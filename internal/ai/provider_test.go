@@ -71,7 +71,7 @@ func TestAIProvider_ChatStream(t *testing.T) {
 
 	for {
 		select {
-		case chunk, ok := <-stream:
+		case event, ok := <-stream:
 			if !ok {
 				// channel closed
 				if response.String() == "" {
@@ -79,7 +79,7 @@ func TestAIProvider_ChatStream(t *testing.T) {
 				}
 				return
 			}
-			response.WriteString(chunk)
+			response.WriteString(event.Content)
 		case <-timeout:
 			t.Fatal("Timeout waiting for stream")
 		}
@@ -101,13 +101,13 @@ func (m *mockAIProvider) Chat(ctx context.Context, messages []Message) (string,
 	return "response", nil
 }
 
-func (m *mockAIProvider) ChatStream(ctx context.Context, messages []Message) (<-chan string, error) {
-	ch := make(chan string)
+func (m *mockAIProvider) ChatStream(ctx context.Context, messages []Message) (<-chan StreamEvent, error) {
+	ch := make(chan StreamEvent)
 	go func() {
 		defer close(ch)
-		ch <- "Hello"
+		ch <- StreamEvent{Content: "Hello"}
 		time.Sleep(10 * time.Millisecond)
-		ch <- " World"
+		ch <- StreamEvent{Content: " World", FinishReason: FinishStop}
 	}()
 	return ch, nil
 }
@@ -0,0 +1,285 @@
+package conversation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Branch 对话中的一个分支，由叶子消息标识
+type Branch struct {
+	Number   int    // 从 1 开始的分支编号，按创建顺序
+	LeafID   string // 分支最后一条消息的 ID
+	Length   int    // 分支上的消息数
+	Preview  string // 最后一条消息的开头
+	Active   bool
+	LastTime time.Time
+}
+
+// ensureTree 为旧版本保存的对话补全消息 ID，按顺序组成单一分支
+func (c *Conversation) ensureTree() {
+	legacy := false
+	for _, msg := range c.Messages {
+		if msg.ID == "" {
+			legacy = true
+			break
+		}
+	}
+	if !legacy {
+		return
+	}
+
+	parent := ""
+	for i := range c.Messages {
+		if c.Messages[i].ID == "" {
+			c.Messages[i].ID = c.nextMessageID()
+		}
+		c.Messages[i].ParentID = parent
+		parent = c.Messages[i].ID
+	}
+	c.ActiveLeaf = parent
+}
+
+// nextMessageID 返回一个未使用的消息 ID
+func (c *Conversation) nextMessageID() string {
+	used := make(map[string]bool, len(c.Messages))
+	for _, msg := range c.Messages {
+		used[msg.ID] = true
+	}
+	for n := len(c.Messages) + 1; ; n++ {
+		id := fmt.Sprintf("m%d", n)
+		if !used[id] {
+			return id
+		}
+	}
+}
+
+// appendChild 添加 parentID 的子消息并切换到该分支
+func (c *Conversation) appendChild(parentID string, msg Message) {
+	if msg.ID == "" {
+		msg.ID = c.nextMessageID()
+	}
+	msg.ParentID = parentID
+	c.Messages = append(c.Messages, msg)
+	c.ActiveLeaf = msg.ID
+	c.UpdatedAt = time.Now()
+}
+
+// indexByID 返回消息 ID 到下标的映射
+func (c *Conversation) indexByID() map[string]int {
+	byID := make(map[string]int, len(c.Messages))
+	for i, msg := range c.Messages {
+		if msg.ID != "" {
+			byID[msg.ID] = i
+		}
+	}
+	return byID
+}
+
+// ActivePath 返回当前分支上的消息下标（从根到叶子）
+func (c *Conversation) ActivePath() []int {
+	for _, msg := range c.Messages {
+		if msg.ID == "" {
+			// 旧版本对话：所有消息组成单一分支
+			path := make([]int, len(c.Messages))
+			for i := range path {
+				path[i] = i
+			}
+			return path
+		}
+	}
+	return c.pathTo(c.ActiveLeaf, c.indexByID())
+}
+
+// pathTo 返回从根到 leafID 的消息下标
+func (c *Conversation) pathTo(leafID string, byID map[string]int) []int {
+	var path []int
+	for id := leafID; id != ""; {
+		idx, ok := byID[id]
+		if !ok || len(path) > len(c.Messages) {
+			break
+		}
+		path = append(path, idx)
+		id = c.Messages[idx].ParentID
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// ActiveMessages 返回当前分支上的消息
+func (c *Conversation) ActiveMessages() []Message {
+	path := c.ActivePath()
+	messages := make([]Message, 0, len(path))
+	for _, idx := range path {
+		messages = append(messages, c.Messages[idx])
+	}
+	return messages
+}
+
+// Branches 返回对话的所有分支，按创建顺序编号
+func (c *Conversation) Branches() []Branch {
+	c.ensureTree()
+
+	hasChildren := make(map[string]bool, len(c.Messages))
+	for _, msg := range c.Messages {
+		if msg.ParentID != "" {
+			hasChildren[msg.ParentID] = true
+		}
+	}
+
+	byID := c.indexByID()
+	activeLeaf := c.ActiveLeaf
+	activeFound := false
+
+	var branches []Branch
+	for _, msg := range c.Messages {
+		if hasChildren[msg.ID] {
+			continue
+		}
+		branch := Branch{
+			Number:   len(branches) + 1,
+			LeafID:   msg.ID,
+			Length:   len(c.pathTo(msg.ID, byID)),
+			Preview:  preview(msg.Content, 40),
+			Active:   msg.ID == activeLeaf,
+			LastTime: msg.Timestamp,
+		}
+		activeFound = activeFound || branch.Active
+		branches = append(branches, branch)
+	}
+
+	// /undo 后当前分支停在中间消息上，也作为一个分支列出
+	if !activeFound && activeLeaf != "" {
+		if idx, ok := byID[activeLeaf]; ok {
+			msg := c.Messages[idx]
+			branches = append(branches, Branch{
+				Number:   len(branches) + 1,
+				LeafID:   msg.ID,
+				Length:   len(c.pathTo(msg.ID, byID)),
+				Preview:  preview(msg.Content, 40),
+				Active:   true,
+				LastTime: msg.Timestamp,
+			})
+		}
+	}
+	return branches
+}
+
+// preview 截取文本开头用于显示
+func preview(text string, maxRunes int) string {
+	runes := []rune(text)
+	for i, r := range runes {
+		if r == '\n' {
+			runes[i] = ' '
+		}
+	}
+	if len(runes) > maxRunes {
+		return string(runes[:maxRunes]) + "..."
+	}
+	return string(runes)
+}
+
+// SwitchBranch 切换到编号为 n 的分支
+func (c *Conversation) SwitchBranch(n int) error {
+	branches := c.Branches()
+	if n < 1 || n > len(branches) {
+		return fmt.Errorf("branch %d not found (have %d)", n, len(branches))
+	}
+	c.ActiveLeaf = branches[n-1].LeafID
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// UserMessages 返回当前分支上用户消息的下标，/edit 按此编号
+func (c *Conversation) UserMessages() []int {
+	var indexes []int
+	for _, idx := range c.ActivePath() {
+		if c.Messages[idx].Role == "user" {
+			indexes = append(indexes, idx)
+		}
+	}
+	return indexes
+}
+
+// PrepareRetry 回到当前分支最后一条用户消息，之后生成的回复成为新的分支
+func (c *Conversation) PrepareRetry() error {
+	c.ensureTree()
+	path := c.ActivePath()
+	for i := len(path) - 1; i >= 0; i-- {
+		if c.Messages[path[i]].Role == "user" {
+			c.ActiveLeaf = c.Messages[path[i]].ID
+			c.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return fmt.Errorf("no user message to retry")
+}
+
+// EditUserMessage 用新内容替换当前分支上第 n 条用户消息（从 1 开始）
+//
+// 原消息及其后续对话保留为另一个分支，新消息成为当前分支的末尾。
+func (c *Conversation) EditUserMessage(n int, content string) error {
+	c.ensureTree()
+	users := c.UserMessages()
+	if n < 1 || n > len(users) {
+		return fmt.Errorf("user message %d not found (have %d)", n, len(users))
+	}
+
+	original := c.Messages[users[n-1]]
+	c.appendChild(original.ParentID, Message{
+		Role:      "user",
+		Content:   content,
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// Undo 撤销当前分支的最后一轮对话，返回被撤销的消息数
+//
+// 被撤销的消息保留为另一个分支，可以用 SwitchBranch 恢复。
+func (c *Conversation) Undo() (int, error) {
+	c.ensureTree()
+	path := c.ActivePath()
+	for i := len(path) - 1; i >= 0; i-- {
+		msg := c.Messages[path[i]]
+		if msg.Role == "user" {
+			c.ActiveLeaf = msg.ParentID
+			c.UpdatedAt = time.Now()
+			return len(path) - i, nil
+		}
+	}
+	return 0, fmt.Errorf("nothing to undo")
+}
+
+// Fork 复制当前分支为新对话
+func (c *Conversation) Fork(name string) *Conversation {
+	now := time.Now()
+	fork := &Conversation{
+		ID:         uuid.New().String(),
+		Name:       name,
+		PromptName: c.PromptName,
+		Messages:   c.ActiveMessages(),
+		Status:     StatusActive,
+		ForkedFrom: c.ID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ephemeral:  c.ephemeral,
+	}
+	if len(fork.Messages) > 0 {
+		fork.ActiveLeaf = fork.Messages[len(fork.Messages)-1].ID
+	}
+
+	// 保留适用于当前分支的压缩记录
+	if pos, _ := c.activeCompaction(c.ActivePath()); pos >= 0 {
+		upTo := fork.Messages[pos].ID
+		for _, compaction := range c.Compactions {
+			if compaction.UpTo == upTo {
+				fork.Compactions = append(fork.Compactions, compaction)
+			}
+		}
+	}
+	return fork
+}
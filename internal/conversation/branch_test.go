@@ -0,0 +1,188 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// contents 返回消息内容，便于比较分支
+func contents(messages []Message) []string {
+	var result []string
+	for _, msg := range messages {
+		result = append(result, msg.Content)
+	}
+	return result
+}
+
+func TestConversation_AddMessage_BuildsTree(t *testing.T) {
+	conv := newTestConversation(2, "x")
+
+	for i, msg := range conv.Messages {
+		if msg.ID == "" {
+			t.Fatalf("Expected message %d to have an ID", i)
+		}
+		if i > 0 && msg.ParentID != conv.Messages[i-1].ID {
+			t.Errorf("Expected message %d to follow message %d", i, i-1)
+		}
+	}
+	if conv.ActiveLeaf != conv.Messages[4].ID {
+		t.Errorf("Expected active leaf %s, got %s", conv.Messages[4].ID, conv.ActiveLeaf)
+	}
+}
+
+func TestConversation_LegacyMessages(t *testing.T) {
+	// 旧版本保存的对话没有消息 ID
+	var conv Conversation
+	data := `{"id": "old", "messages": [{"role": "user", "content": "a"}, {"role": "assistant", "content": "b"}]}`
+	if err := json.Unmarshal([]byte(data), &conv); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := contents(conv.ActiveMessages()); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Expected legacy messages as a single branch, got %v", got)
+	}
+
+	conv.AddMessage(Message{Role: "user", Content: "c"})
+	if got := contents(conv.ActiveMessages()); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Expected new message appended to legacy branch, got %v", got)
+	}
+	if conv.Messages[1].ID == "" || conv.Messages[2].ParentID != conv.Messages[1].ID {
+		t.Error("Expected legacy messages to be migrated to a tree")
+	}
+}
+
+func TestConversation_PrepareRetry(t *testing.T) {
+	conv := newTestConversation(0, "")
+	conv.AddMessage(Message{Role: "user", Content: "q"})
+	conv.AddMessage(Message{Role: "assistant", Content: "first answer"})
+
+	if err := conv.PrepareRetry(); err != nil {
+		t.Fatalf("PrepareRetry failed: %v", err)
+	}
+	conv.AddMessage(Message{Role: "assistant", Content: "second answer"})
+
+	if got := contents(conv.ActiveMessages()); !reflect.DeepEqual(got, []string{"system prompt", "q", "second answer"}) {
+		t.Errorf("Unexpected active branch: %v", got)
+	}
+
+	branches := conv.Branches()
+	if len(branches) != 2 || !branches[1].Active {
+		t.Fatalf("Expected 2 branches with the second active, got %+v", branches)
+	}
+
+	if err := conv.SwitchBranch(1); err != nil {
+		t.Fatalf("SwitchBranch failed: %v", err)
+	}
+	if got := contents(conv.ActiveMessages()); !reflect.DeepEqual(got, []string{"system prompt", "q", "first answer"}) {
+		t.Errorf("Expected first branch after switching, got %v", got)
+	}
+	if err := conv.SwitchBranch(3); err == nil {
+		t.Error("Expected error for missing branch")
+	}
+}
+
+func TestConversation_EditUserMessage(t *testing.T) {
+	conv := newTestConversation(0, "")
+	conv.AddMessage(Message{Role: "user", Content: "q1"})
+	conv.AddMessage(Message{Role: "assistant", Content: "a1"})
+	conv.AddMessage(Message{Role: "user", Content: "q2"})
+	conv.AddMessage(Message{Role: "assistant", Content: "a2"})
+
+	if err := conv.EditUserMessage(1, "q1 edited"); err != nil {
+		t.Fatalf("EditUserMessage failed: %v", err)
+	}
+	if got := contents(conv.ActiveMessages()); !reflect.DeepEqual(got, []string{"system prompt", "q1 edited"}) {
+		t.Errorf("Unexpected active branch: %v", got)
+	}
+	if len(conv.Messages) != 6 {
+		t.Errorf("Expected original messages to be kept, got %d messages", len(conv.Messages))
+	}
+	if err := conv.EditUserMessage(5, "x"); err == nil {
+		t.Error("Expected error for missing user message")
+	}
+}
+
+func TestConversation_Undo(t *testing.T) {
+	conv := newTestConversation(2, "x")
+
+	count, err := conv.Undo()
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 messages undone, got %d, %v", count, err)
+	}
+	if got := len(conv.ActiveMessages()); got != 3 {
+		t.Errorf("Expected 3 active messages after undo, got %d", got)
+	}
+
+	// 撤销的对话可以通过分支找回
+	branches := conv.Branches()
+	if len(branches) != 2 {
+		t.Fatalf("Expected undone turn to remain as a branch, got %+v", branches)
+	}
+
+	conv.Undo()
+	if _, err := conv.Undo(); err == nil {
+		t.Error("Expected nothing left to undo")
+	}
+}
+
+func TestConversation_SwitchPrompt_KeepsTree(t *testing.T) {
+	conv := newTestConversation(1, "x")
+	conv.PrepareRetry()
+	conv.AddMessage(Message{Role: "assistant", Content: "y"})
+
+	conv.SwitchPrompt("coder", "new prompt")
+
+	if got := contents(conv.ActiveMessages()); !reflect.DeepEqual(got, []string{"new prompt", "x", "y"}) {
+		t.Errorf("Unexpected active branch after switching prompt: %v", got)
+	}
+	if len(conv.Branches()) != 2 {
+		t.Errorf("Expected branches to survive prompt switch")
+	}
+}
+
+func TestConversation_Fork(t *testing.T) {
+	conv := newTestConversation(3, "x")
+	conv.Undo()
+
+	fork := conv.Fork("experiment")
+	if fork.ID == conv.ID || fork.ForkedFrom != conv.ID || fork.Name != "experiment" {
+		t.Errorf("Unexpected fork metadata: %+v", fork)
+	}
+	if len(fork.Messages) != 5 {
+		t.Errorf("Expected fork to copy the 5 active messages, got %d", len(fork.Messages))
+	}
+
+	fork.AddMessage(Message{Role: "user", Content: "new"})
+	if len(conv.Messages) != 7 {
+		t.Error("Expected fork to be independent of the original")
+	}
+}
+
+func TestContextBuilder_Compact_FollowsBranch(t *testing.T) {
+	conv := newTestConversation(5, "hi")
+	b := &ContextBuilder{Estimate: EstimatorForModel("")}
+	if _, err := b.Compact(context.Background(), conv, 0, true); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	// 编辑第一条用户消息后，新分支不包含被压缩的消息，也不使用摘要
+	conv.Compactions[0].Summary = "summary"
+	if err := conv.EditUserMessage(1, "edited"); err != nil {
+		t.Fatalf("EditUserMessage failed: %v", err)
+	}
+	if conv.LatestSummary() != "" {
+		t.Errorf("Expected no summary on the new branch, got %q", conv.LatestSummary())
+	}
+	if got := len(conv.GetMessagesForAI()); got != 2 {
+		t.Errorf("Expected system + edited message, got %d messages", got)
+	}
+
+	if err := conv.SwitchBranch(1); err != nil {
+		t.Fatal(err)
+	}
+	if conv.LatestSummary() != "summary" {
+		t.Errorf("Expected summary on the original branch, got %q", conv.LatestSummary())
+	}
+}
@@ -0,0 +1,251 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+// summaryPrefix 标记注入到上下文中的历史摘要消息
+const summaryPrefix = "[较早对话的摘要]\n"
+
+// defaultContextWindow 未知模型使用的上下文窗口大小
+const defaultContextWindow = 8192
+
+// keepRecentMessages 压缩时始终保留的最近消息数（约两轮对话）
+const keepRecentMessages = 4
+
+// TokenEstimator 估算一段文本的 token 数
+type TokenEstimator func(text string) int
+
+// modelProfile 描述模型的上下文窗口和分词特征
+type modelProfile struct {
+	prefix        string
+	contextWindow int
+	charsPerToken float64 // 英文等非 CJK 文本平均每 token 字符数
+	tokensPerCJK  float64 // 每个 CJK 字符的 token 数
+}
+
+// modelProfiles 按前缀匹配，越具体的前缀越靠前
+var modelProfiles = []modelProfile{
+	{prefix: "gpt-4o", contextWindow: 128000, charsPerToken: 4, tokensPerCJK: 0.8},
+	{prefix: "gpt-4.1", contextWindow: 1000000, charsPerToken: 4, tokensPerCJK: 0.8},
+	{prefix: "gpt-4-turbo", contextWindow: 128000, charsPerToken: 4, tokensPerCJK: 1},
+	{prefix: "gpt-4", contextWindow: 8192, charsPerToken: 4, tokensPerCJK: 1},
+	{prefix: "gpt-3.5", contextWindow: 16385, charsPerToken: 4, tokensPerCJK: 1},
+	{prefix: "o1", contextWindow: 128000, charsPerToken: 4, tokensPerCJK: 0.8},
+	{prefix: "o3", contextWindow: 200000, charsPerToken: 4, tokensPerCJK: 0.8},
+	{prefix: "glm-4", contextWindow: 128000, charsPerToken: 4, tokensPerCJK: 0.7},
+	{prefix: "glm-5", contextWindow: 128000, charsPerToken: 4, tokensPerCJK: 0.7},
+}
+
+// profileForModel 返回模型对应的配置，未知模型使用保守的默认值
+func profileForModel(model string) modelProfile {
+	model = strings.ToLower(model)
+	for _, p := range modelProfiles {
+		if strings.HasPrefix(model, p.prefix) {
+			return p
+		}
+	}
+	return modelProfile{contextWindow: defaultContextWindow, charsPerToken: 4, tokensPerCJK: 1}
+}
+
+// ContextWindow 返回模型的上下文窗口大小（token）
+func ContextWindow(model string) int {
+	return profileForModel(model).contextWindow
+}
+
+// EstimatorForModel 返回适用于模型的 token 估算函数
+//
+// 这是近似估算：CJK 字符按单字计，其余文本按平均字符数计。
+func EstimatorForModel(model string) TokenEstimator {
+	p := profileForModel(model)
+	return func(text string) int {
+		cjk := 0
+		other := 0
+		for _, r := range text {
+			if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+				unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+				cjk++
+			} else {
+				other++
+			}
+		}
+		return int(float64(cjk)*p.tokensPerCJK+float64(other)/p.charsPerToken) + 1
+	}
+}
+
+// Summarizer 将较早的消息压缩成摘要
+type Summarizer func(ctx context.Context, previousSummary string, messages []ai.Message) (string, error)
+
+// Compaction 记录一次历史压缩
+type Compaction struct {
+	Time         time.Time `json:"time"`
+	MessageCount int       `json:"message_count"` // 本次被压缩的消息数
+	Summary      string    `json:"summary,omitempty"`
+	TokensBefore int       `json:"tokens_before"`
+	TokensAfter  int       `json:"tokens_after"`
+	Manual       bool      `json:"manual,omitempty"` // 由 /compact 触发
+	// UpTo 最后一条被压缩的消息 ID，该消息及之前的消息由摘要代替
+	UpTo string `json:"up_to"`
+}
+
+// ContextBuilder 控制发送给 AI 的历史长度
+type ContextBuilder struct {
+	// MaxTokens 发送给 AI 的消息总 token 预算，0 表示不限制
+	MaxTokens int
+	// MaxMessages 保留的非系统消息数，0 表示不限制
+	MaxMessages int
+	// Estimate 估算 token 数
+	Estimate TokenEstimator
+	// Summarize 为被压缩的消息生成摘要，nil 表示直接丢弃
+	Summarize Summarizer
+}
+
+// NewContextBuilder 根据模型创建 ContextBuilder
+//
+// maxTokens 为 0 时使用模型上下文窗口减去 reserve（为回复预留的 token）。
+func NewContextBuilder(model string, maxTokens, reserve, maxMessages int) *ContextBuilder {
+	if maxTokens <= 0 {
+		maxTokens = ContextWindow(model) - reserve
+	}
+	return &ContextBuilder{
+		MaxTokens:   maxTokens,
+		MaxMessages: maxMessages,
+		Estimate:    EstimatorForModel(model),
+	}
+}
+
+// CountTokens 估算一组消息的 token 数
+func (b *ContextBuilder) CountTokens(messages []ai.Message) int {
+	total := 0
+	for _, msg := range messages {
+		// 每条消息有少量角色和分隔符开销
+		total += b.Estimate(msg.Content) + 4
+	}
+	return total
+}
+
+// NeedsCompaction 判断消息是否超出预算
+//
+// overhead 是不在对话中、但会一起发送的 token（如记忆上下文）。
+func (b *ContextBuilder) NeedsCompaction(conv *Conversation, overhead int) bool {
+	if b == nil {
+		return false
+	}
+	messages := conv.GetMessagesForAI()
+	if b.MaxTokens > 0 && overhead+b.CountTokens(messages) > b.MaxTokens {
+		return true
+	}
+	return b.MaxMessages > 0 && len(conv.activeIndexes()) > b.MaxMessages
+}
+
+// Compact 压缩最早的对话轮次，直到满足预算
+//
+// force 为 true 时（/compact）压缩除最近几条以外的全部消息。被压缩的消息
+// 仍保存在对话中，但不再发送给 AI；压缩记录追加到 conv.Compactions。
+// 没有可压缩的消息时返回 nil。
+func (b *ContextBuilder) Compact(ctx context.Context, conv *Conversation, overhead int, force bool) (*Compaction, error) {
+	conv.ensureTree()
+	active := conv.activeIndexes()
+	if len(active) <= keepRecentMessages {
+		return nil, nil
+	}
+
+	tokensBefore := overhead + b.CountTokens(conv.GetMessagesForAI())
+
+	// 可压缩的范围：除最近 keepRecentMessages 条以外
+	candidates := active[:len(active)-keepRecentMessages]
+
+	var drop []int
+	if force {
+		drop = candidates
+	} else {
+		remaining := tokensBefore
+		count := len(active)
+		for i := 0; i < len(candidates); i++ {
+			fits := (b.MaxTokens <= 0 || remaining <= b.MaxTokens) &&
+				(b.MaxMessages <= 0 || count <= b.MaxMessages)
+			if fits {
+				break
+			}
+			msg := conv.Messages[candidates[i]]
+			remaining -= b.Estimate(msg.Content) + 4
+			count--
+			drop = append(drop, candidates[i])
+		}
+		// 按轮次压缩：不把助手回复和它对应的用户消息拆开
+		for len(drop) > 0 && len(drop) < len(candidates) && conv.Messages[candidates[len(drop)]].Role != "user" {
+			drop = append(drop, candidates[len(drop)])
+		}
+	}
+
+	if len(drop) == 0 {
+		return nil, nil
+	}
+
+	dropped := make([]ai.Message, 0, len(drop))
+	for _, idx := range drop {
+		dropped = append(dropped, conv.Messages[idx].ToAIFormat())
+	}
+
+	summary := conv.LatestSummary()
+	if b.Summarize != nil {
+		s, err := b.Summarize(ctx, summary, dropped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize history: %w", err)
+		}
+		summary = strings.TrimSpace(s)
+	}
+
+	conv.Compactions = append(conv.Compactions, Compaction{
+		Time:         time.Now(),
+		MessageCount: len(drop),
+		Summary:      summary,
+		TokensBefore: tokensBefore,
+		Manual:       force,
+		UpTo:         conv.Messages[drop[len(drop)-1]].ID,
+	})
+	// 摘要已生效后再统计压缩后的 token 数
+	last := &conv.Compactions[len(conv.Compactions)-1]
+	last.TokensAfter = overhead + b.CountTokens(conv.GetMessagesForAI())
+	conv.UpdatedAt = time.Now()
+
+	compaction := *last
+	return &compaction, nil
+}
+
+// NewAISummarizer 使用 AI 生成历史摘要
+func NewAISummarizer(provider ai.AIProvider) Summarizer {
+	return func(ctx context.Context, previousSummary string, messages []ai.Message) (string, error) {
+		var b strings.Builder
+		if previousSummary != "" {
+			b.WriteString("已有摘要:\n")
+			b.WriteString(previousSummary)
+			b.WriteString("\n\n")
+		}
+		b.WriteString("新的对话内容:\n")
+		for _, msg := range messages {
+			content := msg.Content
+			if utf8.RuneCountInString(content) > 2000 {
+				content = string([]rune(content)[:2000]) + "..."
+			}
+			fmt.Fprintf(&b, "%s: %s\n", msg.Role, content)
+		}
+
+		prompt := []ai.Message{
+			{
+				Role: "system",
+				Content: "你负责压缩对话历史。将已有摘要和新的对话内容合并成一段简洁的摘要，" +
+					"保留关键事实、决定、文件名、命令和未完成的事项，不要添加新信息。只输出摘要。",
+			},
+			{Role: "user", Content: b.String()},
+		}
+		return provider.Chat(ctx, prompt)
+	}
+}
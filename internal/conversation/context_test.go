@@ -0,0 +1,227 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+// newTestConversation 创建包含系统消息和 turns 轮对话的对话
+func newTestConversation(turns int, content string) *Conversation {
+	conv := NewConversation("default")
+	conv.AddMessage(Message{Role: "system", Content: "system prompt"})
+	for i := 0; i < turns; i++ {
+		conv.AddMessage(Message{Role: "user", Content: content})
+		conv.AddMessage(Message{Role: "assistant", Content: content})
+	}
+	return conv
+}
+
+func TestEstimatorForModel(t *testing.T) {
+	estimate := EstimatorForModel("unknown-model")
+
+	ascii := estimate(strings.Repeat("a", 400))
+	if ascii < 90 || ascii > 110 {
+		t.Errorf("Expected about 100 tokens for 400 ASCII chars, got %d", ascii)
+	}
+
+	cjk := estimate(strings.Repeat("中", 100))
+	if cjk < ascii {
+		t.Errorf("Expected 100 CJK chars (%d) to cost at least as much as 400 ASCII chars (%d)", cjk, ascii)
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-4o-mini", 128000},
+		{"gpt-4", 8192},
+		{"GLM-4-Flash", 128000},
+		{"something-else", defaultContextWindow},
+	}
+	for _, tt := range tests {
+		if got := ContextWindow(tt.model); got != tt.want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestNewContextBuilder_ReservesResponseTokens(t *testing.T) {
+	b := NewContextBuilder("gpt-4", 0, 1000, 0)
+	if b.MaxTokens != 8192-1000 {
+		t.Errorf("Expected MaxTokens %d, got %d", 8192-1000, b.MaxTokens)
+	}
+
+	b = NewContextBuilder("gpt-4", 500, 1000, 0)
+	if b.MaxTokens != 500 {
+		t.Errorf("Expected explicit MaxTokens 500, got %d", b.MaxTokens)
+	}
+}
+
+func TestContextBuilder_NeedsCompaction(t *testing.T) {
+	var nilBuilder *ContextBuilder
+	conv := newTestConversation(10, "hello")
+	if nilBuilder.NeedsCompaction(conv, 0) {
+		t.Error("Expected nil builder to never compact")
+	}
+
+	b := &ContextBuilder{MaxMessages: 30, Estimate: EstimatorForModel("")}
+	if b.NeedsCompaction(conv, 0) {
+		t.Error("Expected 20 messages to fit in MaxMessages 30")
+	}
+
+	b.MaxMessages = 10
+	if !b.NeedsCompaction(conv, 0) {
+		t.Error("Expected 20 messages to exceed MaxMessages 10")
+	}
+
+	b = &ContextBuilder{MaxTokens: 1000, Estimate: EstimatorForModel("")}
+	if b.NeedsCompaction(conv, 0) {
+		t.Error("Expected short conversation to fit in budget")
+	}
+	if !b.NeedsCompaction(conv, 1000) {
+		t.Error("Expected overhead to count against the budget")
+	}
+}
+
+func TestContextBuilder_Compact_ByTokens(t *testing.T) {
+	conv := newTestConversation(10, strings.Repeat("word ", 80))
+	b := &ContextBuilder{MaxTokens: 500, Estimate: EstimatorForModel("")}
+
+	compaction, err := b.Compact(context.Background(), conv, 0, false)
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if compaction == nil {
+		t.Fatal("Expected a compaction")
+	}
+
+	if compaction.TokensAfter > b.MaxTokens {
+		t.Errorf("Expected %d tokens after compaction to fit in %d", compaction.TokensAfter, b.MaxTokens)
+	}
+	if compaction.TokensAfter >= compaction.TokensBefore {
+		t.Errorf("Expected tokens to shrink, got %d → %d", compaction.TokensBefore, compaction.TokensAfter)
+	}
+	if compaction.MessageCount%2 != 0 {
+		t.Errorf("Expected whole turns to be compacted, got %d messages", compaction.MessageCount)
+	}
+
+	// 完整历史仍然保留
+	if len(conv.Messages) != 21 {
+		t.Errorf("Expected all 21 messages kept, got %d", len(conv.Messages))
+	}
+	if len(conv.Compactions) != 1 {
+		t.Errorf("Expected 1 compaction recorded, got %d", len(conv.Compactions))
+	}
+
+	messages := conv.GetMessagesForAI()
+	if messages[0].Role != "system" {
+		t.Errorf("Expected system prompt to be kept first, got %s", messages[0].Role)
+	}
+	if messages[1].Role != "user" {
+		t.Errorf("Expected conversation to continue with a user message, got %s", messages[1].Role)
+	}
+}
+
+func TestContextBuilder_Compact_ByMessages(t *testing.T) {
+	conv := newTestConversation(10, "hi")
+	b := &ContextBuilder{MaxMessages: 8, Estimate: EstimatorForModel("")}
+
+	compaction, err := b.Compact(context.Background(), conv, 0, false)
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if compaction == nil || compaction.MessageCount != 12 {
+		t.Fatalf("Expected 12 messages compacted, got %+v", compaction)
+	}
+	if got := len(conv.activeIndexes()); got != 8 {
+		t.Errorf("Expected 8 active messages, got %d", got)
+	}
+}
+
+func TestContextBuilder_Compact_Force(t *testing.T) {
+	conv := newTestConversation(5, "hi")
+	b := &ContextBuilder{Estimate: EstimatorForModel("")}
+
+	compaction, err := b.Compact(context.Background(), conv, 0, true)
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if compaction == nil || !compaction.Manual {
+		t.Fatalf("Expected a manual compaction, got %+v", compaction)
+	}
+	if got := len(conv.activeIndexes()); got != keepRecentMessages {
+		t.Errorf("Expected %d recent messages kept, got %d", keepRecentMessages, got)
+	}
+
+	// 再次压缩时没有可压缩的消息
+	compaction, err = b.Compact(context.Background(), conv, 0, true)
+	if err != nil || compaction != nil {
+		t.Errorf("Expected nothing to compact, got %+v, %v", compaction, err)
+	}
+}
+
+func TestContextBuilder_Compact_Summarize(t *testing.T) {
+	conv := newTestConversation(5, "hi")
+
+	var gotPrevious string
+	var gotCount int
+	b := &ContextBuilder{
+		Estimate: EstimatorForModel(""),
+		Summarize: func(ctx context.Context, previous string, messages []ai.Message) (string, error) {
+			gotPrevious = previous
+			gotCount = len(messages)
+			return "summary " + previous, nil
+		},
+	}
+
+	if _, err := b.Compact(context.Background(), conv, 0, true); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if gotCount != 6 {
+		t.Errorf("Expected summarizer to receive 6 messages, got %d", gotCount)
+	}
+	if conv.LatestSummary() != "summary" {
+		t.Errorf("Expected summary 'summary', got %q", conv.LatestSummary())
+	}
+
+	messages := conv.GetMessagesForAI()
+	if len(messages) != 2+keepRecentMessages {
+		t.Fatalf("Expected system + summary + %d messages, got %d", keepRecentMessages, len(messages))
+	}
+	if !strings.HasPrefix(messages[1].Content, summaryPrefix) {
+		t.Errorf("Expected summary message after system prompt, got %q", messages[1].Content)
+	}
+
+	// 后续压缩会收到之前的摘要
+	conv.AddMessage(Message{Role: "user", Content: "more"})
+	conv.AddMessage(Message{Role: "assistant", Content: "more"})
+	if _, err := b.Compact(context.Background(), conv, 0, true); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if gotPrevious != "summary" {
+		t.Errorf("Expected previous summary to be passed on, got %q", gotPrevious)
+	}
+}
+
+func TestContextBuilder_Compact_SummarizeError(t *testing.T) {
+	conv := newTestConversation(5, "hi")
+	b := &ContextBuilder{
+		Estimate: EstimatorForModel(""),
+		Summarize: func(ctx context.Context, previous string, messages []ai.Message) (string, error) {
+			return "", errors.New("boom")
+		},
+	}
+
+	if _, err := b.Compact(context.Background(), conv, 0, true); err == nil {
+		t.Fatal("Expected summarizer error")
+	}
+	if len(conv.activeIndexes()) != 10 || len(conv.Compactions) != 0 {
+		t.Error("Expected conversation to be unchanged after a failed summary")
+	}
+}
@@ -0,0 +1,220 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// ExportFormatName 导出 JSON 中标记 tada 对话的格式名
+const ExportFormatName = "tada-conversation"
+
+// exportVersion 导出 JSON 的格式版本
+const exportVersion = 1
+
+// 支持的导出格式
+const (
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+	FormatText     = "txt"
+)
+
+// ExportFormats 返回支持的导出格式
+func ExportFormats() []string {
+	return []string{FormatMarkdown, FormatJSON, FormatHTML, FormatText}
+}
+
+// exportTimeLayout 导出文本中的时间格式
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// exportEnvelope 导出 JSON 的外层结构，导入时用来识别 tada 的导出文件
+type exportEnvelope struct {
+	Format       string        `json:"format"`
+	Version      int           `json:"version"`
+	ExportedAt   time.Time     `json:"exported_at"`
+	Conversation *Conversation `json:"conversation"`
+}
+
+// Export 按格式导出对话
+//
+// JSON 包含所有分支，其他格式只包含当前分支。
+func Export(w io.Writer, conv *Conversation, format string) error {
+	switch format {
+	case FormatMarkdown, "markdown":
+		return exportMarkdown(w, conv)
+	case FormatJSON:
+		return exportJSON(w, conv)
+	case FormatHTML:
+		return exportHTML(w, conv)
+	case FormatText, "text":
+		return exportText(w, conv)
+	default:
+		return fmt.Errorf("unsupported export format: %s (supported: %s)", format, strings.Join(ExportFormats(), ", "))
+	}
+}
+
+// conversationTitle 返回对话标题，没有标题时使用 ID
+func conversationTitle(conv *Conversation) string {
+	if title := conv.Title(); title != "" {
+		return title
+	}
+	return "对话 " + conv.ID
+}
+
+// roleLabel 返回角色的显示名称
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "👤 用户"
+	case "assistant":
+		return "🤖 助手"
+	case "system":
+		return "⚙️ 系统"
+	default:
+		return role
+	}
+}
+
+// formatTime 格式化时间，零值返回空字符串
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(exportTimeLayout)
+}
+
+func exportJSON(w io.Writer, conv *Conversation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(exportEnvelope{
+		Format:       ExportFormatName,
+		Version:      exportVersion,
+		ExportedAt:   time.Now(),
+		Conversation: conv,
+	})
+}
+
+func exportMarkdown(w io.Writer, conv *Conversation) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", conversationTitle(conv))
+	b.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| ID | `%s` |\n", conv.ID)
+	fmt.Fprintf(&b, "| Prompt | %s |\n", conv.PromptName)
+	if len(conv.Tags) > 0 {
+		fmt.Fprintf(&b, "| 标签 | %s |\n", strings.Join(conv.Tags, ", "))
+	}
+	fmt.Fprintf(&b, "| 创建时间 | %s |\n", formatTime(conv.CreatedAt))
+	fmt.Fprintf(&b, "| 更新时间 | %s |\n", formatTime(conv.UpdatedAt))
+	messages := conv.ActiveMessages()
+	fmt.Fprintf(&b, "| 消息数 | %d |\n", len(messages))
+
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "\n---\n\n### %s", roleLabel(msg.Role))
+		if ts := formatTime(msg.Timestamp); ts != "" {
+			fmt.Fprintf(&b, " · %s", ts)
+		}
+		if label := msg.IncompleteLabel(); label != "" {
+			b.WriteString(" · " + label)
+		}
+		b.WriteString("\n\n")
+
+		if msg.Role == "system" {
+			// 系统提示作为引用块，和对话内容区分
+			for _, line := range strings.Split(strings.TrimRight(msg.Content, "\n"), "\n") {
+				fmt.Fprintf(&b, "> %s\n", line)
+			}
+			continue
+		}
+		b.WriteString(strings.TrimRight(msg.Content, "\n"))
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func exportText(w io.Writer, conv *Conversation) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n", conversationTitle(conv))
+	fmt.Fprintf(&b, "ID: %s\n", conv.ID)
+	fmt.Fprintf(&b, "Prompt: %s\n", conv.PromptName)
+	if len(conv.Tags) > 0 {
+		fmt.Fprintf(&b, "标签: %s\n", strings.Join(conv.Tags, ", "))
+	}
+	fmt.Fprintf(&b, "创建时间: %s\n", formatTime(conv.CreatedAt))
+	fmt.Fprintf(&b, "更新时间: %s\n", formatTime(conv.UpdatedAt))
+	messages := conv.ActiveMessages()
+	fmt.Fprintf(&b, "消息数: %d\n", len(messages))
+
+	for _, msg := range messages {
+		b.WriteString("\n")
+		if ts := formatTime(msg.Timestamp); ts != "" {
+			fmt.Fprintf(&b, "[%s] ", ts)
+		}
+		role := msg.Role
+		if label := msg.IncompleteLabel(); label != "" {
+			role += " (" + label + ")"
+		}
+		fmt.Fprintf(&b, "%s:\n%s\n", role, strings.TrimRight(msg.Content, "\n"))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// htmlTemplate 自包含的 HTML 导出模板，不依赖外部资源
+var htmlTemplate = template.Must(template.New("conversation").Funcs(template.FuncMap{
+	"role": roleLabel,
+	"time": formatTime,
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #222; }
+table.meta { border-collapse: collapse; margin-bottom: 2em; }
+table.meta td { padding: 2px 12px 2px 0; color: #555; }
+.message { border-radius: 8px; padding: 0.8em 1em; margin: 1em 0; }
+.message.user { background: #eef5ff; }
+.message.assistant { background: #f5f5f5; }
+.message.system { background: #fff8e6; font-size: 0.9em; }
+.header { font-size: 0.85em; color: #666; margin-bottom: 0.4em; }
+.content { white-space: pre-wrap; word-wrap: break-word; font-family: inherit; margin: 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table class="meta">
+<tr><td>ID</td><td><code>{{.Conv.ID}}</code></td></tr>
+<tr><td>Prompt</td><td>{{.Conv.PromptName}}</td></tr>
+{{with .Conv.Tags}}<tr><td>标签</td><td>{{range $i, $t := .}}{{if $i}}, {{end}}{{$t}}{{end}}</td></tr>
+{{end}}<tr><td>创建时间</td><td>{{time .Conv.CreatedAt}}</td></tr>
+<tr><td>更新时间</td><td>{{time .Conv.UpdatedAt}}</td></tr>
+<tr><td>消息数</td><td>{{len .Messages}}</td></tr>
+</table>
+{{range .Messages}}<div class="message {{.Role}}">
+<div class="header">{{role .Role}}{{with time .Timestamp}} · {{.}}{{end}}{{with .IncompleteLabel}} · {{.}}{{end}}</div>
+<pre class="content">{{.Content}}</pre>
+</div>
+{{end}}</body>
+</html>
+`))
+
+func exportHTML(w io.Writer, conv *Conversation) error {
+	return htmlTemplate.Execute(w, struct {
+		Title    string
+		Conv     *Conversation
+		Messages []Message
+	}{
+		Title:    conversationTitle(conv),
+		Conv:     conv,
+		Messages: conv.ActiveMessages(),
+	})
+}
@@ -0,0 +1,123 @@
+package conversation
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+func newExportConversation() *Conversation {
+	ts := time.Date(2026, 3, 1, 10, 30, 0, 0, time.Local)
+	conv := NewConversation("coder")
+	conv.Name = "nginx 排查"
+	conv.CreatedAt = ts
+	conv.Messages = []Message{
+		{Role: "system", Content: "你是编程助手", Timestamp: ts},
+		{Role: "user", Content: "nginx 返回 502", Timestamp: ts},
+		{Role: "assistant", Content: "检查 upstream <server>", Timestamp: ts.Add(time.Minute)},
+	}
+	conv.UpdatedAt = ts.Add(time.Minute)
+	return conv
+}
+
+func TestExport_Markdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, newExportConversation(), FormatMarkdown); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"# nginx 排查", "| Prompt | coder |", "> 你是编程助手", "### 👤 用户 · 2026-03-01 10:30:00", "nginx 返回 502"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestExport_HTMLEscapesContent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, newExportConversation(), FormatHTML); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "upstream &lt;server&gt;") {
+		t.Errorf("Expected content to be escaped, got:\n%s", out)
+	}
+	if !strings.Contains(out, `<div class="message assistant">`) {
+		t.Errorf("Expected assistant message block, got:\n%s", out)
+	}
+}
+
+func TestExport_Text(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, newExportConversation(), FormatText); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "[2026-03-01 10:31:00] assistant:\n检查 upstream <server>") {
+		t.Errorf("Unexpected text export:\n%s", buf.String())
+	}
+}
+
+func TestExport_UnsupportedFormat(t *testing.T) {
+	if err := Export(&bytes.Buffer{}, newExportConversation(), "pdf"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestExport_JSONRoundTrip(t *testing.T) {
+	conv := newExportConversation()
+
+	var buf bytes.Buffer
+	if err := Export(&buf, conv, FormatJSON); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	convs, err := ParseImport(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseImport failed: %v", err)
+	}
+	if len(convs) != 1 {
+		t.Fatalf("Expected 1 conversation, got %d", len(convs))
+	}
+
+	got := convs[0]
+	if got.ID != conv.ID || got.Name != conv.Name || got.PromptName != "coder" {
+		t.Errorf("Expected metadata to round-trip, got %+v", got)
+	}
+	if len(got.Messages) != 3 || got.Messages[2].Content != conv.Messages[2].Content {
+		t.Errorf("Expected messages to round-trip, got %+v", got.Messages)
+	}
+	if !got.CreatedAt.Equal(conv.CreatedAt) {
+		t.Errorf("Expected created time %v, got %v", conv.CreatedAt, got.CreatedAt)
+	}
+}
+
+func TestExport_MarksIncompleteReplies(t *testing.T) {
+	tests := []struct {
+		label string
+		mark  func(msg *Message)
+	}{
+		{interruptedLabel, func(msg *Message) { msg.Interrupted = true }},
+		{truncatedLabel, func(msg *Message) { msg.FinishReason = ai.FinishLength }},
+		{failedLabel, func(msg *Message) { msg.Error = "connection reset" }},
+	}
+
+	for _, tt := range tests {
+		conv := newExportConversation()
+		tt.mark(&conv.Messages[2])
+
+		for _, format := range []string{FormatMarkdown, FormatHTML, FormatText} {
+			var buf bytes.Buffer
+			if err := Export(&buf, conv, format); err != nil {
+				t.Fatalf("Export %s failed: %v", format, err)
+			}
+			if !strings.Contains(buf.String(), tt.label) {
+				t.Errorf("%s export does not mark the reply as %s:\n%s", format, tt.label, buf.String())
+			}
+		}
+	}
+}
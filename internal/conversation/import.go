@@ -0,0 +1,395 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// importedPromptName 从其他工具导入的对话使用的 prompt 名称
+const importedPromptName = "default"
+
+// idPattern 对话 ID 的合法格式，ID 用作目录名，不能包含路径分隔符或以 . 开头
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// validID 检查对话 ID 能否安全地用作目录名
+func validID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// ParseImport 解析导入文件，返回其中的对话
+//
+// 支持的格式：
+//   - tada 的 JSON 导出（--export --format json）和 messages.json
+//   - OpenAI 风格的消息列表：{"messages": [{"role", "content"}]} 或直接是数组
+//   - ChatGPT 数据导出的 conversations.json（mapping 树）
+//   - Claude 数据导出的 conversations.json（chat_messages）
+func ParseImport(data []byte) ([]*Conversation, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("import file is empty")
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("import file is not valid JSON (only JSON exports can be imported)")
+	}
+
+	if data[0] == '[' {
+		return parseImportArray(data)
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse import file: %w", err)
+	}
+	conv, err := parseImportObject(obj, data)
+	if err != nil {
+		return nil, err
+	}
+	return []*Conversation{conv}, nil
+}
+
+// parseImportArray 解析数组：多个对话的导出，或单个对话的消息列表
+func parseImportArray(data []byte) ([]*Conversation, error) {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("unsupported import format: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("import file contains no conversations")
+	}
+
+	// 元素本身是消息时，整个数组是一个对话
+	if _, ok := items[0]["role"]; ok {
+		conv, err := conversationFromMessages(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*Conversation{conv}, nil
+	}
+
+	var convs []*Conversation
+	for i, item := range items {
+		raw, _ := json.Marshal(item)
+		conv, err := parseImportObject(item, raw)
+		if err != nil {
+			return nil, fmt.Errorf("conversation %d: %w", i+1, err)
+		}
+		convs = append(convs, conv)
+	}
+	return convs, nil
+}
+
+// parseImportObject 按字段识别单个对话的格式
+func parseImportObject(obj map[string]json.RawMessage, raw []byte) (*Conversation, error) {
+	switch {
+	case has(obj, "format") && has(obj, "conversation"):
+		var env exportEnvelope
+		if err := json.Unmarshal(raw, &env); err != nil {
+			return nil, fmt.Errorf("failed to parse tada export: %w", err)
+		}
+		if env.Format != ExportFormatName || env.Conversation == nil {
+			return nil, fmt.Errorf("unsupported export format: %s", env.Format)
+		}
+		return normalizeImported(env.Conversation), nil
+
+	case has(obj, "mapping"):
+		return parseChatGPT(raw)
+
+	case has(obj, "chat_messages"):
+		return parseClaude(raw)
+
+	case has(obj, "prompt_name") && has(obj, "messages"):
+		var conv Conversation
+		if err := json.Unmarshal(raw, &conv); err != nil {
+			return nil, fmt.Errorf("failed to parse tada conversation: %w", err)
+		}
+		return normalizeImported(&conv), nil
+
+	case has(obj, "messages"):
+		conv, err := conversationFromMessages(obj["messages"])
+		if err != nil {
+			return nil, err
+		}
+		var meta struct {
+			Title string `json:"title"`
+			Name  string `json:"name"`
+		}
+		_ = json.Unmarshal(raw, &meta)
+		conv.Name = firstNonEmpty(meta.Title, meta.Name)
+		return conv, nil
+	}
+
+	return nil, fmt.Errorf("unsupported import format: no messages found")
+}
+
+func has(obj map[string]json.RawMessage, key string) bool {
+	_, ok := obj[key]
+	return ok
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// normalizeImported 补全 tada 对话缺失的字段
+//
+// 导入的文件来自他人，不合法的 ID（例如 "../../tmp/x"）会被替换为新的 ID。
+func normalizeImported(conv *Conversation) *Conversation {
+	if !validID(conv.ID) {
+		conv.ID = uuid.New().String()
+	}
+	if conv.PromptName == "" {
+		conv.PromptName = importedPromptName
+	}
+	if conv.Status == "" {
+		conv.Status = StatusActive
+	}
+	if conv.Messages == nil {
+		conv.Messages = []Message{}
+	}
+	fillTimes(conv)
+	return conv
+}
+
+// fillTimes 根据消息时间补全对话的创建和更新时间
+func fillTimes(conv *Conversation) {
+	for _, msg := range conv.Messages {
+		if msg.Timestamp.IsZero() {
+			continue
+		}
+		if conv.CreatedAt.IsZero() || msg.Timestamp.Before(conv.CreatedAt) {
+			conv.CreatedAt = msg.Timestamp
+		}
+		if msg.Timestamp.After(conv.UpdatedAt) {
+			conv.UpdatedAt = msg.Timestamp
+		}
+	}
+	now := time.Now()
+	if conv.CreatedAt.IsZero() {
+		conv.CreatedAt = now
+	}
+	if conv.UpdatedAt.IsZero() {
+		conv.UpdatedAt = conv.CreatedAt
+	}
+}
+
+// newImportedConversation 为其他工具的导出创建对话
+func newImportedConversation(name string, messages []Message, created, updated time.Time) *Conversation {
+	conv := &Conversation{
+		ID:         uuid.New().String(),
+		Name:       name,
+		PromptName: importedPromptName,
+		Messages:   messages,
+		Status:     StatusActive,
+		CreatedAt:  created,
+		UpdatedAt:  updated,
+	}
+	if conv.Messages == nil {
+		conv.Messages = []Message{}
+	}
+	fillTimes(conv)
+	return conv
+}
+
+// openAIMessage OpenAI 风格的消息，content 可以是字符串或内容块数组
+type openAIMessage struct {
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	Timestamp *time.Time      `json:"timestamp"`
+}
+
+// conversationFromMessages 从 OpenAI 风格的消息列表创建对话
+func conversationFromMessages(data json.RawMessage) (*Conversation, error) {
+	var raw []openAIMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse messages: %w", err)
+	}
+
+	var messages []Message
+	for _, m := range raw {
+		content := contentText(m.Content)
+		if m.Role == "" || content == "" {
+			continue
+		}
+		msg := Message{Role: normalizeRole(m.Role), Content: content}
+		if m.Timestamp != nil {
+			msg.Timestamp = *m.Timestamp
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("import file contains no messages")
+	}
+	return newImportedConversation("", messages, time.Time{}, time.Time{}), nil
+}
+
+// contentText 提取消息内容中的文本
+func contentText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	var parts []json.RawMessage
+	if json.Unmarshal(raw, &parts) == nil {
+		var texts []string
+		for _, part := range parts {
+			if t := contentText(part); t != "" {
+				texts = append(texts, t)
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+
+	var block struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Parts json.RawMessage `json:"parts"`
+	}
+	if json.Unmarshal(raw, &block) == nil {
+		if block.Text != "" {
+			return block.Text
+		}
+		return contentText(block.Parts)
+	}
+	return ""
+}
+
+// normalizeRole 统一其他工具的角色名称
+func normalizeRole(role string) string {
+	switch strings.ToLower(role) {
+	case "human", "user":
+		return "user"
+	case "assistant", "ai", "model", "bot":
+		return "assistant"
+	case "system", "developer":
+		return "system"
+	default:
+		return strings.ToLower(role)
+	}
+}
+
+// unixTime 将秒级浮点时间戳转换为时间
+func unixTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// chatGPTMessage ChatGPT 导出中 mapping 节点的消息
+type chatGPTMessage struct {
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	Content    json.RawMessage `json:"content"`
+	CreateTime float64         `json:"create_time"`
+}
+
+// parseChatGPT 解析 ChatGPT 导出的对话
+//
+// 消息保存在 mapping 树中，沿 current_node 向上回溯得到当前分支；
+// 没有 current_node 时按创建时间排序所有消息。
+func parseChatGPT(raw []byte) (*Conversation, error) {
+	var export struct {
+		Title       string  `json:"title"`
+		CreateTime  float64 `json:"create_time"`
+		UpdateTime  float64 `json:"update_time"`
+		CurrentNode string  `json:"current_node"`
+		Mapping     map[string]struct {
+			Parent  string          `json:"parent"`
+			Message *chatGPTMessage `json:"message"`
+		} `json:"mapping"`
+	}
+	if err := json.Unmarshal(raw, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse ChatGPT export: %w", err)
+	}
+
+	var nodeIDs []string
+	if _, ok := export.Mapping[export.CurrentNode]; ok {
+		for id := export.CurrentNode; id != ""; id = export.Mapping[id].Parent {
+			nodeIDs = append([]string{id}, nodeIDs...)
+			if len(nodeIDs) > len(export.Mapping) {
+				return nil, fmt.Errorf("failed to parse ChatGPT export: cycle in message tree")
+			}
+		}
+	} else {
+		for id := range export.Mapping {
+			nodeIDs = append(nodeIDs, id)
+		}
+		sort.Slice(nodeIDs, func(i, j int) bool {
+			return export.Mapping[nodeIDs[i]].Message.createTime() < export.Mapping[nodeIDs[j]].Message.createTime()
+		})
+	}
+
+	var messages []Message
+	for _, id := range nodeIDs {
+		node := export.Mapping[id]
+		if node.Message == nil {
+			continue
+		}
+		content := contentText(node.Message.Content)
+		role := normalizeRole(node.Message.Author.Role)
+		// 工具调用等中间消息不导入
+		if content == "" || (role != "user" && role != "assistant" && role != "system") {
+			continue
+		}
+		messages = append(messages, Message{Role: role, Content: content, Timestamp: unixTime(node.Message.CreateTime)})
+	}
+
+	return newImportedConversation(export.Title, messages, unixTime(export.CreateTime), unixTime(export.UpdateTime)), nil
+}
+
+// createTime 返回消息的创建时间，用于排序
+func (m *chatGPTMessage) createTime() float64 {
+	if m == nil {
+		return 0
+	}
+	return m.CreateTime
+}
+
+// parseClaude 解析 Claude 导出的对话
+func parseClaude(raw []byte) (*Conversation, error) {
+	var export struct {
+		Name         string    `json:"name"`
+		CreatedAt    time.Time `json:"created_at"`
+		UpdatedAt    time.Time `json:"updated_at"`
+		ChatMessages []struct {
+			Sender    string          `json:"sender"`
+			Text      string          `json:"text"`
+			Content   json.RawMessage `json:"content"`
+			CreatedAt time.Time       `json:"created_at"`
+		} `json:"chat_messages"`
+	}
+	if err := json.Unmarshal(raw, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse Claude export: %w", err)
+	}
+
+	var messages []Message
+	for _, m := range export.ChatMessages {
+		content := firstNonEmpty(m.Text, contentText(m.Content))
+		if content == "" {
+			continue
+		}
+		messages = append(messages, Message{Role: normalizeRole(m.Sender), Content: content, Timestamp: m.CreatedAt})
+	}
+
+	return newImportedConversation(export.Name, messages, export.CreatedAt, export.UpdatedAt), nil
+}
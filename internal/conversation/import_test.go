@@ -0,0 +1,101 @@
+package conversation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseImport_OpenAIMessages(t *testing.T) {
+	data := `{"title": "shared chat", "messages": [
+		{"role": "system", "content": "be brief"},
+		{"role": "user", "content": [{"type": "text", "text": "hello"}]},
+		{"role": "assistant", "content": "hi"},
+		{"role": "assistant", "content": null}
+	]}`
+
+	convs, err := ParseImport([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseImport failed: %v", err)
+	}
+	conv := convs[0]
+	if conv.Name != "shared chat" || conv.PromptName != importedPromptName {
+		t.Errorf("Unexpected metadata: %+v", conv)
+	}
+	if len(conv.Messages) != 3 || conv.Messages[1].Content != "hello" {
+		t.Errorf("Unexpected messages: %+v", conv.Messages)
+	}
+}
+
+func TestParseImport_MessageArray(t *testing.T) {
+	convs, err := ParseImport([]byte(`[{"role": "user", "content": "a"}, {"role": "assistant", "content": "b"}]`))
+	if err != nil {
+		t.Fatalf("ParseImport failed: %v", err)
+	}
+	if len(convs) != 1 || len(convs[0].Messages) != 2 {
+		t.Errorf("Expected one conversation with 2 messages, got %+v", convs)
+	}
+}
+
+func TestParseImport_ChatGPT(t *testing.T) {
+	data := `[{
+		"title": "Nginx help",
+		"create_time": 1772000000.5,
+		"update_time": 1772000100,
+		"current_node": "c",
+		"mapping": {
+			"root": {"parent": null, "message": null},
+			"a": {"parent": "root", "message": {"author": {"role": "user"}, "content": {"content_type": "text", "parts": ["fix nginx"]}, "create_time": 1772000001}},
+			"b-old": {"parent": "a", "message": {"author": {"role": "assistant"}, "content": {"parts": ["old branch"]}, "create_time": 1772000002}},
+			"b": {"parent": "a", "message": {"author": {"role": "tool"}, "content": {"parts": ["tool output"]}, "create_time": 1772000003}},
+			"c": {"parent": "b", "message": {"author": {"role": "assistant"}, "content": {"parts": ["restart it"]}, "create_time": 1772000004}}
+		}
+	}]`
+
+	convs, err := ParseImport([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseImport failed: %v", err)
+	}
+	conv := convs[0]
+	if conv.Name != "Nginx help" {
+		t.Errorf("Expected title, got %q", conv.Name)
+	}
+	if len(conv.Messages) != 2 || conv.Messages[0].Content != "fix nginx" || conv.Messages[1].Content != "restart it" {
+		t.Errorf("Expected current branch without tool messages, got %+v", conv.Messages)
+	}
+	if !conv.CreatedAt.Equal(time.Unix(1772000000, 5e8)) {
+		t.Errorf("Unexpected created time %v", conv.CreatedAt)
+	}
+}
+
+func TestParseImport_Claude(t *testing.T) {
+	data := `[{
+		"uuid": "x",
+		"name": "Deploy",
+		"created_at": "2026-02-01T10:00:00Z",
+		"updated_at": "2026-02-01T10:05:00Z",
+		"chat_messages": [
+			{"sender": "human", "text": "how to deploy", "created_at": "2026-02-01T10:00:00Z"},
+			{"sender": "assistant", "text": "", "content": [{"type": "text", "text": "use kubectl"}], "created_at": "2026-02-01T10:01:00Z"}
+		]
+	}]`
+
+	convs, err := ParseImport([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseImport failed: %v", err)
+	}
+	conv := convs[0]
+	if conv.Name != "Deploy" || len(conv.Messages) != 2 {
+		t.Fatalf("Unexpected conversation: %+v", conv)
+	}
+	if conv.Messages[0].Role != "user" || conv.Messages[1].Content != "use kubectl" {
+		t.Errorf("Unexpected messages: %+v", conv.Messages)
+	}
+}
+
+func TestParseImport_Invalid(t *testing.T) {
+	for _, data := range []string{"", "# markdown", `{"foo": 1}`, `[]`} {
+		if _, err := ParseImport([]byte(data)); err == nil {
+			t.Errorf("Expected error for %q", data)
+		}
+	}
+}
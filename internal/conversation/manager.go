@@ -5,18 +5,32 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 	"github.com/Lin-Jiong-HDU/tada/internal/memory"
+	"github.com/google/uuid"
 )
 
 // Manager 对话管理器
 type Manager struct {
-	storage      Storage
-	promptLoader *PromptLoader
-	aiProvider   ai.AIProvider
-	memoryMgr    *memory.Manager
+	storage        Storage
+	promptLoader   *PromptLoader
+	aiProvider     ai.AIProvider
+	memoryMgr      *memory.Manager
+	contextBuilder *ContextBuilder
+	titleGenerator TitleGenerator
+
+	// 临时对话不写入存储，保存在内存中供 Get 查找
+	ephemeralMu sync.Mutex
+	ephemeral   map[string]*Conversation
+
+	// 保存对话时按对话 ID 加锁，后台写入标题时不会和其他保存交错
+	saveLocks sync.Map // 对话 ID -> *sync.Mutex
+
+	// 流式回复结束后在后台生成的标题
+	background sync.WaitGroup
 }
 
 // NewManager 创建 Manager
@@ -26,6 +40,7 @@ func NewManager(storage Storage, promptLoader *PromptLoader, aiProvider ai.AIPro
 		promptLoader: promptLoader,
 		aiProvider:   aiProvider,
 		memoryMgr:    nil,
+		ephemeral:    make(map[string]*Conversation),
 	}
 }
 
@@ -34,6 +49,16 @@ func (m *Manager) SetMemoryManager(memMgr *memory.Manager) {
 	m.memoryMgr = memMgr
 }
 
+// SetContextBuilder 设置上下文构建器，启用历史长度控制
+func (m *Manager) SetContextBuilder(builder *ContextBuilder) {
+	m.contextBuilder = builder
+}
+
+// SetTitleGenerator 设置标题生成器，第一轮对话后为没有名称的对话生成标题
+func (m *Manager) SetTitleGenerator(generator TitleGenerator) {
+	m.titleGenerator = generator
+}
+
 // GetMemoryManager returns the memory manager
 func (m *Manager) GetMemoryManager() *memory.Manager {
 	return m.memoryMgr
@@ -60,6 +85,14 @@ func (m *Manager) OnSessionEnd(convID string) error {
 	return m.memoryMgr.OnSessionEnd(adapter)
 }
 
+// checkpointMemory 每完成一轮对话通知记忆，定期写入检查点，避免异常退出时丢失
+func (m *Manager) checkpointMemory(conv *Conversation) {
+	if m.memoryMgr == nil || conv.IsEphemeral() {
+		return
+	}
+	m.memoryMgr.OnTurn(NewMemoryAdapter(conv))
+}
+
 // Create 创建新对话
 func (m *Manager) Create(name, promptName string) (*Conversation, error) {
 	conv := NewConversation(promptName)
@@ -82,7 +115,7 @@ func (m *Manager) Create(name, promptName string) (*Conversation, error) {
 	}
 
 	// 保存
-	if err := m.storage.Save(conv); err != nil {
+	if err := m.save(conv); err != nil {
 		return nil, fmt.Errorf("failed to save conversation: %w", err)
 	}
 
@@ -111,11 +144,25 @@ func (m *Manager) CreateEphemeral(name, promptName string) (*Conversation, error
 	}
 
 	// 不保存到存储
+	m.addEphemeral(conv)
 	return conv, nil
 }
 
-// Get 获取对话
+// addEphemeral 记录临时对话，使其可以通过 Get 查找
+func (m *Manager) addEphemeral(conv *Conversation) {
+	m.ephemeralMu.Lock()
+	defer m.ephemeralMu.Unlock()
+	m.ephemeral[conv.ID] = conv
+}
+
+// Get 获取对话，临时对话从内存中返回
 func (m *Manager) Get(id string) (*Conversation, error) {
+	m.ephemeralMu.Lock()
+	conv, ok := m.ephemeral[id]
+	m.ephemeralMu.Unlock()
+	if ok {
+		return conv, nil
+	}
 	return m.storage.Get(id)
 }
 
@@ -129,6 +176,166 @@ func (m *Manager) ListToday() ([]*Conversation, error) {
 	return m.storage.ListToday()
 }
 
+// Search 全文搜索对话历史
+func (m *Manager) Search(opts SearchOptions) ([]SearchResult, error) {
+	searcher, ok := m.storage.(Searcher)
+	if !ok {
+		return nil, fmt.Errorf("conversation storage does not support search")
+	}
+	return searcher.Search(opts)
+}
+
+// Import 导入对话文件并保存，返回导入的对话
+//
+// 与已有对话 ID 冲突时分配新的 ID，不覆盖本地对话。
+func (m *Manager) Import(data []byte) ([]*Conversation, error) {
+	convs, err := ParseImport(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, conv := range convs {
+		if existing, err := m.storage.Get(conv.ID); err == nil && existing.ID == conv.ID {
+			conv.ID = uuid.New().String()
+		}
+		if err := m.save(conv); err != nil {
+			return nil, fmt.Errorf("failed to save imported conversation: %w", err)
+		}
+	}
+	return convs, nil
+}
+
+// PrepareRetry 回到最后一条用户消息，之后用 Reply 重新生成回复（/retry）
+func (m *Manager) PrepareRetry(convID string) error {
+	return m.update(convID, func(conv *Conversation) error {
+		return conv.PrepareRetry()
+	})
+}
+
+// EditMessage 修改第 n 条用户消息，之后用 Reply 生成新的回复（/edit）
+func (m *Manager) EditMessage(convID string, n int, content string) error {
+	return m.update(convID, func(conv *Conversation) error {
+		return conv.EditUserMessage(n, content)
+	})
+}
+
+// Undo 撤销最后一轮对话（/undo），返回被撤销的消息数
+func (m *Manager) Undo(convID string) (int, error) {
+	var count int
+	err := m.update(convID, func(conv *Conversation) error {
+		var err error
+		count, err = conv.Undo()
+		return err
+	})
+	return count, err
+}
+
+// SwitchBranch 切换到第 n 个分支
+func (m *Manager) SwitchBranch(convID string, n int) error {
+	return m.update(convID, func(conv *Conversation) error {
+		return conv.SwitchBranch(n)
+	})
+}
+
+// Fork 将当前分支复制为新对话并保存（/fork），临时对话的分叉同样是临时的
+func (m *Manager) Fork(convID, name string) (*Conversation, error) {
+	conv, err := m.Get(convID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation not found: %w", err)
+	}
+
+	fork := conv.Fork(name)
+	if fork.IsEphemeral() {
+		m.addEphemeral(fork)
+		return fork, nil
+	}
+	if err := m.save(fork); err != nil {
+		return nil, fmt.Errorf("failed to save forked conversation: %w", err)
+	}
+	return fork, nil
+}
+
+// update 加载对话、修改并保存
+func (m *Manager) update(convID string, fn func(conv *Conversation) error) error {
+	conv, err := m.Get(convID)
+	if err != nil {
+		return fmt.Errorf("conversation not found: %w", err)
+	}
+
+	if err := fn(conv); err != nil {
+		return err
+	}
+
+	// 保存（临时对话不保存）
+	if !conv.IsEphemeral() {
+		if err := m.save(conv); err != nil {
+			return fmt.Errorf("failed to save conversation: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rename 修改对话标题（/rename）
+func (m *Manager) Rename(convID, name string) error {
+	return m.update(convID, func(conv *Conversation) error {
+		conv.Name = strings.TrimSpace(name)
+		conv.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// UpdateTags 添加和移除对话标签（/tag），返回更新后的标签
+func (m *Manager) UpdateTags(convID string, add, remove []string) ([]string, error) {
+	var tags []string
+	err := m.update(convID, func(conv *Conversation) error {
+		conv.UpdateTags(add, remove)
+		tags = conv.Tags
+		return nil
+	})
+	return tags, err
+}
+
+// SetArchived 归档或取消归档对话
+func (m *Manager) SetArchived(convID string, archived bool) (*Conversation, error) {
+	var result *Conversation
+	err := m.update(convID, func(conv *Conversation) error {
+		conv.SetArchived(archived)
+		result = conv
+		return nil
+	})
+	return result, err
+}
+
+// generateTitle 为没有名称的对话生成标题，失败时保持没有标题
+func (m *Manager) generateTitle(ctx context.Context, conv *Conversation) {
+	if title := m.newTitle(ctx, conv); title != "" {
+		conv.Name = title
+	}
+}
+
+// newTitle 返回对话需要的标题，不需要或生成失败时返回空字符串
+//
+// 临时对话不会出现在列表中，不生成标题。
+func (m *Manager) newTitle(ctx context.Context, conv *Conversation) string {
+	if m.titleGenerator == nil || conv.IsEphemeral() || !conv.needsTitle() {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+	defer cancel()
+
+	messages := make([]ai.Message, 0, len(conv.Messages))
+	for _, msg := range conv.ActiveMessages() {
+		messages = append(messages, msg.ToAIFormat())
+	}
+	title, err := m.titleGenerator(ctx, messages)
+	if err != nil {
+		log.Printf("Warning: failed to generate conversation title: %v", err)
+		return ""
+	}
+	return cleanTitle(title)
+}
+
 // Delete 删除对话
 func (m *Manager) Delete(id string) error {
 	return m.storage.Delete(id)
@@ -153,7 +360,7 @@ func (m *Manager) SwitchPrompt(convID, newPromptName string) error {
 
 	// 保存（临时对话不保存）
 	if !conv.IsEphemeral() {
-		if err := m.storage.Save(conv); err != nil {
+		if err := m.save(conv); err != nil {
 			return fmt.Errorf("failed to save conversation: %w", err)
 		}
 	}
@@ -166,8 +373,119 @@ func (m *Manager) ListPrompts() ([]*PromptTemplate, error) {
 	return m.promptLoader.List()
 }
 
+// AppendMessage 向对话追加一条消息（如命令执行结果），作为下一轮对话的上下文
+func (m *Manager) AppendMessage(convID, role, content string) error {
+	conv, err := m.Get(convID)
+	if err != nil {
+		return fmt.Errorf("conversation not found: %w", err)
+	}
+
+	conv.AddMessage(Message{
+		Role:      role,
+		Content:   content,
+		Timestamp: time.Now(),
+	})
+
+	// 保存（临时对话不保存）
+	if !conv.IsEphemeral() {
+		if err := m.save(conv); err != nil {
+			return fmt.Errorf("failed to save conversation: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Compact 手动压缩对话历史（/compact），没有可压缩的消息时返回 nil
+func (m *Manager) Compact(ctx context.Context, convID string) (*Compaction, error) {
+	conv, err := m.Get(convID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation not found: %w", err)
+	}
+
+	builder := m.contextBuilder
+	if builder == nil {
+		builder = &ContextBuilder{Estimate: EstimatorForModel(""), Summarize: NewAISummarizer(m.aiProvider)}
+	}
+
+	compaction, err := builder.Compact(ctx, conv, 0, true)
+	if err != nil || compaction == nil {
+		return compaction, err
+	}
+
+	if !conv.IsEphemeral() {
+		if err := m.save(conv); err != nil {
+			return nil, fmt.Errorf("failed to save conversation: %w", err)
+		}
+	}
+	return compaction, nil
+}
+
+// prepareMessages 构建发送给 AI 的消息
+//
+// 注入记忆上下文；配置了 ContextBuilder 时，超出预算会先压缩最早的对话轮次
+// 并保存压缩记录。系统提示和记忆上下文始终保留。
+func (m *Manager) prepareMessages(ctx context.Context, conv *Conversation) ([]ai.Message, error) {
+	messages := m.withMemory(conv.ID, conv.GetMessagesForAI())
+
+	b := m.contextBuilder
+	if b == nil {
+		return messages, nil
+	}
+
+	// 记忆上下文等不属于对话本身的部分
+	overhead := b.CountTokens(messages) - b.CountTokens(conv.GetMessagesForAI())
+	if overhead < 0 {
+		overhead = 0
+	}
+	if !b.NeedsCompaction(conv, overhead) {
+		return messages, nil
+	}
+
+	compaction, err := b.Compact(ctx, conv, overhead, false)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		// 摘要失败时退化为直接丢弃最早的消息
+		log.Printf("Warning: %v, dropping oldest messages without summary", err)
+		fallback := *b
+		fallback.Summarize = nil
+		compaction, err = fallback.Compact(ctx, conv, overhead, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if compaction != nil && !conv.IsEphemeral() {
+		if err := m.save(conv); err != nil {
+			return nil, fmt.Errorf("failed to save compacted conversation: %w", err)
+		}
+	}
+
+	return m.withMemory(conv.ID, conv.GetMessagesForAI()), nil
+}
+
+// withMemory 注入记忆上下文
+//
+// 为避免出现多个 system 提示，在构建记忆上下文前移除已有的 system 消息，
+// 让 BuildContext 负责生成统一的带记忆的 system 提示。
+func (m *Manager) withMemory(convID string, messages []ai.Message) []ai.Message {
+	if m.memoryMgr == nil {
+		return messages
+	}
+
+	var nonSystemMessages []ai.Message
+	for _, msg := range messages {
+		if msg.Role != "system" {
+			nonSystemMessages = append(nonSystemMessages, msg)
+		}
+	}
+	return m.memoryMgr.BuildContextFor(convID, nonSystemMessages)
+}
+
 // Chat 发送消息并获取回复
-func (m *Manager) Chat(convID string, userInput string) (string, error) {
+func (m *Manager) Chat(ctx context.Context, convID string, userInput string) (string, error) {
 	conv, err := m.Get(convID)
 	if err != nil {
 		return "", fmt.Errorf("conversation not found: %w", err)
@@ -181,22 +499,26 @@ func (m *Manager) Chat(convID string, userInput string) (string, error) {
 	}
 	conv.AddMessage(userMsg)
 
+	return m.reply(ctx, conv)
+}
+
+// Reply 为当前分支最后一条用户消息生成回复（/retry、/edit 之后使用）
+func (m *Manager) Reply(ctx context.Context, convID string) (string, error) {
+	conv, err := m.Get(convID)
+	if err != nil {
+		return "", fmt.Errorf("conversation not found: %w", err)
+	}
+	return m.reply(ctx, conv)
+}
+
+// reply 调用 AI 并将回复添加到当前分支
+func (m *Manager) reply(ctx context.Context, conv *Conversation) (string, error) {
 	// 调用 AI
-	messages := conv.GetMessagesForAI()
-	// Inject memory context if available
-	// 为避免出现多个 system 提示，在构建记忆上下文前移除已有的 system 消息，
-	// 让 BuildContext 负责生成统一的带记忆的 system 提示。
-	if m.memoryMgr != nil {
-		// 移除已有的 system 消息（第一条消息）
-		var nonSystemMessages []ai.Message
-		for _, msg := range messages {
-			if msg.Role != "system" {
-				nonSystemMessages = append(nonSystemMessages, msg)
-			}
-		}
-		messages = m.memoryMgr.BuildContext(nonSystemMessages)
+	messages, err := m.prepareMessages(ctx, conv)
+	if err != nil {
+		return "", err
 	}
-	response, err := m.aiProvider.Chat(context.Background(), messages)
+	response, err := m.aiProvider.Chat(ctx, messages)
 	if err != nil {
 		return "", fmt.Errorf("AI call failed: %w", err)
 	}
@@ -209,11 +531,20 @@ func (m *Manager) Chat(convID string, userInput string) (string, error) {
 	}
 	conv.AddMessage(assistantMsg)
 
+	// 回复后被中断时只保存回复，不再生成标题和记忆检查点
+	completed := ctx.Err() == nil
+	if completed {
+		m.generateTitle(ctx, conv)
+	}
+
 	// 保存（临时对话不保存）
 	if !conv.IsEphemeral() {
-		if err := m.storage.Save(conv); err != nil {
+		if err := m.save(conv); err != nil {
 			return "", fmt.Errorf("failed to save conversation: %w", err)
 		}
+		if completed {
+			m.checkpointMemory(conv)
+		}
 	}
 
 	return response, nil
@@ -225,22 +556,26 @@ func (m *Manager) Chat(convID string, userInput string) (string, error) {
 // 1. 添加用户消息到对话
 // 2. 调用 AI 提供者的流式接口
 // 3. 在独立 goroutine 中：
-//   - 逐块发送响应到输出 channel
+//   - 逐个转发 AI 提供者的事件到输出 channel
 //   - 完成后重新加载对话（避免竞态条件）
 //   - 添加助手消息并保存
+//   - 写入记忆检查点，需要标题时在后台生成（见 Wait）
+//
+// ctx 取消后停止生成，已收到的部分回复标记为中断后保存；
+// 流中途出错时部分回复连同错误信息一起保存，结束原因也会记录在消息上。
 //
 // 参数：
 //
+//	ctx - 取消时中断生成
 //	convID - 对话 ID
 //	userInput - 用户输入内容
 //
 // 返回：
 //
-//	<-chan string - 响应内容流，消费完后 channel 自动关闭
+//	<-chan ai.StreamEvent - 响应事件流，消费完后 channel 自动关闭
 //	error - 错误信息（nil 表示成功）
-func (m *Manager) ChatStream(convID string, userInput string) (<-chan string, error) {
-	// 对于临时对话，无法从存储加载，需要特殊处理
-	// 这里我们先尝试获取，如果失败则检查是否可能是临时对话
+func (m *Manager) ChatStream(ctx context.Context, convID string, userInput string) (<-chan ai.StreamEvent, error) {
+	// 临时对话由 Get 从内存中返回
 	conv, err := m.Get(convID)
 	if err != nil {
 		return nil, fmt.Errorf("conversation not found: %w", err)
@@ -254,50 +589,74 @@ func (m *Manager) ChatStream(convID string, userInput string) (<-chan string, er
 	}
 	conv.AddMessage(userMsg)
 
-	// 记录是否为临时对话，在 goroutine 中使用
-	isEphemeral := conv.IsEphemeral()
-
 	// 先保存用户消息（确保后续重新加载时能获取到）
 	// 临时对话不保存，在 goroutine 中直接使用内存对象
-	if !isEphemeral {
-		if err := m.storage.Save(conv); err != nil {
+	if !conv.IsEphemeral() {
+		if err := m.save(conv); err != nil {
 			return nil, fmt.Errorf("failed to save user message: %w", err)
 		}
 	}
 
+	return m.replyStream(ctx, conv)
+}
+
+// ReplyStream 为当前分支最后一条用户消息流式生成回复
+func (m *Manager) ReplyStream(ctx context.Context, convID string) (<-chan ai.StreamEvent, error) {
+	conv, err := m.Get(convID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation not found: %w", err)
+	}
+	return m.replyStream(ctx, conv)
+}
+
+// replyStream 调用 AI 流式接口，完成后将回复添加到当前分支
+func (m *Manager) replyStream(ctx context.Context, conv *Conversation) (<-chan ai.StreamEvent, error) {
+	// 记录是否为临时对话，在 goroutine 中使用
+	isEphemeral := conv.IsEphemeral()
+
 	// 调用 AI 流式接口
-	messages := conv.GetMessagesForAI()
-	// Inject memory context if available
-	// 为避免出现多个 system 提示，在构建记忆上下文前移除已有的 system 消息，
-	// 让 BuildContext 负责生成统一的带记忆的 system 提示。
-	if m.memoryMgr != nil {
-		// 移除已有的 system 消息（第一条消息）
-		var nonSystemMessages []ai.Message
-		for _, msg := range messages {
-			if msg.Role != "system" {
-				nonSystemMessages = append(nonSystemMessages, msg)
-			}
-		}
-		messages = m.memoryMgr.BuildContext(nonSystemMessages)
+	messages, err := m.prepareMessages(ctx, conv)
+	if err != nil {
+		return nil, err
 	}
-	stream, err := m.aiProvider.ChatStream(context.Background(), messages)
+	stream, err := m.aiProvider.ChatStream(ctx, messages)
 	if err != nil {
 		return nil, fmt.Errorf("AI call failed: %w", err)
 	}
 
 	// 创建输出 channel
-	out := make(chan string)
+	out := make(chan ai.StreamEvent)
 	// 使用局部变量避免在 goroutine 中引用可能变化的变量
 	id := conv.ID
 
 	go func() {
 		defer close(out)
 
-		var fullResponse strings.Builder
+		var fullResponse, reasoning strings.Builder
+		var finishReason string
+		var streamErr error
 
-		for chunk := range stream {
-			fullResponse.WriteString(chunk)
-			out <- chunk
+		for event := range stream {
+			fullResponse.WriteString(event.Content)
+			reasoning.WriteString(event.Reasoning)
+			if event.FinishReason != "" {
+				finishReason = event.FinishReason
+			}
+			if event.Err != nil {
+				streamErr = event.Err
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				// 调用方可能已停止读取，继续读到 provider 关闭 channel
+			}
+		}
+
+		// 被中断或出错且没有收到内容时不添加回复，可以用 /retry 重新生成
+		interrupted := ctx.Err() != nil
+		completed := !interrupted && streamErr == nil
+		if !completed && fullResponse.Len() == 0 {
+			return
 		}
 
 		// 根据对话类型选择处理方式
@@ -316,17 +675,78 @@ func (m *Manager) ChatStream(convID string, userInput string) (<-chan string, er
 
 		// 添加助手回复
 		assistantMsg := Message{
-			Role:      "assistant",
-			Content:   fullResponse.String(),
-			Timestamp: time.Now(),
+			Role:         "assistant",
+			Content:      fullResponse.String(),
+			Timestamp:    time.Now(),
+			Reasoning:    reasoning.String(),
+			Interrupted:  interrupted,
+			FinishReason: finishReason,
+		}
+		if streamErr != nil {
+			assistantMsg.Error = streamErr.Error()
 		}
 		targetConv.AddMessage(assistantMsg)
 
 		// 保存（临时对话不保存）
-		if !isEphemeral {
-			_ = m.storage.Save(targetConv) // 保存失败时至少已发送到 channel
+		if isEphemeral {
+			return
+		}
+		_ = m.save(targetConv) // 保存失败时至少已发送到 channel
+
+		// 被中断或出错的回复不完整，不生成标题和记忆检查点
+		if !completed {
+			return
+		}
+		m.checkpointMemory(targetConv)
+
+		// 标题生成需要再调用一次 AI，在后台进行，输出流关闭后调用方不必等待
+		if m.titleGenerator != nil && targetConv.needsTitle() {
+			m.background.Add(1)
+			go m.titleInBackground(context.WithoutCancel(ctx), targetConv)
 		}
 	}()
 
 	return out, nil
 }
+
+// titleInBackground 为 conv 生成标题，合并到最新保存的对话中
+//
+// 等待 AI 时不持有锁，只在重新读取和保存时加锁；这期间用户已经重命名时保留用户的名称。
+func (m *Manager) titleInBackground(ctx context.Context, conv *Conversation) {
+	defer m.background.Done()
+
+	title := m.newTitle(ctx, conv)
+	if title == "" {
+		return
+	}
+
+	unlock := m.lockConv(conv.ID)
+	defer unlock()
+	latest, err := m.storage.Get(conv.ID)
+	if err != nil || latest.Name != "" {
+		return
+	}
+	latest.Name = title
+	if err := m.storage.Save(latest); err != nil {
+		log.Printf("Warning: failed to save conversation title: %v", err)
+	}
+}
+
+// Wait 等待后台的标题生成完成，退出前调用
+func (m *Manager) Wait() {
+	m.background.Wait()
+}
+
+// lockConv 锁定一个对话的保存，返回解锁函数
+func (m *Manager) lockConv(id string) func() {
+	mu, _ := m.saveLocks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// save 保存对话，与后台写入的标题互斥
+func (m *Manager) save(conv *Conversation) error {
+	unlock := m.lockConv(conv.ID)
+	defer unlock()
+	return m.storage.Save(conv)
+}
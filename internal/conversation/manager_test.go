@@ -1,8 +1,13 @@
 package conversation
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
@@ -25,11 +30,11 @@ func (m *mockChatAIProvider) Chat(ctx context.Context, messages []ai.Message) (s
 	return m.response, nil
 }
 
-func (m *mockChatAIProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan string, error) {
-	ch := make(chan string)
+func (m *mockChatAIProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
+	ch := make(chan ai.StreamEvent)
 	go func() {
 		defer close(ch)
-		ch <- m.response
+		ch <- ai.StreamEvent{Content: m.response, FinishReason: ai.FinishStop}
 	}()
 	return ch, nil
 }
@@ -76,7 +81,7 @@ func TestManager_Chat(t *testing.T) {
 
 	conv, _ := manager.Create("test", "default")
 
-	response, err := manager.Chat(conv.ID, "hi")
+	response, err := manager.Chat(context.Background(), conv.ID, "hi")
 	if err != nil {
 		t.Fatalf("Chat failed: %v", err)
 	}
@@ -121,3 +126,642 @@ func TestManager_CreateEphemeral(t *testing.T) {
 		t.Error("Expected ephemeral conversation to not be saved")
 	}
 }
+
+func TestManager_AppendMessage(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &mockChatAIProvider{response: "Hello"})
+	conv, _ := manager.Create("test", "default")
+
+	if err := manager.AppendMessage(conv.ID, "user", "$ ls\nfoo"); err != nil {
+		t.Fatalf("AppendMessage failed: %v", err)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	last := loaded.Messages[len(loaded.Messages)-1]
+	if last.Role != "user" || last.Content != "$ ls\nfoo" {
+		t.Errorf("Unexpected appended message: %+v", last)
+	}
+}
+
+func TestManager_Chat_CompactsHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	aiProvider := &mockChatAIProvider{response: "ok"}
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), aiProvider)
+	manager.SetContextBuilder(&ContextBuilder{
+		MaxMessages: 6,
+		Estimate:    EstimatorForModel(""),
+		Summarize:   NewAISummarizer(aiProvider),
+	})
+
+	conv, _ := manager.Create("test", "default")
+	for i := 0; i < 5; i++ {
+		if _, err := manager.Chat(context.Background(), conv.ID, "hi"); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+
+	loaded, err := manager.Get(conv.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(loaded.Compactions) == 0 {
+		t.Fatal("Expected history to be compacted and saved")
+	}
+	if got := len(loaded.activeIndexes()); got > 7 {
+		t.Errorf("Expected at most 7 active messages, got %d", got)
+	}
+	if loaded.LatestSummary() != "ok" {
+		t.Errorf("Expected summary from AI, got %q", loaded.LatestSummary())
+	}
+}
+
+func TestManager_Compact(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &mockChatAIProvider{response: "summary"})
+	conv, _ := manager.Create("test", "default")
+
+	compaction, err := manager.Compact(context.Background(), conv.ID)
+	if err != nil || compaction != nil {
+		t.Fatalf("Expected nothing to compact in a new conversation, got %+v, %v", compaction, err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := manager.Chat(context.Background(), conv.ID, "hi"); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+
+	compaction, err = manager.Compact(context.Background(), conv.ID)
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if compaction == nil || compaction.MessageCount != 4 || !compaction.Manual {
+		t.Fatalf("Expected manual compaction of 4 messages, got %+v", compaction)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	if loaded.LatestSummary() != "summary" {
+		t.Errorf("Expected saved summary, got %q", loaded.LatestSummary())
+	}
+}
+
+func TestManager_Import_AssignsNewIDOnConflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &mockChatAIProvider{})
+
+	conv, _ := manager.Create("original", "default")
+	original, _ := manager.Get(conv.ID)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, original, FormatJSON); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	imported, err := manager.Import(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(imported) != 1 || imported[0].ID == conv.ID {
+		t.Fatalf("Expected a new ID for the conflicting conversation, got %+v", imported)
+	}
+
+	convs, _ := manager.List()
+	if len(convs) != 2 {
+		t.Errorf("Expected 2 conversations after import, got %d", len(convs))
+	}
+}
+
+func TestManager_Import_RejectsTraversalID(t *testing.T) {
+	root := t.TempDir()
+	convDir := filepath.Join(root, "conversations")
+	manager := NewManager(NewFileStorage(convDir), NewPromptLoader(root), &mockChatAIProvider{})
+
+	for _, id := range []string{"../../../../escaped", ".hidden", "a/b", `..\x`} {
+		conv, _ := json.Marshal(map[string]any{
+			"id":          id,
+			"prompt_name": "default",
+			"messages":    []map[string]string{{"role": "user", "content": "hi"}},
+		})
+		data := `{"format": "` + ExportFormatName + `", "conversation": ` + string(conv) + `}`
+		imported, err := manager.Import([]byte(data))
+		if err != nil {
+			t.Fatalf("Import(%q) failed: %v", id, err)
+		}
+		if got := imported[0].ID; got == id || !validID(got) {
+			t.Errorf("Expected a new ID for %q, got %q", id, got)
+		}
+	}
+
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if entry.Name() != "conversations" {
+			t.Errorf("Expected nothing written outside the conversations directory, found %s", entry.Name())
+		}
+	}
+
+	if err := NewFileStorage(convDir).Save(&Conversation{ID: "../escaped"}); err == nil {
+		t.Error("Expected Save to reject a path as ID")
+	}
+}
+
+func TestManager_RetryAndEdit(t *testing.T) {
+	tmpDir := t.TempDir()
+	aiProvider := &mockChatAIProvider{response: "first"}
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), aiProvider)
+	conv, _ := manager.Create("test", "default")
+
+	if _, err := manager.Chat(context.Background(), conv.ID, "question"); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	// /retry
+	aiProvider.response = "second"
+	if err := manager.PrepareRetry(conv.ID); err != nil {
+		t.Fatalf("PrepareRetry failed: %v", err)
+	}
+	if _, err := manager.Reply(context.Background(), conv.ID); err != nil {
+		t.Fatalf("Reply failed: %v", err)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	active := loaded.ActiveMessages()
+	if active[len(active)-1].Content != "second" || len(loaded.Branches()) != 2 {
+		t.Errorf("Expected regenerated reply on a new branch, got %v", contents(active))
+	}
+
+	// /edit，流式生成回复
+	aiProvider.response = "third"
+	if err := manager.EditMessage(conv.ID, 1, "edited question"); err != nil {
+		t.Fatalf("EditMessage failed: %v", err)
+	}
+	stream, err := manager.ReplyStream(context.Background(), conv.ID)
+	if err != nil {
+		t.Fatalf("ReplyStream failed: %v", err)
+	}
+	for range stream {
+	}
+
+	loaded, _ = manager.Get(conv.ID)
+	active = loaded.ActiveMessages()
+	if got := contents(active[1:]); len(got) != 2 || got[0] != "edited question" || got[1] != "third" {
+		t.Errorf("Unexpected active branch after edit: %v", got)
+	}
+	if len(loaded.Branches()) != 3 {
+		t.Errorf("Expected 3 branches, got %d", len(loaded.Branches()))
+	}
+
+	// --continue --branch
+	if err := manager.SwitchBranch(conv.ID, 1); err != nil {
+		t.Fatalf("SwitchBranch failed: %v", err)
+	}
+	loaded, _ = manager.Get(conv.ID)
+	active = loaded.ActiveMessages()
+	if active[len(active)-1].Content != "first" {
+		t.Errorf("Expected first branch, got %v", contents(active))
+	}
+}
+
+func TestManager_UndoAndFork(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &mockChatAIProvider{response: "ok"})
+	conv, _ := manager.Create("test", "default")
+	manager.Chat(context.Background(), conv.ID, "one")
+	manager.Chat(context.Background(), conv.ID, "two")
+
+	count, err := manager.Undo(conv.ID)
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 messages undone, got %d, %v", count, err)
+	}
+
+	fork, err := manager.Fork(conv.ID, "copy")
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	loadedFork, err := manager.Get(fork.ID)
+	if err != nil {
+		t.Fatalf("Expected fork to be saved: %v", err)
+	}
+	if got := contents(loadedFork.ActiveMessages()[1:]); len(got) != 2 || got[0] != "one" {
+		t.Errorf("Expected fork of the active branch, got %v", got)
+	}
+}
+
+func TestManager_EphemeralFork(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage := NewFileStorage(tmpDir)
+	manager := NewManager(storage, NewPromptLoader(tmpDir), &mockChatAIProvider{response: "ok"})
+	conv, _ := manager.CreateEphemeral("test", "default")
+	if _, err := manager.Chat(context.Background(), conv.ID, "one"); err != nil {
+		t.Fatalf("Chat on an ephemeral conversation failed: %v", err)
+	}
+
+	fork, err := manager.Fork(conv.ID, "copy")
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if !fork.IsEphemeral() {
+		t.Error("Expected the fork of an ephemeral conversation to be ephemeral")
+	}
+	if _, err := storage.Get(fork.ID); err == nil {
+		t.Error("Expected the ephemeral fork to not be saved")
+	}
+	if got, err := manager.Get(fork.ID); err != nil || got != fork {
+		t.Errorf("Expected the fork to be found in memory, got %v, %v", got, err)
+	}
+	if list, _ := storage.List(); len(list) != 0 {
+		t.Errorf("Expected nothing saved, got %d conversations", len(list))
+	}
+}
+
+func TestManager_GeneratesTitle(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &mockChatAIProvider{response: "ok"})
+
+	calls := 0
+	manager.SetTitleGenerator(func(ctx context.Context, messages []ai.Message) (string, error) {
+		calls++
+		return "\"Greeting\"", nil
+	})
+
+	conv, _ := manager.Create("", "default")
+	stream, err := manager.ChatStream(context.Background(), conv.ID, "hi")
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	for range stream {
+	}
+	manager.Wait()
+	manager.Chat(context.Background(), conv.ID, "again")
+	manager.Wait()
+
+	loaded, _ := manager.Get(conv.ID)
+	if loaded.Name != "Greeting" {
+		t.Errorf("Expected generated title, got %q", loaded.Name)
+	}
+	if calls != 1 {
+		t.Errorf("Expected title to be generated once, got %d calls", calls)
+	}
+
+	// 已有名称的对话不生成标题
+	named, _ := manager.Create("mine", "default")
+	manager.Chat(context.Background(), named.ID, "hi")
+	loaded, _ = manager.Get(named.ID)
+	if loaded.Name != "mine" || calls != 1 {
+		t.Errorf("Expected named conversation to keep its name, got %q", loaded.Name)
+	}
+}
+
+func TestManager_ChatStream_ClosesBeforeTitle(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &mockChatAIProvider{response: "ok"})
+
+	release := make(chan struct{})
+	manager.SetTitleGenerator(func(ctx context.Context, messages []ai.Message) (string, error) {
+		<-release
+		return "Greeting", ctx.Err()
+	})
+
+	conv, _ := manager.Create("", "default")
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := manager.ChatStream(ctx, conv.ID, "hi")
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	// 标题还在生成时流已经关闭
+	for range stream {
+	}
+	cancel()
+
+	// 标题生成期间读取和保存对话不会被阻塞
+	if _, err := manager.Get(conv.ID); err != nil {
+		t.Fatalf("Get failed while the title was being generated: %v", err)
+	}
+	if err := manager.Rename(conv.ID, ""); err != nil {
+		t.Fatalf("Rename failed while the title was being generated: %v", err)
+	}
+
+	close(release)
+	manager.Wait()
+
+	loaded, _ := manager.Get(conv.ID)
+	if loaded.Name != "Greeting" {
+		t.Errorf("Expected the title to be saved after the stream closed, got %q", loaded.Name)
+	}
+}
+
+func TestManager_RenameTagArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &mockChatAIProvider{})
+	conv, _ := manager.Create("", "default")
+
+	if err := manager.Rename(conv.ID, " New title "); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	tags, err := manager.UpdateTags(conv.ID, []string{"work", "nginx"}, []string{"nginx"})
+	if err != nil || len(tags) != 1 || tags[0] != "work" {
+		t.Fatalf("Unexpected tags %v, %v", tags, err)
+	}
+	if _, err := manager.SetArchived(conv.ID, true); err != nil {
+		t.Fatalf("SetArchived failed: %v", err)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	if loaded.Name != "New title" || !loaded.HasTag("work") || !loaded.IsArchived() {
+		t.Errorf("Expected changes to be saved, got %+v", loaded)
+	}
+}
+
+// blockingStreamProvider 发送一块内容后一直等待，直到 ctx 被取消
+type blockingStreamProvider struct {
+	mockChatAIProvider
+}
+
+func (m *blockingStreamProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
+	ch := make(chan ai.StreamEvent)
+	go func() {
+		defer close(ch)
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case ch <- ai.StreamEvent{Content: "partial answer"}:
+		case <-ctx.Done():
+			return
+		}
+		<-ctx.Done()
+	}()
+	return ch, nil
+}
+
+func TestManager_ChatStream_Interrupted(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &blockingStreamProvider{})
+	conv, _ := manager.Create("test", "default")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := manager.ChatStream(ctx, conv.ID, "write a long story")
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if event := <-stream; event.Content != "partial answer" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	cancel()
+	for range stream {
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	messages := loaded.ActiveMessages()
+	last := messages[len(messages)-1]
+	if last.Role != "assistant" || last.Content != "partial answer" || !last.Interrupted {
+		t.Fatalf("expected interrupted partial answer, got %+v", last)
+	}
+
+	// 发送给 AI 时带上中断标记
+	aiMessages := loaded.GetMessagesForAI()
+	if got := aiMessages[len(aiMessages)-1].Content; got != "partial answer"+interruptedNote {
+		t.Errorf("AI message = %q", got)
+	}
+}
+
+func TestManager_ChatStream_InterruptedBeforeContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &blockingStreamProvider{})
+	conv, _ := manager.Create("test", "default")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream, err := manager.ChatStream(ctx, conv.ID, "hi")
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	for range stream {
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	messages := loaded.ActiveMessages()
+	if last := messages[len(messages)-1]; last.Role != "user" {
+		t.Errorf("no assistant message should be saved, got %+v", last)
+	}
+}
+
+// eventStreamProvider 依次发送预设的流式事件
+type eventStreamProvider struct {
+	mockChatAIProvider
+	events []ai.StreamEvent
+}
+
+func (m *eventStreamProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
+	ch := make(chan ai.StreamEvent, len(m.events))
+	for _, event := range m.events {
+		ch <- event
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestManager_ChatStream_Error(t *testing.T) {
+	tmpDir := t.TempDir()
+	streamErr := errors.New("connection reset")
+	provider := &eventStreamProvider{events: []ai.StreamEvent{
+		{Content: "partial"},
+		{Err: streamErr},
+	}}
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), provider)
+	conv, _ := manager.Create("test", "default")
+
+	stream, err := manager.ChatStream(context.Background(), conv.ID, "hi")
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	var gotErr error
+	for event := range stream {
+		if event.Err != nil {
+			gotErr = event.Err
+		}
+	}
+	if !errors.Is(gotErr, streamErr) {
+		t.Fatalf("stream error not propagated, got %v", gotErr)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	messages := loaded.ActiveMessages()
+	last := messages[len(messages)-1]
+	if last.Role != "assistant" || last.Content != "partial" || last.Error != streamErr.Error() {
+		t.Fatalf("expected failed partial answer, got %+v", last)
+	}
+	if last.IncompleteLabel() != failedLabel {
+		t.Errorf("IncompleteLabel = %q", last.IncompleteLabel())
+	}
+	aiMessages := loaded.GetMessagesForAI()
+	if got := aiMessages[len(aiMessages)-1].Content; got != "partial"+failedNote {
+		t.Errorf("AI message = %q", got)
+	}
+
+	// 没有收到内容时不保存回复
+	provider.events = []ai.StreamEvent{{Err: streamErr}}
+	stream, _ = manager.ChatStream(context.Background(), conv.ID, "again")
+	for range stream {
+	}
+	loaded, _ = manager.Get(conv.ID)
+	messages = loaded.ActiveMessages()
+	if last := messages[len(messages)-1]; last.Role != "user" {
+		t.Errorf("no assistant message should be saved, got %+v", last)
+	}
+}
+
+// cancelingChatProvider 回复后取消 ctx，模拟回复刚返回时用户按下 Ctrl-C
+type cancelingChatProvider struct {
+	mockChatAIProvider
+	cancel context.CancelFunc
+}
+
+func (m *cancelingChatProvider) Chat(ctx context.Context, messages []ai.Message) (string, error) {
+	m.cancel()
+	return "ok", nil
+}
+
+func TestManager_SkipsTitleForIncompleteReplies(t *testing.T) {
+	tmpDir := t.TempDir()
+	calls := 0
+	titles := func(ctx context.Context, messages []ai.Message) (string, error) {
+		calls++
+		return "Title", nil
+	}
+
+	provider := &eventStreamProvider{events: []ai.StreamEvent{
+		{Content: "partial"},
+		{Err: errors.New("connection reset")},
+	}}
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), provider)
+	manager.SetTitleGenerator(titles)
+	conv, _ := manager.Create("", "default")
+	stream, _ := manager.ChatStream(context.Background(), conv.ID, "hi")
+	for range stream {
+	}
+
+	interrupted := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &blockingStreamProvider{})
+	interrupted.SetTitleGenerator(titles)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, _ = interrupted.ChatStream(ctx, conv.ID, "again")
+	<-stream
+	cancel()
+	for range stream {
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	canceled := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &cancelingChatProvider{cancel: cancel})
+	canceled.SetTitleGenerator(titles)
+	if _, err := canceled.Chat(ctx, conv.ID, "once more"); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	if calls != 0 || loaded.Name != "" {
+		t.Errorf("Expected no title for incomplete replies, got %d calls, name %q", calls, loaded.Name)
+	}
+	if got := len(loaded.ActiveMessages()); got != 7 {
+		t.Errorf("Expected the partial replies to be saved, got %d messages", got)
+	}
+}
+
+func TestManager_GeneratesTitleAfterInterruptedReply(t *testing.T) {
+	tmpDir := t.TempDir()
+	titles := func(ctx context.Context, messages []ai.Message) (string, error) {
+		return "Title", nil
+	}
+
+	interrupted := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &blockingStreamProvider{})
+	interrupted.SetTitleGenerator(titles)
+	conv, _ := interrupted.Create("", "default")
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, _ := interrupted.ChatStream(ctx, conv.ID, "hi")
+	<-stream
+	cancel()
+	for range stream {
+	}
+	interrupted.Wait()
+
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), &mockChatAIProvider{response: "ok"})
+	manager.SetTitleGenerator(titles)
+	if err := manager.PrepareRetry(conv.ID); err != nil {
+		t.Fatalf("PrepareRetry failed: %v", err)
+	}
+	stream, err := manager.ReplyStream(context.Background(), conv.ID)
+	if err != nil {
+		t.Fatalf("ReplyStream failed: %v", err)
+	}
+	for range stream {
+	}
+	manager.Wait()
+
+	loaded, _ := manager.Get(conv.ID)
+	if loaded.Name != "Title" {
+		t.Errorf("Expected a title once a retried reply completes, got %q", loaded.Name)
+	}
+}
+
+func TestManager_ChatStream_Truncated(t *testing.T) {
+	tmpDir := t.TempDir()
+	provider := &eventStreamProvider{events: []ai.StreamEvent{
+		{Content: "a long"},
+		{Content: " answer", FinishReason: ai.FinishLength},
+		{Usage: &ai.Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7}},
+	}}
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), provider)
+	conv, _ := manager.Create("test", "default")
+
+	stream, err := manager.ChatStream(context.Background(), conv.ID, "hi")
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	var usage *ai.Usage
+	for event := range stream {
+		if event.Usage != nil {
+			usage = event.Usage
+		}
+	}
+	if usage == nil || usage.TotalTokens != 7 {
+		t.Errorf("usage not propagated, got %+v", usage)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	messages := loaded.ActiveMessages()
+	last := messages[len(messages)-1]
+	if last.Content != "a long answer" || last.FinishReason != ai.FinishLength || last.IncompleteLabel() != truncatedLabel {
+		t.Errorf("expected truncated answer, got %+v", last)
+	}
+}
+
+func TestManager_ChatStream_KeepsReasoningSeparate(t *testing.T) {
+	tmpDir := t.TempDir()
+	provider := &eventStreamProvider{events: []ai.StreamEvent{
+		{Reasoning: "The user greets me."},
+		{Content: "Hello!", FinishReason: ai.FinishStop},
+	}}
+	manager := NewManager(NewFileStorage(tmpDir), NewPromptLoader(tmpDir), provider)
+	conv, _ := manager.Create("test", "default")
+
+	stream, err := manager.ChatStream(context.Background(), conv.ID, "hi")
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	for range stream {
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	messages := loaded.ActiveMessages()
+	last := messages[len(messages)-1]
+	if last.Content != "Hello!" || last.Reasoning != "The user greets me." {
+		t.Fatalf("unexpected assistant message %+v", last)
+	}
+	// 思考过程不作为上下文发送
+	for _, msg := range loaded.GetMessagesForAI() {
+		if strings.Contains(msg.Content, "greets") {
+			t.Errorf("reasoning sent to AI: %q", msg.Content)
+		}
+	}
+}
@@ -21,11 +21,12 @@ func (a *MemoryAdapter) ID() string {
 	return a.conv.ID
 }
 
-// GetMessages returns the conversation messages
+// GetMessages returns the messages on the active branch
 func (a *MemoryAdapter) GetMessages() []memory.ConversationMessage {
-	msgs := make([]memory.ConversationMessage, len(a.conv.Messages))
-	for i := range a.conv.Messages {
-		msgs[i] = memoryMessage{msg: &a.conv.Messages[i]}
+	path := a.conv.ActivePath()
+	msgs := make([]memory.ConversationMessage, len(path))
+	for i, idx := range path {
+		msgs[i] = memoryMessage{msg: &a.conv.Messages[idx]}
 	}
 	return msgs
 }
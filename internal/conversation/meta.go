@@ -0,0 +1,176 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+// maxTitleRunes 标题的最大长度（字符）
+const maxTitleRunes = 40
+
+// titleTimeout 生成标题的超时时间，标题不重要，不值得长时间等待
+const titleTimeout = 30 * time.Second
+
+// TitleGenerator 根据第一轮对话生成标题
+type TitleGenerator func(ctx context.Context, messages []ai.Message) (string, error)
+
+// NewAITitleGenerator 使用 AI 生成标题
+func NewAITitleGenerator(provider ai.AIProvider) TitleGenerator {
+	return func(ctx context.Context, messages []ai.Message) (string, error) {
+		var b strings.Builder
+		for _, msg := range messages {
+			if msg.Role == "system" {
+				continue
+			}
+			content := msg.Content
+			if utf8.RuneCountInString(content) > 1000 {
+				content = string([]rune(content)[:1000]) + "..."
+			}
+			fmt.Fprintf(&b, "%s: %s\n", msg.Role, content)
+		}
+
+		prompt := []ai.Message{
+			{
+				Role: "system",
+				Content: "为下面的对话起一个简短的标题（不超过 20 个字），概括讨论的主题。" +
+					"使用对话所用的语言，只输出标题，不要引号和标点。",
+			},
+			{Role: "user", Content: b.String()},
+		}
+		return provider.Chat(ctx, prompt)
+	}
+}
+
+// cleanTitle 清理 AI 生成的标题
+func cleanTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = title[:i]
+	}
+	title = strings.TrimPrefix(title, "标题：")
+	title = strings.TrimPrefix(title, "Title:")
+	title = strings.Trim(strings.TrimSpace(title), "\"'“”「」《》#*. 。")
+	if utf8.RuneCountInString(title) > maxTitleRunes {
+		title = string([]rune(title)[:maxTitleRunes])
+	}
+	return title
+}
+
+// needsTitle 判断是否应该生成标题：没有名称且当前分支上已有完成的回复
+//
+// 第一条回复被中断、出错，或者 /retry、分叉之后，下一条完成的回复仍会触发标题生成。
+func (c *Conversation) needsTitle() bool {
+	if c.Name != "" {
+		return false
+	}
+	for _, msg := range c.ActiveMessages() {
+		if msg.Role == "assistant" && !msg.Interrupted && msg.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Title 返回对话标题，没有名称时使用第一条用户消息的开头
+func (c *Conversation) Title() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	for _, msg := range c.ActiveMessages() {
+		if msg.Role == "user" {
+			return preview(strings.Join(strings.Fields(msg.Content), " "), maxTitleRunes)
+		}
+	}
+	return ""
+}
+
+// IsArchived 返回对话是否已归档
+func (c *Conversation) IsArchived() bool {
+	return c.Status == StatusArchived
+}
+
+// SetArchived 归档或取消归档对话
+func (c *Conversation) SetArchived(archived bool) {
+	if archived {
+		c.Status = StatusArchived
+	} else {
+		c.Status = StatusActive
+	}
+	c.UpdatedAt = time.Now()
+}
+
+// NormalizeTag 统一标签格式：去掉 # 前缀，小写，空白替换为 -
+func NormalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	return strings.ToLower(strings.Join(strings.Fields(tag), "-"))
+}
+
+// HasTag 返回对话是否带有标签
+func (c *Conversation) HasTag(tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, t := range c.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateTags 添加和移除标签，标签保持排序且不重复
+func (c *Conversation) UpdateTags(add, remove []string) {
+	set := make(map[string]bool, len(c.Tags)+len(add))
+	for _, t := range c.Tags {
+		set[t] = true
+	}
+	for _, t := range add {
+		if t = NormalizeTag(t); t != "" {
+			set[t] = true
+		}
+	}
+	for _, t := range remove {
+		delete(set, NormalizeTag(t))
+	}
+
+	tags := make([]string, 0, len(set))
+	for t := range set {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	c.Tags = tags
+	c.UpdatedAt = time.Now()
+}
+
+// ListOptions 列出对话的过滤条件
+type ListOptions struct {
+	IncludeArchived bool   // 包含已归档的对话
+	ArchivedOnly    bool   // 只列出已归档的对话
+	Tag             string // 只列出带有该标签的对话
+}
+
+// FilterConversations 按条件过滤对话
+func FilterConversations(convs []*Conversation, opts ListOptions) []*Conversation {
+	var result []*Conversation
+	for _, conv := range convs {
+		if opts.matches(conv) {
+			result = append(result, conv)
+		}
+	}
+	return result
+}
+
+// matches 检查对话是否满足过滤条件
+func (o ListOptions) matches(conv *Conversation) bool {
+	if conv.IsArchived() && !o.IncludeArchived && !o.ArchivedOnly {
+		return false
+	}
+	if o.ArchivedOnly && !conv.IsArchived() {
+		return false
+	}
+	return o.Tag == "" || conv.HasTag(o.Tag)
+}
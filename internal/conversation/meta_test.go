@@ -0,0 +1,122 @@
+package conversation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCleanTitle(t *testing.T) {
+	tests := map[string]string{
+		"  \"Nginx 502 排查\"  ":  "Nginx 502 排查",
+		"标题：部署 Kubernetes\n说明":  "部署 Kubernetes",
+		"Title: Fix the build.": "Fix the build",
+	}
+	for input, want := range tests {
+		if got := cleanTitle(input); got != want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestConversation_Title(t *testing.T) {
+	conv := newTestConversation(0, "")
+	if conv.Title() != "" {
+		t.Errorf("Expected empty title for empty conversation, got %q", conv.Title())
+	}
+
+	conv.AddMessage(Message{Role: "user", Content: "how do I\nfix nginx"})
+	if conv.Title() != "how do I fix nginx" {
+		t.Errorf("Expected title from first user message, got %q", conv.Title())
+	}
+
+	conv.Name = "Nginx"
+	if conv.Title() != "Nginx" {
+		t.Errorf("Expected name as title, got %q", conv.Title())
+	}
+}
+
+func TestConversation_NeedsTitle(t *testing.T) {
+	conv := newTestConversation(0, "")
+	conv.AddMessage(Message{Role: "user", Content: "q"})
+	if conv.needsTitle() {
+		t.Error("Expected no title before the first reply")
+	}
+
+	// 不完整的回复不触发标题生成
+	conv.AddMessage(Message{Role: "assistant", Content: "a", Interrupted: true})
+	conv.AddMessage(Message{Role: "user", Content: "q"})
+	conv.AddMessage(Message{Role: "assistant", Content: "a", Error: "connection reset"})
+	if conv.needsTitle() {
+		t.Error("Expected no title for incomplete replies")
+	}
+
+	conv.AddMessage(Message{Role: "user", Content: "q"})
+	conv.AddMessage(Message{Role: "assistant", Content: "a"})
+	if !conv.needsTitle() {
+		t.Error("Expected title after the first completed reply")
+	}
+
+	// 分叉出的对话同样没有名称
+	if !conv.Fork("").needsTitle() {
+		t.Error("Expected title for an unnamed fork")
+	}
+
+	conv.Name = "Named"
+	if conv.needsTitle() {
+		t.Error("Expected no title for a named conversation")
+	}
+}
+
+func TestConversation_NeedsTitleAfterRetry(t *testing.T) {
+	conv := newTestConversation(0, "")
+	conv.AddMessage(Message{Role: "user", Content: "q"})
+	conv.AddMessage(Message{Role: "assistant", Content: "a", Interrupted: true})
+	if err := conv.PrepareRetry(); err != nil {
+		t.Fatalf("PrepareRetry failed: %v", err)
+	}
+	conv.AddMessage(Message{Role: "assistant", Content: "a"})
+
+	// 所有分支共有两条助手回复，当前分支上只有一条完成的回复
+	if !conv.needsTitle() {
+		t.Error("Expected title after a retried reply completes")
+	}
+}
+
+func TestConversation_UpdateTags(t *testing.T) {
+	conv := NewConversation("default")
+	conv.UpdateTags([]string{"#Work", "nginx", "dev ops", "nginx"}, nil)
+	if !reflect.DeepEqual(conv.Tags, []string{"dev-ops", "nginx", "work"}) {
+		t.Errorf("Unexpected tags: %v", conv.Tags)
+	}
+
+	conv.UpdateTags(nil, []string{"WORK"})
+	if !reflect.DeepEqual(conv.Tags, []string{"dev-ops", "nginx"}) {
+		t.Errorf("Unexpected tags after removal: %v", conv.Tags)
+	}
+	if !conv.HasTag("#NGINX") {
+		t.Error("Expected HasTag to normalize the tag")
+	}
+}
+
+func TestFilterConversations(t *testing.T) {
+	active := NewConversation("default")
+	active.UpdateTags([]string{"work"}, nil)
+	archived := NewConversation("default")
+	archived.UpdateTags([]string{"work"}, nil)
+	archived.SetArchived(true)
+	other := NewConversation("default")
+	convs := []*Conversation{active, archived, other}
+
+	if got := FilterConversations(convs, ListOptions{}); len(got) != 2 {
+		t.Errorf("Expected archived conversation to be hidden, got %d", len(got))
+	}
+	if got := FilterConversations(convs, ListOptions{IncludeArchived: true}); len(got) != 3 {
+		t.Errorf("Expected all conversations, got %d", len(got))
+	}
+	if got := FilterConversations(convs, ListOptions{ArchivedOnly: true}); len(got) != 1 || got[0] != archived {
+		t.Errorf("Expected only the archived conversation, got %v", got)
+	}
+	if got := FilterConversations(convs, ListOptions{Tag: "work"}); len(got) != 1 || got[0] != active {
+		t.Errorf("Expected tag filter to keep the active work conversation, got %v", got)
+	}
+}
@@ -0,0 +1,648 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+)
+
+// indexDirName 倒排索引目录，保存在对话目录下
+//
+// 每个对话的词项单独保存为 <ID>.json，保存对话时只重写这一个文件。
+const indexDirName = "index"
+
+// indexVersionFile 索引目录中记录格式版本的文件，缺失时从对话文件重建
+const indexVersionFile = "version"
+
+// legacyIndexFileName 版本 3 之前的单文件索引，重建时删除
+const legacyIndexFileName = "index.json"
+
+// indexVersion 索引格式版本，变化时自动重建
+//
+// 版本 2 起只索引当前分支的消息，版本 3 起每个对话一个索引文件。
+const indexVersion = 3
+
+// snippetRunes 摘录片段的长度（字符）
+const snippetRunes = 120
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// SearchOptions 搜索条件
+type SearchOptions struct {
+	Query      string
+	PromptName string    // 仅搜索使用该 prompt 的对话，空表示不限
+	Since      time.Time // 仅搜索在此之后更新过的对话
+	Until      time.Time // 仅搜索在此之前创建的对话
+	Limit      int       // 最多返回的结果数，0 表示不限
+	Tag        string    // 仅搜索带有该标签的对话，空表示不限
+
+	IncludeArchived bool // 包含已归档的对话，默认和 --list 一样不搜索
+	ArchivedOnly    bool // 只搜索已归档的对话
+}
+
+// SearchResult 一条搜索结果
+type SearchResult struct {
+	Conversation *Conversation
+	Score        float64
+	MessageIndex int    // 最匹配的消息下标
+	Snippet      string // 最匹配消息的摘录
+	Matches      [][2]int
+}
+
+// Searcher 支持全文搜索的存储
+type Searcher interface {
+	Search(opts SearchOptions) ([]SearchResult, error)
+}
+
+// posting 词项在一个对话中的出现情况
+type posting struct {
+	Freq     int   `json:"f"`
+	Messages []int `json:"m"`
+}
+
+// indexedDoc 索引中的对话信息
+type indexedDoc struct {
+	PromptName string    `json:"prompt_name"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Length     int       `json:"length"`
+	Terms      []string  `json:"-"` // 由 postings 还原
+}
+
+// docEntry 一个对话的索引文件内容
+type docEntry struct {
+	Doc      *indexedDoc         `json:"doc"`
+	Postings map[string]*posting `json:"postings"`
+}
+
+// searchIndex 对话消息内容的倒排索引，由各对话的 docEntry 合并而成
+type searchIndex struct {
+	Docs     map[string]*indexedDoc
+	Postings map[string]map[string]*posting
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		Docs:     make(map[string]*indexedDoc),
+		Postings: make(map[string]map[string]*posting),
+	}
+}
+
+// tokenize 将文本切分为小写词项
+//
+// 字母数字按单词切分；CJK 文本没有空格分词，按单字和相邻两字（bigram）切分，
+// 这样单字和词语查询都能命中。
+func tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i := range cjk {
+			tokens = append(tokens, string(cjk[i]))
+			if i+1 < len(cjk) {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// queryTerms 返回查询中需要全部命中的词项
+//
+// 多字的 CJK 片段只要求其 bigram 命中，避免单字带来过多噪音。
+func queryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range tokenize(query) {
+		if len([]rune(token)) == 1 && isCJK([]rune(token)[0]) && cjkRunLength(query, token) > 1 {
+			continue
+		}
+		if !seen[token] {
+			seen[token] = true
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
+
+// cjkRunLength 返回查询中包含该字的 CJK 连续片段长度
+func cjkRunLength(query, char string) int {
+	longest := 0
+	run := 0
+	contains := false
+	for _, r := range query + " " {
+		if isCJK(r) {
+			run++
+			if string(r) == char {
+				contains = true
+			}
+			continue
+		}
+		if contains && run > longest {
+			longest = run
+		}
+		run = 0
+		contains = false
+	}
+	return longest
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// newDocEntry 生成对话的索引内容
+//
+// 只索引当前分支的消息，被放弃的分支（/retry、/edit、/undo 之前的内容）不会被搜到；
+// 切换分支时对话被保存，索引随之更新。
+func newDocEntry(conv *Conversation) *docEntry {
+	postings := make(map[string]*posting)
+	length := 0
+	for _, i := range conv.ActivePath() {
+		msg := conv.Messages[i]
+		// 系统提示来自模板，不参与搜索
+		if msg.Role == "system" {
+			continue
+		}
+		// 索引和对话文件一样不保存密钥
+		for _, term := range tokenize(privacy.Redact("search", msg.Content)) {
+			length++
+			p, ok := postings[term]
+			if !ok {
+				p = &posting{}
+				postings[term] = p
+			}
+			p.Freq++
+			if n := len(p.Messages); n == 0 || p.Messages[n-1] != i {
+				p.Messages = append(p.Messages, i)
+			}
+		}
+	}
+
+	return &docEntry{
+		Doc: &indexedDoc{
+			PromptName: conv.PromptName,
+			CreatedAt:  conv.CreatedAt,
+			UpdatedAt:  conv.UpdatedAt,
+			Length:     length,
+		},
+		Postings: postings,
+	}
+}
+
+// put 将对话的索引内容加入索引，已存在时先移除旧内容
+func (idx *searchIndex) put(id string, entry *docEntry) {
+	idx.remove(id)
+
+	doc := *entry.Doc
+	doc.Terms = nil
+	for term, p := range entry.Postings {
+		if idx.Postings[term] == nil {
+			idx.Postings[term] = make(map[string]*posting)
+		}
+		idx.Postings[term][id] = p
+		doc.Terms = append(doc.Terms, term)
+	}
+	sort.Strings(doc.Terms)
+	idx.Docs[id] = &doc
+}
+
+// remove 从索引中移除对话
+func (idx *searchIndex) remove(id string) {
+	doc, ok := idx.Docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.Terms {
+		delete(idx.Postings[term], id)
+		if len(idx.Postings[term]) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	delete(idx.Docs, id)
+}
+
+// scoredDoc 排序用的中间结果
+type scoredDoc struct {
+	id       string
+	score    float64
+	messages map[int]int // 消息下标 -> 命中的词项数
+}
+
+// search 返回命中全部查询词项的对话，按 BM25 得分排序
+func (idx *searchIndex) search(opts SearchOptions) []scoredDoc {
+	terms := queryTerms(opts.Query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var candidates map[string]bool
+	for _, term := range terms {
+		docs := idx.Postings[term]
+		next := make(map[string]bool)
+		for id := range docs {
+			if candidates == nil || candidates[id] {
+				next[id] = true
+			}
+		}
+		candidates = next
+		if len(candidates) == 0 {
+			return nil
+		}
+	}
+
+	avgLength := 0.0
+	for _, doc := range idx.Docs {
+		avgLength += float64(doc.Length)
+	}
+	avgLength /= float64(len(idx.Docs))
+	total := float64(len(idx.Docs))
+
+	var results []scoredDoc
+	for id := range candidates {
+		doc := idx.Docs[id]
+		if !opts.matches(doc) {
+			continue
+		}
+
+		sd := scoredDoc{id: id, messages: make(map[int]int)}
+		for _, term := range terms {
+			p := idx.Postings[term][id]
+			df := float64(len(idx.Postings[term]))
+			idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+			tf := float64(p.Freq)
+			norm := 1 - bm25B + bm25B*float64(doc.Length)/avgLength
+			sd.score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			for _, i := range p.Messages {
+				sd.messages[i]++
+			}
+		}
+		results = append(results, sd)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return idx.Docs[results[i].id].UpdatedAt.After(idx.Docs[results[j].id].UpdatedAt)
+	})
+	return results
+}
+
+// matches 检查对话是否满足过滤条件
+func (o SearchOptions) matches(doc *indexedDoc) bool {
+	if o.PromptName != "" && doc.PromptName != o.PromptName {
+		return false
+	}
+	if !o.Since.IsZero() && doc.UpdatedAt.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && doc.CreatedAt.After(o.Until) {
+		return false
+	}
+	return true
+}
+
+// bestMessage 返回命中词项最多的消息下标，相同时取最早的
+func (sd scoredDoc) bestMessage() int {
+	best, bestHits := -1, 0
+	for i, hits := range sd.messages {
+		if hits > bestHits || (hits == bestHits && i < best) {
+			best, bestHits = i, hits
+		}
+	}
+	return best
+}
+
+// Snippet 截取文本中第一个匹配附近的片段，返回片段和匹配位置（按字符计）
+func Snippet(text, query string, width int) (string, [][2]int) {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	matches := findMatches(runes, query)
+
+	start := 0
+	if len(matches) > 0 {
+		start = matches[0][0] - width/4
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		start = end - width
+		if start < 0 {
+			start = 0
+		}
+	}
+
+	var snippet strings.Builder
+	offset := 0
+	if start > 0 {
+		snippet.WriteString("...")
+		offset = 3
+	}
+	snippet.WriteString(string(runes[start:end]))
+	if end < len(runes) {
+		snippet.WriteString("...")
+	}
+
+	var shifted [][2]int
+	for _, m := range matches {
+		if m[1] <= start || m[0] >= end {
+			continue
+		}
+		from, to := m[0], m[1]
+		if from < start {
+			from = start
+		}
+		if to > end {
+			to = end
+		}
+		shifted = append(shifted, [2]int{from - start + offset, to - start + offset})
+	}
+	return snippet.String(), shifted
+}
+
+// findMatches 查找查询词在文本中的位置（忽略大小写），重叠的位置会合并
+func findMatches(runes []rune, query string) [][2]int {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	for _, word := range strings.FieldsFunc(query, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	}) {
+		needle := []rune(strings.ToLower(word))
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == string(needle) {
+				for j := i; j < i+len(needle); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	var matches [][2]int
+	for i := 0; i < len(marked); i++ {
+		if !marked[i] {
+			continue
+		}
+		j := i
+		for j < len(marked) && marked[j] {
+			j++
+		}
+		matches = append(matches, [2]int{i, j})
+		i = j
+	}
+	return matches
+}
+
+// Highlight 用 open/close 包裹片段中的匹配位置
+func Highlight(snippet string, matches [][2]int, open, close string) string {
+	runes := []rune(snippet)
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(string(runes[last:m[0]]))
+		b.WriteString(open)
+		b.WriteString(string(runes[m[0]:m[1]]))
+		b.WriteString(close)
+		last = m[1]
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String()
+}
+
+// fileIndex 保存在磁盘上的索引，由 FileStorage 在保存和删除对话时更新
+type fileIndex struct {
+	dir      string
+	mu       sync.Mutex
+	index    *searchIndex
+	modTimes map[string]time.Time // 已读取的索引文件的修改时间，其他进程写入后重新读取
+}
+
+// entryPath 返回对话的索引文件路径
+func (f *fileIndex) entryPath(id string) string {
+	return filepath.Join(f.dir, id+".json")
+}
+
+// current 返回索引目录是否为当前版本
+func (f *fileIndex) current() bool {
+	data, err := privacy.ReadFile(filepath.Join(f.dir, indexVersionFile))
+	if err != nil {
+		return false
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return err == nil && version == indexVersion
+}
+
+// load 读取索引，只重新读取有变化的索引文件；版本不符或文件损坏时从对话文件重建
+func (f *fileIndex) load(s *FileStorage) (*searchIndex, error) {
+	if !f.current() {
+		return f.rebuild(s)
+	}
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search index: %w", err)
+	}
+	if f.index == nil {
+		f.index = newSearchIndex()
+		f.modTimes = make(map[string]time.Time)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !entry.Type().IsRegular() || !validID(id) {
+			continue
+		}
+		seen[id] = true
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if modTime, ok := f.modTimes[id]; ok && modTime.Equal(info.ModTime()) {
+			continue
+		}
+
+		data, err := privacy.ReadFile(f.entryPath(id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read search index: %w", err)
+		}
+		var doc docEntry
+		if json.Unmarshal(data, &doc) != nil || doc.Doc == nil {
+			return f.rebuild(s)
+		}
+		f.index.put(id, &doc)
+		f.modTimes[id] = info.ModTime()
+	}
+
+	// 其他进程删除的对话
+	for id := range f.index.Docs {
+		if !seen[id] {
+			f.index.remove(id)
+			delete(f.modTimes, id)
+		}
+	}
+	return f.index, nil
+}
+
+// rebuild 从所有对话重建索引
+func (f *fileIndex) rebuild(s *FileStorage) (*searchIndex, error) {
+	convs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.RemoveAll(f.dir); err != nil {
+		return nil, fmt.Errorf("failed to remove search index: %w", err)
+	}
+	_ = os.Remove(filepath.Join(filepath.Dir(f.dir), legacyIndexFileName))
+
+	f.index = newSearchIndex()
+	f.modTimes = make(map[string]time.Time)
+	for _, conv := range convs {
+		if err := f.write(conv.ID, newDocEntry(conv)); err != nil {
+			return nil, err
+		}
+	}
+	// 版本文件最后写入，重建中断时下次重新开始
+	if err := privacy.WritePrivate(filepath.Join(f.dir, indexVersionFile), []byte(strconv.Itoa(indexVersion))); err != nil {
+		return nil, fmt.Errorf("failed to write search index: %w", err)
+	}
+	return f.index, nil
+}
+
+// write 将一个对话的索引内容写入磁盘并加入已加载的索引
+func (f *fileIndex) write(id string, entry *docEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %w", err)
+	}
+	path := f.entryPath(id)
+	if err := privacy.WritePrivate(path, data); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	if f.index != nil {
+		f.index.put(id, entry)
+		if info, err := os.Stat(path); err == nil {
+			f.modTimes[id] = info.ModTime()
+		}
+	}
+	return nil
+}
+
+// update 更新索引中的一个对话，conv 为 nil 时删除 id
+//
+// 只写入这个对话的索引文件，其余对话的索引在下次搜索时按需读取。
+func (f *fileIndex) update(s *FileStorage, id string, conv *Conversation) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// 还没有索引时先重建，保证其他对话也被索引
+	if !f.current() {
+		if _, err := f.rebuild(s); err != nil {
+			return err
+		}
+	}
+	if conv != nil {
+		return f.write(id, newDocEntry(conv))
+	}
+
+	if f.index != nil {
+		f.index.remove(id)
+		delete(f.modTimes, id)
+	}
+	if err := os.Remove(f.entryPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove search index: %w", err)
+	}
+	return nil
+}
+
+// Reindex 从所有对话重建搜索索引
+func (s *FileStorage) Reindex() error {
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+
+	_, err := s.index.rebuild(s)
+	return err
+}
+
+// Search 全文搜索对话消息
+func (s *FileStorage) Search(opts SearchOptions) ([]SearchResult, error) {
+	s.index.mu.Lock()
+	idx, err := s.index.load(s)
+	var scored []scoredDoc
+	if err == nil {
+		scored = idx.search(opts)
+	}
+	s.index.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, sd := range scored {
+		if opts.Limit > 0 && len(results) >= opts.Limit {
+			break
+		}
+
+		// 索引可能落后于手动删除的对话
+		conv, err := s.Get(sd.id)
+		if err != nil {
+			continue
+		}
+		filter := ListOptions{IncludeArchived: opts.IncludeArchived, ArchivedOnly: opts.ArchivedOnly, Tag: opts.Tag}
+		if !filter.matches(conv) {
+			continue
+		}
+
+		result := SearchResult{Conversation: conv, Score: sd.score, MessageIndex: sd.bestMessage()}
+		if result.MessageIndex >= 0 && result.MessageIndex < len(conv.Messages) {
+			result.Snippet, result.Matches = Snippet(conv.Messages[result.MessageIndex].Content, opts.Query, snippetRunes)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,288 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// saveTestConversation 保存一个包含给定用户消息的对话
+func saveTestConversation(t *testing.T, s *FileStorage, id, prompt string, updated time.Time, contents ...string) *Conversation {
+	t.Helper()
+	conv := NewConversation(prompt)
+	conv.ID = id
+	conv.CreatedAt = updated
+	conv.AddMessage(Message{Role: "system", Content: "system prompt about nginx"})
+	for _, c := range contents {
+		conv.AddMessage(Message{Role: "user", Content: c, Timestamp: updated})
+	}
+	conv.UpdatedAt = updated
+	if err := s.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	return conv
+}
+
+func resultIDs(results []SearchResult) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Conversation.ID)
+	}
+	return ids
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Fix nginx.conf 配置文件")
+	want := []string{"fix", "nginx", "conf", "配", "配置", "置", "置文", "文", "文件", "件"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %v, want %v", got, want)
+	}
+
+	if got := queryTerms("配置 Nginx"); !reflect.DeepEqual(got, []string{"配置", "nginx"}) {
+		t.Errorf("queryTerms = %v", got)
+	}
+}
+
+func TestFileStorage_Search(t *testing.T) {
+	s := NewFileStorage(t.TempDir())
+	now := time.Now()
+
+	saveTestConversation(t, s, "conv-nginx", "coder", now.Add(-time.Hour), "how do I fix the nginx config", "nginx returns 502, nginx upstream is down")
+	saveTestConversation(t, s, "conv-other", "default", now, "write a poem about the sea")
+	saveTestConversation(t, s, "conv-mention", "default", now.Add(-2*time.Hour), "what is the difference between apache and nginx")
+
+	results, err := s.Search(SearchOptions{Query: "nginx"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := resultIDs(results); !reflect.DeepEqual(got, []string{"conv-nginx", "conv-mention"}) {
+		t.Errorf("Expected ranked results [conv-nginx conv-mention], got %v", got)
+	}
+
+	// 所有词项都要命中
+	results, _ = s.Search(SearchOptions{Query: "nginx config"})
+	if got := resultIDs(results); !reflect.DeepEqual(got, []string{"conv-nginx"}) {
+		t.Errorf("Expected [conv-nginx], got %v", got)
+	}
+
+	// 系统提示不参与搜索
+	results, _ = s.Search(SearchOptions{Query: "system"})
+	if len(results) != 0 {
+		t.Errorf("Expected system prompt not to be indexed, got %v", resultIDs(results))
+	}
+
+	// 过滤条件
+	results, _ = s.Search(SearchOptions{Query: "nginx", PromptName: "default"})
+	if got := resultIDs(results); !reflect.DeepEqual(got, []string{"conv-mention"}) {
+		t.Errorf("Expected prompt filter to keep [conv-mention], got %v", got)
+	}
+	results, _ = s.Search(SearchOptions{Query: "nginx", Since: now.Add(-90 * time.Minute)})
+	if got := resultIDs(results); !reflect.DeepEqual(got, []string{"conv-nginx"}) {
+		t.Errorf("Expected since filter to keep [conv-nginx], got %v", got)
+	}
+	results, _ = s.Search(SearchOptions{Query: "nginx", Limit: 1})
+	if len(results) != 1 {
+		t.Errorf("Expected limit 1, got %d results", len(results))
+	}
+}
+
+func TestFileStorage_Search_Snippet(t *testing.T) {
+	s := NewFileStorage(t.TempDir())
+	saveTestConversation(t, s, "conv-1", "default", time.Now(), "hello", "我们修改了 Nginx 配置文件")
+
+	results, err := s.Search(SearchOptions{Query: "nginx 配置"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %v, %v", results, err)
+	}
+
+	r := results[0]
+	if r.MessageIndex != 2 {
+		t.Errorf("Expected best message 2, got %d", r.MessageIndex)
+	}
+	highlighted := Highlight(r.Snippet, r.Matches, "[", "]")
+	if highlighted != "我们修改了 [Nginx] [配置]文件" {
+		t.Errorf("Unexpected highlight: %q", highlighted)
+	}
+}
+
+func TestFileStorage_Search_UpdatesIndex(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileStorage(dir)
+	conv := saveTestConversation(t, s, "conv-1", "default", time.Now(), "first message")
+
+	conv.AddMessage(Message{Role: "user", Content: "kubernetes deployment"})
+	if err := s.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	results, _ := s.Search(SearchOptions{Query: "kubernetes"})
+	if len(results) != 1 {
+		t.Fatalf("Expected updated conversation to be found, got %d results", len(results))
+	}
+
+	// 其他进程打开的存储读取同一份索引
+	other := NewFileStorage(dir)
+	results, _ = other.Search(SearchOptions{Query: "kubernetes"})
+	if len(results) != 1 {
+		t.Errorf("Expected index to be persisted, got %d results", len(results))
+	}
+
+	if err := s.Delete("conv-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	results, _ = other.Search(SearchOptions{Query: "kubernetes"})
+	if len(results) != 0 {
+		t.Errorf("Expected deleted conversation to be removed from index, got %d results", len(results))
+	}
+}
+
+func TestFileStorage_Search_ActiveBranchOnly(t *testing.T) {
+	s := NewFileStorage(t.TempDir())
+	conv := NewConversation("default")
+	conv.AddMessage(Message{Role: "user", Content: "nginx reverse proxy"})
+	conv.AddMessage(Message{Role: "assistant", Content: "use proxy_pass"})
+	if _, err := conv.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	conv.AddMessage(Message{Role: "user", Content: "postgres tuning"})
+	if err := s.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if results, _ := s.Search(SearchOptions{Query: "nginx"}); len(results) != 0 {
+		t.Errorf("Expected the abandoned branch to not be indexed, got %d results", len(results))
+	}
+	if results, _ := s.Search(SearchOptions{Query: "postgres"}); len(results) != 1 {
+		t.Errorf("Expected the active branch to be found, got %d results", len(results))
+	}
+
+	// 切换分支后索引随之更新
+	if err := conv.SwitchBranch(1); err != nil {
+		t.Fatalf("SwitchBranch failed: %v", err)
+	}
+	if err := s.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if results, _ := s.Search(SearchOptions{Query: "nginx"}); len(results) != 1 {
+		t.Errorf("Expected the switched-to branch to be found, got %d results", len(results))
+	}
+	if results, _ := s.Search(SearchOptions{Query: "postgres"}); len(results) != 0 {
+		t.Errorf("Expected the inactive branch to be dropped, got %d results", len(results))
+	}
+}
+
+func TestFileStorage_Search_RebuildsMissingIndex(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileStorage(dir)
+	saveTestConversation(t, s, "conv-1", "default", time.Now(), "terraform plan output")
+	indexDir := filepath.Join(dir, indexDirName)
+
+	// 损坏的索引文件
+	if err := os.WriteFile(filepath.Join(indexDir, "conv-1.json"), []byte("{broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := NewFileStorage(dir).Search(SearchOptions{Query: "terraform"})
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected index to be rebuilt, got %v, %v", results, err)
+	}
+
+	// 旧版本的单文件索引
+	if err := os.RemoveAll(indexDir); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(dir, legacyIndexFileName)
+	if err := os.WriteFile(legacy, []byte(`{"version":2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err = NewFileStorage(dir).Search(SearchOptions{Query: "terraform"})
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected index to be rebuilt, got %v, %v", results, err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("Expected the legacy index to be removed, got %v", err)
+	}
+}
+
+func TestFileStorage_Search_SaveWritesOneIndexFile(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileStorage(dir)
+	saveTestConversation(t, s, "conv-1", "default", time.Now(), "terraform plan output")
+	saveTestConversation(t, s, "conv-2", "default", time.Now(), "ansible playbook")
+
+	first := filepath.Join(dir, indexDirName, "conv-1.json")
+	before, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatalf("Expected an index file per conversation: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(first, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// 另一个进程保存的对话只改写自己的索引文件
+	other := NewFileStorage(dir)
+	saveTestConversation(t, other, "conv-3", "default", time.Now(), "terraform apply")
+	after, _ := os.ReadFile(first)
+	info, _ := os.Stat(first)
+	if string(after) != string(before) || !info.ModTime().Equal(old) {
+		t.Error("Expected saving one conversation to leave other index files alone")
+	}
+
+	results, err := s.Search(SearchOptions{Query: "terraform"})
+	if err != nil || len(results) != 2 {
+		t.Errorf("Expected the index written by the other storage to be read, got %d results, %v", len(results), err)
+	}
+}
+
+func TestFileStorage_Search_HidesArchived(t *testing.T) {
+	s := NewFileStorage(t.TempDir())
+	saveTestConversation(t, s, "active", "default", time.Now(), "helm chart values")
+	archived := saveTestConversation(t, s, "archived", "default", time.Now(), "helm chart upgrade")
+	archived.SetArchived(true)
+	if err := s.Save(archived); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	tests := []struct {
+		opts SearchOptions
+		want []string
+	}{
+		{SearchOptions{Query: "helm"}, []string{"active"}},
+		{SearchOptions{Query: "helm", IncludeArchived: true}, []string{"active", "archived"}},
+		{SearchOptions{Query: "helm", ArchivedOnly: true}, []string{"archived"}},
+	}
+	for _, tt := range tests {
+		results, err := s.Search(tt.opts)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		got := resultIDs(results)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestFileStorage_GetByPrefix(t *testing.T) {
+	s := NewFileStorage(t.TempDir())
+	saveTestConversation(t, s, "abc-111", "default", time.Now(), "one")
+	saveTestConversation(t, s, "abc-222", "default", time.Now(), "two")
+
+	conv, err := s.Get("abc-111")
+	if err != nil || conv.ID != "abc-111" {
+		t.Fatalf("Expected exact match, got %v, %v", conv, err)
+	}
+
+	if _, err := s.Get("abc"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected ambiguous prefix error, got %v", err)
+	}
+
+	conv, err = s.Get("abc-2")
+	if err != nil || conv.ID != "abc-222" {
+		t.Errorf("Expected unique prefix to resolve, got %v, %v", conv, err)
+	}
+}
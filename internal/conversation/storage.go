@@ -3,10 +3,14 @@ package conversation
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 )
 
 // Storage 对话存储接口
@@ -21,12 +25,14 @@ type Storage interface {
 // FileStorage 文件系统存储实现
 type FileStorage struct {
 	conversationsDir string
+	index            *fileIndex
 }
 
 // NewFileStorage 创建 FileStorage
 func NewFileStorage(conversationsDir string) *FileStorage {
 	return &FileStorage{
 		conversationsDir: conversationsDir,
+		index:            &fileIndex{dir: filepath.Join(conversationsDir, indexDirName)},
 	}
 }
 
@@ -37,6 +43,8 @@ func (s *FileStorage) GetDatePath(conv *Conversation) string {
 }
 
 // GetConversationPath 获取对话的完整路径
+//
+// convID 可以是完整 ID，也可以是唯一的 ID 前缀（如 --list 显示的 12 位短 ID）。
 func (s *FileStorage) GetConversationPath(convID string) (string, error) {
 	// 遍历日期文件夹查找
 	entries, err := os.ReadDir(s.conversationsDir)
@@ -44,6 +52,7 @@ func (s *FileStorage) GetConversationPath(convID string) (string, error) {
 		return "", fmt.Errorf("failed to read conversations directory: %w", err)
 	}
 
+	var prefixMatches []string
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -53,41 +62,95 @@ func (s *FileStorage) GetConversationPath(convID string) (string, error) {
 		if _, err := os.Stat(convPath); err == nil {
 			return convPath, nil
 		}
+
+		if convID == "" {
+			continue
+		}
+		convEntries, err := os.ReadDir(filepath.Join(s.conversationsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, convEntry := range convEntries {
+			if convEntry.IsDir() && strings.HasPrefix(convEntry.Name(), convID) {
+				prefixMatches = append(prefixMatches, filepath.Join(s.conversationsDir, entry.Name(), convEntry.Name()))
+			}
+		}
 	}
 
+	if len(prefixMatches) == 1 {
+		return prefixMatches[0], nil
+	}
+	if len(prefixMatches) > 1 {
+		return "", fmt.Errorf("ambiguous conversation ID: %s", convID)
+	}
 	return "", fmt.Errorf("conversation not found: %s", convID)
 }
 
 // Save 保存对话
 func (s *FileStorage) Save(conv *Conversation) error {
+	if !validID(conv.ID) {
+		return fmt.Errorf("invalid conversation ID: %q", conv.ID)
+	}
 	datePath := s.GetDatePath(conv)
 
 	// 创建日期目录
-	if err := os.MkdirAll(datePath, 0755); err != nil {
+	if err := os.MkdirAll(datePath, privacy.DirMode); err != nil {
 		return fmt.Errorf("failed to create date directory: %w", err)
 	}
 
 	convPath := filepath.Join(datePath, conv.ID)
 
 	// 创建对话目录
-	if err := os.MkdirAll(convPath, 0755); err != nil {
+	if err := os.MkdirAll(convPath, privacy.DirMode); err != nil {
 		return fmt.Errorf("failed to create conversation directory: %w", err)
 	}
 
 	// 写入 messages.json
 	messagesFile := filepath.Join(convPath, "messages.json")
-	data, err := json.MarshalIndent(conv, "", "  ")
+	data, err := json.MarshalIndent(redactConversation(conv), "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal conversation: %w", err)
 	}
 
-	if err := os.WriteFile(messagesFile, data, 0644); err != nil {
+	if err := privacy.WritePrivate(messagesFile, data); err != nil {
 		return fmt.Errorf("failed to write messages file: %w", err)
 	}
 
+	// 索引失败不影响保存，下次搜索时会重建
+	if err := s.index.update(s, conv.ID, conv); err != nil {
+		log.Printf("Warning: failed to update search index: %v", err)
+	}
+
 	return nil
 }
 
+// redactConversation 返回脱敏后的副本，内存中的对话保持原样
+//
+// 在序列化之前处理字段值，直接处理 JSON 可能截断转义字符，写出无效的文件。
+func redactConversation(conv *Conversation) *Conversation {
+	redact := func(text string) string {
+		return privacy.Redact("conversation", text)
+	}
+
+	redacted := *conv
+	redacted.Name = redact(conv.Name)
+	redacted.Messages = make([]Message, len(conv.Messages))
+	for i, msg := range conv.Messages {
+		msg.Content = redact(msg.Content)
+		msg.Reasoning = redact(msg.Reasoning)
+		msg.Error = redact(msg.Error)
+		redacted.Messages[i] = msg
+	}
+	if conv.Compactions != nil {
+		redacted.Compactions = make([]Compaction, len(conv.Compactions))
+		for i, compaction := range conv.Compactions {
+			compaction.Summary = redact(compaction.Summary)
+			redacted.Compactions[i] = compaction
+		}
+	}
+	return &redacted
+}
+
 // Get 获取对话
 func (s *FileStorage) Get(id string) (*Conversation, error) {
 	convPath, err := s.GetConversationPath(id)
@@ -96,7 +159,7 @@ func (s *FileStorage) Get(id string) (*Conversation, error) {
 	}
 
 	messagesFile := filepath.Join(convPath, "messages.json")
-	data, err := os.ReadFile(messagesFile)
+	data, err := privacy.ReadFile(messagesFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read messages file: %w", err)
 	}
@@ -197,5 +260,12 @@ func (s *FileStorage) Delete(id string) error {
 		return err
 	}
 
-	return os.RemoveAll(convPath)
+	if err := os.RemoveAll(convPath); err != nil {
+		return err
+	}
+
+	if err := s.index.update(s, filepath.Base(convPath), nil); err != nil {
+		log.Printf("Warning: failed to update search index: %v", err)
+	}
+	return nil
 }
@@ -1,9 +1,14 @@
 package conversation
 
 import (
+	"bytes"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 )
 
 func TestFileStorage_SaveAndGet(t *testing.T) {
@@ -129,3 +134,70 @@ func TestFileStorage_ListToday(t *testing.T) {
 		t.Errorf("Expected conversation ID %s, got %s", conv.ID, convs[0].ID)
 	}
 }
+
+func TestFileStorage_Encrypted(t *testing.T) {
+	c, _ := privacy.NewCipher(bytes.Repeat([]byte{1}, 32))
+	privacy.SetCipher(c)
+	defer privacy.SetCipher(nil)
+
+	tmpDir := t.TempDir()
+	storage := NewFileStorage(tmpDir)
+
+	conv := NewConversation("default")
+	conv.AddMessage(Message{Role: "user", Content: "我的地址是杭州", Timestamp: time.Now()})
+	if err := storage.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// 磁盘上没有明文
+	files, _ := filepath.Glob(filepath.Join(tmpDir, "*", "*", "messages.json"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one messages file, got %v", files)
+	}
+	raw, _ := os.ReadFile(files[0])
+	if !privacy.IsEncrypted(raw) || bytes.Contains(raw, []byte("杭州")) {
+		t.Error("Expected the conversation to be encrypted")
+	}
+
+	loaded, err := storage.Get(conv.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(loaded.Messages) != 1 || loaded.Messages[0].Content != "我的地址是杭州" {
+		t.Errorf("Unexpected messages: %+v", loaded.Messages)
+	}
+}
+
+func TestFileStorage_RedactsSecrets(t *testing.T) {
+	redactor, _ := privacy.NewRedactor(nil)
+	privacy.SetDefault(redactor)
+	defer privacy.SetDefault(nil)
+
+	storage := NewFileStorage(t.TempDir())
+	secret := "abcd1234efgh5678"
+	content := `curl -H "X-Api-Key: ` + secret + `" https://x`
+
+	conv := NewConversation("default")
+	conv.AddMessage(Message{Role: "user", Content: content, Timestamp: time.Now()})
+	if err := storage.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if conv.Messages[0].Content != content {
+		t.Error("Expected the conversation in memory to be left alone")
+	}
+
+	// 引号中的密钥不能破坏 JSON
+	loaded, err := storage.Get(conv.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	got := loaded.Messages[0].Content
+	if strings.Contains(got, secret) || !strings.HasSuffix(got, `" https://x`) {
+		t.Errorf("Unexpected content: %q", got)
+	}
+
+	list, err := storage.List()
+	if err != nil || len(list) != 1 {
+		t.Errorf("Expected the conversation to be listed, got %d, %v", len(list), err)
+	}
+}
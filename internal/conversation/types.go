@@ -17,21 +17,68 @@ const (
 
 // Conversation 表示一个对话
 type Conversation struct {
-	ID         string             `json:"id"`
-	Name       string             `json:"name"`
-	PromptName string             `json:"prompt_name"`
-	Messages   []Message          `json:"messages"`
-	Status     ConversationStatus `json:"status"`
-	CreatedAt  time.Time          `json:"created_at"`
-	UpdatedAt  time.Time          `json:"updated_at"`
-	ephemeral  bool               `json:"-"` // 不保存到文件，不记录历史
+	ID         string `json:"id"`
+	Name       string `json:"name"` // 标题，--name、/rename 设置或由 AI 生成
+	PromptName string `json:"prompt_name"`
+	// Tags 用户添加的标签
+	Tags     []string           `json:"tags,omitempty"`
+	Messages []Message          `json:"messages"`
+	Status   ConversationStatus `json:"status"`
+	// ActiveLeaf 当前分支最后一条消息的 ID，消息通过 ParentID 组成树
+	ActiveLeaf string `json:"active_leaf,omitempty"`
+	// ForkedFrom 由 /fork 创建时的源对话 ID
+	ForkedFrom string `json:"forked_from,omitempty"`
+	// Compactions 历史压缩记录，当前分支上最近一次压缩的摘要代替被压缩的消息发送给 AI
+	Compactions []Compaction `json:"compactions,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	ephemeral   bool         `json:"-"` // 不保存到文件，不记录历史
 }
 
 // Message 表示单条消息
 type Message struct {
+	// ID 对话内唯一的消息 ID，旧版本保存的对话没有 ID，按顺序组成单一分支
+	ID string `json:"id,omitempty"`
+	// ParentID 上一条消息的 ID，为空表示根消息
+	ParentID  string    `json:"parent_id,omitempty"`
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	// Reasoning 推理模型的思考过程，只用于显示，不发送给 AI
+	Reasoning string `json:"reasoning,omitempty"`
+	// Interrupted 回复在生成过程中被用户中断，Content 只是部分内容
+	Interrupted bool `json:"interrupted,omitempty"`
+	// FinishReason AI 返回的结束原因，ai.FinishLength 表示回复因长度限制被截断
+	FinishReason string `json:"finish_reason,omitempty"`
+	// Error 流式回复中途出错时的错误信息，Content 只是部分内容
+	Error string `json:"error,omitempty"`
+}
+
+// 发送给 AI 时附加在不完整回复之后的说明
+const (
+	interruptedNote = "\n\n[回复被用户中断]"
+	truncatedNote   = "\n\n[回复因长度限制被截断]"
+	failedNote      = "\n\n[回复因错误中断]"
+)
+
+// 不完整回复在界面和导出中的标记
+const (
+	interruptedLabel = "已中断"
+	truncatedLabel   = "已截断"
+	failedLabel      = "出错"
+)
+
+// IncompleteLabel 返回回复不完整的原因，完整的消息返回空字符串
+func (m *Message) IncompleteLabel() string {
+	switch {
+	case m.Interrupted:
+		return interruptedLabel
+	case m.Error != "":
+		return failedLabel
+	case m.FinishReason == ai.FinishLength:
+		return truncatedLabel
+	}
+	return ""
 }
 
 // NewConversation 创建新对话
@@ -47,10 +94,10 @@ func NewConversation(promptName string) *Conversation {
 	}
 }
 
-// AddMessage 添加消息
+// AddMessage 在当前分支末尾添加消息
 func (c *Conversation) AddMessage(msg Message) {
-	c.Messages = append(c.Messages, msg)
-	c.UpdatedAt = time.Now()
+	c.ensureTree()
+	c.appendChild(c.ActiveLeaf, msg)
 }
 
 // IsEphemeral 返回对话是否为临时模式（不保存历史）
@@ -64,37 +111,121 @@ func (c *Conversation) SetEphemeral(ephemeral bool) {
 }
 
 // SwitchPrompt 切换对话的 prompt 模板
-// 替换系统消息为新的 prompt，保留用户和助手的对话历史
+// 替换系统消息为新的 prompt，保留用户和助手的对话历史（包括所有分支）
 func (c *Conversation) SwitchPrompt(newPromptName string, newSystemPrompt string) {
-	// 移除旧的系统消息（第一条消息）
-	if len(c.Messages) > 0 && c.Messages[0].Role == "system" {
-		c.Messages = c.Messages[1:]
-	}
-
-	// 在开头插入新的系统消息
+	c.ensureTree()
 	c.PromptName = newPromptName
 	systemMsg := Message{
 		Role:      "system",
 		Content:   newSystemPrompt,
 		Timestamp: time.Now(),
 	}
+
+	// 替换旧的系统消息（第一条消息），沿用其 ID 保持树结构
+	if len(c.Messages) > 0 && c.Messages[0].Role == "system" {
+		systemMsg.ID = c.Messages[0].ID
+		c.Messages[0] = systemMsg
+		c.UpdatedAt = time.Now()
+		return
+	}
+
+	// 在开头插入新的系统消息，原来的根消息挂到它下面
+	systemMsg.ID = c.nextMessageID()
+	for i := range c.Messages {
+		if c.Messages[i].ParentID == "" {
+			c.Messages[i].ParentID = systemMsg.ID
+		}
+	}
+	if c.ActiveLeaf == "" {
+		c.ActiveLeaf = systemMsg.ID
+	}
 	c.Messages = append([]Message{systemMsg}, c.Messages...)
 	c.UpdatedAt = time.Now()
 }
 
 // ToAIFormat 转换为 AI 消息格式
 func (m *Message) ToAIFormat() ai.Message {
+	content := m.Content
+	switch {
+	case m.Interrupted:
+		content += interruptedNote
+	case m.Error != "":
+		content += failedNote
+	case m.FinishReason == ai.FinishLength:
+		content += truncatedNote
+	}
 	return ai.Message{
 		Role:    m.Role,
-		Content: m.Content,
+		Content: content,
 	}
 }
 
 // GetMessagesForAI 获取用于 AI 的消息列表
+//
+// 只包含当前分支的消息。已压缩的消息被跳过，压缩摘要紧跟在系统消息之后。
 func (c *Conversation) GetMessagesForAI() []ai.Message {
-	messages := make([]ai.Message, 0, len(c.Messages))
-	for _, msg := range c.Messages {
+	path := c.ActivePath()
+	compactedUpTo, summary := c.activeCompaction(path)
+
+	messages := make([]ai.Message, 0, len(path)+1)
+	summaryAdded := summary == ""
+
+	for pos, idx := range path {
+		msg := c.Messages[idx]
+		if !summaryAdded && msg.Role != "system" {
+			messages = append(messages, ai.Message{Role: "user", Content: summaryPrefix + summary})
+			summaryAdded = true
+		}
+		if pos <= compactedUpTo && msg.Role != "system" {
+			continue
+		}
 		messages = append(messages, msg.ToAIFormat())
 	}
+	if !summaryAdded {
+		messages = append(messages, ai.Message{Role: "user", Content: summaryPrefix + summary})
+	}
 	return messages
 }
+
+// LatestSummary 返回当前分支上最近一次压缩的摘要
+func (c *Conversation) LatestSummary() string {
+	_, summary := c.activeCompaction(c.ActivePath())
+	return summary
+}
+
+// activeCompaction 返回当前分支上最近一次压缩的位置（path 下标）和摘要，
+// 没有压缩时位置为 -1
+//
+// 每次压缩的摘要都包含之前的摘要，所以只需要最近的一次。
+func (c *Conversation) activeCompaction(path []int) (int, string) {
+	if len(c.Compactions) == 0 {
+		return -1, ""
+	}
+
+	positions := make(map[string]int, len(path))
+	for pos, idx := range path {
+		if id := c.Messages[idx].ID; id != "" {
+			positions[id] = pos
+		}
+	}
+	for i := len(c.Compactions) - 1; i >= 0; i-- {
+		if pos, ok := positions[c.Compactions[i].UpTo]; ok {
+			return pos, c.Compactions[i].Summary
+		}
+	}
+	return -1, ""
+}
+
+// activeIndexes 返回当前分支上未压缩的非系统消息下标
+func (c *Conversation) activeIndexes() []int {
+	path := c.ActivePath()
+	compactedUpTo, _ := c.activeCompaction(path)
+
+	var indexes []int
+	for pos, idx := range path {
+		if pos > compactedUpTo && c.Messages[idx].Role != "system" {
+			indexes = append(indexes, idx)
+		}
+	}
+	return indexes
+}
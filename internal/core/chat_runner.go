@@ -0,0 +1,322 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+	"github.com/Lin-Jiong-HDU/tada/internal/terminal"
+)
+
+// maxTranscriptOutput caps the command output recorded in the conversation
+const maxTranscriptOutput = 4000
+
+// CommandOutcome records what happened to one command run from chat
+type CommandOutcome struct {
+	Command  ai.Command
+	Executed bool   // False if the command was denied or skipped
+	Skipped  string // Why the command did not run
+	ExitCode int
+	Output   string
+	Error    string
+}
+
+// Transcript formats the outcome as conversation context
+func (o CommandOutcome) Transcript() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ %s\n", commandLine(o.Command))
+	if !o.Executed {
+		fmt.Fprintf(&b, "(未执行: %s)\n", o.Skipped)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "退出码: %d\n", o.ExitCode)
+	if o.Error != "" {
+		fmt.Fprintf(&b, "错误: %s\n", o.Error)
+	}
+	output := o.Output
+	if len(output) > maxTranscriptOutput {
+		output = output[:maxTranscriptOutput] + "\n... (输出已截断)"
+	}
+	if output != "" {
+		fmt.Fprintf(&b, "```\n%s\n```\n", output)
+	}
+	return b.String()
+}
+
+// ConfirmFunc asks the user whether a command may run
+type ConfirmFunc func(cmd ai.Command, checkResult *security.CheckResult) (bool, error)
+
+// ChatRunner runs commands on behalf of a chat conversation. It implements
+// terminal.CommandRunner so the REPL can execute commands without importing core.
+type ChatRunner struct {
+	engine  *Engine
+	confirm ConfirmFunc
+}
+
+// NewChatRunner creates a chat runner for the engine. Confirmation prompts
+// read from input, which should be the reader the REPL uses for its own input.
+func NewChatRunner(engine *Engine, input io.Reader) *ChatRunner {
+	return &ChatRunner{
+		engine: engine,
+		confirm: func(cmd ai.Command, checkResult *security.CheckResult) (bool, error) {
+			return terminal.ConfirmWithIO(cmd, checkResult, input, nil)
+		},
+	}
+}
+
+// RunRequest turns a natural language request into commands and runs them
+func (r *ChatRunner) RunRequest(ctx context.Context, request string) (string, error) {
+	fmt.Println("🧠 Thinking...")
+	intent, err := r.engine.ai.ParseIntent(ctx, request, r.engine.intentPrompt(request, ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse intent: %w", err)
+	}
+	if intent.Reason != "" {
+		fmt.Printf("📝 Plan: %s\n", intent.Reason)
+	}
+	if len(intent.Commands) == 0 {
+		fmt.Println("没有需要执行的命令")
+		return "", nil
+	}
+
+	outcomes, err := r.engine.RunCommands(ctx, intent.Commands, false, r.confirm)
+	return transcript(fmt.Sprintf("执行请求: %s", request), outcomes), err
+}
+
+// RunShell runs a shell command line typed by the user
+func (r *ChatRunner) RunShell(ctx context.Context, line string) (string, error) {
+	outcomes, err := r.engine.RunCommands(ctx, []ai.Command{ShellCommand(line)}, false, r.confirm)
+	return transcript("执行 shell 命令", outcomes), err
+}
+
+// RunProposed runs shell command lines proposed by the assistant.
+// Each one is confirmed even if the security check does not require it.
+func (r *ChatRunner) RunProposed(ctx context.Context, lines []string) (string, error) {
+	cmds := make([]ai.Command, 0, len(lines))
+	for _, line := range lines {
+		cmds = append(cmds, ShellCommand(line))
+	}
+	outcomes, err := r.engine.RunCommands(ctx, cmds, true, r.confirm)
+	return transcript("执行助手建议的命令", outcomes), err
+}
+
+// RunCommands security-checks and executes commands one by one, asking for
+// confirmation through confirm when required (or always if alwaysConfirm is set).
+// It stops early when the user cancels all remaining commands.
+func (e *Engine) RunCommands(ctx context.Context, cmds []ai.Command, alwaysConfirm bool, confirm ConfirmFunc) ([]CommandOutcome, error) {
+	var outcomes []CommandOutcome
+
+	for i, cmd := range cmds {
+		outcome := CommandOutcome{Command: cmd}
+
+		result, err := e.checkCommand(cmd)
+		if err != nil {
+			return outcomes, fmt.Errorf("security check failed: %w", err)
+		}
+
+		if !result.Allowed {
+			fmt.Printf("🚫 拒绝执行: %s\n", result.Reason)
+			outcome.Skipped = "安全检查拒绝: " + result.Reason
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		if result.RequiresAuth || alwaysConfirm {
+			confirmed, err := confirm(cmd, result)
+			if err == terminal.ErrQuitAll {
+				outcome.Skipped = "用户取消"
+				outcomes = append(outcomes, outcome)
+				return outcomes, nil
+			}
+			if err != nil {
+				return outcomes, fmt.Errorf("confirmation error: %w", err)
+			}
+			if !confirmed {
+				outcome.Skipped = "用户跳过"
+				outcomes = append(outcomes, outcome)
+				continue
+			}
+		}
+
+		fmt.Printf("\n🔧 Executing [%d/%d]: %s\n", i+1, len(cmds), commandLine(cmd))
+
+		execResult, err := e.executor.Execute(ctx, cmd)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			outcome.Skipped = err.Error()
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+		e.recordCommand(cmd, execResult)
+
+		e.displayOutput(execResult.Output)
+		if execResult.Error != nil {
+			fmt.Printf("📊 Command failed (exit code %d)\n", execResult.ExitCode)
+			outcome.Error = execResult.Error.Error()
+		}
+
+		outcome.Executed = true
+		outcome.ExitCode = execResult.ExitCode
+		outcome.Output = execResult.Output
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes, nil
+}
+
+// checkCommand runs the security check. For shell command lines each part of a
+// pipeline or command list is checked as well, since the checkers only look at
+// the command name of the "sh" wrapper.
+func (e *Engine) checkCommand(cmd ai.Command) (*security.CheckResult, error) {
+	result, err := e.securityController.CheckCommand(cmd)
+	if err != nil || !result.Allowed {
+		return result, err
+	}
+
+	line, ok := shellLine(cmd)
+	if !ok {
+		return result, nil
+	}
+
+	for _, part := range splitShellLine(line) {
+		fields, sure := commandFields(part)
+		if !sure {
+			result = requireAuth(result, "Wrapped command with options",
+				fmt.Sprintf("cannot tell which command %q runs", part))
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		partResult, err := e.securityController.CheckCommand(ai.Command{Cmd: fields[0], Args: fields[1:]})
+		if err != nil {
+			return nil, err
+		}
+		if !partResult.Allowed {
+			return partResult, nil
+		}
+		if partResult.RequiresAuth {
+			result = requireAuth(result, partResult.Warning, partResult.Reason)
+		}
+	}
+	return result, nil
+}
+
+// requireAuth returns a copy of result that requires confirmation, with the
+// warning and reason appended
+func requireAuth(result *security.CheckResult, warning, reason string) *security.CheckResult {
+	merged := *result
+	merged.RequiresAuth = true
+	merged.Warning = strings.TrimSpace(merged.Warning + " " + warning)
+	merged.Reason = strings.TrimSpace(merged.Reason + " " + reason)
+	return &merged
+}
+
+// splitShellLine splits a command line on pipes and command separators. The
+// contents of command substitutions, $(...) and backticks, and of subshells
+// are split out as well, so the commands they run are checked like the others.
+// Quotes are not parsed: a quoted "(" splits too, which only checks more.
+func splitShellLine(line string) []string {
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		switch r {
+		case '|', ';', '&', '\n', '`', '(', ')':
+			return true
+		}
+		return false
+	})
+
+	var parts []string
+	for _, field := range fields {
+		// Drop what is left around a substitution, e.g. the `echo "$` of `echo "$(date)"`
+		if part := strings.Trim(field, " \t\"'$"); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// shellKeywords start or end a compound command or prefix the command that follows
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "do": true,
+	"while": true, "until": true, "!": true, "{": true, "time": true,
+	"fi": true, "done": true, "}": true,
+}
+
+// commandWrappers run the command given in their arguments
+var commandWrappers = map[string]bool{
+	"env": true, "xargs": true, "nohup": true, "command": true, "exec": true,
+	"nice": true, "timeout": true, "builtin": true,
+}
+
+// commandFields returns the fields of a part of a shell line starting at the
+// command it runs. Shell keywords and environment assignments such as FOO=1
+// are skipped, otherwise "FOO=1 rm -rf ~" would be checked as "FOO=1", and so
+// are wrappers such as env or xargs. The options of a wrapper may take values
+// that cannot be told apart from the command ("xargs -I {} rm {}"), so sure is
+// false when a wrapper has any.
+func commandFields(part string) (fields []string, sure bool) {
+	fields = strings.Fields(part)
+	sure = true
+	wrapped := false
+	for len(fields) > 0 {
+		field := fields[0]
+		switch {
+		case shellKeywords[field]:
+		case commandWrappers[filepath.Base(field)]:
+			wrapped = true
+		case strings.Contains(field, "=") && !strings.HasPrefix(field, "="):
+		case wrapped && (strings.HasPrefix(field, "-") || durationPattern.MatchString(field)):
+			// Options of a wrapper, e.g. "nice -n 10" or "timeout 5s"
+			sure = false
+		default:
+			return fields, sure
+		}
+		fields = fields[1:]
+	}
+	return nil, sure
+}
+
+// durationPattern matches numbers and durations passed to wrappers, e.g. "timeout 5s"
+var durationPattern = regexp.MustCompile(`^[0-9.]+[smhd]?$`)
+
+// shellLine returns the command line of a ShellCommand wrapper
+func shellLine(cmd ai.Command) (string, bool) {
+	if cmd.Cmd == "sh" && len(cmd.Args) == 2 && cmd.Args[0] == "-c" {
+		return cmd.Args[1], true
+	}
+	return "", false
+}
+
+// ShellCommand wraps a shell command line so pipes and redirects work
+func ShellCommand(line string) ai.Command {
+	return ai.Command{Cmd: "sh", Args: []string{"-c", line}}
+}
+
+// commandLine formats a command for display, unwrapping shell command lines
+func commandLine(cmd ai.Command) string {
+	if line, ok := shellLine(cmd); ok {
+		return line
+	}
+	if len(cmd.Args) == 0 {
+		return cmd.Cmd
+	}
+	return cmd.Cmd + " " + strings.Join(cmd.Args, " ")
+}
+
+// transcript joins command outcomes into a single conversation message
+func transcript(title string, outcomes []CommandOutcome) string {
+	if len(outcomes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\n", title)
+	for _, o := range outcomes {
+		b.WriteString(o.Transcript())
+	}
+	return b.String()
+}
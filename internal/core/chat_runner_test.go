@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+	"github.com/Lin-Jiong-HDU/tada/internal/terminal"
+)
+
+func newTestChatRunner(intent *ai.Intent, confirm ConfirmFunc) *ChatRunner {
+	engine := NewEngine(&mockAIProvider{intent: intent}, NewExecutor(5*time.Second), security.DefaultPolicy())
+	return &ChatRunner{engine: engine, confirm: confirm}
+}
+
+func TestChatRunner_RunShell(t *testing.T) {
+	confirmed := false
+	runner := newTestChatRunner(nil, func(cmd ai.Command, r *security.CheckResult) (bool, error) {
+		confirmed = true
+		return true, nil
+	})
+
+	transcript, err := runner.RunShell(context.Background(), "echo hello | tr a-z A-Z")
+	if err != nil {
+		t.Fatalf("RunShell failed: %v", err)
+	}
+	if confirmed {
+		t.Error("Expected safe command to run without confirmation")
+	}
+	if !strings.Contains(transcript, "$ echo hello | tr a-z A-Z") || !strings.Contains(transcript, "HELLO") {
+		t.Errorf("Unexpected transcript: %q", transcript)
+	}
+}
+
+func TestChatRunner_RunRequestSkipped(t *testing.T) {
+	intent := &ai.Intent{Commands: []ai.Command{{Cmd: "rm", Args: []string{"/tmp/tada-does-not-exist"}}}}
+	runner := newTestChatRunner(intent, func(cmd ai.Command, r *security.CheckResult) (bool, error) {
+		return false, nil
+	})
+
+	transcript, err := runner.RunRequest(context.Background(), "delete the file")
+	if err != nil {
+		t.Fatalf("RunRequest failed: %v", err)
+	}
+	if !strings.Contains(transcript, "用户跳过") {
+		t.Errorf("Expected skipped command in transcript, got %q", transcript)
+	}
+}
+
+func TestChatRunner_RunProposedAlwaysConfirms(t *testing.T) {
+	calls := 0
+	runner := newTestChatRunner(nil, func(cmd ai.Command, r *security.CheckResult) (bool, error) {
+		calls++
+		return false, terminal.ErrQuitAll
+	})
+
+	transcript, err := runner.RunProposed(context.Background(), []string{"echo one", "echo two"})
+	if err != nil {
+		t.Fatalf("RunProposed failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected quitting to stop after first prompt, got %d prompts", calls)
+	}
+	if strings.Contains(transcript, "echo two") {
+		t.Error("Expected remaining commands to be dropped after quit")
+	}
+}
+
+func TestEngine_CheckCommandShellLine(t *testing.T) {
+	engine := NewEngine(nil, NewExecutor(time.Second), security.DefaultPolicy())
+
+	result, err := engine.checkCommand(ShellCommand("ls && rm -r build"))
+	if err != nil {
+		t.Fatalf("checkCommand failed: %v", err)
+	}
+	if !result.RequiresAuth {
+		t.Error("Expected dangerous command inside shell line to require auth")
+	}
+
+	result, _ = engine.checkCommand(ShellCommand("ls -la"))
+	if result.RequiresAuth {
+		t.Error("Expected plain ls to pass without auth")
+	}
+
+	// Commands inside substitutions and subshells are checked too
+	for _, line := range []string{
+		"echo $(rm -r build)",
+		`echo "$(rm -r build)"`,
+		"echo `rm -r build`",
+		"(cd /tmp && rm -r build)",
+		"diff <(rm -r build) b",
+	} {
+		result, err := engine.checkCommand(ShellCommand(line))
+		if err != nil {
+			t.Fatalf("checkCommand(%q) failed: %v", line, err)
+		}
+		if !result.RequiresAuth {
+			t.Errorf("Expected %q to require auth", line)
+		}
+	}
+
+	result, _ = engine.checkCommand(ShellCommand(`echo "$(date)"`))
+	if result.RequiresAuth {
+		t.Error("Expected a safe substitution to pass without auth")
+	}
+}
+
+func TestEngine_CheckCommandSkipsPrefixes(t *testing.T) {
+	engine := NewEngine(nil, NewExecutor(time.Second), security.DefaultPolicy())
+
+	tests := []struct {
+		line         string
+		requiresAuth bool
+	}{
+		{"if true; then rm -rf build; fi", true},
+		{"{ rm -rf build; }", true},
+		{"FOO=1 rm -rf build", true},
+		{"while true; do rm -rf build; done", true},
+		{"! rm -rf build", true},
+		{"time rm -rf build", true},
+		{"env FOO=1 rm -rf build", true},
+		{"nohup rm -rf build", true},
+		{"command rm -rf build", true},
+		{"find . -name '*.o' | xargs rm -rf", true},
+		// Options of a wrapper could hide the command
+		{"xargs -I {} echo {}", true},
+		{"timeout 5s ls", true},
+		{"FOO=1 ls", false},
+		{"if true; then ls; fi", false},
+		{"time go build ./...", false},
+	}
+	for _, tt := range tests {
+		result, err := engine.checkCommand(ShellCommand(tt.line))
+		if err != nil {
+			t.Fatalf("checkCommand(%q) failed: %v", tt.line, err)
+		}
+		if result.RequiresAuth != tt.requiresAuth {
+			t.Errorf("checkCommand(%q).RequiresAuth = %v, want %v", tt.line, result.RequiresAuth, tt.requiresAuth)
+		}
+	}
+}
+
+func TestCommandOutcome_TranscriptTruncates(t *testing.T) {
+	o := CommandOutcome{
+		Command:  ai.Command{Cmd: "cat", Args: []string{"big.log"}},
+		Executed: true,
+		Output:   strings.Repeat("x", maxTranscriptOutput+100),
+	}
+	if !strings.Contains(o.Transcript(), "输出已截断") {
+		t.Error("Expected long output to be truncated in transcript")
+	}
+}
@@ -18,6 +18,8 @@ type Engine struct {
 	executor           *Executor
 	securityController *security.SecurityController
 	queue              *queue.Manager
+	memory             Memory // nil when memory is disabled
+	learn              bool   // record executed commands in memory
 }
 
 // NewEngine creates a new engine
@@ -69,7 +71,7 @@ func (e *Engine) Process(ctx context.Context, input string, systemPrompt string)
 
 	// Step 1: Parse intent
 	fmt.Println("🧠 Thinking...")
-	intent, err := e.ai.ParseIntent(ctx, input, systemPrompt)
+	intent, err := e.ai.ParseIntent(ctx, input, e.intentPrompt(input, systemPrompt))
 	if err != nil {
 		return fmt.Errorf("failed to parse intent: %w", err)
 	}
@@ -106,8 +108,17 @@ func (e *Engine) Process(ctx context.Context, input string, systemPrompt string)
 				if err != nil {
 					return fmt.Errorf("failed to queue task: %w", err)
 				}
+				if task.Status == queue.TaskStatusApproved {
+					fmt.Printf("📋 命令已自动批准并加入队列 (ID: %s)\n", task.ID)
+					fmt.Printf("   使用 'tada run' 执行\n")
+					continue
+				}
 				fmt.Printf("📋 命令已加入队列 (ID: %s)\n", task.ID)
-				fmt.Printf("   使用 'tada tasks' 查看并授权\n")
+				if task.RequiredApprovals > 1 {
+					fmt.Printf("   需要 %d 人批准，使用 'tada tasks' 查看并授权\n", task.RequiredApprovals)
+				} else {
+					fmt.Printf("   使用 'tada tasks' 查看并授权\n")
+				}
 				continue
 			}
 			// Fall through to sync execution if no queue available
@@ -135,6 +146,7 @@ func (e *Engine) Process(ctx context.Context, input string, systemPrompt string)
 			fmt.Printf("❌ Error: %v\n", err)
 			continue
 		}
+		e.recordCommand(cmd, execResult)
 
 		// Show output (truncated if too long)
 		e.displayOutput(execResult.Output)
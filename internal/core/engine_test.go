@@ -14,10 +14,12 @@ import (
 
 // Mock AI provider for testing
 type mockAIProvider struct {
-	intent *ai.Intent
+	intent       *ai.Intent
+	systemPrompt string // system prompt of the last ParseIntent call
 }
 
 func (m *mockAIProvider) ParseIntent(ctx context.Context, input string, systemPrompt string) (*ai.Intent, error) {
+	m.systemPrompt = systemPrompt
 	return m.intent, nil
 }
 
@@ -29,11 +31,11 @@ func (m *mockAIProvider) Chat(ctx context.Context, messages []ai.Message) (strin
 	return "response", nil
 }
 
-func (m *mockAIProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan string, error) {
-	ch := make(chan string)
+func (m *mockAIProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
+	ch := make(chan ai.StreamEvent)
 	go func() {
 		defer close(ch)
-		ch <- "stream"
+		ch <- ai.StreamEvent{Content: "stream", FinishReason: ai.FinishStop}
 	}()
 	return ch, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/core"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/notify"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
 )
 
@@ -12,6 +13,7 @@ import (
 type TaskExecutor struct {
 	queue    *queue.Manager
 	executor *core.Executor
+	notifier *notify.Notifier
 }
 
 // NewTaskExecutor creates a new task executor
@@ -22,6 +24,11 @@ func NewTaskExecutor(q *queue.Manager, executor *core.Executor) *TaskExecutor {
 	}
 }
 
+// SetNotifier sets the notifier told about finished tasks
+func (e *TaskExecutor) SetNotifier(n *notify.Notifier) {
+	e.notifier = n
+}
+
 // ExecuteTask executes a single task by ID
 func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID string) error {
 	// Get the task
@@ -49,8 +56,10 @@ func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID string) error {
 		return fmt.Errorf("failed to mark executing: %w", err)
 	}
 
-	// Execute the command
-	result, err := e.executor.Execute(ctx, target.Command)
+	// Execute the command, forwarding output to queue subscribers as it arrives
+	result, err := e.executor.ExecuteStream(ctx, target.Command, func(chunk string) {
+		e.queue.AppendOutput(taskID, chunk)
+	})
 
 	// Convert result to queue result
 	queueResult := &queue.ExecutionResult{
@@ -82,9 +91,24 @@ func (e *TaskExecutor) ExecuteTask(ctx context.Context, taskID string) error {
 		return fmt.Errorf("failed to set result: %w", err)
 	}
 
+	e.notifyFinished(ctx, taskID)
 	return nil
 }
 
+// notifyFinished sends a notification for the finished task. Notifications are
+// best-effort and still go out when ctx was cancelled while the task ran.
+func (e *TaskExecutor) notifyFinished(ctx context.Context, taskID string) {
+	if e.notifier == nil {
+		return
+	}
+	for _, task := range e.queue.GetAllTasks() {
+		if task.ID == taskID {
+			_ = e.notifier.Notify(context.WithoutCancel(ctx), notify.FromTask(task))
+			return
+		}
+	}
+}
+
 // ExecuteAllApproved executes all approved tasks
 func (e *TaskExecutor) ExecuteAllApproved(ctx context.Context) ([]*queue.ExecutionResult, error) {
 	tasks := e.queue.GetAllTasks()
@@ -9,6 +9,7 @@ import (
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 	"github.com/Lin-Jiong-HDU/tada/internal/core"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/notify"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
 )
@@ -97,3 +98,40 @@ func TestTaskExecutor_ExecuteAllApproved(t *testing.T) {
 		t.Errorf("Expected 2 completed tasks, got %d", completed)
 	}
 }
+
+// recordingSink collects notifications for assertions
+type recordingSink struct {
+	got []notify.Notification
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Notify(ctx context.Context, n notify.Notification) error {
+	s.got = append(s.got, n)
+	return nil
+}
+
+func TestTaskExecutor_NotifiesOnFinish(t *testing.T) {
+	q := queue.NewQueue(filepath.Join(t.TempDir(), "queue.json"), "test-session")
+
+	task, _ := q.AddTask(ai.Command{Cmd: "false", IsAsync: true}, &security.CheckResult{Allowed: true, RequiresAuth: true})
+	q.ApproveTask(task.ID)
+
+	sink := &recordingSink{}
+	notifier := &notify.Notifier{}
+	notifier.Add(sink)
+
+	taskExecutor := NewTaskExecutor(q, core.NewExecutor(5*time.Second))
+	taskExecutor.SetNotifier(notifier)
+
+	if err := taskExecutor.ExecuteTask(context.Background(), task.ID); err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+
+	if len(sink.got) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(sink.got))
+	}
+	if sink.got[0].Status != queue.TaskStatusFailed || sink.got[0].TaskID != task.ID {
+		t.Errorf("Unexpected notification: %+v", sink.got[0])
+	}
+}
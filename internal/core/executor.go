@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
@@ -30,8 +31,17 @@ type Result struct {
 	Error    error
 }
 
+// OutputFunc receives chunks of command output as they are produced
+type OutputFunc func(chunk string)
+
 // Execute runs a command and returns the result
 func (e *Executor) Execute(ctx context.Context, cmd ai.Command) (*Result, error) {
+	return e.ExecuteStream(ctx, cmd, nil)
+}
+
+// ExecuteStream runs a command like Execute, additionally passing stdout and
+// stderr chunks to onOutput while the command runs
+func (e *Executor) ExecuteStream(ctx context.Context, cmd ai.Command, onOutput OutputFunc) (*Result, error) {
 	ctx, cancel := context.WithTimeout(ctx, e.timeout)
 	defer cancel()
 
@@ -40,8 +50,15 @@ func (e *Executor) Execute(ctx context.Context, cmd ai.Command) (*Result, error)
 	execCmd := exec.CommandContext(ctx, cmdParts[0], cmdParts[1:]...)
 
 	var stdout, stderr bytes.Buffer
-	execCmd.Stdout = &stdout
-	execCmd.Stderr = &stderr
+	if onOutput != nil {
+		// stdout and stderr are copied from separate goroutines
+		var mu sync.Mutex
+		execCmd.Stdout = &streamWriter{buf: &stdout, mu: &mu, onOutput: onOutput}
+		execCmd.Stderr = &streamWriter{buf: &stderr, mu: &mu, onOutput: onOutput}
+	} else {
+		execCmd.Stdout = &stdout
+		execCmd.Stderr = &stderr
+	}
 
 	err := execCmd.Run()
 
@@ -64,6 +81,23 @@ func (e *Executor) Execute(ctx context.Context, cmd ai.Command) (*Result, error)
 	return result, nil
 }
 
+// streamWriter buffers output and forwards each write to a callback
+type streamWriter struct {
+	buf      *bytes.Buffer
+	mu       *sync.Mutex
+	onOutput OutputFunc
+}
+
+// Write implements io.Writer
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.buf.Write(p)
+	w.onOutput(string(p[:n]))
+	return n, err
+}
+
 // ExecuteBatch runs multiple commands sequentially
 func (e *Executor) ExecuteBatch(ctx context.Context, commands []ai.Command) ([]*Result, error) {
 	results := make([]*Result, len(commands))
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -66,3 +67,26 @@ func TestExecuteBatch_MultipleCommands(t *testing.T) {
 		t.Errorf("Expected 'second', got '%s'", results[1].Output)
 	}
 }
+
+func TestExecuteStream_ForwardsOutput(t *testing.T) {
+	executor := NewExecutor(5 * time.Second)
+
+	var streamed strings.Builder
+	result, err := executor.ExecuteStream(context.Background(), ai.Command{
+		Cmd:  "sh",
+		Args: []string{"-c", "echo out; echo err 1>&2"},
+	}, func(chunk string) {
+		streamed.WriteString(chunk)
+	})
+
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+
+	if !strings.Contains(streamed.String(), "out") || !strings.Contains(streamed.String(), "err") {
+		t.Errorf("Expected stdout and stderr to be streamed, got %q", streamed.String())
+	}
+	if result.Output != "out\n\nerr" {
+		t.Errorf("Expected buffered output to match Execute, got %q", result.Output)
+	}
+}
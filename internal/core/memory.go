@@ -0,0 +1,87 @@
+package core
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+// Memory gives the engine what is known about the user and learns from the
+// commands it runs. memory.Manager implements it.
+type Memory interface {
+	// CommandContext returns facts that help turn request into commands, or ""
+	CommandContext(request string) string
+	// RecordCommand remembers that a command was run and whether it succeeded
+	RecordCommand(name string, success bool) error
+}
+
+// SetMemory adds what memory knows to intent prompts. With learn, executed
+// commands are recorded in memory; without it memory is only read, e.g. in
+// incognito mode.
+func (e *Engine) SetMemory(memory Memory, learn bool) {
+	e.memory = memory
+	e.learn = learn
+}
+
+// intentPrompt returns the system prompt for parsing request, with the
+// facts from memory appended
+func (e *Engine) intentPrompt(request, systemPrompt string) string {
+	if e.memory == nil {
+		return systemPrompt
+	}
+	facts := e.memory.CommandContext(request)
+	if facts == "" {
+		return systemPrompt
+	}
+	if systemPrompt == "" {
+		systemPrompt = ai.DefaultIntentPrompt
+	}
+	return systemPrompt + "\n\n" + facts
+}
+
+// exitNotFound is the exit code of a shell that could not find the command
+const exitNotFound = 127
+
+// recordCommand feeds an executed command and its outcome back into memory.
+// Commands that could not be started are skipped, so mistyped or made up
+// command names do not become entities.
+func (e *Engine) recordCommand(cmd ai.Command, result *Result) {
+	if e.memory == nil || !e.learn {
+		return
+	}
+	var exitErr *exec.ExitError
+	if result.Error != nil && (!errors.As(result.Error, &exitErr) || result.ExitCode == exitNotFound) {
+		return
+	}
+	for _, name := range commandNames(cmd) {
+		// Memory is best effort, a failed write must not fail the command
+		_ = e.memory.RecordCommand(name, result.Error == nil)
+	}
+}
+
+// commandNames returns the programs a command runs. Shell command lines may
+// run several, e.g. "git log | grep fix".
+func commandNames(cmd ai.Command) []string {
+	line, ok := shellLine(cmd)
+	if !ok {
+		return []string{filepath.Base(cmd.Cmd)}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range splitShellLine(line) {
+		// Skips environment assignments such as GOOS=linux and wrappers such as time
+		fields, _ := commandFields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := filepath.Base(fields[0])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+)
+
+// fakeMemory records the commands reported by the engine
+type fakeMemory struct {
+	context string
+	runs    map[string][]bool
+}
+
+func (m *fakeMemory) CommandContext(request string) string {
+	return m.context
+}
+
+func (m *fakeMemory) RecordCommand(name string, success bool) error {
+	if m.runs == nil {
+		m.runs = make(map[string][]bool)
+	}
+	m.runs[name] = append(m.runs[name], success)
+	return nil
+}
+
+func TestEngine_Memory(t *testing.T) {
+	provider := &mockAIProvider{intent: &ai.Intent{
+		Commands: []ai.Command{
+			{Cmd: "echo", Args: []string{"hi"}},
+			ShellCommand("echo ok | grep missing"),
+			{Cmd: "tada-no-such-command"},
+		},
+		Reason: "test",
+	}}
+	engine := NewEngine(provider, NewExecutor(5*time.Second), security.DefaultPolicy())
+	memory := &fakeMemory{context: "## About the user\n- Shell: fish"}
+	engine.SetMemory(memory, true)
+
+	if err := engine.Process(context.Background(), "say hi", ""); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if !strings.HasPrefix(provider.systemPrompt, ai.DefaultIntentPrompt) || !strings.HasSuffix(provider.systemPrompt, "- Shell: fish") {
+		t.Errorf("expected memory appended to the default intent prompt, got:\n%s", provider.systemPrompt)
+	}
+
+	want := map[string][]bool{"echo": {true, false}, "grep": {false}}
+	if !reflect.DeepEqual(memory.runs, want) {
+		t.Errorf("recorded runs = %v, want %v", memory.runs, want)
+	}
+
+	// Without learn memory is only read
+	readOnly := &fakeMemory{}
+	engine.SetMemory(readOnly, false)
+	if err := engine.Process(context.Background(), "say hi", ""); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(readOnly.runs) != 0 {
+		t.Errorf("expected nothing recorded without learn, got %v", readOnly.runs)
+	}
+}
+
+func TestCommandNames(t *testing.T) {
+	tests := []struct {
+		cmd  ai.Command
+		want []string
+	}{
+		{ai.Command{Cmd: "/usr/bin/git", Args: []string{"status"}}, []string{"git"}},
+		{ShellCommand("GOOS=linux go build ./... && go test ./..."), []string{"go"}},
+		{ShellCommand("git log | grep fix; ls"), []string{"git", "grep", "ls"}},
+		{ShellCommand("if true; then FOO=1 time make; fi"), []string{"true", "make"}},
+		{ShellCommand(`echo "$(date)" && (cd /tmp; ls) ` + "`whoami`"), []string{"echo", "date", "cd", "ls", "whoami"}},
+	}
+	for _, tt := range tests {
+		if got := commandNames(tt.cmd); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("commandNames(%v) = %v, want %v", tt.cmd, got, tt.want)
+		}
+	}
+}
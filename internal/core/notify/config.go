@@ -0,0 +1,48 @@
+package notify
+
+import "github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+
+// Config selects the notification sinks and the statuses each one reports
+type Config struct {
+	Bell    SinkConfig    `mapstructure:"bell"`
+	Desktop SinkConfig    `mapstructure:"desktop"`
+	Webhook WebhookConfig `mapstructure:"webhook"`
+	Hook    HookConfig    `mapstructure:"hook"`
+}
+
+// SinkConfig holds the settings shared by all sinks
+type SinkConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// On lists the task statuses to notify about; empty means completed and failed
+	On []string `mapstructure:"on"`
+}
+
+// WebhookConfig configures the webhook sink
+type WebhookConfig struct {
+	SinkConfig `mapstructure:",squash"`
+	URL        string `mapstructure:"url"`
+	Timeout    int    `mapstructure:"timeout"` // Seconds, 0 uses the default
+}
+
+// HookConfig configures the shell hook sink
+type HookConfig struct {
+	SinkConfig `mapstructure:",squash"`
+	Command    string `mapstructure:"command"`
+}
+
+// DefaultConfig returns the default notification configuration:
+// only the terminal bell is enabled
+func DefaultConfig() Config {
+	return Config{
+		Bell: SinkConfig{Enabled: true},
+	}
+}
+
+// statuses converts the configured status names
+func (c SinkConfig) statuses() []queue.TaskStatus {
+	statuses := make([]queue.TaskStatus, 0, len(c.On))
+	for _, s := range c.On {
+		statuses = append(statuses, queue.TaskStatus(s))
+	}
+	return statuses
+}
@@ -0,0 +1,138 @@
+// Package notify sends notifications when queued tasks finish.
+//
+// A Notifier fans a Notification out to the configured sinks: a terminal
+// bell with an OSC 9 escape, a desktop notification through notify-send,
+// a webhook and a shell hook. Each sink can be limited to certain statuses.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+)
+
+// Notification describes a finished task
+type Notification struct {
+	TaskID    string           `json:"task_id"`
+	SessionID string           `json:"session_id"`
+	Command   string           `json:"command"`
+	Status    queue.TaskStatus `json:"status"`
+	ExitCode  int              `json:"exit_code"`
+	Error     string           `json:"error,omitempty"`
+	Elapsed   time.Duration    `json:"elapsed_ns"`
+	Time      time.Time        `json:"time"`
+}
+
+// Title returns a short summary line for the notification
+func (n Notification) Title() string {
+	if n.Status == queue.TaskStatusCompleted {
+		return "tada: 任务完成"
+	}
+	return "tada: 任务失败"
+}
+
+// Body returns the notification text
+func (n Notification) Body() string {
+	if n.Error != "" {
+		return fmt.Sprintf("%s (退出码 %d): %s", n.Command, n.ExitCode, n.Error)
+	}
+	return fmt.Sprintf("%s (退出码 %d)", n.Command, n.ExitCode)
+}
+
+// FromTask builds a notification from a finished task. Secrets in the command
+// and error are redacted, notifications may leave the machine.
+func FromTask(task *queue.Task) Notification {
+	n := Notification{
+		TaskID:    task.ID,
+		SessionID: task.SessionID,
+		Command:   task.Command.Cmd,
+		Status:    task.Status,
+		Elapsed:   task.Elapsed(time.Now()),
+		Time:      time.Now(),
+	}
+	if len(task.Command.Args) > 0 {
+		n.Command += " " + strings.Join(task.Command.Args, " ")
+	}
+	if task.Result != nil {
+		n.ExitCode = task.Result.ExitCode
+		n.Error = privacy.Redact("notify", task.Result.Error)
+	}
+	n.Command = privacy.Redact("notify", n.Command)
+	return n
+}
+
+// Sink delivers notifications to one destination
+type Sink interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// route pairs a sink with the statuses it is interested in
+type route struct {
+	sink     Sink
+	statuses map[queue.TaskStatus]bool
+}
+
+// Notifier dispatches notifications to sinks
+type Notifier struct {
+	routes []route
+}
+
+// New creates a notifier with the sinks enabled in cfg.
+// It returns nil when no sink is enabled.
+func New(cfg Config) *Notifier {
+	n := &Notifier{}
+	if cfg.Bell.Enabled {
+		n.Add(NewBellSink(os.Stderr), cfg.Bell.statuses()...)
+	}
+	if cfg.Desktop.Enabled {
+		n.Add(NewDesktopSink(), cfg.Desktop.statuses()...)
+	}
+	if cfg.Webhook.Enabled && cfg.Webhook.URL != "" {
+		n.Add(NewWebhookSink(cfg.Webhook.URL, time.Duration(cfg.Webhook.Timeout)*time.Second), cfg.Webhook.statuses()...)
+	}
+	if cfg.Hook.Enabled && cfg.Hook.Command != "" {
+		n.Add(NewHookSink(cfg.Hook.Command), cfg.Hook.statuses()...)
+	}
+	if len(n.routes) == 0 {
+		return nil
+	}
+	return n
+}
+
+// Add registers a sink for the given statuses; none means completed and failed
+func (n *Notifier) Add(sink Sink, statuses ...queue.TaskStatus) {
+	if len(statuses) == 0 {
+		statuses = []queue.TaskStatus{queue.TaskStatusCompleted, queue.TaskStatusFailed}
+	}
+	r := route{sink: sink, statuses: make(map[queue.TaskStatus]bool)}
+	for _, s := range statuses {
+		r.statuses[s] = true
+	}
+	n.routes = append(n.routes, r)
+}
+
+// Notify sends the notification to every sink subscribed to its status.
+// All sinks are tried; their errors are joined. A nil Notifier does nothing.
+func (n *Notifier) Notify(ctx context.Context, notification Notification) error {
+	if n == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, r := range n.routes {
+		if !r.statuses[notification.Status] {
+			continue
+		}
+		if err := r.sink.Notify(ctx, notification); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+)
+
+// recordingSink records notifications it receives
+type recordingSink struct {
+	got []Notification
+	err error
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Notify(ctx context.Context, n Notification) error {
+	s.got = append(s.got, n)
+	return s.err
+}
+
+func TestNotifier_RoutesByStatus(t *testing.T) {
+	all := &recordingSink{}
+	failedOnly := &recordingSink{}
+
+	n := &Notifier{}
+	n.Add(all)
+	n.Add(failedOnly, queue.TaskStatusFailed)
+
+	_ = n.Notify(context.Background(), Notification{Status: queue.TaskStatusCompleted})
+	_ = n.Notify(context.Background(), Notification{Status: queue.TaskStatusFailed})
+
+	if len(all.got) != 2 {
+		t.Errorf("Expected default sink to get 2 notifications, got %d", len(all.got))
+	}
+	if len(failedOnly.got) != 1 || failedOnly.got[0].Status != queue.TaskStatusFailed {
+		t.Errorf("Expected failed-only sink to get 1 failure, got %+v", failedOnly.got)
+	}
+}
+
+func TestNotifier_JoinsErrors(t *testing.T) {
+	broken := &recordingSink{err: errors.New("boom")}
+	ok := &recordingSink{}
+
+	n := &Notifier{}
+	n.Add(broken)
+	n.Add(ok)
+
+	err := n.Notify(context.Background(), Notification{Status: queue.TaskStatusCompleted})
+	if err == nil {
+		t.Error("Expected error from broken sink")
+	}
+	if len(ok.got) != 1 {
+		t.Error("Expected remaining sinks to still be notified")
+	}
+}
+
+func TestNotifier_Nil(t *testing.T) {
+	var n *Notifier
+	if err := n.Notify(context.Background(), Notification{}); err != nil {
+		t.Errorf("Expected nil notifier to do nothing, got %v", err)
+	}
+	if New(Config{}) != nil {
+		t.Error("Expected New to return nil without enabled sinks")
+	}
+}
+
+func TestFromTask(t *testing.T) {
+	task := &queue.Task{
+		ID:        "task-1",
+		SessionID: "s1",
+		Command:   ai.Command{Cmd: "make", Args: []string{"build"}},
+		Status:    queue.TaskStatusFailed,
+		Result:    &queue.ExecutionResult{ExitCode: 2, Error: "exit status 2"},
+	}
+
+	n := FromTask(task)
+	if n.Command != "make build" || n.ExitCode != 2 || n.Error != "exit status 2" {
+		t.Errorf("Unexpected notification: %+v", n)
+	}
+	if n.Title() != "tada: 任务失败" {
+		t.Errorf("Unexpected title: %s", n.Title())
+	}
+}
+
+func TestFromTask_RedactsSecrets(t *testing.T) {
+	redactor, _ := privacy.NewRedactor(nil)
+	privacy.SetDefault(redactor)
+	defer privacy.SetDefault(nil)
+
+	secret := "sk-abcdefghijklmnopqrstuvwx"
+	task := &queue.Task{
+		Command: ai.Command{Cmd: "deploy", Args: []string{"--key", secret}},
+		Status:  queue.TaskStatusFailed,
+		Result:  &queue.ExecutionResult{ExitCode: 1, Error: "invalid key " + secret},
+	}
+
+	n := FromTask(task)
+	if strings.Contains(n.Command, secret) || strings.Contains(n.Error, secret) {
+		t.Errorf("Expected secrets to be redacted: %+v", n)
+	}
+	if task.Command.Args[1] != secret {
+		t.Error("Expected the task to be left alone")
+	}
+}
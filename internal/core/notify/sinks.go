@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultWebhookTimeout is used when the webhook timeout is not configured
+const defaultWebhookTimeout = 10 * time.Second
+
+// BellSink rings the terminal bell and emits an OSC 9 notification,
+// which terminals such as iTerm2, WezTerm and Windows Terminal show natively
+type BellSink struct {
+	w io.Writer
+}
+
+// NewBellSink creates a bell sink writing to w
+func NewBellSink(w io.Writer) *BellSink {
+	return &BellSink{w: w}
+}
+
+// Name returns the sink name
+func (s *BellSink) Name() string { return "bell" }
+
+// Notify writes the OSC 9 escape followed by a bell
+func (s *BellSink) Notify(ctx context.Context, n Notification) error {
+	// Control characters in the text would terminate the escape sequence early
+	text := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, n.Title()+": "+n.Body())
+	_, err := fmt.Fprintf(s.w, "\x1b]9;%s\x07\a", text)
+	return err
+}
+
+// DesktopSink shows a desktop notification through notify-send
+type DesktopSink struct {
+	lookPath func(string) (string, error)
+}
+
+// NewDesktopSink creates a desktop notification sink
+func NewDesktopSink() *DesktopSink {
+	return &DesktopSink{lookPath: exec.LookPath}
+}
+
+// Name returns the sink name
+func (s *DesktopSink) Name() string { return "desktop" }
+
+// Notify runs notify-send; it does nothing when notify-send is not installed
+func (s *DesktopSink) Notify(ctx context.Context, n Notification) error {
+	path, err := s.lookPath("notify-send")
+	if err != nil {
+		return nil
+	}
+
+	urgency := "normal"
+	if n.Error != "" || n.ExitCode != 0 {
+		urgency = "critical"
+	}
+	return exec.CommandContext(ctx, path, "--app-name=tada", "--urgency="+urgency, n.Title(), n.Body()).Run()
+}
+
+// WebhookSink posts the notification as JSON to a URL
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a webhook sink
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &WebhookSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Name returns the sink name
+func (s *WebhookSink) Name() string { return "webhook" }
+
+// Notify posts the notification
+func (s *WebhookSink) Notify(ctx context.Context, n Notification) error {
+	payload := struct {
+		Notification
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}{n, n.Title(), n.Body()}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// HookSink runs a shell command with the task details in environment variables:
+// TADA_TASK_ID, TADA_SESSION_ID, TADA_TASK_STATUS, TADA_TASK_COMMAND,
+// TADA_TASK_EXIT_CODE and TADA_TASK_ERROR
+type HookSink struct {
+	command string
+}
+
+// NewHookSink creates a shell hook sink
+func NewHookSink(command string) *HookSink {
+	return &HookSink{command: command}
+}
+
+// Name returns the sink name
+func (s *HookSink) Name() string { return "hook" }
+
+// Notify runs the hook command
+func (s *HookSink) Notify(ctx context.Context, n Notification) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command)
+	cmd.Env = append(os.Environ(),
+		"TADA_TASK_ID="+n.TaskID,
+		"TADA_SESSION_ID="+n.SessionID,
+		"TADA_TASK_STATUS="+string(n.Status),
+		"TADA_TASK_COMMAND="+n.Command,
+		"TADA_TASK_EXIT_CODE="+strconv.Itoa(n.ExitCode),
+		"TADA_TASK_ERROR="+n.Error,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("hook failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
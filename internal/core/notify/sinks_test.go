@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+)
+
+func TestBellSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewBellSink(&buf)
+
+	err := sink.Notify(context.Background(), Notification{Command: "ls\n-la", Status: queue.TaskStatusCompleted})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "\x1b]9;tada: 任务完成: ls -la") {
+		t.Errorf("Expected OSC 9 sequence with sanitized text, got %q", out)
+	}
+	if !strings.HasSuffix(out, "\x07\a") {
+		t.Errorf("Expected terminator and bell, got %q", out)
+	}
+}
+
+func TestDesktopSink_MissingNotifySend(t *testing.T) {
+	sink := &DesktopSink{lookPath: func(string) (string, error) { return "", errors.New("not found") }}
+	if err := sink.Notify(context.Background(), Notification{}); err != nil {
+		t.Errorf("Expected missing notify-send to be ignored, got %v", err)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON content type, got %s", r.Header.Get("Content-Type"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, 0)
+	err := sink.Notify(context.Background(), Notification{TaskID: "task-1", Command: "make", Status: queue.TaskStatusCompleted})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if received["task_id"] != "task-1" || received["status"] != "completed" {
+		t.Errorf("Unexpected payload: %v", received)
+	}
+	if received["title"] != "tada: 任务完成" {
+		t.Errorf("Expected title in payload, got %v", received["title"])
+	}
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, 0)
+	if err := sink.Notify(context.Background(), Notification{}); err == nil {
+		t.Error("Expected error for non-2xx response")
+	}
+}
+
+func TestHookSink(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook.out")
+	sink := NewHookSink(`echo "$TADA_TASK_ID $TADA_TASK_STATUS $TADA_TASK_EXIT_CODE" > ` + out)
+
+	err := sink.Notify(context.Background(), Notification{TaskID: "task-1", Status: queue.TaskStatusFailed, ExitCode: 3})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Expected hook to write output: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "task-1 failed 3" {
+		t.Errorf("Unexpected hook output: %q", data)
+	}
+
+	if err := NewHookSink("exit 1").Notify(context.Background(), Notification{}); err == nil {
+		t.Error("Expected error from failing hook")
+	}
+}
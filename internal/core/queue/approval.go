@@ -0,0 +1,195 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+)
+
+// ApproverPolicy is the approver identity recorded for automatic approvals
+const ApproverPolicy = "policy"
+
+// ApprovalPolicy decides how new tasks are approved
+type ApprovalPolicy struct {
+	// AutoApproveSafe approves tasks whose security check passed without requiring authorization
+	AutoApproveSafe bool `mapstructure:"auto_approve_safe"`
+
+	// AutoApprovePatterns approves tasks whose command matches one of the glob patterns
+	// ("*" matches any text, "?" a single character), e.g. "git status*"
+	AutoApprovePatterns []string `mapstructure:"auto_approve_patterns"`
+
+	// Rules require several distinct approvals for matching commands.
+	// A matching rule takes precedence over auto-approval.
+	Rules []ApprovalRule `mapstructure:"rules"`
+}
+
+// ApprovalRule requires a number of approvals for commands matching a pattern
+type ApprovalRule struct {
+	Pattern           string `mapstructure:"pattern"`
+	RequiredApprovals int    `mapstructure:"required_approvals"`
+}
+
+// Approval records who approved a task and when
+type Approval struct {
+	Approver string    `json:"approver"`
+	Time     time.Time `json:"time"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// ApprovalDecision is the outcome of evaluating a policy for a new task
+type ApprovalDecision struct {
+	AutoApprove       bool
+	RequiredApprovals int
+	Reason            string
+}
+
+// Evaluate applies the policy to a command and its security check result.
+// A nil policy requires a single manual approval.
+func (p *ApprovalPolicy) Evaluate(cmd ai.Command, checkResult *security.CheckResult) ApprovalDecision {
+	decision := ApprovalDecision{RequiredApprovals: 1}
+	if p == nil {
+		return decision
+	}
+
+	cmdStr := commandLine(cmd)
+
+	for _, rule := range p.Rules {
+		if rule.RequiredApprovals > 0 && matchPattern(rule.Pattern, cmdStr) {
+			decision.RequiredApprovals = rule.RequiredApprovals
+			decision.Reason = fmt.Sprintf("匹配规则 %q，需要 %d 人批准", rule.Pattern, rule.RequiredApprovals)
+			return decision
+		}
+	}
+
+	for _, pattern := range p.AutoApprovePatterns {
+		if matchPattern(pattern, cmdStr) {
+			decision.AutoApprove = true
+			decision.Reason = fmt.Sprintf("匹配自动批准规则 %q", pattern)
+			return decision
+		}
+	}
+
+	if p.AutoApproveSafe && checkResult != nil && checkResult.Allowed && !checkResult.RequiresAuth {
+		decision.AutoApprove = true
+		decision.Reason = "安全检查通过"
+	}
+
+	return decision
+}
+
+// SetApprovalPolicy sets the policy applied to tasks added afterwards
+func (m *Manager) SetApprovalPolicy(policy *ApprovalPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = policy
+}
+
+// ApproveTaskAs records an approval by the given approver. The task moves to
+// approved once it has collected the required number of distinct approvals.
+// It returns a snapshot of the task after the approval.
+func (m *Manager) ApproveTaskAs(taskID string, approver string) (*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, task := range m.tasks {
+		if task.ID != taskID {
+			continue
+		}
+		if !task.CanTransitionTo(TaskStatusApproved) {
+			return nil, fmt.Errorf("cannot transition task %s from %s to approved",
+				taskID, task.Status)
+		}
+		if task.HasApprovalFrom(approver) {
+			return nil, fmt.Errorf("task %s already approved by %s", taskID, approver)
+		}
+
+		task.Approvals = append(task.Approvals, Approval{Approver: approver, Time: time.Now()})
+		task.UpdatedAt = time.Now()
+		if len(task.Approvals) >= task.requiredApprovals() {
+			task.TransitionStatus(TaskStatusApproved)
+		}
+
+		if err := m.saveAndPublish(task); err != nil {
+			return nil, err
+		}
+		return snapshot(task), nil
+	}
+	return nil, fmt.Errorf("task not found: %s", taskID)
+}
+
+// HasApprovalFrom reports whether the approver has already approved the task
+func (t *Task) HasApprovalFrom(approver string) bool {
+	for _, a := range t.Approvals {
+		if a.Approver == approver {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredApprovals returns the number of approvals needed, at least one
+func (t *Task) requiredApprovals() int {
+	if t.RequiredApprovals < 1 {
+		return 1
+	}
+	return t.RequiredApprovals
+}
+
+// ApprovalProgress returns the collected and required approval counts
+func (t *Task) ApprovalProgress() (int, int) {
+	return len(t.Approvals), t.requiredApprovals()
+}
+
+// CurrentApprover returns the identity recorded for manual approvals:
+// $TADA_APPROVER if set, otherwise the OS user name
+func CurrentApprover() string {
+	if name := os.Getenv("TADA_APPROVER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// commandLine joins a command and its arguments for pattern matching
+func commandLine(cmd ai.Command) string {
+	if len(cmd.Args) == 0 {
+		return cmd.Cmd
+	}
+	return cmd.Cmd + " " + strings.Join(cmd.Args, " ")
+}
+
+// matchPattern reports whether s matches the glob pattern as a whole.
+// Unlike path.Match, "*" also matches "/" so patterns work on command lines.
+func matchPattern(pattern, s string) bool {
+	if pattern == "" {
+		return false
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	matched, err := regexp.MatchString(b.String(), s)
+	return err == nil && matched
+}
@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"git status*", "git status", true},
+		{"git status*", "git status --short", true},
+		{"ls *", "ls /tmp/a", true},
+		{"ls", "ls -la", false},
+		{"rm ?", "rm a", true},
+		{"rm ?", "rm ab", false},
+		{"a.b", "axb", false},
+		{"", "anything", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestApprovalPolicy_Evaluate(t *testing.T) {
+	safe := &security.CheckResult{Allowed: true, RequiresAuth: false}
+	risky := &security.CheckResult{Allowed: true, RequiresAuth: true}
+
+	policy := &ApprovalPolicy{
+		AutoApproveSafe:     true,
+		AutoApprovePatterns: []string{"make test*"},
+		Rules:               []ApprovalRule{{Pattern: "rm *", RequiredApprovals: 2}},
+	}
+
+	if d := policy.Evaluate(ai.Command{Cmd: "ls"}, safe); !d.AutoApprove {
+		t.Error("Expected safe command to be auto-approved")
+	}
+	if d := policy.Evaluate(ai.Command{Cmd: "make", Args: []string{"test"}}, risky); !d.AutoApprove {
+		t.Error("Expected pattern match to be auto-approved")
+	}
+	if d := policy.Evaluate(ai.Command{Cmd: "curl"}, risky); d.AutoApprove || d.RequiredApprovals != 1 {
+		t.Errorf("Expected manual single approval, got %+v", d)
+	}
+
+	// Rules take precedence over auto-approval
+	d := policy.Evaluate(ai.Command{Cmd: "rm", Args: []string{"-rf", "/tmp/x"}}, safe)
+	if d.AutoApprove || d.RequiredApprovals != 2 {
+		t.Errorf("Expected rule to require 2 approvals, got %+v", d)
+	}
+
+	var nilPolicy *ApprovalPolicy
+	if d := nilPolicy.Evaluate(ai.Command{Cmd: "ls"}, safe); d.AutoApprove || d.RequiredApprovals != 1 {
+		t.Errorf("Expected nil policy to require manual approval, got %+v", d)
+	}
+}
+
+func TestQueue_AddTaskAutoApproves(t *testing.T) {
+	q := NewQueue(filepath.Join(t.TempDir(), "queue.json"), "session-123")
+	q.SetApprovalPolicy(&ApprovalPolicy{AutoApproveSafe: true})
+
+	task, err := q.AddTask(ai.Command{Cmd: "ls"}, &security.CheckResult{Allowed: true})
+	if err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+	if task.Status != TaskStatusApproved {
+		t.Errorf("Expected approved, got %s", task.Status)
+	}
+	if len(task.Approvals) != 1 || task.Approvals[0].Approver != ApproverPolicy {
+		t.Errorf("Expected policy approval to be recorded, got %+v", task.Approvals)
+	}
+
+	task, _ = q.AddTask(ai.Command{Cmd: "rm"}, &security.CheckResult{Allowed: true, RequiresAuth: true})
+	if task.Status != TaskStatusPending {
+		t.Errorf("Expected task requiring auth to stay pending, got %s", task.Status)
+	}
+}
+
+func TestQueue_MultiPartyApproval(t *testing.T) {
+	queueFile := filepath.Join(t.TempDir(), "queue.json")
+	q := NewQueue(queueFile, "session-123")
+	q.SetApprovalPolicy(&ApprovalPolicy{
+		Rules: []ApprovalRule{{Pattern: "deploy*", RequiredApprovals: 2}},
+	})
+
+	task, _ := q.AddTask(ai.Command{Cmd: "deploy", Args: []string{"prod"}}, &security.CheckResult{Allowed: true, RequiresAuth: true})
+
+	got, err := q.ApproveTaskAs(task.ID, "alice")
+	if err != nil {
+		t.Fatalf("First approval failed: %v", err)
+	}
+	if got.Status != TaskStatusPending {
+		t.Errorf("Expected pending after first approval, got %s", got.Status)
+	}
+
+	if _, err := q.ApproveTaskAs(task.ID, "alice"); err == nil {
+		t.Error("Expected duplicate approval by the same approver to fail")
+	}
+
+	got, err = q.ApproveTaskAs(task.ID, "bob")
+	if err != nil {
+		t.Fatalf("Second approval failed: %v", err)
+	}
+	if got.Status != TaskStatusApproved {
+		t.Errorf("Expected approved after second approval, got %s", got.Status)
+	}
+
+	// Approvers are persisted
+	reloaded := findTask(NewQueue(queueFile, "session-123"), task.ID)
+	if reloaded == nil || len(reloaded.Approvals) != 2 || reloaded.Approvals[1].Approver != "bob" {
+		t.Errorf("Expected approvals to be persisted, got %+v", reloaded)
+	}
+}
+
+func TestCurrentApprover_Env(t *testing.T) {
+	t.Setenv("TADA_APPROVER", "reviewer")
+	if got := CurrentApprover(); got != "reviewer" {
+		t.Errorf("Expected reviewer, got %s", got)
+	}
+}
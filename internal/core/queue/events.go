@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of queue event
+type EventType string
+
+const (
+	EventTaskAdded  EventType = "task_added"  // A task was added to the queue
+	EventTaskStatus EventType = "task_status" // A task changed status
+	EventTaskOutput EventType = "task_output" // A running task produced output
+)
+
+// eventBufferSize is the per-subscriber channel capacity
+const eventBufferSize = 256
+
+// Event describes a change in the task queue
+type Event struct {
+	Type      EventType
+	TaskID    string
+	SessionID string
+	Status    TaskStatus
+	Chunk     string // Output chunk, only set for EventTaskOutput
+	Task      *Task  // Snapshot of the task after the change, nil for EventTaskOutput
+	Time      time.Time
+}
+
+// broker fans queue events out to subscribers
+type broker struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]chan Event
+}
+
+// subscribe registers a new subscriber channel
+func (b *broker) subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs == nil {
+		b.subs = make(map[int]chan Event)
+	}
+
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Event, eventBufferSize)
+	b.subs[id] = ch
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, id)
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// publish delivers an event to every subscriber without blocking.
+// Slow subscribers miss events rather than stalling queue operations.
+func (b *broker) publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of queue events and a function that cancels the
+// subscription and closes the channel
+func (m *Manager) Subscribe() (<-chan Event, func()) {
+	return m.events.subscribe()
+}
+
+// AppendOutput publishes an output chunk for a running task.
+// Chunks are not persisted; the full output is stored with the result.
+func (m *Manager) AppendOutput(taskID string, chunk string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, task := range m.tasks {
+		if task.ID == taskID {
+			m.events.publish(Event{
+				Type:      EventTaskOutput,
+				TaskID:    taskID,
+				SessionID: task.SessionID,
+				Status:    task.Status,
+				Chunk:     chunk,
+				Time:      time.Now(),
+			})
+			return
+		}
+	}
+}
+
+// publishTask publishes an event carrying a snapshot of the task.
+// Callers must hold m.mu.
+func (m *Manager) publishTask(eventType EventType, task *Task) {
+	m.events.publish(Event{
+		Type:      eventType,
+		TaskID:    task.ID,
+		SessionID: task.SessionID,
+		Status:    task.Status,
+		Task:      snapshot(task),
+		Time:      time.Now(),
+	})
+}
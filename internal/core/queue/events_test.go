@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+)
+
+func receiveEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestManager_Subscribe_LifecycleEvents(t *testing.T) {
+	q := NewQueue(filepath.Join(t.TempDir(), "queue.json"), "session-123")
+
+	events, unsubscribe := q.Subscribe()
+	defer unsubscribe()
+
+	cmd := ai.Command{Cmd: "echo", Args: []string{"hi"}}
+	check := &security.CheckResult{Allowed: true, RequiresAuth: true}
+	task, err := q.AddTask(cmd, check)
+	if err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+
+	event := receiveEvent(t, events)
+	if event.Type != EventTaskAdded || event.TaskID != task.ID {
+		t.Errorf("Expected task_added for %s, got %s for %s", task.ID, event.Type, event.TaskID)
+	}
+
+	q.ApproveTask(task.ID)
+	q.MarkExecuting(task.ID)
+	q.AppendOutput(task.ID, "hi\n")
+	q.SetTaskResult(task.ID, &ExecutionResult{ExitCode: 0, Output: "hi"})
+
+	expected := []struct {
+		eventType EventType
+		status    TaskStatus
+	}{
+		{EventTaskStatus, TaskStatusApproved},
+		{EventTaskStatus, TaskStatusExecuting},
+		{EventTaskOutput, TaskStatusExecuting},
+		{EventTaskStatus, TaskStatusCompleted},
+	}
+	for _, want := range expected {
+		event := receiveEvent(t, events)
+		if event.Type != want.eventType || event.Status != want.status {
+			t.Errorf("Expected %s/%s, got %s/%s", want.eventType, want.status, event.Type, event.Status)
+		}
+		if event.Type == EventTaskOutput && event.Chunk != "hi\n" {
+			t.Errorf("Expected output chunk 'hi\\n', got %q", event.Chunk)
+		}
+		if event.Type == EventTaskStatus && event.Task == nil {
+			t.Error("Expected status event to carry a task snapshot")
+		}
+	}
+}
+
+func TestManager_Subscribe_SnapshotIsCopy(t *testing.T) {
+	q := NewQueue(filepath.Join(t.TempDir(), "queue.json"), "session-123")
+
+	events, unsubscribe := q.Subscribe()
+	defer unsubscribe()
+
+	task, _ := q.AddTask(ai.Command{Cmd: "ls"}, &security.CheckResult{Allowed: true})
+	added := receiveEvent(t, events)
+
+	q.ApproveTask(task.ID)
+	if added.Task.Status != TaskStatusPending {
+		t.Errorf("Expected snapshot to keep pending status, got %s", added.Task.Status)
+	}
+}
+
+func TestManager_Unsubscribe_ClosesChannel(t *testing.T) {
+	q := NewQueue(filepath.Join(t.TempDir(), "queue.json"), "session-123")
+
+	events, unsubscribe := q.Subscribe()
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed after unsubscribe")
+	}
+
+	// Publishing without subscribers must not block
+	q.AddTask(ai.Command{Cmd: "ls"}, &security.CheckResult{Allowed: true})
+}
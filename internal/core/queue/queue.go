@@ -15,6 +15,10 @@ type Manager struct {
 	store     *Store
 	tasks     []*Task
 	mu        sync.RWMutex
+	events    broker
+
+	outputLimit int             // Maximum stored output per task in bytes, 0 means unlimited
+	policy      *ApprovalPolicy // Applied to new tasks, nil requires manual approval
 }
 
 // NewQueue creates a new queue manager
@@ -35,33 +39,49 @@ func NewQueue(filePath string, sessionID string) *Manager {
 	}
 }
 
-// AddTask adds a new task to the queue
+// AddTask adds a new task to the queue, applying the approval policy
 func (m *Manager) AddTask(cmd ai.Command, checkResult *security.CheckResult) (*Task, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	task := NewTask(m.sessionID, cmd, checkResult)
+
+	decision := m.policy.Evaluate(cmd, checkResult)
+	task.RequiredApprovals = decision.RequiredApprovals
+	if decision.AutoApprove {
+		task.Approvals = append(task.Approvals, Approval{
+			Approver: ApproverPolicy,
+			Time:     task.CreatedAt,
+			Reason:   decision.Reason,
+		})
+		task.TransitionStatus(TaskStatusApproved)
+	}
+
 	m.tasks = append(m.tasks, task)
 
 	if err := m.store.Save(m.tasks); err != nil {
 		return nil, err
 	}
 
+	m.publishTask(EventTaskAdded, task)
 	return task, nil
 }
 
-// GetAllTasks returns all tasks
+// GetAllTasks returns snapshots of all tasks. The queue updates its tasks
+// from other goroutines, so callers never get the tasks themselves; changes go
+// through the Manager methods.
 func (m *Manager) GetAllTasks() []*Task {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Return a copy to avoid race conditions
 	result := make([]*Task, len(m.tasks))
-	copy(result, m.tasks)
+	for i, task := range m.tasks {
+		result[i] = snapshot(task)
+	}
 	return result
 }
 
-// GetPendingTasks returns all pending tasks
+// GetPendingTasks returns snapshots of all pending tasks
 func (m *Manager) GetPendingTasks() []*Task {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -69,13 +89,13 @@ func (m *Manager) GetPendingTasks() []*Task {
 	var pending []*Task
 	for _, task := range m.tasks {
 		if task.Status == TaskStatusPending {
-			pending = append(pending, task)
+			pending = append(pending, snapshot(task))
 		}
 	}
 	return pending
 }
 
-// GetTasksBySession returns tasks for a specific session
+// GetTasksBySession returns snapshots of the tasks for a specific session
 func (m *Manager) GetTasksBySession(sessionID string) []*Task {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -83,27 +103,17 @@ func (m *Manager) GetTasksBySession(sessionID string) []*Task {
 	var result []*Task
 	for _, task := range m.tasks {
 		if task.SessionID == sessionID {
-			result = append(result, task)
+			result = append(result, snapshot(task))
 		}
 	}
 	return result
 }
 
-// ApproveTask approves a task for execution
+// ApproveTask records an approval by the current user.
+// See ApproveTaskAs for tasks that need several approvals.
 func (m *Manager) ApproveTask(taskID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	for _, task := range m.tasks {
-		if task.ID == taskID {
-			if !task.TransitionStatus(TaskStatusApproved) {
-				return fmt.Errorf("cannot transition task %s from %s to approved",
-					taskID, task.Status)
-			}
-			return m.store.Save(m.tasks)
-		}
-	}
-	return fmt.Errorf("task not found: %s", taskID)
+	_, err := m.ApproveTaskAs(taskID, CurrentApprover())
+	return err
 }
 
 // RejectTask rejects a task
@@ -117,7 +127,7 @@ func (m *Manager) RejectTask(taskID string) error {
 				return fmt.Errorf("cannot transition task %s from %s to rejected",
 					taskID, task.Status)
 			}
-			return m.store.Save(m.tasks)
+			return m.saveAndPublish(task)
 		}
 	}
 	return fmt.Errorf("task not found: %s", taskID)
@@ -135,7 +145,7 @@ func (m *Manager) MarkExecuting(taskID string) error {
 					taskID, task.Status)
 			}
 			task.TransitionStatus(TaskStatusExecuting)
-			return m.store.Save(m.tasks)
+			return m.saveAndPublish(task)
 		}
 	}
 	return fmt.Errorf("task not found: %s", taskID)
@@ -162,11 +172,36 @@ func (m *Manager) SetTaskResult(taskID string, result *ExecutionResult) error {
 					taskID, task.Status, targetStatus)
 			}
 
+			// Cap the stored output
+			if m.outputLimit > 0 && len(result.Output) > m.outputLimit {
+				capped := *result
+				capped.Output = TruncateOutput(result.Output, m.outputLimit)
+				result = &capped
+			}
+
 			// Set result and transition status
 			task.SetResult(result)
 			task.TransitionStatus(targetStatus)
-			return m.store.Save(m.tasks)
+			return m.saveAndPublish(task)
 		}
 	}
 	return fmt.Errorf("task not found: %s", taskID)
 }
+
+// snapshot returns a copy of the task that does not change when the queue
+// updates the task. Callers must hold m.mu.
+func snapshot(task *Task) *Task {
+	copied := *task
+	copied.Approvals = append([]Approval(nil), task.Approvals...)
+	return &copied
+}
+
+// saveAndPublish persists the queue and publishes a status event for the task.
+// Callers must hold m.mu.
+func (m *Manager) saveAndPublish(task *Task) error {
+	if err := m.store.Save(m.tasks); err != nil {
+		return err
+	}
+	m.publishTask(EventTaskStatus, task)
+	return nil
+}
@@ -150,3 +150,27 @@ func TestQueue_GetTasksBySession(t *testing.T) {
 		t.Errorf("Expected 0 tasks for other session, got %d", len(tasks))
 	}
 }
+
+func TestQueue_GetAllTasksReturnsSnapshots(t *testing.T) {
+	q := NewQueue(filepath.Join(t.TempDir(), "queue.json"), "session-123")
+	task, _ := q.AddTask(ai.Command{Cmd: "rm", Args: []string{"/tmp/test"}}, &security.CheckResult{Allowed: true, RequiresAuth: true})
+
+	// Changing a returned task leaves the queue alone
+	q.GetAllTasks()[0].Status = TaskStatusApproved
+	q.GetPendingTasks()[0].Command.Cmd = "ls"
+
+	// Returned snapshots do not follow later changes
+	before := q.GetAllTasks()[0]
+	if before.Status != TaskStatusPending || before.Command.Cmd != "rm" {
+		t.Errorf("Expected the queue to be unchanged, got %s %s", before.Status, before.Command.Cmd)
+	}
+	if err := q.RejectTask(task.ID); err != nil {
+		t.Fatalf("RejectTask failed: %v", err)
+	}
+	if before.Status != TaskStatusPending {
+		t.Errorf("Expected an unchanged snapshot, got %s", before.Status)
+	}
+	if got := q.GetTasksBySession("session-123")[0].Status; got != TaskStatusRejected {
+		t.Errorf("Expected the queue to hold the rejection, got %s", got)
+	}
+}
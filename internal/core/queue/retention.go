@@ -0,0 +1,155 @@
+package queue
+
+import (
+	"fmt"
+	"sort"
+)
+
+// truncatedMarker is appended to outputs cut by the output size cap
+const truncatedMarker = "\n... [输出已截断]"
+
+// RetentionPolicy limits how much finished task history a queue keeps
+type RetentionPolicy struct {
+	// MaxCompletedTasks is the number of finished (completed, failed or rejected)
+	// tasks kept per session; older ones are dropped. 0 means unlimited.
+	MaxCompletedTasks int
+
+	// MaxOutputBytes caps the stored output of each task. 0 means unlimited.
+	MaxOutputBytes int
+}
+
+// IsFinished reports whether the task has reached a terminal status
+func (t *Task) IsFinished() bool {
+	switch t.Status {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// TruncateOutput shortens output to at most maxBytes, keeping the beginning
+// and appending a marker. maxBytes <= 0 disables truncation.
+func TruncateOutput(output string, maxBytes int) string {
+	if maxBytes <= 0 || len(output) <= maxBytes {
+		return output
+	}
+
+	cut := maxBytes
+	// Avoid splitting a multi-byte UTF-8 sequence
+	for cut > 0 && output[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return output[:cut] + truncatedMarker
+}
+
+// SetOutputLimit caps the output stored by SetTaskResult. 0 means unlimited.
+func (m *Manager) SetOutputLimit(maxBytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outputLimit = maxBytes
+}
+
+// PruneResult counts what Prune changes, or would change
+type PruneResult struct {
+	Removed   int // Finished tasks dropped over the per-session limit
+	Truncated int // Outputs of kept tasks cut to the size cap
+}
+
+// Prune applies the retention policy to the queue and persists the result.
+// It returns the number of tasks removed and outputs truncated.
+func (m *Manager) Prune(policy RetentionPolicy) (PruneResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	drop, truncate := m.pruneTargets(policy)
+	if len(drop) == 0 && len(truncate) == 0 {
+		return PruneResult{}, nil
+	}
+
+	for _, task := range truncate {
+		result := *task.Result
+		result.Output = TruncateOutput(result.Output, policy.MaxOutputBytes)
+		task.Result = &result
+	}
+
+	if len(drop) > 0 {
+		kept := m.tasks[:0:0]
+		for _, task := range m.tasks {
+			if _, ok := drop[task.ID]; ok {
+				continue
+			}
+			kept = append(kept, task)
+		}
+		m.tasks = kept
+	}
+
+	if err := m.store.Save(m.tasks); err != nil {
+		return PruneResult{}, fmt.Errorf("failed to save pruned queue: %w", err)
+	}
+	return PruneResult{Removed: len(drop), Truncated: len(truncate)}, nil
+}
+
+// CountPrunable returns what Prune would remove and truncate for the given
+// policy, without changing the queue
+func (m *Manager) CountPrunable(policy RetentionPolicy) PruneResult {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	drop, truncate := m.pruneTargets(policy)
+	return PruneResult{Removed: len(drop), Truncated: len(truncate)}
+}
+
+// pruneTargets returns the IDs of the finished tasks over the per-session
+// limit, newest kept, and the remaining tasks whose output is over the size
+// cap. The caller holds the lock.
+func (m *Manager) pruneTargets(policy RetentionPolicy) (map[string]struct{}, []*Task) {
+	drop := make(map[string]struct{})
+	if policy.MaxCompletedTasks > 0 {
+		// Collect finished tasks per session, newest first
+		finished := make(map[string][]*Task)
+		for _, task := range m.tasks {
+			if task.IsFinished() {
+				finished[task.SessionID] = append(finished[task.SessionID], task)
+			}
+		}
+
+		for _, tasks := range finished {
+			if len(tasks) <= policy.MaxCompletedTasks {
+				continue
+			}
+			sort.SliceStable(tasks, func(i, j int) bool {
+				return tasks[i].UpdatedAt.After(tasks[j].UpdatedAt)
+			})
+			for _, task := range tasks[policy.MaxCompletedTasks:] {
+				drop[task.ID] = struct{}{}
+			}
+		}
+	}
+
+	var truncate []*Task
+	if policy.MaxOutputBytes > 0 {
+		for _, task := range m.tasks {
+			if _, ok := drop[task.ID]; ok || task.Result == nil {
+				continue
+			}
+			if TruncateOutput(task.Result.Output, policy.MaxOutputBytes) != task.Result.Output {
+				truncate = append(truncate, task)
+			}
+		}
+	}
+	return drop, truncate
+}
+
+// HasActiveTasks reports whether any task is still waiting or running
+func (m *Manager) HasActiveTasks() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, task := range m.tasks {
+		if !task.IsFinished() {
+			return true
+		}
+	}
+	return false
+}
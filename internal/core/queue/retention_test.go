@@ -0,0 +1,122 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+)
+
+func TestTruncateOutput(t *testing.T) {
+	if got := TruncateOutput("short", 10); got != "short" {
+		t.Errorf("Expected output unchanged, got %q", got)
+	}
+	if got := TruncateOutput("long output", 0); got != "long output" {
+		t.Errorf("Expected limit 0 to disable truncation, got %q", got)
+	}
+
+	got := TruncateOutput("abcdefghij", 4)
+	if !strings.HasPrefix(got, "abcd") || !strings.HasSuffix(got, truncatedMarker) {
+		t.Errorf("Expected truncated output with marker, got %q", got)
+	}
+
+	// "中" is 3 bytes; cutting at 4 must not split the second rune
+	got = TruncateOutput("中文输出", 4)
+	if !strings.HasPrefix(got, "中\n") {
+		t.Errorf("Expected cut on rune boundary, got %q", got)
+	}
+}
+
+func TestQueue_SetTaskResultRespectsOutputLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	q := NewQueue(filepath.Join(tmpDir, "queue.json"), "session-123")
+	q.SetOutputLimit(8)
+
+	task, _ := q.AddTask(ai.Command{Cmd: "ls"}, &security.CheckResult{Allowed: true})
+	_ = q.ApproveTask(task.ID)
+	_ = q.MarkExecuting(task.ID)
+
+	if err := q.SetTaskResult(task.ID, &ExecutionResult{ExitCode: 0, Output: strings.Repeat("x", 100)}); err != nil {
+		t.Fatalf("SetTaskResult failed: %v", err)
+	}
+
+	got := findTask(q, task.ID)
+	if !strings.HasSuffix(got.Result.Output, truncatedMarker) {
+		t.Errorf("Expected stored output to be truncated, got %q", got.Result.Output)
+	}
+}
+
+func TestQueue_Prune(t *testing.T) {
+	tmpDir := t.TempDir()
+	queueFile := filepath.Join(tmpDir, "queue.json")
+	q := NewQueue(queueFile, "session-123")
+
+	base := time.Now().Add(-time.Hour)
+	q.tasks = []*Task{
+		{ID: "old", SessionID: "session-123", Status: TaskStatusCompleted, UpdatedAt: base},
+		{ID: "mid", SessionID: "session-123", Status: TaskStatusFailed, UpdatedAt: base.Add(time.Minute)},
+		{ID: "new", SessionID: "session-123", Status: TaskStatusRejected, UpdatedAt: base.Add(2 * time.Minute),
+			Result: &ExecutionResult{Output: strings.Repeat("y", 50)}},
+		{ID: "pending", SessionID: "session-123", Status: TaskStatusPending, UpdatedAt: base},
+	}
+
+	policy := RetentionPolicy{MaxCompletedTasks: 2, MaxOutputBytes: 10}
+	want := PruneResult{Removed: 1, Truncated: 1}
+	if got := q.CountPrunable(policy); got != want {
+		t.Errorf("Expected %+v prunable, got %+v", want, got)
+	}
+	result, err := q.Prune(policy)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if result != want {
+		t.Errorf("Expected %+v pruned, got %+v", want, result)
+	}
+
+	// Reload from disk to verify persistence
+	reloaded := NewQueue(queueFile, "session-123")
+	if findTask(reloaded, "old") != nil {
+		t.Error("Expected oldest finished task to be pruned")
+	}
+	if findTask(reloaded, "pending") == nil {
+		t.Error("Expected pending task to be kept")
+	}
+	task := findTask(reloaded, "new")
+	if task == nil {
+		t.Fatal("Expected newest finished task to be kept")
+	}
+	if !strings.HasSuffix(task.Result.Output, truncatedMarker) {
+		t.Errorf("Expected output to be truncated, got %q", task.Result.Output)
+	}
+	if !reloaded.HasActiveTasks() {
+		t.Error("Expected queue with pending task to report active tasks")
+	}
+}
+
+func TestQueue_PruneNoChangeDoesNotWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	queueFile := filepath.Join(tmpDir, "queue.json")
+	q := NewQueue(queueFile, "session-123")
+
+	result, err := q.Prune(RetentionPolicy{MaxCompletedTasks: 10})
+	if err != nil || result != (PruneResult{}) {
+		t.Fatalf("Expected no-op prune, got %+v, %v", result, err)
+	}
+	if _, err := os.Stat(queueFile); !os.IsNotExist(err) {
+		t.Error("Expected no queue file to be written")
+	}
+}
+
+// findTask returns the task with the given ID or nil
+func findTask(q *Manager, taskID string) *Task {
+	for _, task := range q.GetAllTasks() {
+		if task.ID == taskID {
+			return task
+		}
+	}
+	return nil
+}
@@ -4,7 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 )
 
 // QueueFile represents the persisted queue data
@@ -24,28 +25,40 @@ func NewStore(filePath string) *Store {
 
 // Save persists tasks to JSON file
 func (s *Store) Save(tasks []*Task) error {
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(s.filePath), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	queueFile := QueueFile{Tasks: tasks}
+	queueFile := QueueFile{Tasks: redactTasks(tasks)}
 
 	data, err := json.MarshalIndent(queueFile, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal queue: %w", err)
 	}
 
-	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+	if err := privacy.WritePrivate(s.filePath, data); err != nil {
 		return fmt.Errorf("failed to write queue file: %w", err)
 	}
 
 	return nil
 }
 
+// redactTasks returns copies of tasks with secrets in their output redacted.
+// Commands are not redacted, they must run exactly as they were approved.
+func redactTasks(tasks []*Task) []*Task {
+	redacted := make([]*Task, len(tasks))
+	for i, task := range tasks {
+		copied := *task
+		if task.Result != nil {
+			result := *task.Result
+			result.Output = privacy.Redact("queue", result.Output)
+			result.Error = privacy.Redact("queue", result.Error)
+			copied.Result = &result
+		}
+		redacted[i] = &copied
+	}
+	return redacted
+}
+
 // Load loads tasks from JSON file
 func (s *Store) Load() ([]*Task, error) {
-	data, err := os.ReadFile(s.filePath)
+	data, err := privacy.ReadFile(s.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// No queue file exists yet
@@ -1,12 +1,15 @@
 package queue
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 )
 
 func TestStore_SaveAndLoad(t *testing.T) {
@@ -97,3 +100,64 @@ func TestStore_InvalidJSON(t *testing.T) {
 		t.Error("Expected error for invalid JSON")
 	}
 }
+
+func TestStore_Encrypted(t *testing.T) {
+	redactor, _ := privacy.NewRedactor(nil)
+	privacy.SetDefault(redactor)
+	defer privacy.SetDefault(nil)
+	c, _ := privacy.NewCipher(bytes.Repeat([]byte{1}, 32))
+	privacy.SetCipher(c)
+	defer privacy.SetCipher(nil)
+
+	queueFile := filepath.Join(t.TempDir(), "queue.json")
+	store := NewStore(queueFile)
+
+	// Queued commands are kept exactly as approved, secrets included
+	secret := "sk-abcdefghijklmnopqrstuvwx"
+	cmd := ai.Command{Cmd: "curl", Args: []string{"-H", "Authorization: Bearer " + secret}}
+	task := NewTask("session-1", cmd, &security.CheckResult{Allowed: true})
+	if err := store.Save([]*Task{task}); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	raw, err := os.ReadFile(queueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !privacy.IsEncrypted(raw) {
+		t.Error("Expected the queue file to be encrypted")
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Command.Args[1] != "Authorization: Bearer "+secret {
+		t.Errorf("Expected the command unchanged, got %+v", loaded)
+	}
+}
+
+func TestStore_RedactsOutput(t *testing.T) {
+	redactor, _ := privacy.NewRedactor(nil)
+	privacy.SetDefault(redactor)
+	defer privacy.SetDefault(nil)
+
+	store := NewStore(filepath.Join(t.TempDir(), "queue.json"))
+	secret := "sk-abcdefghijklmnopqrstuvwx"
+	task := NewTask("session-1", ai.Command{Cmd: "env"}, &security.CheckResult{Allowed: true})
+	task.Result = &ExecutionResult{Output: "OPENAI_API_KEY=" + secret, Error: "token " + secret}
+	if err := store.Save([]*Task{task}); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if task.Result.Output != "OPENAI_API_KEY="+secret {
+		t.Error("Expected the task in memory to be left alone")
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if result := loaded[0].Result; strings.Contains(result.Output, secret) || strings.Contains(result.Error, secret) {
+		t.Errorf("Expected the output to be redacted, got %+v", result)
+	}
+}
@@ -29,7 +29,12 @@ type Task struct {
 	Status      TaskStatus            `json:"status"`
 	CreatedAt   time.Time             `json:"created_at"`
 	UpdatedAt   time.Time             `json:"updated_at"`
+	StartedAt   *time.Time            `json:"started_at,omitempty"`  // Set when execution starts
+	FinishedAt  *time.Time            `json:"finished_at,omitempty"` // Set when execution completes or fails
 	Result      *ExecutionResult      `json:"result,omitempty"`
+
+	RequiredApprovals int        `json:"required_approvals,omitempty"` // Approvals needed before execution, 0 means one
+	Approvals         []Approval `json:"approvals,omitempty"`          // Who approved the task
 }
 
 // ExecutionResult holds the result of a command execution
@@ -79,11 +84,31 @@ func (t *Task) TransitionStatus(newStatus TaskStatus) bool {
 	if !t.CanTransitionTo(newStatus) {
 		return false
 	}
+	now := time.Now()
 	t.Status = newStatus
-	t.UpdatedAt = time.Now()
+	t.UpdatedAt = now
+
+	switch newStatus {
+	case TaskStatusExecuting:
+		t.StartedAt = &now
+	case TaskStatusCompleted, TaskStatusFailed:
+		t.FinishedAt = &now
+	}
 	return true
 }
 
+// Elapsed returns how long the task has been running, or how long it ran
+// if it has finished. It returns zero if execution has not started.
+func (t *Task) Elapsed(now time.Time) time.Duration {
+	if t.StartedAt == nil {
+		return 0
+	}
+	if t.FinishedAt != nil {
+		return t.FinishedAt.Sub(*t.StartedAt)
+	}
+	return now.Sub(*t.StartedAt)
+}
+
 // SetResult records the execution result
 func (t *Task) SetResult(result *ExecutionResult) {
 	t.Result = result
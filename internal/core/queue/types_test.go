@@ -2,6 +2,7 @@ package queue
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
@@ -91,3 +92,28 @@ func TestExecutionResult(t *testing.T) {
 		t.Error("Expected output 'success'")
 	}
 }
+
+func TestTask_TransitionStatus_RecordsTimes(t *testing.T) {
+	task := NewTask("session-123", ai.Command{Cmd: "ls"}, &security.CheckResult{Allowed: true})
+
+	task.TransitionStatus(TaskStatusApproved)
+	if task.StartedAt != nil {
+		t.Error("Expected StartedAt to be unset before execution")
+	}
+	if task.Elapsed(time.Now()) != 0 {
+		t.Error("Expected zero elapsed time before execution")
+	}
+
+	task.TransitionStatus(TaskStatusExecuting)
+	if task.StartedAt == nil {
+		t.Fatal("Expected StartedAt to be set when executing")
+	}
+
+	task.TransitionStatus(TaskStatusCompleted)
+	if task.FinishedAt == nil {
+		t.Fatal("Expected FinishedAt to be set when completed")
+	}
+	if task.Elapsed(time.Now().Add(time.Hour)) != task.FinishedAt.Sub(*task.StartedAt) {
+		t.Error("Expected elapsed time of a finished task to be fixed")
+	}
+}
@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+)
+
+// timeLayout is the timestamp format used in the detail pane
+const timeLayout = "2006-01-02 15:04:05"
+
+// detailLines builds the content of the detail pane for a task, one entry per line.
+// liveOutput is shown while the task is still running.
+func detailLines(task *queue.Task, liveOutput string, now time.Time) []string {
+	if task == nil {
+		return []string{"没有选中的任务"}
+	}
+
+	lines := []string{
+		fmt.Sprintf("任务: %s", task.ID),
+		fmt.Sprintf("会话: %s", task.SessionID),
+		fmt.Sprintf("命令: %s", commandString(task)),
+		fmt.Sprintf("状态: %s %s", getStatusIndicator(task.Status), task.Status),
+		fmt.Sprintf("创建: %s", task.CreatedAt.Format(timeLayout)),
+		fmt.Sprintf("更新: %s", task.UpdatedAt.Format(timeLayout)),
+	}
+
+	if task.StartedAt != nil {
+		lines = append(lines, fmt.Sprintf("开始: %s", task.StartedAt.Format(timeLayout)))
+	}
+	if task.FinishedAt != nil {
+		lines = append(lines, fmt.Sprintf("结束: %s", task.FinishedAt.Format(timeLayout)))
+	}
+	if task.StartedAt != nil {
+		lines = append(lines, fmt.Sprintf("耗时: %s", formatElapsed(task.Elapsed(now))))
+	}
+
+	if task.RequiredApprovals > 1 {
+		got, need := task.ApprovalProgress()
+		lines = append(lines, fmt.Sprintf("批准: %d/%d", got, need))
+	}
+	for _, approval := range task.Approvals {
+		line := fmt.Sprintf("  ✓ %s %s", approval.Approver, approval.Time.Format(timeLayout))
+		if approval.Reason != "" {
+			line += " (" + approval.Reason + ")"
+		}
+		lines = append(lines, line)
+	}
+
+	if task.CheckResult != nil {
+		if task.CheckResult.Warning != "" {
+			lines = append(lines, "警告: "+task.CheckResult.Warning)
+		}
+		if task.CheckResult.Reason != "" {
+			lines = append(lines, "原因: "+task.CheckResult.Reason)
+		}
+	}
+
+	if task.Result == nil {
+		if liveOutput != "" {
+			lines = append(lines, "", "实时输出:")
+			for _, line := range strings.Split(strings.TrimRight(liveOutput, "\n"), "\n") {
+				lines = append(lines, "  "+line)
+			}
+			return lines
+		}
+		lines = append(lines, "", subtleStyle.Render("尚无执行结果"))
+		return lines
+	}
+
+	lines = append(lines, fmt.Sprintf("退出码: %d", task.Result.ExitCode))
+	if task.Result.Error != "" {
+		lines = append(lines, "错误: "+task.Result.Error)
+	}
+
+	lines = append(lines, "", "输出:")
+	if task.Result.Output == "" {
+		lines = append(lines, subtleStyle.Render("  (无输出)"))
+	} else {
+		for _, line := range strings.Split(task.Result.Output, "\n") {
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	return lines
+}
+
+// formatElapsed formats a duration for display, rounded to the second
+func formatElapsed(d time.Duration) string {
+	if d < time.Second {
+		return "<1s"
+	}
+	return d.Round(time.Second).String()
+}
+
+// tailLines returns the last n lines of the output
+func tailLines(output string, n int) []string {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return nil
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,147 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+)
+
+// statusFilter selects which task statuses are visible in the list
+type statusFilter int
+
+const (
+	filterActive    statusFilter = iota // pending, approved and executing tasks
+	filterCompleted                     // completed tasks
+	filterFailed                        // failed tasks
+	filterRejected                      // rejected tasks
+	filterAll                           // every task
+)
+
+// statusFilters lists the filters in tab order
+var statusFilters = []statusFilter{filterActive, filterCompleted, filterFailed, filterRejected, filterAll}
+
+// Label returns the tab label for the filter
+func (f statusFilter) Label() string {
+	switch f {
+	case filterActive:
+		return "待处理"
+	case filterCompleted:
+		return "已完成"
+	case filterFailed:
+		return "失败"
+	case filterRejected:
+		return "已拒绝"
+	case filterAll:
+		return "全部"
+	default:
+		return "?"
+	}
+}
+
+// Matches reports whether a task status passes the filter
+func (f statusFilter) Matches(status queue.TaskStatus) bool {
+	switch f {
+	case filterActive:
+		return status == queue.TaskStatusPending ||
+			status == queue.TaskStatusApproved ||
+			status == queue.TaskStatusExecuting
+	case filterCompleted:
+		return status == queue.TaskStatusCompleted
+	case filterFailed:
+		return status == queue.TaskStatusFailed
+	case filterRejected:
+		return status == queue.TaskStatusRejected
+	case filterAll:
+		return true
+	default:
+		return false
+	}
+}
+
+// next returns the filter after f in tab order
+func (f statusFilter) next() statusFilter {
+	for i, sf := range statusFilters {
+		if sf == f {
+			return statusFilters[(i+1)%len(statusFilters)]
+		}
+	}
+	return filterActive
+}
+
+// taskFilter combines the status tab, session and search query
+type taskFilter struct {
+	status  statusFilter
+	session string // empty means all sessions
+	query   string // case-insensitive substring
+}
+
+// Matches reports whether the task passes all filter criteria
+func (f taskFilter) Matches(task *queue.Task) bool {
+	if !f.status.Matches(task.Status) {
+		return false
+	}
+	if f.session != "" && task.SessionID != f.session {
+		return false
+	}
+	if f.query == "" {
+		return true
+	}
+
+	query := strings.ToLower(f.query)
+	fields := []string{task.ID, task.SessionID, commandString(task)}
+	if task.Result != nil {
+		fields = append(fields, task.Result.Output, task.Result.Error)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTasks returns the tasks matching the filter, grouped by session
+// (newest session first) and ordered by creation time within a session
+func filterTasks(tasks []*queue.Task, f taskFilter) []*queue.Task {
+	var result []*queue.Task
+	for _, task := range tasks {
+		if f.Matches(task) {
+			result = append(result, task)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].SessionID != result[j].SessionID {
+			// Session IDs are timestamp based, so reverse lexical order is newest first
+			return result[i].SessionID > result[j].SessionID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result
+}
+
+// sessionIDs returns the distinct session IDs of the tasks, newest first
+func sessionIDs(tasks []*queue.Task) []string {
+	seen := make(map[string]struct{})
+	var ids []string
+	for _, task := range tasks {
+		if _, ok := seen[task.SessionID]; ok {
+			continue
+		}
+		seen[task.SessionID] = struct{}{}
+		ids = append(ids, task.SessionID)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids
+}
+
+// commandString joins the command and its arguments
+func commandString(task *queue.Task) string {
+	cmdStr := task.Command.Cmd
+	if len(task.Command.Args) > 0 {
+		cmdStr += " " + strings.Join(task.Command.Args, " ")
+	}
+	return cmdStr
+}
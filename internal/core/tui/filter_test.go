@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
+)
+
+func TestStatusFilter_Matches(t *testing.T) {
+	tests := []struct {
+		filter   statusFilter
+		status   queue.TaskStatus
+		expected bool
+	}{
+		{filterActive, queue.TaskStatusPending, true},
+		{filterActive, queue.TaskStatusExecuting, true},
+		{filterActive, queue.TaskStatusCompleted, false},
+		{filterCompleted, queue.TaskStatusCompleted, true},
+		{filterFailed, queue.TaskStatusFailed, true},
+		{filterFailed, queue.TaskStatusCompleted, false},
+		{filterRejected, queue.TaskStatusRejected, true},
+		{filterAll, queue.TaskStatusRejected, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Matches(tt.status); got != tt.expected {
+			t.Errorf("%s.Matches(%s): expected %v, got %v", tt.filter.Label(), tt.status, tt.expected, got)
+		}
+	}
+}
+
+func TestStatusFilter_NextWraps(t *testing.T) {
+	f := filterActive
+	for range statusFilters {
+		f = f.next()
+	}
+	if f != filterActive {
+		t.Errorf("Expected filter to wrap back to active, got %s", f.Label())
+	}
+}
+
+func TestTaskFilter_Query(t *testing.T) {
+	task := &queue.Task{
+		ID:        "abc",
+		SessionID: "s1",
+		Command:   ai.Command{Cmd: "ls", Args: []string{"-la", "/var/log"}},
+		Status:    queue.TaskStatusCompleted,
+		Result:    &queue.ExecutionResult{Output: "nginx.log"},
+	}
+
+	f := taskFilter{status: filterAll, query: "VAR/LOG"}
+	if !f.Matches(task) {
+		t.Error("Expected case-insensitive match on command args")
+	}
+
+	f.query = "nginx"
+	if !f.Matches(task) {
+		t.Error("Expected match on output")
+	}
+
+	f.query = "missing"
+	if f.Matches(task) {
+		t.Error("Expected no match")
+	}
+
+	f = taskFilter{status: filterAll, session: "s2"}
+	if f.Matches(task) {
+		t.Error("Expected session filter to exclude task")
+	}
+}
+
+func TestFilterTasks_Order(t *testing.T) {
+	now := time.Now()
+	tasks := []*queue.Task{
+		{ID: "old-1", SessionID: "2025-01-01", Status: queue.TaskStatusPending, CreatedAt: now},
+		{ID: "new-2", SessionID: "2025-02-01", Status: queue.TaskStatusPending, CreatedAt: now.Add(time.Second)},
+		{ID: "new-1", SessionID: "2025-02-01", Status: queue.TaskStatusPending, CreatedAt: now},
+	}
+
+	result := filterTasks(tasks, taskFilter{status: filterActive})
+	expected := []string{"new-1", "new-2", "old-1"}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d tasks, got %d", len(expected), len(result))
+	}
+	for i, id := range expected {
+		if result[i].ID != id {
+			t.Errorf("Position %d: expected %s, got %s", i, id, result[i].ID)
+		}
+	}
+
+	ids := sessionIDs(tasks)
+	if len(ids) != 2 || ids[0] != "2025-02-01" {
+		t.Errorf("Expected newest session first, got %v", ids)
+	}
+}
@@ -15,6 +15,10 @@ type keyMap struct {
 	AuthorizeAll key
 	RejectAll    key
 	Enter        key
+	NextFilter   key
+	NextSession  key
+	Search       key
+	ShowHelp     key
 	Quit         key
 	ForceQuit    key
 }
@@ -36,7 +40,8 @@ func (k keyMap) fullHelp() []key {
 		k.Up, k.Down, k.Top, k.Bottom,
 		k.Authorize, k.Reject,
 		k.AuthorizeAll, k.RejectAll,
-		k.Enter, k.Quit, k.ForceQuit,
+		k.Enter, k.NextFilter, k.NextSession, k.Search,
+		k.ShowHelp, k.Quit, k.ForceQuit,
 	}
 }
 
@@ -55,7 +60,7 @@ type helpWrapper struct {
 // String returns the full help text with descriptions
 func (h helpWrapper) String() string {
 	// Return descriptive help text for testing
-	return "↑/k:上 ↓/j:下 gg:首 G:尾 a:授权执行 r:拒绝 A:全部授权 R:全部拒绝 q:退出"
+	return "↑/k:上 ↓/j:下 gg:首 G:尾 Enter:详情 Tab:筛选 s:会话 /:搜索 a:授权执行 r:拒绝 A:全部授权 R:全部拒绝 ?:帮助 q:退出"
 }
 
 // View returns the help view with keys and actions
@@ -66,10 +71,15 @@ func (h helpWrapper) View(subtle string) string {
 		"↓/j:下",
 		"gg:首",
 		"G:尾",
+		"Enter:详情",
+		"Tab:筛选",
+		"s:会话",
+		"/:搜索",
 		"a:执行",
 		"r:拒绝",
 		"A:全执行",
 		"R:全拒绝",
+		"?:帮助",
 		"q:退出",
 	}
 
@@ -119,6 +129,22 @@ func defaultKeyMap() keyMap {
 			Key:  tea.Key{Type: tea.KeyEnter},
 			help: "Enter",
 		},
+		NextFilter: key{
+			Key:  tea.Key{Type: tea.KeyTab},
+			help: "Tab",
+		},
+		NextSession: key{
+			Key:  tea.Key{Type: tea.KeyRunes, Runes: []rune{'s'}},
+			help: "s",
+		},
+		Search: key{
+			Key:  tea.Key{Type: tea.KeyRunes, Runes: []rune{'/'}},
+			help: "/",
+		},
+		ShowHelp: key{
+			Key:  tea.Key{Type: tea.KeyRunes, Runes: []rune{'?'}},
+			help: "?",
+		},
 		Quit: key{
 			Key:  tea.Key{Type: tea.KeyRunes, Runes: []rune{'q'}},
 			help: "q",
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
 	tea "github.com/charmbracelet/bubbletea"
@@ -13,17 +14,30 @@ import (
 // TaskReloadFunc is a callback to reload a task from the queue
 type TaskReloadFunc func(taskID string) *queue.Task
 
+// liveOutputLimit caps the live output kept per running task, in bytes
+const liveOutputLimit = 8 * 1024
+
+// liveTailLines is the number of live output lines shown under a running task
+const liveTailLines = 3
+
 // model is the Bubble Tea model for the queue TUI
 type model struct {
 	tasks          []*queue.Task
-	cursor         int
+	cursor         int // Index into visibleTasks()
 	selected       map[string]struct{}
 	keys           keyMap
 	showingHelp    bool
+	showingDetail  bool // Detail pane for the task under the cursor
+	detailOffset   int  // First visible line of the detail pane
+	searching      bool // Search box has focus
+	filter         taskFilter
 	onAuthorize    func(string) tea.Cmd
 	onReject       func(string) tea.Cmd
 	taskReloadFunc TaskReloadFunc
-	pendingG       bool // Tracks if 'g' was pressed for 'gg' command
+	events         <-chan queue.Event
+	liveOutput     map[string]string // Output received so far for running tasks
+	ticking        bool              // Elapsed-time ticker is active
+	pendingG       bool              // Tracks if 'g' was pressed for 'gg' command
 	width          int
 	height         int
 }
@@ -35,6 +49,12 @@ func NewModel(tasks []*queue.Task) Model {
 
 // NewModelWithOptions creates a new queue UI model with custom authorize/reject handlers
 func NewModelWithOptions(tasks []*queue.Task, onAuthorize, onReject func(string) tea.Cmd, taskReloadFunc TaskReloadFunc) Model {
+	return NewModelWithEvents(tasks, onAuthorize, onReject, taskReloadFunc, nil)
+}
+
+// NewModelWithEvents creates a queue UI model that follows task progress through
+// a queue event subscription instead of polling
+func NewModelWithEvents(tasks []*queue.Task, onAuthorize, onReject func(string) tea.Cmd, taskReloadFunc TaskReloadFunc, events <-chan queue.Event) Model {
 	if onAuthorize == nil {
 		onAuthorize = defaultAuthorizeHandler
 	}
@@ -48,9 +68,12 @@ func NewModelWithOptions(tasks []*queue.Task, onAuthorize, onReject func(string)
 		selected:       make(map[string]struct{}),
 		keys:           defaultKeyMap(),
 		showingHelp:    false,
+		filter:         taskFilter{status: filterActive},
 		onAuthorize:    onAuthorize,
 		onReject:       onReject,
 		taskReloadFunc: taskReloadFunc,
+		events:         events,
+		liveOutput:     make(map[string]string),
 	}
 }
 
@@ -70,9 +93,50 @@ func defaultRejectHandler(taskID string) tea.Cmd {
 func (m model) Init() tea.Cmd {
 	return tea.Batch(
 		tea.WindowSize(),
+		waitForEvent(m.events),
 	)
 }
 
+// waitForEvent returns a command that delivers the next queue event
+func waitForEvent(events <-chan queue.Event) tea.Cmd {
+	if events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		event, ok := <-events
+		if !ok {
+			return nil
+		}
+		return TaskEventMsg{Event: event}
+	}
+}
+
+// tick schedules the next elapsed-time refresh
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return TickMsg{}
+	})
+}
+
+// startTicking starts the elapsed-time ticker if it is not already running
+func (m *model) startTicking() tea.Cmd {
+	if m.ticking {
+		return nil
+	}
+	m.ticking = true
+	return tick()
+}
+
+// hasExecuting reports whether any task is currently executing
+func (m model) hasExecuting() bool {
+	for _, task := range m.tasks {
+		if task.Status == queue.TaskStatusExecuting {
+			return true
+		}
+	}
+	return false
+}
+
 // Update handles messages
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -84,50 +148,45 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleKeyMsg(msg)
 
 	case AuthorizeResultMsg:
+		if msg.Success && msg.AwaitingApprovals {
+			// The queue event carries the updated approval count
+			return m, nil
+		}
 		if msg.Success {
-			// Update task status to executing (will be updated by background executor)
+			// Show the task as executing until the queue reports otherwise; events
+			// for fast tasks may already have moved it past executing
 			for i, task := range m.tasks {
 				if task.ID == msg.TaskID {
-					// Create a copy with updated status
-					updatedTask := *task
-					updatedTask.Status = queue.TaskStatusExecuting
-					m.tasks[i] = &updatedTask
-
-					// Start a ticker to check for status updates
-					return m, tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
-						return StatusCheckMsg{TaskID: msg.TaskID}
-					})
+					if task.Status == queue.TaskStatusPending || task.Status == queue.TaskStatusApproved {
+						updatedTask := *task
+						updatedTask.Status = queue.TaskStatusExecuting
+						m.tasks[i] = &updatedTask
+					}
+					return m, m.startTicking()
 				}
 			}
 		}
 		return m, nil
 
-	case StatusCheckMsg:
-		// Reload task from queue to get fresh status
-		if m.taskReloadFunc != nil {
-			if freshTask := m.taskReloadFunc(msg.TaskID); freshTask != nil {
-				// Update the task in our list with fresh data
-				for i, task := range m.tasks {
-					if task.ID == msg.TaskID {
-						m.tasks[i] = freshTask
-						// If still executing, schedule another check
-						if freshTask.Status == queue.TaskStatusExecuting {
-							return m, tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
-								return StatusCheckMsg{TaskID: msg.TaskID}
-							})
-						}
-						break
-					}
-				}
-			}
+	case TaskEventMsg:
+		cmd := m.applyEvent(msg.Event)
+		return m, tea.Batch(cmd, waitForEvent(m.events))
+
+	case TickMsg:
+		if !m.hasExecuting() {
+			m.ticking = false
+			return m, nil
 		}
-		return m, nil
+		return m, tick()
 
 	case RejectResultMsg:
 		if msg.Success {
+			// Replace the task instead of changing it, it may be shared with the caller
 			for i, task := range m.tasks {
 				if task.ID == msg.TaskID {
-					m.tasks[i].Status = queue.TaskStatusRejected
+					updatedTask := *task
+					updatedTask.Status = queue.TaskStatusRejected
+					m.tasks[i] = &updatedTask
 					break
 				}
 			}
@@ -138,19 +197,84 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// applyEvent updates the model from a queue event
+func (m *model) applyEvent(event queue.Event) tea.Cmd {
+	if event.Type == queue.EventTaskOutput {
+		out := m.liveOutput[event.TaskID] + event.Chunk
+		if len(out) > liveOutputLimit {
+			start := len(out) - liveOutputLimit
+			// Don't start in the middle of a multi-byte character
+			for i := 0; i < utf8.UTFMax && start < len(out) && !utf8.RuneStart(out[start]); i++ {
+				start++
+			}
+			out = out[start:]
+		}
+		m.liveOutput[event.TaskID] = out
+		return nil
+	}
+
+	fresh := event.Task
+	if fresh == nil && m.taskReloadFunc != nil {
+		fresh = m.taskReloadFunc(event.TaskID)
+	}
+	if fresh == nil {
+		return nil
+	}
+
+	found := false
+	for i, task := range m.tasks {
+		if task.ID == fresh.ID {
+			m.tasks[i] = fresh
+			found = true
+			break
+		}
+	}
+	if !found {
+		m.tasks = append(m.tasks, fresh)
+	}
+
+	switch fresh.Status {
+	case queue.TaskStatusExecuting:
+		return m.startTicking()
+	case queue.TaskStatusCompleted, queue.TaskStatusFailed:
+		// The persisted result now holds the full output
+		delete(m.liveOutput, fresh.ID)
+	}
+	return nil
+}
+
 func (m model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// Handle quit
-	if msg.String() == "q" || msg.String() == "ctrl+c" || msg.Type == tea.KeyEsc {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	if m.searching {
+		return m.handleSearchKey(msg)
+	}
+
+	if m.showingDetail {
+		return m.handleDetailKey(msg)
+	}
+
+	// Handle quit; Esc clears an active search before quitting
+	if msg.Type == tea.KeyEsc && m.filter.query != "" {
+		m.filter.query = ""
+		m.clampCursor()
+		return m, nil
+	}
+	if msg.String() == "q" || msg.Type == tea.KeyEsc {
 		return m, tea.Quit
 	}
 
 	// Handle help toggle
-	if msg.Type == tea.KeyEnter {
+	if msg.String() == "?" {
 		m.showingHelp = !m.showingHelp
 		return m, nil
 	}
 
-	// Handle navigation
+	visible := m.visibleTasks()
+
+	// Handle navigation and view switching
 	switch msg.String() {
 	case "k", "up":
 		m.pendingG = false
@@ -159,7 +283,7 @@ func (m model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	case "j", "down":
 		m.pendingG = false
-		if m.cursor < len(m.tasks)-1 {
+		if m.cursor < len(visible)-1 {
 			m.cursor++
 		}
 	case "g":
@@ -173,28 +297,56 @@ func (m model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "G":
 		m.pendingG = false
 		// Go to bottom
-		if len(m.tasks) > 0 {
-			m.cursor = len(m.tasks) - 1
+		if len(visible) > 0 {
+			m.cursor = len(visible) - 1
+		}
+	case "enter":
+		m.pendingG = false
+		if len(visible) > 0 {
+			m.showingDetail = true
+			m.detailOffset = 0
 		}
+		return m, nil
+	case "tab":
+		m.pendingG = false
+		m.filter.status = m.filter.status.next()
+		m.clampCursor()
+		return m, nil
+	case "1", "2", "3", "4", "5":
+		m.pendingG = false
+		m.filter.status = statusFilters[int(msg.Runes[0]-'1')]
+		m.clampCursor()
+		return m, nil
+	case "s":
+		m.pendingG = false
+		m.filter.session = m.nextSession()
+		m.clampCursor()
+		return m, nil
+	case "/":
+		m.pendingG = false
+		m.searching = true
+		return m, nil
 	default:
 		m.pendingG = false
 	}
 
-	// Handle actions
+	return m.handleActionKey(msg, visible)
+}
+
+// handleActionKey handles authorize/reject keys against the visible tasks
+func (m model) handleActionKey(msg tea.KeyMsg, visible []*queue.Task) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "a":
-		if len(m.tasks) > 0 {
-			task := m.tasks[m.cursor]
+		if task := m.selectedTask(); task != nil {
 			return m, m.onAuthorize(task.ID)
 		}
 	case "r":
-		if len(m.tasks) > 0 {
-			task := m.tasks[m.cursor]
+		if task := m.selectedTask(); task != nil {
 			return m, m.onReject(task.ID)
 		}
 	case "A":
 		var cmds []tea.Cmd
-		for _, task := range m.tasks {
+		for _, task := range visible {
 			if task.Status == queue.TaskStatusPending {
 				cmds = append(cmds, m.onAuthorize(task.ID))
 			}
@@ -204,7 +356,7 @@ func (m model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	case "R":
 		var cmds []tea.Cmd
-		for _, task := range m.tasks {
+		for _, task := range visible {
 			if task.Status == queue.TaskStatusPending {
 				cmds = append(cmds, m.onReject(task.ID))
 			}
@@ -217,11 +369,138 @@ func (m model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleSearchKey edits the search query while the search box has focus
+func (m model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.searching = false
+	case tea.KeyEsc:
+		m.searching = false
+		m.filter.query = ""
+	case tea.KeyBackspace:
+		if runes := []rune(m.filter.query); len(runes) > 0 {
+			m.filter.query = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		m.filter.query += " "
+	case tea.KeyRunes:
+		m.filter.query += string(msg.Runes)
+	}
+	m.clampCursor()
+	return m, nil
+}
+
+// handleDetailKey scrolls or closes the detail pane
+func (m model) handleDetailKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	maxOffset := len(m.selectedDetailLines()) - m.detailHeight()
+	if maxOffset < 0 {
+		maxOffset = 0
+	}
+
+	switch msg.String() {
+	case "q", "esc", "enter", "h":
+		m.showingDetail = false
+		m.detailOffset = 0
+	case "j", "down":
+		if m.detailOffset < maxOffset {
+			m.detailOffset++
+		}
+	case "k", "up":
+		if m.detailOffset > 0 {
+			m.detailOffset--
+		}
+	case "ctrl+d", "pgdown":
+		m.detailOffset += m.detailHeight() / 2
+		if m.detailOffset > maxOffset {
+			m.detailOffset = maxOffset
+		}
+	case "ctrl+u", "pgup":
+		m.detailOffset -= m.detailHeight() / 2
+		if m.detailOffset < 0 {
+			m.detailOffset = 0
+		}
+	case "g":
+		m.detailOffset = 0
+	case "G":
+		m.detailOffset = maxOffset
+	case "a", "r":
+		return m.handleActionKey(msg, m.visibleTasks())
+	}
+	return m, nil
+}
+
+// visibleTasks returns the tasks that pass the current filter, in display order
+func (m model) visibleTasks() []*queue.Task {
+	return filterTasks(m.tasks, m.filter)
+}
+
+// selectedTask returns the task under the cursor, or nil if none is visible
+func (m model) selectedTask() *queue.Task {
+	visible := m.visibleTasks()
+	if m.cursor < 0 || m.cursor >= len(visible) {
+		return nil
+	}
+	return visible[m.cursor]
+}
+
+// selectedDetailLines returns the detail pane content for the selected task
+func (m model) selectedDetailLines() []string {
+	task := m.selectedTask()
+	if task == nil {
+		return detailLines(nil, "", time.Now())
+	}
+	return detailLines(task, m.liveOutput[task.ID], time.Now())
+}
+
+// clampCursor keeps the cursor inside the visible task list
+func (m *model) clampCursor() {
+	n := len(m.visibleTasks())
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// nextSession returns the session filter after the current one ("" means all)
+func (m model) nextSession() string {
+	ids := sessionIDs(m.tasks)
+	if m.filter.session == "" {
+		if len(ids) == 0 {
+			return ""
+		}
+		return ids[0]
+	}
+	for i, id := range ids {
+		if id == m.filter.session && i+1 < len(ids) {
+			return ids[i+1]
+		}
+	}
+	return ""
+}
+
+// detailHeight returns the number of detail lines that fit on screen
+func (m model) detailHeight() int {
+	if m.height <= 0 {
+		return 20
+	}
+	// Title, blank line and footer status bar
+	h := m.height - 2 - countLines(m.renderFooter())
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
 // View renders the UI
 func (m model) View() string {
 	if m.showingHelp {
 		return m.renderHelp()
 	}
+	if m.showingDetail {
+		return m.renderDetail()
+	}
 	return m.renderQueue()
 }
 
@@ -229,68 +508,90 @@ func (m model) renderQueue() string {
 	var s string
 
 	// Header
-	s += titleStyle.Render(" tada 任务队列 ") + "\n\n"
+	s += titleStyle.Render(" tada 任务队列 ") + "\n"
+	s += m.renderFilterBar() + "\n\n"
 
 	// Content area
-	var content string
-	if len(m.tasks) == 0 {
-		content = subtleStyle.Render("没有待授权任务")
+	var lines []string
+	cursorLine := 0
+	visible := m.visibleTasks()
+	if len(visible) == 0 {
+		lines = append(lines, subtleStyle.Render("没有匹配的任务"))
 	} else {
-		// Group by session
-		grouped := m.groupTasksBySession()
-
-		for sessionID, tasks := range grouped {
-			content += fmt.Sprintf(" 会话: %s \n", sessionID)
-
-			for _, task := range tasks {
-				cursor := " "
-				if m.getCursorForTask(task.ID) == m.cursor {
-					cursor = ">"
+		currentSession := ""
+		for i, task := range visible {
+			if i == 0 || task.SessionID != currentSession {
+				if i > 0 {
+					lines = append(lines, "")
 				}
+				currentSession = task.SessionID
+				lines = append(lines, fmt.Sprintf(" 会话: %s ", currentSession))
+			}
 
-				// Status indicator
-				status := getStatusIndicator(task.Status)
+			cursor := " "
+			if i == m.cursor {
+				cursor = ">"
+				cursorLine = len(lines)
+			}
 
-				// Command string
-				cmdStr := task.Command.Cmd
-				if len(task.Command.Args) > 0 {
-					cmdStr += " " + strings.Join(task.Command.Args, " ")
-				}
+			// Status indicator
+			status := getStatusIndicator(task.Status)
 
-				// Truncate if too long
-				if len(cmdStr) > 50 {
-					cmdStr = cmdStr[:47] + "..."
-				}
+			// Command string, truncated if too long
+			cmdStr := commandString(task)
+			if len(cmdStr) > 50 {
+				cmdStr = cmdStr[:47] + "..."
+			}
+
+			line := fmt.Sprintf("%s [%s] %s", cursor, status, cmdStr)
+			if task.Status == queue.TaskStatusPending && task.RequiredApprovals > 1 {
+				got, need := task.ApprovalProgress()
+				line += subtleStyle.Render(fmt.Sprintf(" 批准 %d/%d", got, need))
+			}
+			if task.StartedAt != nil {
+				line += subtleStyle.Render(" " + formatElapsed(task.Elapsed(time.Now())))
+			}
+			lines = append(lines, line)
 
-				content += fmt.Sprintf("%s [%s] %s\n", cursor, status, cmdStr)
+			if task.CheckResult != nil && task.CheckResult.Warning != "" {
+				lines = append(lines, subtleStyle.Render("     警告: "+task.CheckResult.Warning))
+			}
 
-				if task.CheckResult != nil && task.CheckResult.Warning != "" {
-					content += subtleStyle.Render("     警告: "+task.CheckResult.Warning) + "\n"
+			if task.Status == queue.TaskStatusExecuting {
+				for _, tail := range tailLines(m.liveOutput[task.ID], liveTailLines) {
+					lines = append(lines, subtleStyle.Render("     │ "+tail))
 				}
 			}
-			content += "\n"
 		}
 	}
 
 	// Get footer
 	footer := m.renderFooter()
 
-	// Calculate lines to push footer to bottom
-	// Count lines in header (2), content, and footer
-	headerLines := 2
-	contentLines := countLines(content)
+	// Keep the cursor on screen when the list is taller than the window
+	headerLines := 3
 	footerLines := countLines(footer)
+	if m.height > 0 {
+		available := m.height - headerLines - footerLines
+		if available > 0 && len(lines) > available {
+			start := cursorLine - available/2
+			if start < 0 {
+				start = 0
+			}
+			if start+available > len(lines) {
+				start = len(lines) - available
+			}
+			lines = lines[start : start+available]
+		}
+	}
 
-	totalLines := headerLines + contentLines + footerLines
+	content := strings.Join(lines, "\n") + "\n"
 
 	// If we have window height, add padding to push footer to bottom
 	if m.height > 0 {
-		paddingNeeded := m.height - totalLines
+		paddingNeeded := m.height - headerLines - countLines(content) - footerLines
 		if paddingNeeded > 0 {
-			// Add empty lines to push footer to bottom
-			for i := 0; i < paddingNeeded; i++ {
-				content += "\n"
-			}
+			content += strings.Repeat("\n", paddingNeeded)
 		}
 	}
 
@@ -300,6 +601,64 @@ func (m model) renderQueue() string {
 	return s
 }
 
+// renderFilterBar renders the status tabs, session filter and search box
+func (m model) renderFilterBar() string {
+	var tabs []string
+	for i, f := range statusFilters {
+		label := fmt.Sprintf("%d:%s", i+1, f.Label())
+		if f == m.filter.status {
+			tabs = append(tabs, activeTabStyle.Render(label))
+		} else {
+			tabs = append(tabs, subtleStyle.Render(label))
+		}
+	}
+
+	session := "全部"
+	if m.filter.session != "" {
+		session = m.filter.session
+	}
+
+	bar := " " + strings.Join(tabs, " ") + subtleStyle.Render("  会话: "+session)
+
+	if m.searching || m.filter.query != "" {
+		search := "  搜索: " + m.filter.query
+		if m.searching {
+			search += "▏"
+		}
+		bar += search
+	}
+
+	return bar
+}
+
+// renderDetail renders the scrollable detail pane for the selected task
+func (m model) renderDetail() string {
+	var s string
+	s += titleStyle.Render(" 任务详情 ") + "\n\n"
+
+	lines := m.selectedDetailLines()
+	height := m.detailHeight()
+	start := m.detailOffset
+	if start > len(lines) {
+		start = len(lines)
+	}
+	end := start + height
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	content := strings.Join(lines[start:end], "\n") + "\n"
+	if m.height > 0 {
+		paddingNeeded := height - (end - start)
+		if paddingNeeded > 0 {
+			content += strings.Repeat("\n", paddingNeeded)
+		}
+	}
+
+	footer := "\n" + statusBarStyle.Render(" ↑/k ↓/j:滚动 ^u/^d:翻页 a:执行 r:拒绝 Enter/q:返回") + "\n"
+	return s + content + footer
+}
+
 // countLines counts the number of lines in a string
 func countLines(s string) int {
 	if s == "" {
@@ -320,7 +679,17 @@ func countLines(s string) int {
 
 func (m model) renderHelp() string {
 	return helpStyle.Render(`
-按 q 退出程序
+按键说明:
+  ↑/k ↓/j      上下移动
+  gg / G       跳到首/尾
+  Enter        查看任务详情（输出、错误、退出码）
+  Tab / 1-5    切换状态筛选
+  s            切换会话筛选
+  /            搜索命令、会话、输出
+  a / r        授权执行 / 拒绝
+  A / R        全部授权 / 全部拒绝
+  ?            显示/关闭帮助
+  q / Esc      退出程序
 `) + "\n"
 }
 
@@ -334,21 +703,19 @@ func (m model) renderFooter() string {
 	return "\n" + statusBar + "\n"
 }
 
+// groupTasksBySession groups the visible tasks by session
 func (m model) groupTasksBySession() map[string][]*queue.Task {
 	grouped := make(map[string][]*queue.Task)
 
-	for _, task := range m.tasks {
-		// Show pending and executing tasks
-		if task.Status == queue.TaskStatusPending || task.Status == queue.TaskStatusExecuting {
-			grouped[task.SessionID] = append(grouped[task.SessionID], task)
-		}
+	for _, task := range m.visibleTasks() {
+		grouped[task.SessionID] = append(grouped[task.SessionID], task)
 	}
 
 	return grouped
 }
 
 func (m model) getCursorForTask(taskID string) int {
-	for i, task := range m.tasks {
+	for i, task := range m.visibleTasks() {
 		if task.ID == taskID {
 			return i
 		}
@@ -380,6 +747,7 @@ var (
 	titleStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
 	subtleStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
 	helpStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	activeTabStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true).Underline(true)
 	statusBarStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("252")).
 			Background(lipgloss.Color("235")).
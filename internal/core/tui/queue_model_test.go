@@ -1,8 +1,12 @@
 package tui
 
 import (
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -180,3 +184,197 @@ func TestModel_Update_G_GoToBottom(t *testing.T) {
 		t.Errorf("Expected cursor at 2 (last item), got %d", m.cursor)
 	}
 }
+
+func TestModel_Update_TabChangesFilter(t *testing.T) {
+	tasks := []*queue.Task{
+		{ID: "1", Status: queue.TaskStatusPending},
+		{ID: "2", Status: queue.TaskStatusCompleted},
+	}
+	m := NewModel(tasks).(model)
+
+	if len(m.visibleTasks()) != 1 {
+		t.Fatalf("Expected 1 active task, got %d", len(m.visibleTasks()))
+	}
+
+	newMdl, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m2 := newMdl.(model)
+	if m2.filter.status != filterCompleted {
+		t.Errorf("Expected completed filter, got %s", m2.filter.status.Label())
+	}
+	visible := m2.visibleTasks()
+	if len(visible) != 1 || visible[0].ID != "2" {
+		t.Errorf("Expected only completed task visible, got %v", visible)
+	}
+}
+
+func TestModel_Update_Search(t *testing.T) {
+	tasks := []*queue.Task{
+		{ID: "1", Status: queue.TaskStatusPending, Command: ai.Command{Cmd: "ls"}},
+		{ID: "2", Status: queue.TaskStatusPending, Command: ai.Command{Cmd: "rm"}},
+	}
+	var mdl tea.Model = NewModel(tasks)
+
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune{'/'}},
+		{Type: tea.KeyRunes, Runes: []rune{'r'}},
+		{Type: tea.KeyRunes, Runes: []rune{'m'}},
+		{Type: tea.KeyEnter},
+	} {
+		mdl, _ = mdl.Update(msg)
+	}
+
+	m := mdl.(model)
+	if m.searching {
+		t.Error("Expected search box to lose focus after Enter")
+	}
+	if m.filter.query != "rm" {
+		t.Errorf("Expected query 'rm', got '%s'", m.filter.query)
+	}
+	visible := m.visibleTasks()
+	if len(visible) != 1 || visible[0].ID != "2" {
+		t.Errorf("Expected only rm task visible, got %d tasks", len(visible))
+	}
+
+	// Esc clears the query instead of quitting
+	mdl, cmd := mdl.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd != nil {
+		t.Error("Expected Esc to clear search without quitting")
+	}
+	if mdl.(model).filter.query != "" {
+		t.Error("Expected query to be cleared")
+	}
+}
+
+func TestModel_Update_DetailPane(t *testing.T) {
+	tasks := []*queue.Task{
+		{
+			ID:      "1",
+			Status:  queue.TaskStatusFailed,
+			Command: ai.Command{Cmd: "make"},
+			Result:  &queue.ExecutionResult{ExitCode: 2, Output: "line1\nline2", Error: "exit status 2"},
+		},
+	}
+	m := NewModel(tasks).(model)
+	m.filter.status = filterAll
+
+	newMdl, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m2 := newMdl.(model)
+	if !m2.showingDetail {
+		t.Fatal("Expected detail pane to open on Enter")
+	}
+
+	view := m2.View()
+	for _, want := range []string{"退出码: 2", "exit status 2", "line2"} {
+		if !contains(view, want) {
+			t.Errorf("Expected detail view to contain %q", want)
+		}
+	}
+
+	// q closes the detail pane rather than quitting
+	newMdl, cmd := m2.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if cmd != nil {
+		t.Error("Expected q to close detail pane without quitting")
+	}
+	if newMdl.(model).showingDetail {
+		t.Error("Expected detail pane to be closed")
+	}
+}
+
+func TestModel_Update_TaskEvents(t *testing.T) {
+	tasks := []*queue.Task{
+		{ID: "1", SessionID: "s1", Status: queue.TaskStatusApproved, Command: ai.Command{Cmd: "make"}},
+	}
+	events := make(chan queue.Event)
+	var mdl tea.Model = NewModelWithEvents(tasks, nil, nil, nil, events)
+
+	started := time.Now().Add(-3 * time.Second)
+	executing := &queue.Task{ID: "1", SessionID: "s1", Status: queue.TaskStatusExecuting, Command: ai.Command{Cmd: "make"}, StartedAt: &started}
+
+	mdl, cmd := mdl.Update(TaskEventMsg{Event: queue.Event{Type: queue.EventTaskStatus, TaskID: "1", Status: queue.TaskStatusExecuting, Task: executing}})
+	if cmd == nil {
+		t.Error("Expected follow-up command to keep listening for events")
+	}
+	mdl, _ = mdl.Update(TaskEventMsg{Event: queue.Event{Type: queue.EventTaskOutput, TaskID: "1", Chunk: "compiling foo\n"}})
+
+	m := mdl.(model)
+	if m.tasks[0].Status != queue.TaskStatusExecuting {
+		t.Errorf("Expected executing status, got %s", m.tasks[0].Status)
+	}
+	if !m.ticking {
+		t.Error("Expected elapsed-time ticker to start for executing task")
+	}
+
+	view := m.View()
+	if !contains(view, "compiling foo") {
+		t.Error("Expected live output tail in queue view")
+	}
+	if !contains(view, "3s") {
+		t.Error("Expected elapsed time in queue view")
+	}
+
+	finished := *executing
+	finished.Status = queue.TaskStatusCompleted
+	finished.Result = &queue.ExecutionResult{Output: "compiling foo"}
+	mdl, _ = mdl.Update(TaskEventMsg{Event: queue.Event{Type: queue.EventTaskStatus, TaskID: "1", Status: queue.TaskStatusCompleted, Task: &finished}})
+
+	m = mdl.(model)
+	if _, ok := m.liveOutput["1"]; ok {
+		t.Error("Expected live output to be dropped once the result is stored")
+	}
+
+	_, cmd = m.Update(TickMsg{})
+	if cmd != nil {
+		t.Error("Expected ticker to stop when nothing is executing")
+	}
+}
+
+func TestModel_Update_LiveOutputKeepsRunes(t *testing.T) {
+	tasks := []*queue.Task{
+		{ID: "1", SessionID: "s1", Status: queue.TaskStatusExecuting, Command: ai.Command{Cmd: "make"}},
+	}
+	var mdl tea.Model = NewModelWithEvents(tasks, nil, nil, nil, make(chan queue.Event))
+
+	// 编 is three bytes, one byte too many cuts into the first character
+	chunk := "x" + strings.Repeat("编", liveOutputLimit/3+1)
+	mdl, _ = mdl.Update(TaskEventMsg{Event: queue.Event{Type: queue.EventTaskOutput, TaskID: "1", Chunk: chunk}})
+
+	out := mdl.(model).liveOutput["1"]
+	if !utf8.ValidString(out) {
+		t.Errorf("Expected valid UTF-8 after trimming, got %q...", out[:8])
+	}
+	if len(out) > liveOutputLimit || !strings.HasSuffix(out, "编") {
+		t.Errorf("Expected the tail of the output within the limit, got %d bytes", len(out))
+	}
+}
+
+func TestModel_Update_AwaitingApprovals(t *testing.T) {
+	tasks := []*queue.Task{
+		{ID: "1", SessionID: "s1", Status: queue.TaskStatusPending, Command: ai.Command{Cmd: "deploy"},
+			RequiredApprovals: 2, Approvals: []queue.Approval{{Approver: "alice", Time: time.Now()}}},
+	}
+	var mdl tea.Model = NewModel(tasks)
+
+	mdl, _ = mdl.Update(AuthorizeResultMsg{TaskID: "1", Success: true, AwaitingApprovals: true})
+
+	m := mdl.(model)
+	if m.tasks[0].Status != queue.TaskStatusPending {
+		t.Errorf("Expected task to stay pending, got %s", m.tasks[0].Status)
+	}
+	if !contains(m.View(), "批准 1/2") {
+		t.Error("Expected approval progress in queue view")
+	}
+}
+
+func TestModel_Update_RejectKeepsCallerTasks(t *testing.T) {
+	task := &queue.Task{ID: "1", SessionID: "s1", Status: queue.TaskStatusPending, Command: ai.Command{Cmd: "rm"}}
+	var mdl tea.Model = NewModel([]*queue.Task{task})
+
+	mdl, _ = mdl.Update(RejectResultMsg{TaskID: "1", Success: true})
+
+	if m := mdl.(model); m.tasks[0].Status != queue.TaskStatusRejected {
+		t.Errorf("Expected task to show as rejected, got %s", m.tasks[0].Status)
+	}
+	if task.Status != queue.TaskStatusPending {
+		t.Errorf("Expected the task passed in to be left unchanged, got %s", task.Status)
+	}
+}
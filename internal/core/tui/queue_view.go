@@ -196,7 +196,7 @@ func (r *Renderer) renderFooter(mdl *model) string {
 }
 
 func (r *Renderer) getTaskIndex(mdl *model, taskID string) int {
-	for i, task := range mdl.tasks {
+	for i, task := range mdl.visibleTasks() {
 		if task.ID == taskID {
 			return i
 		}
@@ -9,6 +9,9 @@ import (
 type AuthorizeResultMsg struct {
 	TaskID  string
 	Success bool
+	// AwaitingApprovals is set when the approval was recorded but the task
+	// still needs approvals from other people before it can run
+	AwaitingApprovals bool
 }
 
 // RejectResultMsg is sent when rejection completes
@@ -25,9 +28,9 @@ type TasksLoadedMsg struct {
 	Tasks []*queue.Task
 }
 
-// StatusCheckMsg is sent to check task status updates
-type StatusCheckMsg struct {
-	TaskID string
+// TaskEventMsg wraps a queue event delivered through a subscription
+type TaskEventMsg struct {
+	Event queue.Event
 }
 
 // Model is the interface for the TUI model
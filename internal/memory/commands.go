@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// maxFrequentCommands is the number of frequently run commands listed in CommandContext
+const maxFrequentCommands = 8
+
+// environmentKeywords mark profile lines about the user's environment, which
+// are useful for every command request
+var environmentKeywords = map[string]bool{
+	"shell": true, "os": true, "linux": true, "macos": true, "windows": true,
+	"path": true, "paths": true, "directory": true, "editor": true,
+	"prefer": true, "prefers": true, "preferred": true, "tool": true, "tools": true,
+	"路径": true, "目录": true, "偏好": true, "工具": true, "系统": true, "编辑": true,
+}
+
+// environmentCategories are the profile categories about the user's environment
+var environmentCategories = map[string]bool{
+	"editors": true, "shell": true, "timezone": true, "common_paths": true,
+}
+
+// CommandContext returns what memory knows that helps turn request into shell
+// commands: the OS and shell, the current project and its notes, remembered
+// facts, profile lines about the environment or relevant to request, and the
+// commands the user runs most.
+func (m *Manager) CommandContext(request string) string {
+	if m == nil {
+		return ""
+	}
+
+	facts := environmentFacts()
+	if m.project != nil {
+		facts = append(facts, fmt.Sprintf("Current project: %s (%s)", m.project.Name, m.project.Root))
+		for _, note := range m.projectNotes.GetNotes() {
+			facts = append(facts, "Project note: "+note.Text)
+		}
+	}
+	for _, note := range m.notes.GetNotes() {
+		facts = append(facts, note.Text)
+	}
+	facts = append(facts, m.commandProfileLines(request)...)
+	if commands := m.frequentCommands(); commands != "" {
+		facts = append(facts, "Commands the user runs often: "+commands)
+	}
+
+	var b strings.Builder
+	b.WriteString("## About the user\n")
+	for _, fact := range facts {
+		fmt.Fprintf(&b, "- %s\n", fact)
+	}
+	b.WriteString("\nPrefer the user's tools, paths and conventions when they fit the request.")
+	return b.String()
+}
+
+// RecordCommand remembers that a command was run and whether it succeeded
+func (m *Manager) RecordCommand(name string, success bool) error {
+	if m == nil || name == "" {
+		return nil
+	}
+	return m.longTerm.RecordCommand(name, success)
+}
+
+// environmentFacts describes the system tada runs on
+func environmentFacts() []string {
+	facts := []string{"OS: " + runtime.GOOS}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		facts = append(facts, "Shell: "+filepath.Base(shell))
+	}
+	return facts
+}
+
+// commandProfileLines returns the profile lines about the user's environment
+// and those relevant to request
+func (m *Manager) commandProfileLines(request string) []string {
+	var lines []string
+	environment := make(map[string]bool)
+	for _, fact := range m.longTerm.GetProfileFacts() {
+		line := fact.Value
+		if fact.Category != otherCategory {
+			line = categoryTitle(fact.Category) + ": " + line
+		}
+		lines = append(lines, line)
+		environment[line] = environmentCategories[fact.Category]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	ctx, cancel := retrievalContext()
+	defer cancel()
+
+	selected := make(map[string]bool)
+	for _, line := range m.retriever.selectTexts(ctx, request, lines) {
+		selected[line] = true
+	}
+	var result []string
+	for _, line := range lines {
+		if selected[line] || environment[line] || hasEnvironmentKeyword(line) {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// hasEnvironmentKeyword reports whether a profile line is about the user's environment
+func hasEnvironmentKeyword(line string) bool {
+	for _, token := range tokenize(line) {
+		if environmentKeywords[token] {
+			return true
+		}
+	}
+	return false
+}
+
+// frequentCommands lists the commands run most, e.g. "git (12 runs), go (8 runs, 2 failed)"
+func (m *Manager) frequentCommands() string {
+	entities := m.longTerm.GetEntities()
+	var names []string
+	for name, entity := range entities {
+		if entity.Runs > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := entities[names[i]], entities[names[j]]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > maxFrequentCommands {
+		names = names[:maxFrequentCommands]
+	}
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		entity := entities[name]
+		if entity.Failures > 0 {
+			parts[i] = fmt.Sprintf("%s (%d runs, %d failed)", name, entity.Runs, entity.Failures)
+		} else {
+			parts[i] = fmt.Sprintf("%s (%d runs)", name, entity.Runs)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
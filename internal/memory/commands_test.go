@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManager_CommandContext(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/fish")
+	mgr := newTestManager(t)
+	mgr.SetProject(Project{Root: "/src/api", Name: "api"})
+	if _, err := mgr.ProjectNotes().AddNote("Deploy with make deploy"); err != nil {
+		t.Fatal(err)
+	}
+	profile := "# Profile\n- Preferred editor: neovim\n- Works on Kubernetes clusters\n- Likes coffee"
+	if err := mgr.LongTerm().SetProfileMarkdown(profile); err != nil {
+		t.Fatal(err)
+	}
+	for _, run := range []struct {
+		name    string
+		success bool
+	}{{"git", true}, {"git", true}, {"kubectl", false}} {
+		if err := mgr.RecordCommand(run.name, run.success); err != nil {
+			t.Fatalf("RecordCommand failed: %v", err)
+		}
+	}
+
+	got := mgr.CommandContext("list pods in the kubernetes cluster")
+	for _, want := range []string{
+		"- Shell: fish",
+		"- Current project: api (/src/api)",
+		"- Project note: Deploy with make deploy",
+		"- Preferred editor: neovim",
+		"- Works on Kubernetes clusters",
+		"git (2 runs), kubectl (1 runs, 1 failed)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CommandContext missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "coffee") {
+		t.Errorf("unrelated profile line in CommandContext:\n%s", got)
+	}
+
+	entity := mgr.LongTerm().GetEntities()["kubectl"]
+	if entity.Count != 1 || entity.Runs != 1 || entity.Failures != 1 {
+		t.Errorf("unexpected kubectl entity: %+v", entity)
+	}
+}
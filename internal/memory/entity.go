@@ -0,0 +1,433 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+// Entity categories
+const (
+	CategoryLanguage  = "language"
+	CategoryFramework = "framework"
+	CategoryTool      = "tool"
+	CategoryProject   = "project"
+	CategoryPerson    = "person"
+	CategoryTopic     = "topic"
+)
+
+// entityProfileCategories maps entity categories to the profile category
+// an entity is promoted to
+var entityProfileCategories = map[string]string{
+	CategoryLanguage:  "languages",
+	CategoryFramework: "frameworks",
+	CategoryTool:      "tools",
+	CategoryProject:   "current_projects",
+	CategoryPerson:    "people",
+	CategoryTopic:     "often_asks",
+}
+
+const (
+	// DefaultEntityHalfLife is how long it takes an entity score to halve
+	DefaultEntityHalfLife = 30 * 24 * time.Hour
+
+	// minEntityScore is the score below which a faded entity is dropped
+	minEntityScore = 0.1
+
+	// promotedConfidence is the confidence of profile facts promoted from entities
+	promotedConfidence = 0.6
+)
+
+// knownEntity is an entry of the alias table
+type knownEntity struct {
+	name     string
+	category string
+}
+
+// entityAliases maps common spellings, folded to lower case, to the canonical entity
+var entityAliases = map[string]knownEntity{
+	"go":                 {"Go", CategoryLanguage},
+	"golang":             {"Go", CategoryLanguage},
+	"python":             {"Python", CategoryLanguage},
+	"python3":            {"Python", CategoryLanguage},
+	"py":                 {"Python", CategoryLanguage},
+	"javascript":         {"JavaScript", CategoryLanguage},
+	"js":                 {"JavaScript", CategoryLanguage},
+	"typescript":         {"TypeScript", CategoryLanguage},
+	"ts":                 {"TypeScript", CategoryLanguage},
+	"rust":               {"Rust", CategoryLanguage},
+	"rustlang":           {"Rust", CategoryLanguage},
+	"java":               {"Java", CategoryLanguage},
+	"c++":                {"C++", CategoryLanguage},
+	"cpp":                {"C++", CategoryLanguage},
+	"c#":                 {"C#", CategoryLanguage},
+	"csharp":             {"C#", CategoryLanguage},
+	"ruby":               {"Ruby", CategoryLanguage},
+	"kotlin":             {"Kotlin", CategoryLanguage},
+	"swift":              {"Swift", CategoryLanguage},
+	"react":              {"React", CategoryFramework},
+	"reactjs":            {"React", CategoryFramework},
+	"react.js":           {"React", CategoryFramework},
+	"vue":                {"Vue", CategoryFramework},
+	"vuejs":              {"Vue", CategoryFramework},
+	"vue.js":             {"Vue", CategoryFramework},
+	"django":             {"Django", CategoryFramework},
+	"flask":              {"Flask", CategoryFramework},
+	"spring":             {"Spring", CategoryFramework},
+	"node":               {"Node.js", CategoryTool},
+	"nodejs":             {"Node.js", CategoryTool},
+	"node.js":            {"Node.js", CategoryTool},
+	"docker":             {"Docker", CategoryTool},
+	"kubernetes":         {"Kubernetes", CategoryTool},
+	"k8s":                {"Kubernetes", CategoryTool},
+	"neovim":             {"Neovim", CategoryTool},
+	"nvim":               {"Neovim", CategoryTool},
+	"vim":                {"Vim", CategoryTool},
+	"emacs":              {"Emacs", CategoryTool},
+	"vscode":             {"VS Code", CategoryTool},
+	"vs code":            {"VS Code", CategoryTool},
+	"visual studio code": {"VS Code", CategoryTool},
+	"postgres":           {"PostgreSQL", CategoryTool},
+	"postgresql":         {"PostgreSQL", CategoryTool},
+	"mysql":              {"MySQL", CategoryTool},
+	"redis":              {"Redis", CategoryTool},
+}
+
+// validEntityCategory reports whether category is a known entity category
+func validEntityCategory(category string) bool {
+	_, ok := entityProfileCategories[category]
+	return ok
+}
+
+// ScoreAt returns the score of the entity at now. Scores halve every
+// halfLife, counted in whole hours so mentions close together add up exactly.
+func (e *Entity) ScoreAt(now time.Time, halfLife time.Duration) float64 {
+	hours := math.Floor(now.Sub(e.LastSeen).Hours())
+	if hours <= 0 || halfLife <= 0 {
+		return e.Score
+	}
+	return e.Score * math.Pow(0.5, hours/halfLife.Hours())
+}
+
+// hasAlias reports whether name is one of the spellings of the entity
+func (e *Entity) hasAlias(name string) bool {
+	for _, alias := range e.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// addAlias records another spelling of the entity
+func (e *Entity) addAlias(key, name string) {
+	if name == "" || strings.EqualFold(name, key) || e.hasAlias(name) {
+		return
+	}
+	e.Aliases = append(e.Aliases, name)
+}
+
+// mergeEntity adds the mentions of src to dst
+func mergeEntity(dst, src *Entity, halfLife time.Duration) {
+	last := dst.LastSeen
+	if src.LastSeen.After(last) {
+		last = src.LastSeen
+	}
+	dst.Score = dst.ScoreAt(last, halfLife) + src.ScoreAt(last, halfLife)
+	dst.LastSeen = last
+	if dst.FirstSeen.IsZero() || (!src.FirstSeen.IsZero() && src.FirstSeen.Before(dst.FirstSeen)) {
+		dst.FirstSeen = src.FirstSeen
+	}
+	dst.Count += src.Count
+	dst.Runs += src.Runs
+	dst.Failures += src.Failures
+	if dst.Category == "" {
+		dst.Category = src.Category
+	}
+	for _, alias := range src.Aliases {
+		if !dst.hasAlias(alias) {
+			dst.Aliases = append(dst.Aliases, alias)
+		}
+	}
+}
+
+// resolveEntity returns the key an entity is stored under and its category
+// from the alias table. Names are matched ignoring case against the alias
+// table, the stored entities and their aliases. The caller must hold the lock.
+func (l *LongTermMemory) resolveEntity(name string) (string, string) {
+	name = strings.Join(strings.Fields(name), " ")
+	canonical, category := name, ""
+	if known, ok := entityAliases[strings.ToLower(name)]; ok {
+		canonical, category = known.name, known.category
+	}
+
+	if _, exists := l.entities[canonical]; exists {
+		return canonical, category
+	}
+	for key, entity := range l.entities {
+		if strings.EqualFold(key, canonical) || entity.hasAlias(name) {
+			return key, category
+		}
+	}
+	return canonical, category
+}
+
+// mention counts a mention of name and returns the entity. The caller must
+// hold the lock.
+func (l *LongTermMemory) mention(name string, now time.Time) (string, *Entity) {
+	key, category := l.resolveEntity(name)
+	entity, exists := l.entities[key]
+	if !exists {
+		entity = &Entity{FirstSeen: now, LastSeen: now}
+		l.entities[key] = entity
+	}
+	entity.Score = entity.ScoreAt(now, l.halfLife) + 1
+	entity.Count++
+	entity.LastSeen = now
+	entity.addAlias(key, strings.TrimSpace(name))
+	if entity.Category == "" {
+		entity.Category = category
+	}
+	return key, entity
+}
+
+// reconcileEntities promotes entities whose score reached the threshold to
+// the profile, demotes promoted entities whose score fell below half of it,
+// and drops entities that faded away. It returns the promoted entities. The
+// caller must hold the write lock.
+func (l *LongTermMemory) reconcileEntities(now time.Time) ([]string, error) {
+	facts := append([]ProfileFact(nil), l.profile.Facts...)
+	var changes []ProfileChange
+	var promoted []string
+
+	for key, entity := range l.entities {
+		score := entity.ScoreAt(now, l.halfLife)
+		category, ok := entityProfileCategories[entity.Category]
+
+		switch {
+		case !entity.Promoted && ok && score >= float64(l.threshold) && entity.Runs < entity.Count:
+			// Commands the user only runs are listed as frequent commands instead
+			entity.Promoted = true
+			promoted = append(promoted, key)
+			if findFact(facts, category, key) >= 0 {
+				continue
+			}
+			facts = append(facts, newFact(category, key, SourceEntities, promotedConfidence, now))
+			changes = append(changes, ProfileChange{Op: OpAdd, Category: category, Value: key, Confidence: promotedConfidence, Reason: "mentioned often"})
+
+		case entity.Promoted && score < float64(l.threshold)/2:
+			entity.Promoted = false
+			if change, ok := l.demote(&facts, key, entity); ok {
+				changes = append(changes, change)
+			}
+		}
+
+		if !entity.Promoted && score < minEntityScore {
+			delete(l.entities, key)
+		}
+	}
+
+	return promoted, l.commitProfile(facts, changes, SourceEntities, "entity scores")
+}
+
+// demote removes the profile fact promoted from an entity. Facts the user
+// edited or the model confirmed have another source and are kept.
+func (l *LongTermMemory) demote(facts *[]ProfileFact, key string, entity *Entity) (ProfileChange, bool) {
+	category := entityProfileCategories[entity.Category]
+	i := findFact(*facts, category, key)
+	if i < 0 || (*facts)[i].Source != SourceEntities {
+		return ProfileChange{}, false
+	}
+	*facts = append((*facts)[:i], (*facts)[i+1:]...)
+	return ProfileChange{Op: OpRemove, Category: category, Value: key, Reason: "no longer mentioned"}, true
+}
+
+// MergeEntities merges the entities aliases into canonical, which is created
+// when it does not exist yet. Later mentions of the aliases count for canonical.
+func (l *LongTermMemory) MergeEntities(canonical string, aliases []string, category string) error {
+	canonical = strings.TrimSpace(canonical)
+	if canonical == "" {
+		return fmt.Errorf("entity name is empty")
+	}
+	if category != "" && !validEntityCategory(category) {
+		return fmt.Errorf("unknown entity category: %s", category)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.mergeLocked(canonical, aliases, category, time.Now()); err != nil {
+		return err
+	}
+	if _, err := l.reconcileEntities(time.Now()); err != nil {
+		return err
+	}
+	return l.saveEntities()
+}
+
+// mergeLocked merges entities, the caller must hold the write lock
+func (l *LongTermMemory) mergeLocked(canonical string, aliases []string, category string, now time.Time) error {
+	key, _ := l.resolveEntity(canonical)
+	target, exists := l.entities[key]
+	if !exists {
+		key = canonical
+		target = &Entity{FirstSeen: now, LastSeen: now}
+	}
+
+	facts := append([]ProfileFact(nil), l.profile.Facts...)
+	var changes []ProfileChange
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		aliasKey, _ := l.resolveEntity(alias)
+		if entity, ok := l.entities[aliasKey]; ok && aliasKey != key {
+			if entity.Promoted {
+				if change, ok := l.demote(&facts, aliasKey, entity); ok {
+					changes = append(changes, change)
+				}
+			}
+			mergeEntity(target, entity, l.halfLife)
+			target.addAlias(key, aliasKey)
+			delete(l.entities, aliasKey)
+		}
+		target.addAlias(key, alias)
+	}
+	if category != "" {
+		target.Category = category
+	}
+
+	l.entities[key] = target
+	return l.commitProfile(facts, changes, SourceEntities, "merged entities")
+}
+
+// CanonicalizeWithLLM asks the LLM to categorize the entities without a
+// category and to merge those that are spellings of the same thing. It does
+// nothing when every entity has a category.
+func (l *LongTermMemory) CanonicalizeWithLLM(ctx context.Context) error {
+	l.mu.RLock()
+	provider := l.aiProvider
+	var unknown, known []string
+	for key, entity := range l.entities {
+		if entity.Category == "" {
+			unknown = append(unknown, key)
+		} else {
+			known = append(known, key)
+		}
+	}
+	l.mu.RUnlock()
+
+	if len(unknown) == 0 {
+		return nil
+	}
+	if provider == nil {
+		return fmt.Errorf("AI provider not set")
+	}
+	sort.Strings(unknown)
+	sort.Strings(known)
+
+	messages := []ai.Message{
+		{Role: "system", Content: "You are a helpful assistant that organizes entities. Always respond with valid JSON only."},
+		{Role: "user", Content: l.buildCanonicalizePrompt(unknown, known)},
+	}
+	response, err := provider.Chat(ctx, messages)
+	if err != nil {
+		return fmt.Errorf("LLM entity canonicalization failed: %w", err)
+	}
+	groups, err := parseEntityGroups(response)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for _, group := range groups {
+		category := strings.ToLower(strings.TrimSpace(group.Category))
+		if !validEntityCategory(category) {
+			category = ""
+		}
+		// Only merge entities that exist, the model must not invent names
+		key, _ := l.resolveEntity(group.Name)
+		if _, exists := l.entities[key]; !exists {
+			continue
+		}
+		var aliases []string
+		for _, alias := range group.Aliases {
+			if aliasKey, _ := l.resolveEntity(alias); l.entities[aliasKey] != nil && aliasKey != key {
+				aliases = append(aliases, aliasKey)
+			}
+		}
+		if err := l.mergeLocked(key, aliases, category, now); err != nil {
+			return err
+		}
+	}
+	if _, err := l.reconcileEntities(now); err != nil {
+		return err
+	}
+	return l.saveEntities()
+}
+
+// entityGroup is an entity with its other spellings, as returned by the LLM
+type entityGroup struct {
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Aliases  []string `json:"aliases"`
+}
+
+// parseEntityGroups decodes the LLM response, which may be wrapped in a code block
+func parseEntityGroups(response string) ([]entityGroup, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("entity canonicalization is not JSON")
+	}
+	var result struct {
+		Entities []entityGroup `json:"entities"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("invalid entity canonicalization: %w", err)
+	}
+	return result.Entities, nil
+}
+
+// defaultCanonicalizePrompt asks for entity categories and merges as JSON
+const defaultCanonicalizePrompt = `Categorize the new entities below and merge the ones that name the same thing.
+
+## New Entities
+{{entities}}
+
+## Known Entities
+{{known}}
+
+Return JSON only, no markdown:
+{
+  "entities": [
+    {"name": "Go", "category": "language", "aliases": ["golang"]}
+  ]
+}
+
+Rules:
+1. Categories: language, framework, tool, project, person, topic
+2. name is the canonical spelling, an existing name from either list
+3. aliases are other names from the lists that mean the same thing, e.g. "golang" for "Go"
+4. Every new entity should appear once, as a name or an alias`
+
+// buildCanonicalizePrompt creates the prompt for entity canonicalization
+func (l *LongTermMemory) buildCanonicalizePrompt(unknown, known []string) string {
+	prompt := defaultCanonicalizePrompt
+	if l.promptLoader != nil {
+		prompt = l.promptLoader.LoadOrDefault("canonicalize", prompt)
+	}
+	knownList := "(none)"
+	if len(known) > 0 {
+		knownList = "- " + strings.Join(known, "\n- ")
+	}
+	prompt = strings.ReplaceAll(prompt, "{{entities}}", "- "+strings.Join(unknown, "\n- "))
+	return strings.ReplaceAll(prompt, "{{known}}", knownList)
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLongTermMemory_CanonicalEntities(t *testing.T) {
+	ltm := NewLongTermMemory(t.TempDir(), 10)
+	for _, name := range []string{"Go", "golang", "Golang", " go "} {
+		if _, err := ltm.UpdateEntity(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ltm.UpdateEntity("kubectl")
+	ltm.UpdateEntity("Kubectl")
+
+	entities := ltm.GetEntities()
+	if len(entities) != 2 {
+		t.Fatalf("expected Go and kubectl, got %v", entities)
+	}
+	goEntity := entities["Go"]
+	if goEntity.Count != 4 || goEntity.Category != CategoryLanguage || !goEntity.hasAlias("golang") {
+		t.Errorf("unexpected Go entity: %+v", goEntity)
+	}
+	if entities["kubectl"].Count != 2 || entities["kubectl"].Category != "" {
+		t.Errorf("unexpected kubectl entity: %+v", entities["kubectl"])
+	}
+	if ltm.GetEntityCount("GOLANG") != 4 {
+		t.Error("expected lookups to use aliases")
+	}
+}
+
+func TestEntity_ScoreAt(t *testing.T) {
+	now := time.Now()
+	entity := &Entity{Score: 4, LastSeen: now.Add(-30 * 24 * time.Hour)}
+	if score := entity.ScoreAt(now, 30*24*time.Hour); score < 1.99 || score > 2.01 {
+		t.Errorf("expected the score to halve after a half life, got %v", score)
+	}
+	entity.LastSeen = now.Add(-30 * time.Minute)
+	if score := entity.ScoreAt(now, time.Hour); score != 4 {
+		t.Errorf("expected no decay within an hour, got %v", score)
+	}
+}
+
+func TestLongTermMemory_PromoteAndDemote(t *testing.T) {
+	ltm := NewLongTermMemory(t.TempDir(), 2)
+	ltm.UpdateEntity("rust")
+	promoted, err := ltm.UpdateEntity("Rust")
+	if err != nil || !promoted {
+		t.Fatalf("expected Rust to be promoted, got %v, %v", promoted, err)
+	}
+	if got := ltm.GetProfileMarkdown(); got != "## Languages\n- Rust" {
+		t.Errorf("unexpected profile: %q", got)
+	}
+	facts := ltm.GetProfileFacts()
+	if facts[0].Source != SourceEntities || facts[0].Confidence != promotedConfidence {
+		t.Errorf("unexpected promoted fact: %+v", facts[0])
+	}
+
+	// Run only as a command: listed as a frequent command, not promoted
+	ltm.RecordCommand("make", true)
+	ltm.RecordCommand("make", true)
+	if entity := ltm.GetEntities()["make"]; entity.Promoted || entity.Category != CategoryTool {
+		t.Errorf("unexpected make entity: %+v", entity)
+	}
+
+	// Two half lives later Rust has faded below half the threshold
+	ltm.mu.Lock()
+	ltm.entities["Rust"].LastSeen = time.Now().Add(-2 * DefaultEntityHalfLife)
+	ltm.mu.Unlock()
+	ltm.UpdateEntity("Docker")
+	if strings.Contains(ltm.GetProfileMarkdown(), "Rust") {
+		t.Errorf("expected Rust to be demoted, got %q", ltm.GetProfileMarkdown())
+	}
+	if ltm.GetEntities()["Rust"].Promoted {
+		t.Error("expected Rust to be marked as demoted")
+	}
+
+	// Entities that faded away are dropped
+	ltm.mu.Lock()
+	ltm.entities["Rust"].LastSeen = time.Now().Add(-10 * DefaultEntityHalfLife)
+	ltm.mu.Unlock()
+	ltm.UpdateEntity("Docker")
+	if _, exists := ltm.GetEntities()["Rust"]; exists {
+		t.Error("expected faded entity to be dropped")
+	}
+}
+
+func TestLongTermMemory_DemoteKeepsUserFacts(t *testing.T) {
+	ltm := NewLongTermMemory(t.TempDir(), 1)
+	if err := ltm.SetProfileMarkdown("## Languages\n- Go\n- Rust"); err != nil {
+		t.Fatal(err)
+	}
+	// Go is promoted, but the user already added it to the profile
+	if promoted, _ := ltm.UpdateEntity("Go"); !promoted {
+		t.Error("expected Go to be promoted")
+	}
+	if removed, err := ltm.RemoveEntity("golang"); err != nil || removed != "Go" {
+		t.Fatalf("RemoveEntity = %q, %v", removed, err)
+	}
+	if got := ltm.GetProfileMarkdown(); got != "## Languages\n- Go\n- Rust" {
+		t.Errorf("removing the entity should keep facts it did not add, got %q", got)
+	}
+
+	ltm.UpdateEntity("Python")
+	ltm.RemoveEntity("python")
+	if strings.Contains(ltm.GetProfileMarkdown(), "Python") {
+		t.Errorf("expected the promoted fact to be removed with the entity, got %q", ltm.GetProfileMarkdown())
+	}
+}
+
+func TestLongTermMemory_MergeEntities(t *testing.T) {
+	ltm := NewLongTermMemory(t.TempDir(), 10)
+	ltm.UpdateEntity("tada")
+	ltm.UpdateEntity("tada-cli")
+	ltm.UpdateEntity("tada-cli")
+
+	if err := ltm.MergeEntities("tada", []string{"tada-cli"}, CategoryProject); err != nil {
+		t.Fatalf("MergeEntities failed: %v", err)
+	}
+	entity := ltm.GetEntities()["tada"]
+	if entity.Count != 3 || entity.Category != CategoryProject || !entity.hasAlias("tada-cli") {
+		t.Errorf("unexpected merged entity: %+v", entity)
+	}
+	if score := ltm.EntityScore("tada-cli"); score < 2.99 {
+		t.Errorf("expected merged score 3, got %v", score)
+	}
+
+	// Later mentions of the alias count for the canonical entity
+	ltm.UpdateEntity("TADA-CLI")
+	if len(ltm.GetEntities()) != 1 || ltm.GetEntityCount("tada") != 4 {
+		t.Errorf("expected alias to count for tada, got %v", ltm.GetEntities())
+	}
+
+	if err := ltm.MergeEntities("tada", nil, "animal"); err == nil {
+		t.Error("expected error for an unknown category")
+	}
+}
+
+func TestLongTermMemory_CanonicalizeWithLLM(t *testing.T) {
+	ltm := newProfileMemory(t, "```json\n"+`{"entities": [
+		{"name": "tada", "category": "project", "aliases": ["tada-cli", "invented"]},
+		{"name": "Alice", "category": "person"},
+		{"name": "Unknown", "category": "tool"},
+		{"name": "zsh", "category": "spaceship"}
+	]}`+"\n```")
+	for _, name := range []string{"tada", "tada-cli", "Alice", "zsh"} {
+		ltm.UpdateEntity(name)
+	}
+
+	if err := ltm.CanonicalizeWithLLM(context.Background()); err != nil {
+		t.Fatalf("CanonicalizeWithLLM failed: %v", err)
+	}
+	entities := ltm.GetEntities()
+	if len(entities) != 3 {
+		t.Fatalf("expected tada-cli to be merged and nothing invented, got %v", entities)
+	}
+	if entities["tada"].Category != CategoryProject || entities["tada"].Count != 2 {
+		t.Errorf("unexpected tada entity: %+v", entities["tada"])
+	}
+	if entities["Alice"].Category != CategoryPerson || entities["zsh"].Category != "" {
+		t.Errorf("unexpected categories: %+v", entities)
+	}
+
+	// Nothing left to categorize but zsh, an invalid response is an error
+	ltm.SetAIProvider(&MockAIProvider{response: "no idea"}, nil)
+	if err := ltm.CanonicalizeWithLLM(context.Background()); err == nil {
+		t.Error("expected error for a response without JSON")
+	}
+}
@@ -16,7 +16,7 @@ func (m *MockAIProvider) Chat(ctx context.Context, messages []ai.Message) (strin
 	return m.response, nil
 }
 
-func (m *MockAIProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan string, error) {
+func (m *MockAIProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
 	return nil, nil
 }
 
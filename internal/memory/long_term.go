@@ -4,13 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 )
 
 // LongTermMemory manages user profile and entity tracking
@@ -18,17 +22,21 @@ type LongTermMemory struct {
 	mu           sync.RWMutex
 	storagePath  string
 	entityPath   string
-	profilePath  string
+	profilePath  string // Rendered profile markdown, kept for reading and older versions
+	factsPath    string
+	historyPath  string
 	threshold    int
+	halfLife     time.Duration
 	entities     map[string]*Entity
-	profileMD    string
+	profile      ProfileData
+	history      []ProfileVersion
 	aiProvider   ai.AIProvider
 	promptLoader *PromptLoader
 }
 
 // NewLongTermMemory creates a new long-term memory manager
 func NewLongTermMemory(storagePath string, threshold int) *LongTermMemory {
-	if err := os.MkdirAll(storagePath, 0755); err != nil {
+	if err := os.MkdirAll(storagePath, privacy.DirMode); err != nil {
 		// Continue in-memory only
 	}
 
@@ -36,9 +44,11 @@ func NewLongTermMemory(storagePath string, threshold int) *LongTermMemory {
 		storagePath: storagePath,
 		entityPath:  filepath.Join(storagePath, "entities.json"),
 		profilePath: filepath.Join(storagePath, "user_profile.md"),
+		factsPath:   filepath.Join(storagePath, "profile.json"),
+		historyPath: filepath.Join(storagePath, "profile_history.json"),
 		threshold:   threshold,
+		halfLife:    DefaultEntityHalfLife,
 		entities:    make(map[string]*Entity),
-		profileMD:   "",
 	}
 
 	ltm.load()
@@ -53,40 +63,120 @@ func (l *LongTermMemory) SetAIProvider(provider ai.AIProvider, promptLoader *Pro
 	l.promptLoader = promptLoader
 }
 
+// SetEntityHalfLife sets how long it takes an entity score to halve
+func (l *LongTermMemory) SetEntityHalfLife(halfLife time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if halfLife > 0 {
+		l.halfLife = halfLife
+	}
+}
+
 // load loads long-term memory from disk
 func (l *LongTermMemory) load() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	// Load entities
-	if entityData, err := os.ReadFile(l.entityPath); err == nil {
+	if entityData, ok := readStored(l.entityPath); ok {
 		json.Unmarshal(entityData, &l.entities)
 	}
+	// Entities of older versions only have a count
+	for _, entity := range l.entities {
+		if entity.Score == 0 && entity.Count > 0 {
+			entity.Score = float64(entity.Count)
+		}
+	}
 
-	// Load profile markdown directly
-	if profileData, err := os.ReadFile(l.profilePath); err == nil {
-		l.profileMD = string(profileData)
+	if historyData, ok := readStored(l.historyPath); ok {
+		json.Unmarshal(historyData, &l.history)
 	}
 
+	if factsData, ok := readStored(l.factsPath); ok {
+		json.Unmarshal(factsData, &l.profile)
+		return nil
+	}
+
+	// Older versions only stored the profile markdown, convert it to facts
+	if profileData, ok := readStored(l.profilePath); ok {
+		facts := parseProfile(string(profileData), SourceMigration, migratedConfidence, time.Now())
+		if len(facts) > 0 {
+			return l.commitProfile(facts, diffFacts(nil, facts), SourceMigration, "converted user_profile.md")
+		}
+	}
 	return nil
 }
 
+// readStored reads a memory file. A file that exists but cannot be read is
+// logged and skipped, e.g. a plain text file refused after tada encrypt.
+func readStored(path string) ([]byte, bool) {
+	data, err := privacy.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[memory] Warning: %v", err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
 // saveEntities saves entity data to disk
 func (l *LongTermMemory) saveEntities() error {
-	data, err := json.MarshalIndent(l.entities, "", "  ")
+	data, err := json.MarshalIndent(redactEntities(l.entities), "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(l.entityPath, data, 0644)
+	return privacy.WritePrivate(l.entityPath, data)
 }
 
-// saveProfile saves profile markdown to file
+// saveProfile saves the profile facts, their history and the rendered markdown
 func (l *LongTermMemory) saveProfile() error {
-	return os.WriteFile(l.profilePath, []byte(l.profileMD), 0644)
+	facts := redactFacts(l.profile.Facts)
+	data, err := json.MarshalIndent(ProfileData{Version: l.profile.Version, Facts: facts}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := privacy.WritePrivate(l.factsPath, data); err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(redactHistory(l.history), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := privacy.WritePrivate(l.historyPath, data); err != nil {
+		return err
+	}
+	return privacy.WritePrivate(l.profilePath, []byte(renderProfile(facts)))
 }
 
-// UpdateProfileWithLLM updates user profile markdown using LLM
-func (l *LongTermMemory) UpdateProfileWithLLM(ctx context.Context, newInfo string) error {
+// commitProfile replaces the facts and records the change as a new version.
+// The caller must hold the write lock.
+func (l *LongTermMemory) commitProfile(facts []ProfileFact, changes []ProfileChange, source, note string) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	l.profile.Version++
+	l.profile.Facts = facts
+	l.history = append(l.history, ProfileVersion{
+		Version: l.profile.Version,
+		Time:    time.Now(),
+		Source:  source,
+		Note:    note,
+		Changes: changes,
+		Facts:   append([]ProfileFact(nil), facts...),
+	})
+	if len(l.history) > maxProfileVersions {
+		l.history = l.history[len(l.history)-maxProfileVersions:]
+	}
+	return l.saveProfile()
+}
+
+// UpdateProfileWithLLM asks the LLM which profile facts newInfo adds, changes
+// or contradicts, and merges the validated changes. source is the ID of the
+// conversation the information comes from.
+func (l *LongTermMemory) UpdateProfileWithLLM(ctx context.Context, source, newInfo string) error {
 	// Phase 1: read-only access under read lock
 	l.mu.RLock()
 	if l.aiProvider == nil {
@@ -94,14 +184,14 @@ func (l *LongTermMemory) UpdateProfileWithLLM(ctx context.Context, newInfo strin
 		return fmt.Errorf("AI provider not set")
 	}
 	provider := l.aiProvider
-	currentProfile := l.profileMD
+	current := append([]ProfileFact(nil), l.profile.Facts...)
 	l.mu.RUnlock()
 
-	// Build update prompt with current markdown and new info
-	updatePrompt := l.buildProfileUpdatePrompt(currentProfile, newInfo)
+	// Build update prompt with current facts and new info
+	updatePrompt := l.buildProfileUpdatePrompt(formatFactsForPrompt(current), newInfo)
 
 	messages := []ai.Message{
-		{Role: "system", Content: "You are a helpful assistant that maintains user profiles. Always respond with valid markdown only."},
+		{Role: "system", Content: "You are a helpful assistant that maintains user profiles. Always respond with valid JSON only."},
 		{Role: "user", Content: updatePrompt},
 	}
 
@@ -111,20 +201,25 @@ func (l *LongTermMemory) UpdateProfileWithLLM(ctx context.Context, newInfo strin
 		return fmt.Errorf("LLM profile update failed: %w", err)
 	}
 
-	updatedProfile := strings.TrimSpace(response)
+	changes, err := parseProfileDiff(response)
+	if err != nil {
+		return err
+	}
 
-	// Phase 2: acquire write lock to commit the updated profile and persist
+	// Phase 2: acquire write lock to merge the changes and persist
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	l.profileMD = updatedProfile
-	return l.saveProfile()
+	changes, err = validateChanges(changes, l.profile.Facts)
+	if err != nil {
+		return err
+	}
+	facts, applied := applyChanges(l.profile.Facts, changes, source, time.Now())
+	return l.commitProfile(facts, applied, source, "")
 }
 
-// buildProfileUpdatePrompt creates prompt for profile update
-func (l *LongTermMemory) buildProfileUpdatePrompt(currentMD, newInfo string) string {
-	// Try to load template, or use default
-	defaultPrompt := `Update the following user profile with the new information provided.
+// defaultProfileDiffPrompt asks for the profile changes as JSON
+const defaultProfileDiffPrompt = `Compare the current user profile with the new information and propose changes.
 
 ## Current Profile
 {{profile}}
@@ -132,59 +227,71 @@ func (l *LongTermMemory) buildProfileUpdatePrompt(currentMD, newInfo string) str
 ## New Information
 {{info}}
 
-Please update the profile by:
-1. Adding any new information that should be included
-2. Updating existing information if the new information contradicts or refines it
-3. Removing information that is no longer relevant
-4. Keeping the same markdown format
+Return JSON only, no markdown:
+{
+  "changes": [
+    {"op": "add", "category": "languages", "value": "Go", "confidence": 0.8, "reason": "why"},
+    {"op": "update", "category": "shell", "old_value": "bash", "value": "zsh", "confidence": 0.7, "reason": "why"},
+    {"op": "remove", "category": "current_projects", "value": "old project", "confidence": 0.6, "reason": "why"}
+  ]
+}
 
-Return ONLY the updated profile markdown, no other text.`
+Rules:
+1. Categories: languages, frameworks, editors, shell, timezone, current_projects, common_paths, communication, often_asks, other
+2. Each value is one short fact on a single line
+3. Only propose changes supported by the new information, and remove a fact only when the information contradicts it
+4. Confidence is between 0 and 1: how sure the information is about the user, not about the conversation
+5. Return {"changes": []} when nothing changes`
 
-	var updatePrompt string
+// buildProfileUpdatePrompt creates prompt for profile update
+func (l *LongTermMemory) buildProfileUpdatePrompt(currentFacts, newInfo string) string {
+	updatePrompt := defaultProfileDiffPrompt
 	if l.promptLoader != nil {
-		template, err := l.promptLoader.Load("update-profile")
-		if err == nil {
-			updatePrompt = template.SystemPrompt
-			updatePrompt = strings.ReplaceAll(updatePrompt, "{{profile}}", currentMD)
-			updatePrompt = strings.ReplaceAll(updatePrompt, "{{info}}", newInfo)
-			return updatePrompt
-		}
+		updatePrompt = l.promptLoader.LoadOrDefault("profile-diff", updatePrompt)
 	}
 
-	// Fallback to default
-	return fmt.Sprintf(strings.ReplaceAll(strings.ReplaceAll(defaultPrompt, "{{profile}}", "%s"), "{{info}}", "%s"), currentMD, newInfo)
+	updatePrompt = strings.ReplaceAll(updatePrompt, "{{profile}}", currentFacts)
+	return strings.ReplaceAll(updatePrompt, "{{info}}", newInfo)
 }
 
-// UpdateEntity increments entity count and returns true if threshold reached
+// UpdateEntity counts a mention of an entity under its canonical name and
+// returns true if its score reached the threshold and it was promoted to the
+// profile
 func (l *LongTermMemory) UpdateEntity(name string) (bool, error) {
+	if strings.TrimSpace(name) == "" {
+		return false, nil
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	now := time.Now()
-
-	if entity, exists := l.entities[name]; exists {
-		entity.Count++
-		entity.LastSeen = now
-
-		if entity.Count >= l.threshold {
-			// Threshold reached - caller can trigger profile update
-			return true, l.saveEntities()
-		}
-	} else {
-		l.entities[name] = &Entity{
-			Count:     1,
-			FirstSeen: now,
-			LastSeen:  now,
-		}
+	key, _ := l.mention(name, now)
+	promoted, err := l.reconcileEntities(now)
+	if err != nil {
+		return false, err
 	}
-
-	return false, l.saveEntities()
+	return slices.Contains(promoted, key), l.saveEntities()
 }
 
-// promoteEntityToProfile adds entity to appropriate profile category
-func (l *LongTermMemory) promoteEntityToProfile(name string) {
-	// Entity promotion is now handled by LLM in UpdateProfileWithLLM
-	// This method is kept for compatibility but does nothing
+// RecordCommand counts a run of the command name as a mention of the entity
+func (l *LongTermMemory) RecordCommand(name string, success bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	_, entity := l.mention(name, now)
+	if entity.Category == "" {
+		entity.Category = CategoryTool
+	}
+	entity.Runs++
+	if !success {
+		entity.Failures++
+	}
+	if _, err := l.reconcileEntities(now); err != nil {
+		return err
+	}
+	return l.saveEntities()
 }
 
 // UpdateProfile updates user profile from extraction results (deprecated - use UpdateProfileWithLLM)
@@ -193,28 +300,234 @@ func (l *LongTermMemory) UpdateProfile(extraction *ExtractionResult) error {
 	return nil
 }
 
-// GetProfileMarkdown returns the current user profile markdown
+// GetProfileMarkdown returns the user profile rendered as markdown
 func (l *LongTermMemory) GetProfileMarkdown() string {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	return l.profileMD
+	return renderProfile(l.profile.Facts)
 }
 
-// GetProfile returns the current user profile (deprecated - use GetProfileMarkdown)
+// GetProfile returns the user profile in its structured form
 func (l *LongTermMemory) GetProfile() *UserProfile {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	// Return empty profile for compatibility
-	return &UserProfile{}
+	return buildUserProfile(l.profile.Facts)
+}
+
+// GetProfileFacts returns a copy of the profile facts
+func (l *LongTermMemory) GetProfileFacts() []ProfileFact {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]ProfileFact(nil), l.profile.Facts...)
 }
 
-// GetEntityCount returns the current count for an entity
+// ProfileVersion returns the current version of the profile, 0 before the first change
+func (l *LongTermMemory) ProfileVersion() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.profile.Version
+}
+
+// ProfileHistory returns the saved versions of the profile, oldest first
+func (l *LongTermMemory) ProfileHistory() []ProfileVersion {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]ProfileVersion(nil), l.history...)
+}
+
+// RollbackProfile restores the facts of a saved version. The rollback is
+// itself saved as a new version, so it can be undone.
+func (l *LongTermMemory) RollbackProfile(version int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, saved := range l.history {
+		if saved.Version != version {
+			continue
+		}
+		facts := append([]ProfileFact(nil), saved.Facts...)
+		changes := diffFacts(l.profile.Facts, facts)
+		if len(changes) == 0 {
+			return nil
+		}
+		return l.commitProfile(facts, changes, SourceUser, fmt.Sprintf("rollback to version %d", version))
+	}
+	return fmt.Errorf("profile version %d not found", version)
+}
+
+// GetEntityCount returns the current count for an entity, looked up by any of its names
 func (l *LongTermMemory) GetEntityCount(name string) int {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	if entity, exists := l.entities[name]; exists {
+	key, _ := l.resolveEntity(name)
+	if entity, exists := l.entities[key]; exists {
 		return entity.Count
 	}
 	return 0
 }
+
+// GetEntities returns a copy of all tracked entities
+func (l *LongTermMemory) GetEntities() map[string]Entity {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := make(map[string]Entity, len(l.entities))
+	for name, entity := range l.entities {
+		result[name] = *entity
+	}
+	return result
+}
+
+// EntityNames returns entity names sorted by their decayed score, most
+// relevant first
+func (l *LongTermMemory) EntityNames() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := time.Now()
+	scores := make(map[string]float64, len(l.entities))
+	names := make([]string, 0, len(l.entities))
+	for name, entity := range l.entities {
+		names = append(names, name)
+		scores[name] = entity.ScoreAt(now, l.halfLife)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if scores[names[i]] != scores[names[j]] {
+			return scores[names[i]] > scores[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// EntityHalfLife returns how long it takes an entity score to halve
+func (l *LongTermMemory) EntityHalfLife() time.Duration {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.halfLife
+}
+
+// EntityScore returns the decayed score of an entity, 0 if it is unknown
+func (l *LongTermMemory) EntityScore(name string) float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	key, _ := l.resolveEntity(name)
+	if entity, exists := l.entities[key]; exists {
+		return entity.ScoreAt(time.Now(), l.halfLife)
+	}
+	return 0
+}
+
+// RemoveEntity removes an entity, matching the name case-insensitively and
+// against its aliases, and the profile fact it was promoted to. It returns
+// the removed name, or "" if none matched.
+func (l *LongTermMemory) RemoveEntity(name string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key, _ := l.resolveEntity(name)
+	entity, exists := l.entities[key]
+	if !exists {
+		return "", nil
+	}
+
+	delete(l.entities, key)
+	if entity.Promoted {
+		facts := append([]ProfileFact(nil), l.profile.Facts...)
+		if change, ok := l.demote(&facts, key, entity); ok {
+			if err := l.commitProfile(facts, []ProfileChange{change}, SourceUser, "forgotten"); err != nil {
+				return key, err
+			}
+		}
+	}
+	return key, l.saveEntities()
+}
+
+// SetEntities replaces all entities, used when importing memory
+func (l *LongTermMemory) SetEntities(entities map[string]*Entity) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entities = make(map[string]*Entity, len(entities))
+	for name, entity := range entities {
+		copied := *entity
+		if copied.Score == 0 && copied.Count > 0 {
+			copied.Score = float64(copied.Count)
+		}
+		l.entities[name] = &copied
+	}
+	return l.saveEntities()
+}
+
+// SetProfileMarkdown replaces the user profile, e.g. after the user edited
+// it. Every line is a fact set by the user, facts that did not change keep
+// their source and confidence.
+func (l *LongTermMemory) SetProfileMarkdown(profile string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	facts := keepMetadata(l.profile.Facts, parseProfile(profile, SourceUser, 1, time.Now()))
+	return l.commitProfile(facts, diffFacts(l.profile.Facts, facts), SourceUser, "edited")
+}
+
+// ImportProfile restores profile facts from a snapshot. With merge, facts
+// that are not stored yet are added, otherwise the facts are replaced.
+func (l *LongTermMemory) ImportProfile(facts []ProfileFact, merge bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := append([]ProfileFact(nil), facts...)
+	if merge {
+		result = append([]ProfileFact(nil), l.profile.Facts...)
+		for _, fact := range facts {
+			if findFact(result, fact.Category, fact.Value) < 0 {
+				result = append(result, fact)
+			}
+		}
+	}
+	return l.commitProfile(result, diffFacts(l.profile.Facts, result), SourceImport, "imported")
+}
+
+// RemoveProfileFacts removes the profile facts containing text, ignoring
+// case. It returns the values of the removed facts.
+func (l *LongTermMemory) RemoveProfileFacts(text string) ([]string, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var kept []ProfileFact
+	var removed []string
+	var changes []ProfileChange
+	for _, fact := range l.profile.Facts {
+		if strings.Contains(strings.ToLower(fact.Value), text) {
+			removed = append(removed, fact.Value)
+			changes = append(changes, ProfileChange{Op: OpRemove, Category: fact.Category, Value: fact.Value})
+			continue
+		}
+		kept = append(kept, fact)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, l.commitProfile(kept, changes, SourceUser, "forgotten")
+}
+
+// Clear removes the user profile with its history and all entities
+func (l *LongTermMemory) Clear() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entities = make(map[string]*Entity)
+	l.profile = ProfileData{}
+	l.history = nil
+	if err := l.saveEntities(); err != nil {
+		return err
+	}
+	return l.saveProfile()
+}
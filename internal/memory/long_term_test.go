@@ -59,3 +59,62 @@ func TestLongTermMemory_UpdateProfile(t *testing.T) {
 		t.Error("Expected empty profile markdown initially")
 	}
 }
+
+func TestLongTermMemory_EditAndClear(t *testing.T) {
+	tmpDir := t.TempDir()
+	ltm := NewLongTermMemory(tmpDir, 5)
+
+	ltm.UpdateEntity("Go")
+	ltm.UpdateEntity("Go")
+	ltm.UpdateEntity("React")
+	if names := ltm.EntityNames(); len(names) != 2 || names[0] != "Go" {
+		t.Errorf("Expected entities sorted by count, got %v", names)
+	}
+
+	// Entities are matched case-insensitively when there is no exact match
+	removed, err := ltm.RemoveEntity("react")
+	if err != nil || removed != "React" {
+		t.Fatalf("RemoveEntity = %q, %v", removed, err)
+	}
+	if removed, _ := ltm.RemoveEntity("Rust"); removed != "" {
+		t.Errorf("Expected no match for unknown entity, got %q", removed)
+	}
+
+	if err := ltm.SetProfileMarkdown("## Tech\n- Go\n"); err != nil {
+		t.Fatalf("SetProfileMarkdown failed: %v", err)
+	}
+	reloaded := NewLongTermMemory(tmpDir, 5)
+	if reloaded.GetProfileMarkdown() != "## Tech\n- Go" || reloaded.GetEntityCount("React") != 0 || reloaded.GetEntityCount("Go") != 2 {
+		t.Errorf("Edits not persisted: profile %q, entities %v", reloaded.GetProfileMarkdown(), reloaded.GetEntities())
+	}
+
+	if err := reloaded.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	reloaded = NewLongTermMemory(tmpDir, 5)
+	if reloaded.GetProfileMarkdown() != "" || len(reloaded.GetEntities()) != 0 {
+		t.Error("Expected long-term memory to be empty after Clear")
+	}
+}
+
+func TestLongTermMemory_RemoveProfileFacts(t *testing.T) {
+	ltm := NewLongTermMemory(t.TempDir(), 5)
+	if err := ltm.SetProfileMarkdown("## Tools\n- Uses Docker\n- Uses docker compose\n- Uses Go"); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := ltm.RemoveProfileFacts("DOCKER")
+	if err != nil {
+		t.Fatalf("RemoveProfileFacts failed: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected 2 removed facts, got %v", removed)
+	}
+	if got := ltm.GetProfileMarkdown(); got != "## Tools\n- Uses Go" {
+		t.Errorf("unexpected profile: %q", got)
+	}
+
+	if removed, _ := ltm.RemoveProfileFacts("tools"); len(removed) != 0 {
+		t.Errorf("categories should not match, removed %v", removed)
+	}
+}
@@ -2,11 +2,13 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
@@ -26,14 +28,24 @@ type ConversationMessage interface {
 }
 
 // Manager provides unified interface for multi-level memory management
+//
+// Besides the global profile, summaries, entities and remembered facts,
+// memory has a project layer, set with SetProject, and a conversation layer
+// of notes.
 type Manager struct {
 	config       *Config
+	storagePath  string
+	notes        *NoteStore
+	project      *Project
+	projectNotes *NoteStore
 	shortTerm    *ShortTermMemory
 	longTerm     *LongTermMemory
 	extractor    *Extractor
+	retriever    *Retriever
 	aiProvider   ai.AIProvider
 	promptLoader *PromptLoader
 	wg           sync.WaitGroup
+	checkpoint   atomic.Bool // set while a checkpoint is being written
 }
 
 // NewManager creates a new memory manager
@@ -46,17 +58,176 @@ func NewManager(config *Config, aiProvider ai.AIProvider, promptLoader *PromptLo
 
 	longTerm := NewLongTermMemory(storagePath, config.EntityThreshold)
 	longTerm.SetAIProvider(aiProvider, promptLoader)
+	longTerm.SetEntityHalfLife(time.Duration(config.EntityHalfLifeDays) * 24 * time.Hour)
 
 	return &Manager{
 		config:       config,
+		storagePath:  storagePath,
+		notes:        NewNoteStore(globalNotesPath(storagePath), ScopeGlobal, ""),
 		shortTerm:    NewShortTermMemory(storagePath, config.ShortTermMaxTokens),
 		longTerm:     longTerm,
 		extractor:    NewExtractor(aiProvider, promptLoader),
+		retriever:    NewRetriever(config.RetrievalTopK, config.RetrievalTokens),
 		aiProvider:   aiProvider,
 		promptLoader: promptLoader,
 	}, nil
 }
 
+// SetEmbedder scores memory with embeddings instead of BM25. minScore is the
+// cosine similarity a summary or entity needs to be injected.
+func (m *Manager) SetEmbedder(embedder Embedder, minScore float64) {
+	m.retriever.SetEmbedder(embedder, minScore)
+}
+
+// SetProject scopes memory to a project, see DetectProject. Summaries of
+// later conversations are tagged with the project, and summaries tagged with
+// other projects are no longer used.
+func (m *Manager) SetProject(project Project) {
+	m.project = &project
+	m.projectNotes = NewNoteStore(projectNotesPath(m.storagePath, project), ScopeProject, project.Root)
+}
+
+// Project returns the current project, or nil when memory is not project scoped
+func (m *Manager) Project() *Project {
+	return m.project
+}
+
+// ProjectNotes returns the notes of the current project, or nil when memory is
+// not project scoped
+func (m *Manager) ProjectNotes() *NoteStore {
+	return m.projectNotes
+}
+
+// Notes returns the facts remembered for every chat
+func (m *Manager) Notes() *NoteStore {
+	return m.notes
+}
+
+// ConversationNotes returns the notes of a conversation
+func (m *Manager) ConversationNotes(convID string) *NoteStore {
+	return NewNoteStore(conversationNotesPath(m.storagePath, convID), ScopeConversation, convID)
+}
+
+// ShortTerm returns the short-term memory holding conversation summaries
+func (m *Manager) ShortTerm() *ShortTermMemory {
+	return m.shortTerm
+}
+
+// LongTerm returns the long-term memory holding the profile and entities
+func (m *Manager) LongTerm() *LongTermMemory {
+	return m.longTerm
+}
+
+// Forget removes an entity, or a summary when no entity matches target
+func (m *Manager) Forget(target string) (string, error) {
+	name, err := m.longTerm.RemoveEntity(target)
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		return fmt.Sprintf("entity %q", name), nil
+	}
+
+	summary, err := m.shortTerm.RemoveSummary(target)
+	if err == nil {
+		return fmt.Sprintf("summary %s", summary.ID), nil
+	}
+	note, err := m.notes.RemoveNote(target)
+	if err != nil {
+		return "", fmt.Errorf("no entity, summary or fact matches %q", target)
+	}
+	return fmt.Sprintf("fact %q", note.Text), nil
+}
+
+// Remember stores a fact immediately in the notes of scope. convID is only
+// used for ScopeConversation.
+func (m *Manager) Remember(scope Scope, convID, fact string) (*Note, error) {
+	switch scope {
+	case ScopeGlobal:
+		return m.notes.AddNote(fact)
+	case ScopeProject:
+		if m.projectNotes == nil {
+			return nil, fmt.Errorf("memory is not scoped to a project")
+		}
+		return m.projectNotes.AddNote(fact)
+	case ScopeConversation:
+		if convID == "" {
+			return nil, fmt.Errorf("no conversation to remember for")
+		}
+		return m.ConversationNotes(convID).AddNote(fact)
+	default:
+		return nil, fmt.Errorf("unknown memory scope: %s", scope)
+	}
+}
+
+// ErrNoMatch is returned by ForgetFact when no memory contains the fact
+var ErrNoMatch = errors.New("no memory matches")
+
+// ForgetFact removes the remembered notes and profile lines containing fact,
+// and the entity named fact. It returns what was removed.
+func (m *Manager) ForgetFact(convID, fact string) ([]string, error) {
+	if strings.TrimSpace(fact) == "" {
+		return nil, fmt.Errorf("nothing to forget")
+	}
+
+	stores := []*NoteStore{m.notes}
+	if m.projectNotes != nil {
+		stores = append(stores, m.projectNotes)
+	}
+	if convID != "" {
+		stores = append(stores, m.ConversationNotes(convID))
+	}
+
+	var removed []string
+	for _, store := range stores {
+		notes, err := store.RemoveMatching(fact)
+		if err != nil {
+			return removed, err
+		}
+		for _, note := range notes {
+			removed = append(removed, note.Text)
+		}
+	}
+
+	lines, err := m.longTerm.RemoveProfileFacts(fact)
+	if err != nil {
+		return removed, err
+	}
+	removed = append(removed, lines...)
+
+	name, err := m.longTerm.RemoveEntity(strings.TrimSpace(fact))
+	if err != nil {
+		return removed, err
+	}
+	if name != "" {
+		removed = append(removed, fmt.Sprintf("entity %q", name))
+	}
+
+	if len(removed) == 0 {
+		return nil, fmt.Errorf("%w %q", ErrNoMatch, fact)
+	}
+	return removed, nil
+}
+
+// Clear removes short-term and/or long-term memory. Long-term memory includes
+// the remembered facts.
+func (m *Manager) Clear(short, long bool) error {
+	if short {
+		if err := m.shortTerm.Clear(); err != nil {
+			return fmt.Errorf("failed to clear short-term memory: %w", err)
+		}
+	}
+	if long {
+		if err := m.longTerm.Clear(); err != nil {
+			return fmt.Errorf("failed to clear long-term memory: %w", err)
+		}
+		if err := m.notes.Clear(); err != nil {
+			return fmt.Errorf("failed to clear remembered facts: %w", err)
+		}
+	}
+	return nil
+}
+
 // OnSessionEnd processes a completed conversation
 func (m *Manager) OnSessionEnd(conv Conversation) error {
 	if m == nil {
@@ -71,6 +242,87 @@ func (m *Manager) OnSessionEnd(conv Conversation) error {
 	return nil
 }
 
+// OnTurn is called after each completed turn of a conversation. Every
+// CheckpointTurns turns the conversation is summarized into short-term
+// memory, so it is not lost when the session does not end cleanly.
+func (m *Manager) OnTurn(conv Conversation) {
+	if m == nil || m.config.CheckpointTurns <= 0 {
+		return
+	}
+
+	turns := 0
+	for _, msg := range conv.GetMessages() {
+		if msg.Role() == "user" {
+			turns++
+		}
+	}
+	if turns == 0 || turns%m.config.CheckpointTurns != 0 {
+		return
+	}
+	// A slow checkpoint is still running, the next one will catch up
+	if !m.checkpoint.CompareAndSwap(false, true) {
+		return
+	}
+
+	// Copy the conversation, the caller keeps changing it
+	snapshot := snapshotConversation(conv)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer m.checkpoint.Store(false)
+		if err := m.writeCheckpoint(context.Background(), snapshot); err != nil {
+			log.Printf("[memory] Error writing checkpoint: %v", err)
+		}
+	}()
+}
+
+// writeCheckpoint stores a summary of the conversation so far. Entities and
+// the profile are only updated when the session ends, so counts are not
+// inflated by repeated checkpoints.
+func (m *Manager) writeCheckpoint(ctx context.Context, conv Conversation) error {
+	log.Printf("[memory] Writing checkpoint for conversation %s", conv.ID())
+	summary, err := m.generateSummary(ctx, conv)
+	if err != nil {
+		return err
+	}
+	return m.shortTerm.UpsertSummary(&Summary{
+		ConversationID: conv.ID(),
+		Project:        m.projectRoot(),
+		Summary:        summary,
+		Timestamp:      conv.UpdatedAt(),
+		Tokens:         estimateTokens(summary),
+	})
+}
+
+// conversationSnapshot is a copy of a conversation for async processing
+type conversationSnapshot struct {
+	id        string
+	messages  []ConversationMessage
+	updatedAt time.Time
+}
+
+func (c *conversationSnapshot) ID() string                         { return c.id }
+func (c *conversationSnapshot) GetMessages() []ConversationMessage { return c.messages }
+func (c *conversationSnapshot) UpdatedAt() time.Time               { return c.updatedAt }
+
+// snapshotMessage is a copied ConversationMessage
+type snapshotMessage struct {
+	role, content string
+}
+
+func (m snapshotMessage) Role() string    { return m.role }
+func (m snapshotMessage) Content() string { return m.content }
+
+// snapshotConversation copies the messages of conv
+func snapshotConversation(conv Conversation) *conversationSnapshot {
+	messages := conv.GetMessages()
+	copied := make([]ConversationMessage, len(messages))
+	for i, msg := range messages {
+		copied[i] = snapshotMessage{role: msg.Role(), content: msg.Content()}
+	}
+	return &conversationSnapshot{id: conv.ID(), messages: copied, updatedAt: conv.UpdatedAt()}
+}
+
 // Wait waits for all pending async operations to complete
 func (m *Manager) Wait() {
 	if m == nil {
@@ -98,11 +350,13 @@ func (m *Manager) processSessionEndAsync(conv Conversation) {
 	log.Printf("[memory] Step 2: Writing to short-term memory...")
 	summaryRecord := &Summary{
 		ConversationID: conv.ID(),
+		Project:        m.projectRoot(),
 		Summary:        summary,
 		Timestamp:      conv.UpdatedAt(),
 		Tokens:         estimateTokens(summary),
 	}
-	m.shortTerm.AddSummary(summaryRecord)
+	// Replaces the checkpoints written during the session
+	m.shortTerm.UpsertSummary(summaryRecord)
 
 	// Step 3: Extract entities using LLM
 	log.Printf("[memory] Step 3: Extracting entities...")
@@ -121,6 +375,10 @@ func (m *Manager) processSessionEndAsync(conv Conversation) {
 			log.Printf("[memory] Entity '%s' promoted to profile", entity)
 		}
 	}
+	// Categorize new entities and merge other spellings of known ones
+	if err := m.longTerm.CanonicalizeWithLLM(ctx); err != nil {
+		log.Printf("[memory] Error canonicalizing entities: %v", err)
+	}
 
 	// Step 5: Update profile using LLM with new information
 	log.Printf("[memory] Step 5: Updating user profile...")
@@ -130,7 +388,7 @@ func (m *Manager) processSessionEndAsync(conv Conversation) {
 	if len(extraction.Context) > 0 {
 		newInfo += fmt.Sprintf("\nTopics Discussed: %s", strings.Join(extraction.Context, ", "))
 	}
-	err = m.longTerm.UpdateProfileWithLLM(ctx, newInfo)
+	err = m.longTerm.UpdateProfileWithLLM(ctx, conv.ID(), newInfo)
 	if err != nil {
 		log.Printf("[memory] Error updating profile: %v", err)
 		return
@@ -166,13 +424,37 @@ func (m *Manager) generateSummary(ctx context.Context, conv Conversation) (strin
 	return strings.TrimSpace(summary), nil
 }
 
+// projectRoot returns the root of the current project, or "" when memory is not project scoped
+func (m *Manager) projectRoot() string {
+	if m.project == nil {
+		return ""
+	}
+	return m.project.Root
+}
+
 // BuildContext constructs messages with memory context for AI calls
+//
+// Only the summaries and entities relevant to the last user message are
+// included, see Retriever.
 func (m *Manager) BuildContext(currentMessages []ai.Message) []ai.Message {
+	return m.BuildContextFor("", currentMessages)
+}
+
+// BuildContextFor is BuildContext for a conversation, adding its notes to
+// the project and global memory
+func (m *Manager) BuildContextFor(convID string, currentMessages []ai.Message) []ai.Message {
 	if m == nil {
 		return currentMessages
 	}
 
-	systemPrompt := m.buildSystemPrompt()
+	query := ""
+	for i := len(currentMessages) - 1; i >= 0; i-- {
+		if currentMessages[i].Role == "user" {
+			query = currentMessages[i].Content
+			break
+		}
+	}
+	systemPrompt := m.buildSystemPrompt(convID, query)
 
 	// Prepend system prompt with memory context
 	result := []ai.Message{
@@ -182,18 +464,44 @@ func (m *Manager) BuildContext(currentMessages []ai.Message) []ai.Message {
 	return append(result, currentMessages...)
 }
 
-// buildSystemPrompt creates system prompt with memory context
-func (m *Manager) buildSystemPrompt() string {
+// buildSystemPrompt creates system prompt with the memory relevant to query.
+// Layers are listed from most to least specific.
+func (m *Manager) buildSystemPrompt(convID, query string) string {
+	// Notes are few and written on purpose, so they are always included
+	var conversationParts, projectParts []string
+	if convID != "" {
+		conversationParts = formatNotes(m.ConversationNotes(convID).GetNotes())
+	}
+	if m.projectNotes != nil {
+		projectParts = formatNotes(m.projectNotes.GetNotes())
+	}
+	factParts := formatNotes(m.notes.GetNotes())
+
 	// Get profile markdown directly
 	profileMD := m.longTerm.GetProfileMarkdown()
 
-	// Build summaries section (only the list items, template includes the header)
-	summaries := m.shortTerm.GetSummaries()
+	ctx, cancel := retrievalContext()
+	defer cancel()
+
+	// Build summaries section (only the list items, template includes the header).
+	// Summaries from other projects would leak unrelated facts into this chat.
+	var candidates []Summary
+	for _, s := range append(m.shortTerm.GetSummaries(), m.shortTerm.GetArchive()...) {
+		if s.Project == "" || m.project == nil || s.Project == m.project.Root {
+			candidates = append(candidates, s)
+		}
+	}
 	var summaryParts []string
-	for _, s := range summaries {
+	for _, s := range m.retriever.SelectSummaries(ctx, query, candidates) {
 		summaryParts = append(summaryParts, fmt.Sprintf("- %s", s.Summary))
 	}
 
+	entities := m.longTerm.GetEntities()
+	var entityParts []string
+	for _, name := range m.retriever.SelectEntities(ctx, query, m.longTerm.EntityNames()) {
+		entityParts = append(entityParts, fmt.Sprintf("- %s (mentioned %d times)", name, entities[name].Count))
+	}
+
 	// Try to load system template, or use default
 	systemPrompt := "You are tada, a terminal AI assistant."
 	if m.promptLoader != nil {
@@ -201,21 +509,52 @@ func (m *Manager) buildSystemPrompt() string {
 		if err == nil {
 			// Replace placeholders
 			systemPrompt = template.SystemPrompt
+			// Templates written before the project and conversation layers
+			// existed have no placeholders for them
+			if !strings.Contains(systemPrompt, "{{project_notes}}") && len(projectParts) > 0 {
+				systemPrompt += "\n\n" + projectNotesHeader + "\n{{project_notes}}"
+			}
+			if !strings.Contains(systemPrompt, "{{conversation_notes}}") && len(conversationParts) > 0 {
+				systemPrompt += "\n\n" + conversationNotesHeader + "\n{{conversation_notes}}"
+			}
+			if !strings.Contains(systemPrompt, "{{facts}}") && len(factParts) > 0 {
+				systemPrompt += "\n\n" + factsHeader + "\n{{facts}}"
+			}
+			systemPrompt = strings.ReplaceAll(systemPrompt, "{{facts}}", strings.Join(factParts, "\n"))
+			systemPrompt = strings.ReplaceAll(systemPrompt, "{{conversation_notes}}", strings.Join(conversationParts, "\n"))
+			systemPrompt = strings.ReplaceAll(systemPrompt, "{{project_notes}}", strings.Join(projectParts, "\n"))
 			systemPrompt = strings.ReplaceAll(systemPrompt, "{{profile}}", profileMD)
 			systemPrompt = strings.ReplaceAll(systemPrompt, "{{summaries}}", strings.Join(summaryParts, "\n"))
+			systemPrompt = strings.ReplaceAll(systemPrompt, "{{entities}}", strings.Join(entityParts, "\n"))
 			return systemPrompt
 		}
 	}
 
 	// Fallback to default behavior - add headers manually
 	var parts []string
+	if len(conversationParts) > 0 {
+		parts = append(parts, conversationNotesHeader)
+		parts = append(parts, conversationParts...)
+	}
+	if len(projectParts) > 0 {
+		parts = append(parts, projectNotesHeader)
+		parts = append(parts, projectParts...)
+	}
+	if len(factParts) > 0 {
+		parts = append(parts, factsHeader)
+		parts = append(parts, factParts...)
+	}
 	if profileMD != "" {
 		parts = append(parts, profileMD)
 	}
 	if len(summaryParts) > 0 {
-		parts = append(parts, "## Recent Conversations")
+		parts = append(parts, "## Related Conversations")
 		parts = append(parts, summaryParts...)
 	}
+	if len(entityParts) > 0 {
+		parts = append(parts, "## Related Topics")
+		parts = append(parts, entityParts...)
+	}
 
 	if len(parts) == 0 {
 		return systemPrompt
@@ -228,6 +567,22 @@ func (m *Manager) buildSystemPrompt() string {
 Use this context to provide more personalized responses.`, systemPrompt, strings.Join(parts, "\n"))
 }
 
+// Headers of the notes sections, used when the system template has no placeholder for them
+const (
+	conversationNotesHeader = "## Conversation Notes (override project notes and profile)"
+	projectNotesHeader      = "## Project Notes (override the profile)"
+	factsHeader             = "## Remembered Facts"
+)
+
+// formatNotes formats notes as a markdown list
+func formatNotes(notes []Note) []string {
+	parts := make([]string, len(notes))
+	for i, note := range notes {
+		parts[i] = "- " + note.Text
+	}
+	return parts
+}
+
 // estimateTokens roughly estimates token count (1 token ≈ 4 characters)
 func estimateTokens(text string) int {
 	return (len(text) + 3) / 4
@@ -1,6 +1,10 @@
 package memory
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -36,6 +40,154 @@ func TestManager_BuildContext(t *testing.T) {
 	}
 }
 
+func TestManager_BuildContext_Retrieval(t *testing.T) {
+	mgr := newTestManager(t)
+	for _, text := range []string{"Configured nginx as a reverse proxy", "Fixed a goroutine leak in Go"} {
+		if err := mgr.shortTerm.AddSummary(&Summary{Summary: text, Timestamp: time.Now(), Tokens: 10}); err != nil {
+			t.Fatalf("AddSummary failed: %v", err)
+		}
+	}
+	for _, name := range []string{"nginx", "Go"} {
+		if _, err := mgr.longTerm.UpdateEntity(name); err != nil {
+			t.Fatalf("UpdateEntity failed: %v", err)
+		}
+	}
+
+	system := mgr.BuildContext([]ai.Message{{Role: "user", Content: "my go program has a goroutine leak"}})[0].Content
+	if !strings.Contains(system, "goroutine leak") || !strings.Contains(system, "- Go (mentioned 1 times)") {
+		t.Errorf("expected relevant memory in system prompt, got:\n%s", system)
+	}
+	if strings.Contains(system, "nginx") {
+		t.Errorf("unrelated memory should not be in system prompt, got:\n%s", system)
+	}
+}
+
+func TestManager_BuildContext_Scopes(t *testing.T) {
+	mgr := newTestManager(t)
+	mgr.SetProject(Project{Root: "/src/api", Name: "api"})
+	if err := mgr.LongTerm().SetProfileMarkdown("- Prefers tabs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.ProjectNotes().AddNote("This repo uses spaces"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.ConversationNotes("conv-1").AddNote("Answer in English"); err != nil {
+		t.Fatal(err)
+	}
+	summaries := []*Summary{
+		{Summary: "Tuned the api database pool", Project: "/src/api", Tokens: 10},
+		{Summary: "Tuned the web database pool", Project: "/src/web", Tokens: 10},
+	}
+	for _, s := range summaries {
+		if err := mgr.ShortTerm().AddSummary(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	system := mgr.BuildContextFor("conv-1", []ai.Message{{Role: "user", Content: "database pool size?"}})[0].Content
+
+	// Layers are listed from most to least specific
+	conv := strings.Index(system, "Answer in English")
+	project := strings.Index(system, "This repo uses spaces")
+	profile := strings.Index(system, "Prefers tabs")
+	if conv < 0 || project < 0 || profile < 0 || !(conv < project && project < profile) {
+		t.Errorf("expected conversation, project and profile memory in order, got:\n%s", system)
+	}
+	if !strings.Contains(system, "api database pool") || strings.Contains(system, "web database pool") {
+		t.Errorf("expected only summaries of the current project, got:\n%s", system)
+	}
+
+	// Other conversations do not see the conversation notes
+	system = mgr.BuildContextFor("conv-2", []ai.Message{{Role: "user", Content: "hi"}})[0].Content
+	if strings.Contains(system, "Answer in English") {
+		t.Errorf("conversation notes leaked into another conversation:\n%s", system)
+	}
+}
+
+func TestManager_RememberAndForgetFact(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := mgr.LongTerm().SetProfileMarkdown("# Profile\n- Uses vim\n- Lives in Hangzhou"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.LongTerm().UpdateEntity("vim"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Remember(ScopeGlobal, "", "Prefers vim keybindings"); err != nil {
+		t.Fatalf("Remember failed: %v", err)
+	}
+	if _, err := mgr.Remember(ScopeProject, "", "Uses make"); err == nil {
+		t.Error("expected error without a project")
+	}
+
+	system := mgr.BuildContext([]ai.Message{{Role: "user", Content: "hi"}})[0].Content
+	if !strings.Contains(system, "Prefers vim keybindings") {
+		t.Errorf("expected remembered fact in system prompt, got:\n%s", system)
+	}
+
+	removed, err := mgr.ForgetFact("", "vim")
+	if err != nil {
+		t.Fatalf("ForgetFact failed: %v", err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("expected fact, profile line and entity to be removed, got %v", removed)
+	}
+	if profile := mgr.LongTerm().GetProfileMarkdown(); profile != "- Lives in Hangzhou" {
+		t.Errorf("unexpected profile: %q", profile)
+	}
+	if len(mgr.Notes().GetNotes()) != 0 {
+		t.Error("expected fact to be forgotten")
+	}
+
+	if _, err := mgr.ForgetFact("", "emacs"); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch when nothing matches, got %v", err)
+	}
+
+	// A file that cannot be written is reported as it is, not as no match
+	if _, err := mgr.Remember(ScopeGlobal, "", "Uses tmux"); err != nil {
+		t.Fatal(err)
+	}
+	notesPath := mgr.notes.path
+	os.Remove(notesPath)
+	os.MkdirAll(filepath.Join(notesPath, "blocked"), 0700)
+	if _, err := mgr.ForgetFact("", "tmux"); err == nil || errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected the write error, got %v", err)
+	}
+}
+
+func TestManager_OnTurnCheckpoint(t *testing.T) {
+	config := DefaultConfig()
+	config.StoragePath = t.TempDir()
+	config.CheckpointTurns = 2
+	mgr, err := NewManager(config, &MockAIProvider{response: "Talked about Go"}, nil)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	conv := &mockConversation{id: "conv", updatedAt: time.Now()}
+	turn := func() {
+		conv.messages = append(conv.messages,
+			&mockMessage{role: "user", content: "question"},
+			&mockMessage{role: "assistant", content: "answer"})
+		mgr.OnTurn(conv)
+		mgr.Wait()
+	}
+
+	turn()
+	if len(mgr.ShortTerm().GetSummaries()) != 0 {
+		t.Fatal("expected no checkpoint after the first turn")
+	}
+	turn()
+	if len(mgr.ShortTerm().GetSummaries()) != 1 {
+		t.Fatal("expected a checkpoint after the second turn")
+	}
+	turn()
+	turn()
+	summaries := mgr.ShortTerm().GetSummaries()
+	if len(summaries) != 1 || summaries[0].ConversationID != "conv" {
+		t.Errorf("expected the checkpoint to be replaced, got %+v", summaries)
+	}
+}
+
 func TestManager_OnSessionEnd(t *testing.T) {
 	tmpDir := t.TempDir()
 	config := DefaultConfig()
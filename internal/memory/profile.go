@@ -0,0 +1,428 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Sources of profile facts that are not conversations
+const (
+	SourceUser      = "user"      // Edited, remembered or rolled back by the user
+	SourceImport    = "import"    // Imported with tada memory import
+	SourceMigration = "migration" // Converted from the user_profile.md of an older version
+	SourceEntities  = "entities"  // Promoted from an entity mentioned often
+)
+
+// Limits applied to the profile changes proposed by the model
+const (
+	// maxModelConfidence keeps model facts below facts set by the user, which have confidence 1
+	maxModelConfidence = 0.9
+	defaultConfidence  = 0.5
+	migratedConfidence = 0.7
+	maxProfileChanges  = 20
+	maxFactLength      = 200
+	maxProfileVersions = 50
+)
+
+// Profile change operations
+const (
+	OpAdd    = "add"
+	OpUpdate = "update"
+	OpRemove = "remove"
+)
+
+// ProfileFact is a single fact of the user profile
+type ProfileFact struct {
+	ID         string    `json:"id"`
+	Category   string    `json:"category"`
+	Value      string    `json:"value"`
+	Source     string    `json:"source"` // Conversation ID the fact was learned from, or SourceUser etc.
+	Confidence float64   `json:"confidence"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ProfileChange is a change to the profile, proposed by the model or made by the user
+type ProfileChange struct {
+	Op         string  `json:"op"`
+	Category   string  `json:"category"`
+	Value      string  `json:"value"`
+	OldValue   string  `json:"old_value,omitempty"` // The value replaced by an update
+	Confidence float64 `json:"confidence,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// ProfileVersion is a saved state of the profile
+type ProfileVersion struct {
+	Version int             `json:"version"`
+	Time    time.Time       `json:"time"`
+	Source  string          `json:"source"`
+	Note    string          `json:"note,omitempty"`
+	Changes []ProfileChange `json:"changes"`
+	Facts   []ProfileFact   `json:"facts"` // The profile after the change
+}
+
+// ProfileData holds the profile.json structure
+type ProfileData struct {
+	Version int           `json:"version"`
+	Facts   []ProfileFact `json:"facts"`
+}
+
+// profileCategory describes a known category of facts
+type profileCategory struct {
+	title  string
+	single bool // Only one value at a time, a new value replaces the old one
+}
+
+// profileCategories are the categories of UserProfile. Other categories are
+// allowed and rendered with their own name.
+var profileCategories = map[string]profileCategory{
+	"languages":        {title: "Languages"},
+	"frameworks":       {title: "Frameworks"},
+	"editors":          {title: "Editors"},
+	"tools":            {title: "Tools"},
+	"current_projects": {title: "Current Projects"},
+	"common_paths":     {title: "Common Paths"},
+	"communication":    {title: "Communication Style", single: true},
+	"often_asks":       {title: "Often Asks"},
+	"people":           {title: "People"},
+	"timezone":         {title: "Timezone", single: true},
+	"shell":            {title: "Shell", single: true},
+}
+
+// categoryOrder is the order known categories are rendered in
+var categoryOrder = []string{
+	"languages", "frameworks", "editors", "tools", "shell", "timezone",
+	"current_projects", "common_paths", "people", "communication", "often_asks",
+}
+
+// otherCategory holds facts without a category, rendered without a heading
+const otherCategory = "other"
+
+// normalizeCategory maps a category name or heading to its key
+func normalizeCategory(name string) string {
+	name = strings.TrimSpace(name)
+	for key, category := range profileCategories {
+		if strings.EqualFold(name, category.title) {
+			return key
+		}
+	}
+	key := strings.ToLower(name)
+	key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+	if key == "" {
+		return otherCategory
+	}
+	return key
+}
+
+// categoryTitle returns the heading of a category
+func categoryTitle(key string) string {
+	if category, ok := profileCategories[key]; ok {
+		return category.title
+	}
+	title := strings.ReplaceAll(key, "_", " ")
+	if title == "" {
+		return title
+	}
+	first, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(first)) + title[size:]
+}
+
+// sameFact reports whether a fact has the given category and value
+func sameFact(fact ProfileFact, category, value string) bool {
+	return fact.Category == category && strings.EqualFold(fact.Value, value)
+}
+
+// renderProfile formats facts as markdown, uncategorized facts first and
+// then one section per category
+func renderProfile(facts []ProfileFact) string {
+	byCategory := make(map[string][]string)
+	var extra []string
+	for _, fact := range facts {
+		if _, known := profileCategories[fact.Category]; !known && fact.Category != otherCategory && len(byCategory[fact.Category]) == 0 {
+			extra = append(extra, fact.Category)
+		}
+		byCategory[fact.Category] = append(byCategory[fact.Category], fact.Value)
+	}
+
+	var sections []string
+	if values := byCategory[otherCategory]; len(values) > 0 {
+		sections = append(sections, "- "+strings.Join(values, "\n- "))
+	}
+	for _, key := range append(append([]string(nil), categoryOrder...), extra...) {
+		if values := byCategory[key]; len(values) > 0 {
+			sections = append(sections, fmt.Sprintf("## %s\n- %s", categoryTitle(key), strings.Join(values, "\n- ")))
+		}
+	}
+	return strings.Join(sections, "\n")
+}
+
+// parseProfile reads facts from profile markdown. "## " and deeper headings
+// start a category, the top level heading is ignored, and every list item or
+// other line is a fact.
+func parseProfile(markdown, source string, confidence float64, now time.Time) []ProfileFact {
+	var facts []ProfileFact
+	category := otherCategory
+	for _, line := range strings.Split(markdown, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "##"):
+			category = normalizeCategory(strings.TrimLeft(line, "# "))
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+		for _, marker := range []string{"-", "*"} {
+			if line == marker || strings.HasPrefix(line, marker+" ") {
+				line = strings.TrimSpace(line[len(marker):])
+			}
+		}
+		if line == "" {
+			continue
+		}
+		exists := false
+		for _, fact := range facts {
+			if sameFact(fact, category, line) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			facts = append(facts, newFact(category, line, source, confidence, now))
+		}
+	}
+	return facts
+}
+
+// newFact creates a fact
+func newFact(category, value, source string, confidence float64, now time.Time) ProfileFact {
+	return ProfileFact{
+		ID:         uuid.New().String(),
+		Category:   category,
+		Value:      value,
+		Source:     source,
+		Confidence: confidence,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// buildUserProfile fills the structured UserProfile from facts
+func buildUserProfile(facts []ProfileFact) *UserProfile {
+	profile := &UserProfile{}
+	for _, fact := range facts {
+		switch fact.Category {
+		case "languages":
+			profile.TechPreferences.Languages = append(profile.TechPreferences.Languages, fact.Value)
+		case "frameworks":
+			profile.TechPreferences.Frameworks = append(profile.TechPreferences.Frameworks, fact.Value)
+		case "editors":
+			profile.TechPreferences.Editors = append(profile.TechPreferences.Editors, fact.Value)
+		case "current_projects":
+			profile.WorkContext.CurrentProjects = append(profile.WorkContext.CurrentProjects, fact.Value)
+		case "common_paths":
+			profile.WorkContext.CommonPaths = append(profile.WorkContext.CommonPaths, fact.Value)
+		case "communication":
+			profile.BehaviorPatterns.PreferredCommunication = fact.Value
+		case "often_asks":
+			profile.BehaviorPatterns.OftenAsks = append(profile.BehaviorPatterns.OftenAsks, fact.Value)
+		case "timezone":
+			profile.PersonalSettings.Timezone = fact.Value
+		case "shell":
+			profile.PersonalSettings.Shell = fact.Value
+		}
+	}
+	return profile
+}
+
+// parseProfileDiff decodes the changes proposed by the model. The JSON may be
+// wrapped in a markdown code block.
+func parseProfileDiff(response string) ([]ProfileChange, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("profile update is not JSON")
+	}
+
+	var diff struct {
+		Changes []ProfileChange `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &diff); err != nil {
+		return nil, fmt.Errorf("invalid profile update: %w", err)
+	}
+	return diff.Changes, nil
+}
+
+// validateChanges normalizes the proposed changes and drops the invalid ones.
+// It fails when the proposal looks like it would wipe the profile.
+func validateChanges(changes []ProfileChange, facts []ProfileFact) ([]ProfileChange, error) {
+	if len(changes) > maxProfileChanges {
+		return nil, fmt.Errorf("profile update has %d changes, at most %d are allowed", len(changes), maxProfileChanges)
+	}
+
+	var valid []ProfileChange
+	removals := 0
+	for _, change := range changes {
+		change.Op = strings.ToLower(strings.TrimSpace(change.Op))
+		change.Category = normalizeCategory(change.Category)
+		change.Value = strings.TrimSpace(change.Value)
+		change.OldValue = strings.TrimSpace(change.OldValue)
+
+		if change.Op != OpAdd && change.Op != OpUpdate && change.Op != OpRemove {
+			continue
+		}
+		if change.Value == "" || len(change.Value) > maxFactLength || strings.ContainsAny(change.Value, "\r\n") {
+			continue
+		}
+		if change.Confidence <= 0 {
+			change.Confidence = defaultConfidence
+		}
+		change.Confidence = min(change.Confidence, maxModelConfidence)
+
+		// Updates and removals must refer to an existing fact
+		if change.Op != OpAdd {
+			target := change.Value
+			if change.Op == OpUpdate {
+				target = change.OldValue
+			}
+			if findFact(facts, change.Category, target) < 0 {
+				continue
+			}
+		}
+		if change.Op == OpRemove {
+			removals++
+		}
+		valid = append(valid, change)
+	}
+
+	if len(facts) >= 4 && removals*2 > len(facts) {
+		return nil, fmt.Errorf("profile update would remove %d of %d facts", removals, len(facts))
+	}
+	return valid, nil
+}
+
+// findFact returns the index of the fact with category and value, or -1
+func findFact(facts []ProfileFact, category, value string) int {
+	for i, fact := range facts {
+		if sameFact(fact, category, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyChanges merges validated model changes into facts and returns the new
+// facts and the changes that were applied.
+//
+// Conflicts are resolved by confidence: a fact is only replaced or removed by
+// a change at least as confident, so facts set by the user are never
+// overwritten by the model. In single valued categories such as the shell a
+// new value replaces the old one under the same rule.
+func applyChanges(facts []ProfileFact, changes []ProfileChange, source string, now time.Time) ([]ProfileFact, []ProfileChange) {
+	result := append([]ProfileFact(nil), facts...)
+	var applied []ProfileChange
+
+	for _, change := range changes {
+		switch change.Op {
+		case OpRemove:
+			i := findFact(result, change.Category, change.Value)
+			if i < 0 || result[i].Confidence > change.Confidence {
+				continue
+			}
+			result = append(result[:i], result[i+1:]...)
+			applied = append(applied, change)
+
+		case OpAdd, OpUpdate:
+			target := -1
+			if change.Op == OpUpdate {
+				target = findFact(result, change.Category, change.OldValue)
+			}
+			if existing := findFact(result, change.Category, change.Value); existing >= 0 {
+				// Known fact: confirm it, raising the confidence
+				if result[existing].Confidence < change.Confidence {
+					result[existing].Confidence = change.Confidence
+				}
+				result[existing].UpdatedAt = now
+				if target >= 0 && target != existing && result[target].Confidence <= change.Confidence {
+					result = append(result[:target], result[target+1:]...)
+					applied = append(applied, change)
+				}
+				continue
+			}
+			if target < 0 && profileCategories[change.Category].single {
+				for i, fact := range result {
+					if fact.Category == change.Category {
+						target = i
+						change.Op, change.OldValue = OpUpdate, fact.Value
+						break
+					}
+				}
+			}
+			if target >= 0 {
+				if result[target].Confidence > change.Confidence {
+					continue
+				}
+				result[target].Value = change.Value
+				result[target].Source = source
+				result[target].Confidence = change.Confidence
+				result[target].UpdatedAt = now
+			} else {
+				change.Op = OpAdd
+				result = append(result, newFact(change.Category, change.Value, source, change.Confidence, now))
+			}
+			applied = append(applied, change)
+		}
+	}
+	return result, applied
+}
+
+// diffFacts returns the changes turning before into after
+func diffFacts(before, after []ProfileFact) []ProfileChange {
+	var changes []ProfileChange
+	for _, fact := range before {
+		if findFact(after, fact.Category, fact.Value) < 0 {
+			changes = append(changes, ProfileChange{Op: OpRemove, Category: fact.Category, Value: fact.Value})
+		}
+	}
+	for _, fact := range after {
+		if findFact(before, fact.Category, fact.Value) < 0 {
+			changes = append(changes, ProfileChange{Op: OpAdd, Category: fact.Category, Value: fact.Value, Confidence: fact.Confidence})
+		}
+	}
+	return changes
+}
+
+// keepMetadata carries the ID, source and times of unchanged facts over to
+// facts parsed again from markdown
+func keepMetadata(before, after []ProfileFact) []ProfileFact {
+	for i, fact := range after {
+		if j := findFact(before, fact.Category, fact.Value); j >= 0 {
+			after[i] = before[j]
+		}
+	}
+	return after
+}
+
+// formatFactsForPrompt lists facts for the profile update prompt
+func formatFactsForPrompt(facts []ProfileFact) string {
+	if len(facts) == 0 {
+		return "(empty)"
+	}
+	sorted := append([]ProfileFact(nil), facts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Category < sorted[j].Category })
+
+	var b strings.Builder
+	for _, fact := range sorted {
+		fmt.Fprintf(&b, "- [%s] %s (confidence %.1f)\n", fact.Category, fact.Value, fact.Confidence)
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,220 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newProfileMemory(t *testing.T, response string) *LongTermMemory {
+	t.Helper()
+	ltm := NewLongTermMemory(t.TempDir(), 5)
+	ltm.SetAIProvider(&MockAIProvider{response: response}, nil)
+	return ltm
+}
+
+func TestParseProfile_RoundTrip(t *testing.T) {
+	markdown := "# Profile\nLikes short answers\n## Languages\n- Go\n* Rust\n## Shell\n- zsh\n## Hobbies\n- Chess"
+	facts := parseProfile(markdown, SourceUser, 1, time.Now())
+	if len(facts) != 5 {
+		t.Fatalf("expected 5 facts, got %+v", facts)
+	}
+	if facts[0].Category != otherCategory || facts[1].Category != "languages" || facts[4].Category != "hobbies" {
+		t.Errorf("unexpected categories: %+v", facts)
+	}
+
+	want := "- Likes short answers\n## Languages\n- Go\n- Rust\n## Shell\n- zsh\n## Hobbies\n- Chess"
+	if got := renderProfile(facts); got != want {
+		t.Errorf("renderProfile = %q, want %q", got, want)
+	}
+
+	profile := buildUserProfile(facts)
+	if len(profile.TechPreferences.Languages) != 2 || profile.PersonalSettings.Shell != "zsh" {
+		t.Errorf("unexpected structured profile: %+v", profile)
+	}
+}
+
+func TestParseProfile_RoundTripCJK(t *testing.T) {
+	markdown := "## 爱好\n- 围棋\n## écoles\n- 巴黎"
+	facts := parseProfile(markdown, SourceUser, 1, time.Now())
+	if len(facts) != 2 || facts[0].Category != "爱好" || facts[1].Category != "écoles" {
+		t.Fatalf("unexpected facts: %+v", facts)
+	}
+
+	want := "## 爱好\n- 围棋\n## Écoles\n- 巴黎"
+	got := renderProfile(facts)
+	if got != want {
+		t.Errorf("renderProfile = %q, want %q", got, want)
+	}
+	if again := parseProfile(got, SourceUser, 1, time.Now()); len(again) != 2 || again[0].Category != "爱好" || again[1].Category != "écoles" {
+		t.Errorf("unexpected facts after round trip: %+v", again)
+	}
+}
+
+func TestValidateChanges(t *testing.T) {
+	facts := []ProfileFact{newFact("shell", "bash", "c1", 0.5, time.Now())}
+	changes, err := validateChanges([]ProfileChange{
+		{Op: "ADD", Category: "Languages", Value: " Go ", Confidence: 1},
+		{Op: "add", Category: "languages", Value: ""},
+		{Op: "add", Category: "languages", Value: "Go\nRust"},
+		{Op: "rename", Category: "languages", Value: "Go"},
+		{Op: "update", Category: "shell", OldValue: "fish", Value: "zsh"},
+		{Op: "remove", Category: "shell", Value: "bash"},
+	}, facts)
+	if err != nil {
+		t.Fatalf("validateChanges failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 valid changes, got %+v", changes)
+	}
+	if changes[0].Category != "languages" || changes[0].Value != "Go" || changes[0].Confidence != maxModelConfidence {
+		t.Errorf("change not normalized: %+v", changes[0])
+	}
+	if changes[1].Confidence != defaultConfidence {
+		t.Errorf("expected default confidence, got %v", changes[1].Confidence)
+	}
+
+	// Removing most of the profile at once is rejected
+	var many []ProfileFact
+	var removals []ProfileChange
+	for _, value := range []string{"Go", "Rust", "Python", "Java"} {
+		many = append(many, newFact("languages", value, "c1", 0.5, time.Now()))
+		removals = append(removals, ProfileChange{Op: OpRemove, Category: "languages", Value: value})
+	}
+	if _, err := validateChanges(removals[:3], many); err == nil {
+		t.Error("expected wiping the profile to be rejected")
+	}
+	if _, err := validateChanges(make([]ProfileChange, maxProfileChanges+1), many); err == nil {
+		t.Error("expected too many changes to be rejected")
+	}
+}
+
+func TestApplyChanges_Conflicts(t *testing.T) {
+	now := time.Now()
+	facts := []ProfileFact{
+		newFact("shell", "bash", "c1", 0.5, now),
+		newFact("editors", "neovim", SourceUser, 1, now),
+		newFact("languages", "Go", "c1", 0.5, now),
+	}
+
+	result, applied := applyChanges(facts, []ProfileChange{
+		{Op: OpAdd, Category: "shell", Value: "zsh", Confidence: 0.8},         // replaces the single shell
+		{Op: OpRemove, Category: "editors", Value: "neovim", Confidence: 0.9}, // user fact wins
+		{Op: OpAdd, Category: "languages", Value: "go", Confidence: 0.8},      // confirms Go
+		{Op: OpAdd, Category: "frameworks", Value: "Gin", Confidence: 0.6},
+	}, "c2", now)
+
+	if len(applied) != 2 {
+		t.Errorf("expected 2 applied changes, got %+v", applied)
+	}
+	if applied[0].Op != OpUpdate || applied[0].OldValue != "bash" {
+		t.Errorf("expected the shell to be updated, got %+v", applied[0])
+	}
+	if i := findFact(result, "shell", "zsh"); i < 0 || result[i].Source != "c2" {
+		t.Errorf("expected shell zsh from c2, got %+v", result)
+	}
+	if findFact(result, "editors", "neovim") < 0 {
+		t.Error("the model should not remove facts set by the user")
+	}
+	if i := findFact(result, "languages", "Go"); i < 0 || result[i].Confidence != 0.8 {
+		t.Errorf("expected Go to be confirmed, got %+v", result)
+	}
+
+	// A less confident value does not replace the shell
+	_, applied = applyChanges(result, []ProfileChange{{Op: OpAdd, Category: "shell", Value: "fish", Confidence: 0.3}}, "c3", now)
+	if len(applied) != 0 {
+		t.Errorf("expected low confidence update to be ignored, got %+v", applied)
+	}
+}
+
+func TestLongTermMemory_UpdateProfileWithLLM(t *testing.T) {
+	ltm := newProfileMemory(t, "```json\n"+`{"changes": [
+		{"op": "add", "category": "languages", "value": "Go", "confidence": 0.8},
+		{"op": "remove", "category": "editors", "value": "vim"}
+	]}`+"\n```")
+	if err := ltm.SetProfileMarkdown("## Editors\n- vim"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ltm.UpdateProfileWithLLM(context.Background(), "conv-1", "Wrote a Go service"); err != nil {
+		t.Fatalf("UpdateProfileWithLLM failed: %v", err)
+	}
+	if got := ltm.GetProfileMarkdown(); got != "## Languages\n- Go\n## Editors\n- vim" {
+		t.Errorf("unexpected profile: %q", got)
+	}
+	facts := ltm.GetProfileFacts()
+	if i := findFact(facts, "languages", "Go"); i < 0 || facts[i].Source != "conv-1" {
+		t.Errorf("expected Go learned from conv-1, got %+v", facts)
+	}
+	if ltm.ProfileVersion() != 2 {
+		t.Errorf("expected version 2, got %d", ltm.ProfileVersion())
+	}
+
+	// A response that is not a diff leaves the profile alone
+	ltm.SetAIProvider(&MockAIProvider{response: "# Profile\nnothing"}, nil)
+	if err := ltm.UpdateProfileWithLLM(context.Background(), "conv-2", "info"); err == nil {
+		t.Error("expected error for a response without JSON")
+	}
+	if ltm.ProfileVersion() != 2 || len(ltm.GetProfileFacts()) != 2 {
+		t.Error("a bad response should not change the profile")
+	}
+}
+
+func TestLongTermMemory_ProfileHistory(t *testing.T) {
+	dir := t.TempDir()
+	ltm := NewLongTermMemory(dir, 5)
+	ltm.SetProfileMarkdown("- Uses Go")
+	ltm.SetProfileMarkdown("- Uses Go\n- Uses Rust")
+	ltm.SetProfileMarkdown("- Uses Go\n- Uses Rust")
+	if _, err := ltm.RemoveProfileFacts("go"); err != nil {
+		t.Fatal(err)
+	}
+
+	history := ltm.ProfileHistory()
+	if len(history) != 3 {
+		t.Fatalf("expected 3 versions, unchanged edits are not saved, got %+v", history)
+	}
+	if history[2].Changes[0].Op != OpRemove || history[2].Note != "forgotten" {
+		t.Errorf("unexpected last version: %+v", history[2])
+	}
+
+	if err := ltm.RollbackProfile(2); err != nil {
+		t.Fatalf("RollbackProfile failed: %v", err)
+	}
+	if ltm.GetProfileMarkdown() != "- Uses Go\n- Uses Rust" || ltm.ProfileVersion() != 4 {
+		t.Errorf("rollback not applied: %q version %d", ltm.GetProfileMarkdown(), ltm.ProfileVersion())
+	}
+	if err := ltm.RollbackProfile(99); err == nil {
+		t.Error("expected error for an unknown version")
+	}
+
+	reloaded := NewLongTermMemory(dir, 5)
+	if len(reloaded.ProfileHistory()) != 4 || reloaded.GetProfileMarkdown() != "- Uses Go\n- Uses Rust" {
+		t.Error("profile history not persisted")
+	}
+}
+
+func TestLongTermMemory_MigratesProfileMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user_profile.md"), []byte("# User Profile\n- Likes tea\n## Shell\n- fish"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ltm := NewLongTermMemory(dir, 5)
+	facts := ltm.GetProfileFacts()
+	if len(facts) != 2 || facts[1].Source != SourceMigration || facts[1].Category != "shell" {
+		t.Fatalf("unexpected migrated facts: %+v", facts)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "profile.json")); err != nil {
+		t.Errorf("expected profile.json to be written: %v", err)
+	}
+	if history := ltm.ProfileHistory(); len(history) != 1 || history[0].Source != SourceMigration {
+		t.Errorf("expected the migration to be versioned, got %+v", history)
+	}
+	if !strings.Contains(ltm.GetProfileMarkdown(), "## Shell\n- fish") {
+		t.Errorf("unexpected profile: %q", ltm.GetProfileMarkdown())
+	}
+}
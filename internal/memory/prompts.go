@@ -49,33 +49,39 @@ description: "包含记忆上下文的系统提示词"
 ---
 You are tada, a terminal AI assistant.
 
+Memory is layered from most to least specific. When layers disagree, conversation notes override project notes, and project notes override remembered facts and the user profile.
+
+## Conversation Notes
+{{conversation_notes}}
+
+## Project Notes
+{{project_notes}}
+
+## Remembered Facts
+{{facts}}
+
 ## User Profile
 {{profile}}
 
-## Recent Conversations
+## Related Conversations
 {{summaries}}
 
+## Related Topics
+{{entities}}
+
 Use this context to provide more personalized responses.`,
-	"update-profile.md": `---
-name: "update-profile"
+	"profile-diff.md": `---
+name: "profile-diff"
 title: "用户画像更新"
-description: "使用LLM更新用户画像"
+description: "让LLM提出用户画像的变更"
 ---
-Update the following user profile with the new information provided.
-
-## Current Profile
-{{profile}}
-
-## New Information
-{{info}}
-
-Please update the profile by:
-1. Adding any new information that should be included
-2. Updating existing information if the new information contradicts or refines it
-3. Removing information that is no longer relevant
-4. Keeping the same markdown format
-
-Return ONLY the updated profile markdown, no other text.`,
+` + defaultProfileDiffPrompt,
+	"canonicalize.md": `---
+name: "canonicalize"
+title: "实体归一化"
+description: "让LLM给实体分类并合并同义的实体"
+---
+` + defaultCanonicalizePrompt,
 }
 
 // EnsureDefaultPrompts 确保默认的 memory prompts 存在
@@ -0,0 +1,77 @@
+package memory
+
+import "github.com/Lin-Jiong-HDU/tada/internal/privacy"
+
+// redactSource is recorded with the secrets redacted from memory files
+const redactSource = "memory"
+
+// The functions below return copies with secrets replaced by placeholders.
+// They redact field values before marshaling, redacting the JSON itself could
+// cut through escape sequences. The memory in use is left unchanged.
+
+func redact(text string) string {
+	return privacy.Redact(redactSource, text)
+}
+
+// redactSummaries returns a redacted copy of summaries
+func redactSummaries(summaries []Summary) []Summary {
+	result := make([]Summary, len(summaries))
+	for i, summary := range summaries {
+		summary.Summary = redact(summary.Summary)
+		result[i] = summary
+	}
+	return result
+}
+
+// redactFacts returns a redacted copy of facts
+func redactFacts(facts []ProfileFact) []ProfileFact {
+	result := make([]ProfileFact, len(facts))
+	for i, fact := range facts {
+		fact.Value = redact(fact.Value)
+		result[i] = fact
+	}
+	return result
+}
+
+// redactHistory returns a redacted copy of the profile history
+func redactHistory(history []ProfileVersion) []ProfileVersion {
+	result := make([]ProfileVersion, len(history))
+	for i, version := range history {
+		version.Note = redact(version.Note)
+		version.Facts = redactFacts(version.Facts)
+		changes := make([]ProfileChange, len(version.Changes))
+		for j, change := range version.Changes {
+			change.Value = redact(change.Value)
+			change.OldValue = redact(change.OldValue)
+			change.Reason = redact(change.Reason)
+			changes[j] = change
+		}
+		version.Changes = changes
+		result[i] = version
+	}
+	return result
+}
+
+// redactEntities returns a redacted copy of entities, names included
+func redactEntities(entities map[string]*Entity) map[string]*Entity {
+	result := make(map[string]*Entity, len(entities))
+	for name, entity := range entities {
+		copied := *entity
+		copied.Aliases = make([]string, len(entity.Aliases))
+		for i, alias := range entity.Aliases {
+			copied.Aliases[i] = redact(alias)
+		}
+		result[redact(name)] = &copied
+	}
+	return result
+}
+
+// redactNotes returns a redacted copy of notes
+func redactNotes(notes []Note) []Note {
+	result := make([]Note, len(notes))
+	for i, note := range notes {
+		note.Text = redact(note.Text)
+		result[i] = note
+	}
+	return result
+}
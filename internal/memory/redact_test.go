@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+)
+
+// quotedSecret is a secret followed by a quote, which is escaped in JSON
+const quotedSecret = `curl -H "X-Api-Key: abcd1234efgh5678" https://x`
+
+func enableRedaction(t *testing.T) {
+	t.Helper()
+	redactor, _ := privacy.NewRedactor(nil)
+	privacy.SetDefault(redactor)
+	t.Cleanup(func() { privacy.SetDefault(nil) })
+}
+
+// checkRedacted fails unless text lost the secret but kept the quote after it
+func checkRedacted(t *testing.T, what, text string) {
+	t.Helper()
+	if strings.Contains(text, "abcd1234efgh5678") || !strings.HasSuffix(text, `" https://x`) {
+		t.Errorf("unexpected %s after reload: %q", what, text)
+	}
+}
+
+func TestRedact_RoundTrip(t *testing.T) {
+	enableRedaction(t)
+	dir := t.TempDir()
+
+	stm := NewShortTermMemory(dir, 1000)
+	if err := stm.AddSummary(&Summary{ConversationID: "c1", Summary: quotedSecret}); err != nil {
+		t.Fatal(err)
+	}
+	if stm.GetSummaries()[0].Summary != quotedSecret {
+		t.Error("expected the summary in memory to be left alone")
+	}
+	summaries := NewShortTermMemory(dir, 1000).GetSummaries()
+	if len(summaries) != 1 {
+		t.Fatalf("expected the summary to load, got %v", summaries)
+	}
+	checkRedacted(t, "summary", summaries[0].Summary)
+
+	ltm := NewLongTermMemory(dir, 10)
+	if err := ltm.SetProfileMarkdown("- " + quotedSecret); err != nil {
+		t.Fatal(err)
+	}
+	ltm.UpdateEntity(quotedSecret)
+	reloaded := NewLongTermMemory(dir, 10)
+	facts := reloaded.GetProfileFacts()
+	if len(facts) != 1 {
+		t.Fatalf("expected the fact to load, got %v", facts)
+	}
+	checkRedacted(t, "fact", facts[0].Value)
+	history := reloaded.ProfileHistory()
+	if len(history) == 0 || len(history[len(history)-1].Changes) == 0 {
+		t.Fatalf("expected the history to load, got %v", history)
+	}
+	checkRedacted(t, "history", history[len(history)-1].Changes[0].Value)
+	if len(reloaded.GetEntities()) != 1 {
+		t.Fatalf("expected the entity to load, got %v", reloaded.GetEntities())
+	}
+	for name := range reloaded.GetEntities() {
+		checkRedacted(t, "entity", name)
+	}
+
+	notesPath := filepath.Join(dir, "notes.json")
+	if _, err := NewNoteStore(notesPath, ScopeGlobal, "").AddNote(quotedSecret); err != nil {
+		t.Fatal(err)
+	}
+	notes := NewNoteStore(notesPath, ScopeGlobal, "").GetNotes()
+	if len(notes) != 1 {
+		t.Fatalf("expected the note to load, got %v", notes)
+	}
+	checkRedacted(t, "note", notes[0].Text)
+}
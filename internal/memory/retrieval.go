@@ -0,0 +1,304 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Default retrieval limits
+const (
+	DefaultRetrievalTopK   = 5
+	DefaultRetrievalTokens = 800
+
+	// embeddingTimeout bounds a call to the embeddings provider while building context
+	embeddingTimeout = 5 * time.Second
+)
+
+// Scorer scores documents against a query, higher scores are more relevant
+type Scorer interface {
+	Score(ctx context.Context, query string, docs []string) ([]float64, error)
+}
+
+// Embedder turns texts into embedding vectors, e.g. through an embeddings API
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// BM25Scorer is an offline Scorer using Okapi BM25
+type BM25Scorer struct {
+	K1 float64
+	B  float64
+}
+
+// NewBM25Scorer creates a BM25 scorer with the usual parameters
+func NewBM25Scorer() *BM25Scorer {
+	return &BM25Scorer{K1: 1.2, B: 0.75}
+}
+
+// Score implements Scorer. Documents sharing no term with the query score 0.
+func (s *BM25Scorer) Score(ctx context.Context, query string, docs []string) ([]float64, error) {
+	scores := make([]float64, len(docs))
+	terms := uniqueTerms(tokenize(query))
+	if len(terms) == 0 || len(docs) == 0 {
+		return scores, nil
+	}
+
+	termFreqs := make([]map[string]int, len(docs))
+	lengths := make([]int, len(docs))
+	docFreq := make(map[string]int)
+	total := 0
+	for i, doc := range docs {
+		tokens := tokenize(doc)
+		lengths[i] = len(tokens)
+		total += len(tokens)
+		termFreqs[i] = make(map[string]int)
+		for _, token := range tokens {
+			termFreqs[i][token]++
+		}
+		for token := range termFreqs[i] {
+			docFreq[token]++
+		}
+	}
+	avgLength := float64(total) / float64(len(docs))
+	if avgLength == 0 {
+		return scores, nil
+	}
+
+	n := float64(len(docs))
+	for i := range docs {
+		for _, term := range terms {
+			tf := float64(termFreqs[i][term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := s.K1 * (1 - s.B + s.B*float64(lengths[i])/avgLength)
+			scores[i] += idf * tf * (s.K1 + 1) / (tf + norm)
+		}
+	}
+	return scores, nil
+}
+
+// EmbeddingScorer scores documents by the cosine similarity of their embeddings
+type EmbeddingScorer struct {
+	embedder Embedder
+}
+
+// NewEmbeddingScorer creates a Scorer backed by an Embedder
+func NewEmbeddingScorer(embedder Embedder) *EmbeddingScorer {
+	return &EmbeddingScorer{embedder: embedder}
+}
+
+// Score implements Scorer
+func (s *EmbeddingScorer) Score(ctx context.Context, query string, docs []string) ([]float64, error) {
+	vectors, err := s.embedder.Embed(ctx, append([]string{query}, docs...))
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(docs)+1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(docs)+1)
+	}
+
+	scores := make([]float64, len(docs))
+	for i := range docs {
+		scores[i] = cosine(vectors[0], vectors[i+1])
+	}
+	return scores, nil
+}
+
+// cosine returns the cosine similarity of two vectors
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Retriever selects the stored memory relevant to the current user turn
+type Retriever struct {
+	scorer   Scorer
+	fallback Scorer // used when scorer fails, e.g. the embeddings API is unreachable
+
+	TopK        int     // Maximum number of summaries to inject
+	TokenBudget int     // Maximum tokens of injected summaries
+	MinScore    float64 // Documents must score above this to be relevant
+}
+
+// NewRetriever creates a Retriever using BM25
+func NewRetriever(topK, tokenBudget int) *Retriever {
+	if topK <= 0 {
+		topK = DefaultRetrievalTopK
+	}
+	if tokenBudget <= 0 {
+		tokenBudget = DefaultRetrievalTokens
+	}
+	return &Retriever{
+		scorer:      NewBM25Scorer(),
+		TopK:        topK,
+		TokenBudget: tokenBudget,
+	}
+}
+
+// SetEmbedder scores with embeddings, falling back to BM25 when the embedder fails.
+// minScore is the cosine similarity a document needs to be considered relevant.
+func (r *Retriever) SetEmbedder(embedder Embedder, minScore float64) {
+	r.scorer = NewEmbeddingScorer(embedder)
+	r.fallback = NewBM25Scorer()
+	r.MinScore = minScore
+}
+
+// rank returns the indexes of relevant documents, most relevant first
+func (r *Retriever) rank(ctx context.Context, query string, docs []string) []int {
+	if strings.TrimSpace(query) == "" || len(docs) == 0 {
+		return nil
+	}
+
+	minScore := r.MinScore
+	scores, err := r.scorer.Score(ctx, query, docs)
+	if err != nil {
+		if r.fallback == nil {
+			return nil
+		}
+		if scores, err = r.fallback.Score(ctx, query, docs); err != nil {
+			return nil
+		}
+		minScore = 0
+	}
+
+	var indexes []int
+	for i, score := range scores {
+		if score > minScore {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+	return indexes
+}
+
+// SelectSummaries returns up to TopK summaries relevant to query that fit in
+// TokenBudget, most relevant first
+func (r *Retriever) SelectSummaries(ctx context.Context, query string, summaries []Summary) []Summary {
+	docs := make([]string, len(summaries))
+	for i, s := range summaries {
+		docs[i] = s.Summary
+	}
+
+	var selected []Summary
+	used := 0
+	for _, i := range r.rank(ctx, query, docs) {
+		if len(selected) >= r.TopK {
+			break
+		}
+		tokens := summaries[i].Tokens
+		if tokens == 0 {
+			tokens = estimateTokens(summaries[i].Summary)
+		}
+		if used+tokens > r.TokenBudget {
+			continue // a shorter, less relevant summary may still fit
+		}
+		used += tokens
+		selected = append(selected, summaries[i])
+	}
+	return selected
+}
+
+// SelectEntities returns up to TopK entity names mentioned by query, most relevant first
+func (r *Retriever) SelectEntities(ctx context.Context, query string, names []string) []string {
+	return r.selectTexts(ctx, query, names)
+}
+
+// selectTexts returns up to TopK texts relevant to query, most relevant first
+func (r *Retriever) selectTexts(ctx context.Context, query string, texts []string) []string {
+	var selected []string
+	for _, i := range r.rank(ctx, query, texts) {
+		if len(selected) >= r.TopK {
+			break
+		}
+		selected = append(selected, texts[i])
+	}
+	return selected
+}
+
+// stopWords are common English words that carry no topic
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"me": true, "my": true, "of": true, "on": true, "or": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "with": true, "you": true,
+}
+
+// tokenize splits text into lowercase terms. Chinese has no spaces between
+// words, so runs of Han characters are split into overlapping bigrams.
+func tokenize(text string) []string {
+	var tokens []string
+	var word, han []rune
+
+	flushWord := func() {
+		if len(word) > 0 && !stopWords[string(word)] {
+			tokens = append(tokens, string(word))
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			tokens = append(tokens, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens = append(tokens, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// uniqueTerms removes duplicate terms, keeping their order
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	var result []string
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// retrievalContext bounds the time spent on retrieval while building context
+func retrievalContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), embeddingTimeout)
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("How do I 配置 Docker-Compose?")
+	want := []string{"配置", "docker", "compose"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %v, want %v", got, want)
+	}
+
+	got = tokenize("数据库迁移")
+	want = []string{"数据", "据库", "库迁", "迁移"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %v, want %v", got, want)
+	}
+}
+
+func TestBM25Scorer_Ranking(t *testing.T) {
+	docs := []string{
+		"Set up nginx as a reverse proxy",
+		"Debugged a goroutine leak in the Go worker pool",
+		"Wrote a Go HTTP handler",
+	}
+	scores, err := NewBM25Scorer().Score(context.Background(), "go goroutine leak", docs)
+	if err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+	if scores[0] != 0 {
+		t.Errorf("unrelated doc scored %v, want 0", scores[0])
+	}
+	if scores[1] <= scores[2] {
+		t.Errorf("expected doc 1 (%v) to outrank doc 2 (%v)", scores[1], scores[2])
+	}
+}
+
+func TestRetriever_SelectSummaries(t *testing.T) {
+	summaries := []Summary{
+		{ID: "a", Summary: "讨论了 nginx 反向代理配置", Tokens: 10},
+		{ID: "b", Summary: "排查 Docker 容器网络问题", Tokens: 10},
+		{ID: "c", Summary: "Docker 镜像构建缓存优化", Tokens: 10},
+		{ID: "d", Summary: "Docker compose 多服务编排", Tokens: 50},
+	}
+
+	r := NewRetriever(5, 30)
+	got := r.SelectSummaries(context.Background(), "docker 网络不通怎么办", summaries)
+	if len(got) == 0 || got[0].ID != "b" {
+		t.Fatalf("expected summary b first, got %+v", got)
+	}
+	for _, s := range got {
+		if s.ID == "a" {
+			t.Error("unrelated summary should not be selected")
+		}
+		if s.ID == "d" {
+			t.Error("summary over the token budget should not be selected")
+		}
+	}
+
+	r.TopK = 1
+	if got := r.SelectSummaries(context.Background(), "docker", summaries); len(got) != 1 {
+		t.Errorf("expected 1 summary with TopK 1, got %d", len(got))
+	}
+
+	if got := r.SelectSummaries(context.Background(), "", summaries); len(got) != 0 {
+		t.Errorf("expected no summaries for an empty query, got %d", len(got))
+	}
+}
+
+// fakeEmbedder maps each text to a vector of keyword hits
+type fakeEmbedder struct {
+	keywords []string
+	err      error
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float64, len(e.keywords))
+		for j, keyword := range e.keywords {
+			for _, token := range tokenize(text) {
+				if token == keyword {
+					vectors[i][j]++
+				}
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestRetriever_Embedder(t *testing.T) {
+	names := []string{"Python", "Go", "Kubernetes"}
+
+	r := NewRetriever(5, 800)
+	r.SetEmbedder(&fakeEmbedder{keywords: []string{"go", "python"}}, 0.5)
+	got := r.SelectEntities(context.Background(), "go generics", names)
+	if !reflect.DeepEqual(got, []string{"Go"}) {
+		t.Errorf("SelectEntities = %v, want [Go]", got)
+	}
+
+	// Falls back to BM25 when the embeddings provider fails
+	r.SetEmbedder(&fakeEmbedder{err: errors.New("unreachable")}, 0.5)
+	got = r.SelectEntities(context.Background(), "deploy to kubernetes", names)
+	if !reflect.DeepEqual(got, []string{"Kubernetes"}) {
+		t.Errorf("SelectEntities = %v, want [Kubernetes]", got)
+	}
+}
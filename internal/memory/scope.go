@@ -0,0 +1,265 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+	"github.com/google/uuid"
+)
+
+// Scope is a layer of memory. More specific scopes take precedence when
+// their facts conflict: conversation over project over global.
+type Scope string
+
+// Memory scopes, from least to most specific
+const (
+	ScopeGlobal       Scope = "global"
+	ScopeProject      Scope = "project"
+	ScopeConversation Scope = "conversation"
+)
+
+// Project identifies the project a chat runs in
+type Project struct {
+	Root string // Git root, or the working directory outside a repository
+	Name string // Base name of Root
+}
+
+// DetectProject returns the project containing dir: the nearest directory
+// with a .git entry, or dir itself when it is not inside a repository
+func DetectProject(dir string) Project {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	root := dir
+	for current := dir; ; {
+		// .git is a directory in a repository and a file in a worktree or submodule
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			root = current
+			break
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+
+	return Project{Root: root, Name: filepath.Base(root)}
+}
+
+// Key returns a file name identifying the project, readable and unique per root
+func (p Project) Key() string {
+	sum := sha256.Sum256([]byte(p.Root))
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, p.Name)
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// Note is a fact remembered explicitly, globally or for a project or a conversation
+type Note struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotesData holds the structure of a notes file
+type NotesData struct {
+	Scope Scope  `json:"scope"`
+	Path  string `json:"path,omitempty"` // Project root or conversation ID the notes belong to
+	Notes []Note `json:"notes"`
+}
+
+// NoteStore manages the notes of a single scope: the global facts, a project or a conversation
+type NoteStore struct {
+	mu   sync.RWMutex
+	path string
+	data *NotesData
+}
+
+// NewNoteStore loads the notes stored at path. The file is only created once
+// a note is added.
+func NewNoteStore(path string, scope Scope, owner string) *NoteStore {
+	store := &NoteStore{
+		path: path,
+		data: &NotesData{Scope: scope, Path: owner},
+	}
+	if data, ok := readStored(path); ok {
+		json.Unmarshal(data, store.data)
+	}
+	return store
+}
+
+// globalNotesPath returns where the facts remembered for every chat are stored
+func globalNotesPath(storagePath string) string {
+	return filepath.Join(storagePath, "notes.json")
+}
+
+// projectNotesPath returns where the notes of a project are stored
+func projectNotesPath(storagePath string, project Project) string {
+	return filepath.Join(storagePath, "projects", project.Key()+".json")
+}
+
+// conversationNotesPath returns where the notes of a conversation are stored
+func conversationNotesPath(storagePath, convID string) string {
+	return filepath.Join(storagePath, "conversations", filepath.Base(convID)+".json")
+}
+
+// save saves notes to disk
+func (s *NoteStore) save() error {
+	redacted := *s.data
+	redacted.Notes = redactNotes(s.data.Notes)
+	data, err := json.MarshalIndent(redacted, "", "  ")
+	if err != nil {
+		return err
+	}
+	return privacy.WritePrivate(s.path, data)
+}
+
+// GetNotes returns all notes, oldest first
+func (s *NoteStore) GetNotes() []Note {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Note, len(s.data.Notes))
+	copy(result, s.data.Notes)
+	return result
+}
+
+// AddNote remembers a new note
+func (s *NoteStore) AddNote(text string) (*Note, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("note is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	note := Note{ID: uuid.New().String(), Text: text, CreatedAt: time.Now()}
+	s.data.Notes = append(s.data.Notes, note)
+	return &note, s.save()
+}
+
+// RemoveNote removes the note whose ID is id or starts with id
+func (s *NoteStore) RemoveNote(id string) (*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	match := -1
+	for i, note := range s.data.Notes {
+		if note.ID == id {
+			match = i
+			break
+		}
+		if id != "" && strings.HasPrefix(note.ID, id) {
+			if match >= 0 {
+				return nil, fmt.Errorf("note ID %s is ambiguous", id)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("note not found: %s", id)
+	}
+
+	removed := s.data.Notes[match]
+	s.data.Notes = append(s.data.Notes[:match], s.data.Notes[match+1:]...)
+	return &removed, s.save()
+}
+
+// RemoveMatching removes the notes containing text, ignoring case
+func (s *NoteStore) RemoveMatching(text string) ([]Note, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept, removed []Note
+	for _, note := range s.data.Notes {
+		if strings.Contains(strings.ToLower(note.Text), text) {
+			removed = append(removed, note)
+		} else {
+			kept = append(kept, note)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	s.data.Notes = kept
+	return removed, s.save()
+}
+
+// Import restores notes from a snapshot. With merge, notes that are not
+// stored yet are appended, otherwise the notes are replaced.
+func (s *NoteStore) Import(notes []Note, merge bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !merge {
+		s.data.Notes = append([]Note(nil), notes...)
+		return s.save()
+	}
+
+	seen := make(map[string]bool, len(s.data.Notes))
+	for _, note := range s.data.Notes {
+		seen[note.ID] = true
+	}
+	for _, note := range notes {
+		if !seen[note.ID] {
+			s.data.Notes = append(s.data.Notes, note)
+		}
+	}
+	return s.save()
+}
+
+// SetNotes replaces the notes with texts, keeping the IDs and times of
+// notes whose text is unchanged
+func (s *NoteStore) SetNotes(texts []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := make(map[string]Note, len(s.data.Notes))
+	for _, note := range s.data.Notes {
+		existing[note.Text] = note
+	}
+
+	var notes []Note
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		note, ok := existing[text]
+		if !ok {
+			note = Note{ID: uuid.New().String(), Text: text, CreatedAt: time.Now()}
+		}
+		notes = append(notes, note)
+	}
+	s.data.Notes = notes
+	return s.save()
+}
+
+// Clear removes all notes
+func (s *NoteStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Notes = nil
+	return s.save()
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectProject(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "cmd", "app")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	project := DetectProject(sub)
+	if project.Root != root {
+		t.Errorf("expected git root %s, got %s", root, project.Root)
+	}
+	if project.Name != filepath.Base(root) {
+		t.Errorf("expected name %s, got %s", filepath.Base(root), project.Name)
+	}
+
+	// Outside a repository the working directory is the project
+	plain := t.TempDir()
+	if got := DetectProject(plain).Root; got != plain {
+		t.Errorf("expected %s, got %s", plain, got)
+	}
+
+	if DetectProject(root).Key() == DetectProject(plain).Key() {
+		t.Error("expected different keys for different projects")
+	}
+}
+
+func TestNoteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects", "app.json")
+	store := NewNoteStore(path, ScopeProject, "/src/app")
+
+	first, err := store.AddNote("  Tests run with make test  ")
+	if err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if first.Text != "Tests run with make test" {
+		t.Errorf("expected trimmed text, got %q", first.Text)
+	}
+	if _, err := store.AddNote("Uses PostgreSQL 16"); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if _, err := store.AddNote(" "); err == nil {
+		t.Error("expected error for an empty note")
+	}
+
+	// Notes persist
+	reloaded := NewNoteStore(path, ScopeProject, "/src/app")
+	if len(reloaded.GetNotes()) != 2 {
+		t.Fatalf("expected 2 notes after reload, got %d", len(reloaded.GetNotes()))
+	}
+
+	// SetNotes keeps unchanged notes
+	if err := reloaded.SetNotes([]string{"Uses PostgreSQL 16", "Deploys with Helm", ""}); err != nil {
+		t.Fatalf("SetNotes failed: %v", err)
+	}
+	notes := reloaded.GetNotes()
+	if len(notes) != 2 || notes[0].Text != "Uses PostgreSQL 16" || notes[1].Text != "Deploys with Helm" {
+		t.Fatalf("unexpected notes: %+v", notes)
+	}
+
+	removed, err := reloaded.RemoveNote(notes[1].ID[:8])
+	if err != nil {
+		t.Fatalf("RemoveNote failed: %v", err)
+	}
+	if removed.Text != "Deploys with Helm" {
+		t.Errorf("removed wrong note: %s", removed.Text)
+	}
+	if _, err := reloaded.RemoveNote("missing"); err == nil {
+		t.Error("expected error for an unknown note")
+	}
+
+	if err := reloaded.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if len(NewNoteStore(path, ScopeProject, "").GetNotes()) != 0 {
+		t.Error("expected no notes after Clear")
+	}
+}
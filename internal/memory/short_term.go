@@ -2,29 +2,40 @@ package memory
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+	"github.com/google/uuid"
 )
 
 // ShortTermMemory manages conversation summaries
+//
+// Summaries pushed out by the token limit are moved to an archive, which is
+// not injected as a whole but can still be searched by the Retriever.
 type ShortTermMemory struct {
-	mu        sync.RWMutex
-	path      string
-	maxTokens int
-	data      *ShortTermMemoryData
+	mu          sync.RWMutex
+	path        string
+	archivePath string
+	maxTokens   int
+	data        *ShortTermMemoryData
+	archive     []Summary
 }
 
 // NewShortTermMemory creates a new short-term memory manager
 func NewShortTermMemory(storagePath string, maxTokens int) *ShortTermMemory {
-	if err := os.MkdirAll(storagePath, 0755); err != nil {
+	if err := os.MkdirAll(storagePath, privacy.DirMode); err != nil {
 		// If directory creation fails, continue in-memory only
 	}
 
 	stm := &ShortTermMemory{
-		path:      filepath.Join(storagePath, "summaries.json"),
-		maxTokens: maxTokens,
-		data:      &ShortTermMemoryData{MaxTokens: maxTokens},
+		path:        filepath.Join(storagePath, "summaries.json"),
+		archivePath: filepath.Join(storagePath, "summaries_archive.json"),
+		maxTokens:   maxTokens,
+		data:        &ShortTermMemoryData{MaxTokens: maxTokens},
 	}
 
 	stm.load()
@@ -36,7 +47,11 @@ func (s *ShortTermMemory) load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := os.ReadFile(s.path)
+	if archive, ok := readStored(s.archivePath); ok {
+		json.Unmarshal(archive, &s.archive)
+	}
+
+	data, err := privacy.ReadFile(s.path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // First run, no file yet
@@ -44,44 +59,114 @@ func (s *ShortTermMemory) load() error {
 		return err
 	}
 
-	return json.Unmarshal(data, s.data)
+	if err := json.Unmarshal(data, s.data); err != nil {
+		return err
+	}
+
+	// Summaries written by older versions have no ID, assign one so they can be forgotten
+	missing := false
+	for i := range s.data.Summaries {
+		if s.data.Summaries[i].ID == "" {
+			s.data.Summaries[i].ID = uuid.New().String()
+			missing = true
+		}
+	}
+	if missing {
+		return s.save()
+	}
+	return nil
 }
 
 // save saves summaries to disk
 func (s *ShortTermMemory) save() error {
-	data, err := json.MarshalIndent(s.data, "", "  ")
+	redacted := ShortTermMemoryData{MaxTokens: s.data.MaxTokens, Summaries: redactSummaries(s.data.Summaries)}
+	data, err := json.MarshalIndent(redacted, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(s.path, data, 0644)
+	return privacy.WritePrivate(s.path, data)
 }
 
-// AddSummary adds a new summary, managing token limit via FIFO eviction
+// saveArchive saves archived summaries to disk
+func (s *ShortTermMemory) saveArchive() error {
+	data, err := json.MarshalIndent(redactSummaries(s.archive), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return privacy.WritePrivate(s.archivePath, data)
+}
+
+// AddSummary adds a new summary, archiving the oldest ones when the token limit is exceeded
 func (s *ShortTermMemory) AddSummary(summary *Summary) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.addSummary(summary)
+}
+
+// addSummary adds a summary, the caller must hold the lock
+func (s *ShortTermMemory) addSummary(summary *Summary) error {
+	if summary.ID == "" {
+		summary.ID = uuid.New().String()
+	}
 	s.data.Summaries = append(s.data.Summaries, *summary)
 
 	// Enforce token limit
-	s.evictIfNeeded()
+	if evicted := s.evictIfNeeded(); len(evicted) > 0 {
+		s.archive = append(s.archive, evicted...)
+		if err := s.saveArchive(); err != nil {
+			return err
+		}
+	}
 
 	return s.save()
 }
 
-// evictIfNeeded removes oldest summaries if token limit exceeded
-func (s *ShortTermMemory) evictIfNeeded() {
+// UpsertSummary adds a summary, replacing the earlier summaries of the same
+// conversation. Checkpoints and the final summary of a session cover the
+// whole conversation, so only the latest one is kept.
+func (s *ShortTermMemory) UpsertSummary(summary *Summary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removeConversation := func(summaries []Summary) ([]Summary, bool) {
+		var kept []Summary
+		for _, existing := range summaries {
+			if existing.ConversationID != summary.ConversationID {
+				kept = append(kept, existing)
+			}
+		}
+		return kept, len(kept) != len(summaries)
+	}
+	s.data.Summaries, _ = removeConversation(s.data.Summaries)
+	var archived bool
+	s.archive, archived = removeConversation(s.archive)
+	if archived {
+		if err := s.saveArchive(); err != nil {
+			return err
+		}
+	}
+
+	return s.addSummary(summary)
+}
+
+// evictIfNeeded removes and returns the oldest summaries if token limit exceeded
+func (s *ShortTermMemory) evictIfNeeded() []Summary {
 	totalTokens := 0
 	for _, s := range s.data.Summaries {
 		totalTokens += s.Tokens
 	}
 
+	var evicted []Summary
 	for totalTokens > s.maxTokens && len(s.data.Summaries) > 0 {
 		removed := s.data.Summaries[0]
 		s.data.Summaries = s.data.Summaries[1:]
 		totalTokens -= removed.Tokens
+		evicted = append(evicted, removed)
 	}
+	return evicted
 }
 
 // GetSummaries returns all summaries
@@ -94,11 +179,100 @@ func (s *ShortTermMemory) GetSummaries() []Summary {
 	return result
 }
 
-// Clear removes all summaries
+// GetArchive returns the summaries moved out of short-term memory, oldest first
+func (s *ShortTermMemory) GetArchive() []Summary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Summary, len(s.archive))
+	copy(result, s.archive)
+	return result
+}
+
+// Clear removes all summaries, including archived ones
 func (s *ShortTermMemory) Clear() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.data.Summaries = nil
+	s.archive = nil
+	if err := s.saveArchive(); err != nil {
+		return err
+	}
 	return s.save()
 }
+
+// RemoveSummary removes the summary, current or archived, whose ID is id or starts with id
+func (s *ShortTermMemory) RemoveSummary(id string) (*Summary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id == "" {
+		return nil, fmt.Errorf("summary not found: %s", id)
+	}
+
+	// An exact ID wins over prefixes, a prefix must match a single summary
+	type match struct {
+		list  *[]Summary
+		index int
+	}
+	var exact, prefix []match
+	for _, candidates := range []*[]Summary{&s.data.Summaries, &s.archive} {
+		for i, summary := range *candidates {
+			if summary.ID == id {
+				exact = append(exact, match{candidates, i})
+			} else if strings.HasPrefix(summary.ID, id) {
+				prefix = append(prefix, match{candidates, i})
+			}
+		}
+	}
+	matches := exact
+	if len(matches) == 0 {
+		matches = prefix
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("summary not found: %s", id)
+	case 1:
+	default:
+		return nil, fmt.Errorf("summary ID %s is ambiguous", id)
+	}
+
+	list, index := matches[0].list, matches[0].index
+	removed := (*list)[index]
+	*list = append((*list)[:index], (*list)[index+1:]...)
+	if list == &s.archive {
+		return &removed, s.saveArchive()
+	}
+	return &removed, s.save()
+}
+
+// SetSummaries replaces all summaries, used when importing memory
+func (s *ShortTermMemory) SetSummaries(summaries []Summary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Summaries = make([]Summary, len(summaries))
+	copy(s.data.Summaries, summaries)
+	for i := range s.data.Summaries {
+		if s.data.Summaries[i].ID == "" {
+			s.data.Summaries[i].ID = uuid.New().String()
+		}
+	}
+	return s.save()
+}
+
+// SetArchive replaces all archived summaries, used when importing memory
+func (s *ShortTermMemory) SetArchive(summaries []Summary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.archive = make([]Summary, len(summaries))
+	copy(s.archive, summaries)
+	for i := range s.archive {
+		if s.archive[i].ID == "" {
+			s.archive[i].ID = uuid.New().String()
+		}
+	}
+	return s.saveArchive()
+}
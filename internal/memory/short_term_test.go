@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -60,6 +62,41 @@ func TestShortTermMemory_TokenLimit(t *testing.T) {
 	}
 }
 
+func TestShortTermMemory_Archive(t *testing.T) {
+	tmpDir := t.TempDir()
+	stm := NewShortTermMemory(tmpDir, 60)
+
+	for i := 0; i < 3; i++ {
+		if err := stm.AddSummary(&Summary{
+			ConversationID: string(rune('a' + i)),
+			Summary:        "Summary " + string(rune('a'+i)),
+			Timestamp:      time.Now(),
+			Tokens:         30,
+		}); err != nil {
+			t.Fatalf("AddSummary failed: %v", err)
+		}
+	}
+
+	archived := stm.GetArchive()
+	if len(archived) != 1 || archived[0].ConversationID != "a" {
+		t.Fatalf("expected the oldest summary to be archived, got %+v", archived)
+	}
+
+	// The archive survives a reload
+	stm2 := NewShortTermMemory(tmpDir, 60)
+	if len(stm2.GetArchive()) != 1 {
+		t.Fatalf("expected 1 archived summary after reload, got %d", len(stm2.GetArchive()))
+	}
+
+	// Archived summaries can be removed too
+	if _, err := stm2.RemoveSummary(archived[0].ID); err != nil {
+		t.Fatalf("RemoveSummary failed: %v", err)
+	}
+	if len(stm2.GetArchive()) != 0 {
+		t.Error("expected archived summary to be removed")
+	}
+}
+
 func TestShortTermMemory_LoadAndPersist(t *testing.T) {
 	tmpDir := t.TempDir()
 	stm := NewShortTermMemory(tmpDir, 1000)
@@ -88,3 +125,49 @@ func TestShortTermMemory_LoadAndPersist(t *testing.T) {
 		t.Errorf("Expected conversation_id persist-test, got %s", summaries[0].ConversationID)
 	}
 }
+
+func TestShortTermMemory_RemoveSummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	stm := NewShortTermMemory(tmpDir, 1000)
+
+	stm.AddSummary(&Summary{ID: "abc123", ConversationID: "c1", Summary: "first", Tokens: 10})
+	stm.AddSummary(&Summary{ID: "abd456", ConversationID: "c2", Summary: "second", Tokens: 10})
+
+	if _, err := stm.RemoveSummary("ab"); err == nil {
+		t.Error("Expected error for ambiguous prefix")
+	}
+	if _, err := stm.RemoveSummary("zzz"); err == nil {
+		t.Error("Expected error for unknown ID")
+	}
+
+	removed, err := stm.RemoveSummary("abd")
+	if err != nil {
+		t.Fatalf("RemoveSummary failed: %v", err)
+	}
+	if removed.Summary != "second" {
+		t.Errorf("Removed wrong summary: %+v", removed)
+	}
+
+	// Removal is persisted
+	summaries := NewShortTermMemory(tmpDir, 1000).GetSummaries()
+	if len(summaries) != 1 || summaries[0].ID != "abc123" {
+		t.Errorf("Expected only the first summary after reload, got %+v", summaries)
+	}
+}
+
+func TestShortTermMemory_AssignsIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	legacy := `{"max_tokens": 1000, "summaries": [{"conversation_id": "old", "summary": "legacy", "tokens": 5}]}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "summaries.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	id := NewShortTermMemory(tmpDir, 1000).GetSummaries()[0].ID
+	if id == "" {
+		t.Fatal("Expected legacy summary to get an ID")
+	}
+	// The ID is saved, so it stays the same across loads
+	if again := NewShortTermMemory(tmpDir, 1000).GetSummaries()[0].ID; again != id {
+		t.Errorf("Summary ID changed across loads: %s != %s", again, id)
+	}
+}
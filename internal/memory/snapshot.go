@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SnapshotVersion is the current version of the memory export format
+//
+// Version 2 added the structured profile facts, version 1 exports only have
+// the profile markdown.
+const SnapshotVersion = 2
+
+// Snapshot is a complete copy of stored memory, used by tada memory export/import
+type Snapshot struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Profile    string             `json:"profile"` // Rendered profile, for reading
+	Facts      []ProfileFact      `json:"profile_facts,omitempty"`
+	Entities   map[string]*Entity `json:"entities"`
+	Summaries  []Summary          `json:"summaries"`
+	Archive    []Summary          `json:"archive,omitempty"`
+	Notes      []Note             `json:"notes,omitempty"`
+}
+
+// Export returns a snapshot of all stored memory
+func (m *Manager) Export() *Snapshot {
+	entities := make(map[string]*Entity)
+	for name, entity := range m.longTerm.GetEntities() {
+		copied := entity
+		entities[name] = &copied
+	}
+	return &Snapshot{
+		Version:    SnapshotVersion,
+		ExportedAt: time.Now(),
+		Profile:    m.longTerm.GetProfileMarkdown(),
+		Facts:      m.longTerm.GetProfileFacts(),
+		Entities:   entities,
+		Summaries:  m.shortTerm.GetSummaries(),
+		Archive:    m.shortTerm.GetArchive(),
+		Notes:      m.notes.GetNotes(),
+	}
+}
+
+// ParseSnapshot decodes an exported snapshot
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid memory export: %w", err)
+	}
+	if snapshot.Version > SnapshotVersion {
+		return nil, fmt.Errorf("memory export version %d is newer than supported version %d", snapshot.Version, SnapshotVersion)
+	}
+	return &snapshot, nil
+}
+
+// Import restores memory from a snapshot
+//
+// Without merge the stored memory is replaced. With merge entity counts are
+// added up, and summaries and profile facts that are not stored yet are added.
+func (m *Manager) Import(snapshot *Snapshot, merge bool) error {
+	if err := m.notes.Import(snapshot.Notes, merge); err != nil {
+		return err
+	}
+
+	facts := snapshot.Facts
+	if len(facts) == 0 && snapshot.Profile != "" {
+		facts = parseProfile(snapshot.Profile, SourceImport, migratedConfidence, time.Now())
+	}
+	if err := m.longTerm.ImportProfile(facts, merge); err != nil {
+		return err
+	}
+
+	if !merge {
+		if err := m.longTerm.SetEntities(snapshot.Entities); err != nil {
+			return err
+		}
+		if err := m.shortTerm.SetArchive(snapshot.Archive); err != nil {
+			return err
+		}
+		return m.shortTerm.SetSummaries(snapshot.Summaries)
+	}
+
+	entities := make(map[string]*Entity)
+	for name, entity := range m.longTerm.GetEntities() {
+		copied := entity
+		entities[name] = &copied
+	}
+	for name, imported := range snapshot.Entities {
+		existing, ok := entities[name]
+		if !ok {
+			copied := *imported
+			entities[name] = &copied
+			continue
+		}
+		copied := *imported
+		if copied.Score == 0 {
+			copied.Score = float64(copied.Count)
+		}
+		mergeEntity(existing, &copied, m.longTerm.EntityHalfLife())
+	}
+	if err := m.longTerm.SetEntities(entities); err != nil {
+		return err
+	}
+
+	if err := m.shortTerm.SetArchive(mergeSummaries(m.shortTerm.GetArchive(), snapshot.Archive)); err != nil {
+		return err
+	}
+	return m.shortTerm.SetSummaries(mergeSummaries(m.shortTerm.GetSummaries(), snapshot.Summaries))
+}
+
+// mergeSummaries appends the imported summaries that are not stored yet, oldest first
+func mergeSummaries(summaries, imported []Summary) []Summary {
+	seen := make(map[string]bool, len(summaries))
+	for _, summary := range summaries {
+		seen[summary.ID] = true
+	}
+	for _, summary := range imported {
+		if summary.ID == "" || !seen[summary.ID] {
+			summaries = append(summaries, summary)
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Timestamp.Before(summaries[j].Timestamp)
+	})
+	return summaries
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	config := DefaultConfig()
+	config.StoragePath = t.TempDir()
+	mgr, err := NewManager(config, &MockAIProvider{}, nil)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	return mgr
+}
+
+func TestManager_ExportImport(t *testing.T) {
+	src := newTestManager(t)
+	src.LongTerm().SetProfileMarkdown("- Uses Go")
+	src.LongTerm().UpdateEntity("Go")
+	src.ShortTerm().AddSummary(&Summary{ConversationID: "c1", Summary: "Talked about Go", Tokens: 5})
+
+	data, err := json.Marshal(src.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ParseSnapshot(data)
+	if err != nil {
+		t.Fatalf("ParseSnapshot failed: %v", err)
+	}
+
+	dst := newTestManager(t)
+	dst.LongTerm().UpdateEntity("Rust")
+	if err := dst.Import(snapshot, false); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if dst.LongTerm().GetProfileMarkdown() != "- Uses Go" {
+		t.Errorf("Profile not imported: %q", dst.LongTerm().GetProfileMarkdown())
+	}
+	if dst.LongTerm().GetEntityCount("Go") != 1 || dst.LongTerm().GetEntityCount("Rust") != 0 {
+		t.Errorf("Expected entities to be replaced, got %v", dst.LongTerm().GetEntities())
+	}
+	if summaries := dst.ShortTerm().GetSummaries(); len(summaries) != 1 || summaries[0].Summary != "Talked about Go" {
+		t.Errorf("Summaries not imported: %+v", summaries)
+	}
+
+	if _, err := ParseSnapshot([]byte(`{"version": 99}`)); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+}
+
+func TestManager_ImportMerge(t *testing.T) {
+	mgr := newTestManager(t)
+	mgr.LongTerm().SetProfileMarkdown("- Current profile")
+	mgr.LongTerm().UpdateEntity("Go")
+	mgr.ShortTerm().AddSummary(&Summary{ID: "s1", Summary: "existing", Timestamp: time.Now(), Tokens: 5})
+
+	old := time.Now().Add(-24 * time.Hour)
+	snapshot := &Snapshot{
+		Version: SnapshotVersion,
+		Profile: "- Imported profile",
+		Entities: map[string]*Entity{
+			"Go":   {Count: 2, FirstSeen: old, LastSeen: old},
+			"Rust": {Count: 1, FirstSeen: old, LastSeen: old},
+		},
+		Summaries: []Summary{
+			{ID: "s1", Summary: "existing"},
+			{ID: "s0", Summary: "older", Timestamp: old, Tokens: 5},
+		},
+	}
+	if err := mgr.Import(snapshot, true); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if got := mgr.LongTerm().GetProfileMarkdown(); got != "- Current profile\n- Imported profile" {
+		t.Errorf("Merge should keep the current profile and add imported facts, got %q", got)
+	}
+	entities := mgr.LongTerm().GetEntities()
+	if entities["Go"].Count != 3 || entities["Rust"].Count != 1 || !entities["Go"].FirstSeen.Equal(old) {
+		t.Errorf("Unexpected merged entities: %+v", entities)
+	}
+	summaries := mgr.ShortTerm().GetSummaries()
+	if len(summaries) != 2 || summaries[0].ID != "s0" {
+		t.Errorf("Expected the older summary first and no duplicates, got %+v", summaries)
+	}
+}
+
+func TestManager_ForgetAndClear(t *testing.T) {
+	mgr := newTestManager(t)
+	mgr.LongTerm().UpdateEntity("Docker")
+	mgr.ShortTerm().AddSummary(&Summary{ID: "f00d", Summary: "Deployed with Docker", Tokens: 5})
+
+	if _, err := mgr.Forget("docker"); err != nil || mgr.LongTerm().GetEntityCount("Docker") != 0 {
+		t.Errorf("Expected entity to be forgotten, err=%v", err)
+	}
+	if _, err := mgr.Forget("f00d"); err != nil || len(mgr.ShortTerm().GetSummaries()) != 0 {
+		t.Errorf("Expected summary to be forgotten, err=%v", err)
+	}
+	if _, err := mgr.Forget("nothing"); err == nil {
+		t.Error("Expected error when nothing matches")
+	}
+
+	mgr.LongTerm().SetProfileMarkdown("- profile")
+	mgr.ShortTerm().AddSummary(&Summary{Summary: "kept", Tokens: 5})
+	if err := mgr.Clear(false, true); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if mgr.LongTerm().GetProfileMarkdown() != "" || len(mgr.ShortTerm().GetSummaries()) != 1 {
+		t.Error("Clear(long) should only clear long-term memory")
+	}
+}
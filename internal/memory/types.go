@@ -4,7 +4,9 @@ import "time"
 
 // Summary represents a single conversation summary in short-term memory
 type Summary struct {
+	ID             string    `json:"id,omitempty"`
 	ConversationID string    `json:"conversation_id"`
+	Project        string    `json:"project,omitempty"` // Root of the project the conversation ran in
 	Summary        string    `json:"summary"`
 	Timestamp      time.Time `json:"timestamp"`
 	Tokens         int       `json:"tokens"`
@@ -16,11 +18,17 @@ type ShortTermMemoryData struct {
 	Summaries []Summary `json:"summaries"`
 }
 
-// Entity tracks occurrences of a mentioned entity
+// Entity tracks occurrences of a mentioned entity, stored under its canonical name
 type Entity struct {
 	Count     int       `json:"count"`
 	FirstSeen time.Time `json:"first_seen"`
 	LastSeen  time.Time `json:"last_seen"`
+	Runs      int       `json:"runs,omitempty"`     // Times the entity was run as a command
+	Failures  int       `json:"failures,omitempty"` // Runs that failed
+	Category  string    `json:"category,omitempty"` // language, framework, tool, project, person or topic
+	Aliases   []string  `json:"aliases,omitempty"`  // Other spellings merged into the entity
+	Score     float64   `json:"score,omitempty"`    // Mentions decayed over time, as of LastSeen
+	Promoted  bool      `json:"promoted,omitempty"` // Added to the profile
 }
 
 // EntityData holds the entities.json structure
@@ -80,6 +88,14 @@ type Config struct {
 	ShortTermMaxTokens int    `json:"short_term_max_tokens"`
 	EntityThreshold    int    `json:"entity_threshold"`
 	StoragePath        string `json:"storage_path"`
+	// RetrievalTopK is the number of relevant summaries and entities injected per request
+	RetrievalTopK int `json:"retrieval_top_k"`
+	// RetrievalTokens is the token budget for injected summaries
+	RetrievalTokens int `json:"retrieval_tokens"`
+	// CheckpointTurns is how often, in turns, a running conversation is summarized. 0 disables checkpoints.
+	CheckpointTurns int `json:"checkpoint_turns"`
+	// EntityHalfLifeDays is how many days it takes an entity score to halve
+	EntityHalfLifeDays int `json:"entity_half_life_days"`
 }
 
 // DefaultConfig returns default memory configuration
@@ -89,5 +105,9 @@ func DefaultConfig() *Config {
 		ShortTermMaxTokens: 4000,
 		EntityThreshold:    5,
 		StoragePath:        "~/.tada/memory",
+		RetrievalTopK:      DefaultRetrievalTopK,
+		RetrievalTokens:    DefaultRetrievalTokens,
+		CheckpointTurns:    5,
+		EntityHalfLifeDays: 30,
 	}
 }
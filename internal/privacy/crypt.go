@@ -0,0 +1,344 @@
+package privacy
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// encryptedMagic starts every encrypted file, files without it are read as plain text
+var encryptedMagic = []byte("TADAENC1")
+
+// secretPrefix marks an encrypted value in the config, e.g. the API key
+const secretPrefix = "enc:"
+
+// Key derivation parameters
+const (
+	keySize       = 32 // AES-256
+	saltSize      = 16
+	kdfPassphrase = "pbkdf2-sha256"
+	kdfKeyfile    = "hkdf-sha256"
+	keyCheckText  = "tada"
+	keyInfoLabel  = "tada storage key"
+)
+
+// kdfIterations is the PBKDF2 work factor for new passphrase keys, the value
+// used is stored in the key info. A variable so tests can lower it.
+var kdfIterations = 600000
+
+// ErrLocked is returned when an encrypted file is read without a key
+var ErrLocked = errors.New("file is encrypted, enable encryption in config.yaml to read it")
+
+// ErrPlaintext is returned when a plain text file is read after the stored
+// data was encrypted, such a file was not written by tada
+var ErrPlaintext = errors.New("file is not encrypted but the stored data is, refusing to read it")
+
+// ErrWrongKey is returned when the passphrase or keyfile does not match the stored data
+var ErrWrongKey = errors.New("wrong passphrase or keyfile")
+
+// Cipher encrypts stored data with AES-256-GCM. Every encryption uses a new
+// random nonce, and a modified or truncated file fails to decrypt.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a Cipher from a 32 byte key
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("invalid key size %d, expected %d", len(key), keySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts data: magic, nonce, then the ciphertext and tag
+func (c *Cipher) Seal(data []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append([]byte(nil), encryptedMagic...), nonce...)
+	return c.aead.Seal(out, nonce, data, encryptedMagic), nil
+}
+
+// Open decrypts data produced by Seal
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, errors.New("data is not encrypted")
+	}
+	data = data[len(encryptedMagic):]
+	if len(data) < c.aead.NonceSize() {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, encryptedMagic)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plain, nil
+}
+
+// EncryptString encrypts a config value, the result starts with "enc:"
+func (c *Cipher) EncryptString(value string) (string, error) {
+	sealed, err := c.Seal([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString decrypts a value produced by EncryptString. Values without
+// the "enc:" prefix are returned unchanged.
+func (c *Cipher) DecryptString(value string) (string, error) {
+	if !IsEncryptedString(value) {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrLocked
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	plain, err := c.Open(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted reports whether data was written by Seal
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// IsEncryptedString reports whether value was produced by EncryptString
+func IsEncryptedString(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// KeyInfo is stored next to the encrypted data, it holds everything needed to
+// derive the key again except the passphrase or keyfile itself
+type KeyInfo struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt"`
+	Check      []byte `json:"check"`               // keyCheckText encrypted with the key, detects a wrong passphrase
+	Converted  bool   `json:"converted,omitempty"` // stored files were encrypted with tada encrypt, plain text files are refused
+}
+
+// KeySource is where the key comes from, exactly one field should be set
+type KeySource struct {
+	Passphrase string
+	Keyfile    string
+}
+
+// LoadCipher derives the key from source and the key info at infoPath. The
+// key info is created on first use, and a keyfile that does not exist yet is
+// generated with random content.
+func LoadCipher(infoPath string, source KeySource) (*Cipher, error) {
+	kdf := kdfPassphrase
+	if source.Keyfile != "" {
+		kdf = kdfKeyfile
+	} else if source.Passphrase == "" {
+		return nil, errors.New("no passphrase or keyfile given")
+	}
+
+	info, err := readKeyInfo(infoPath)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil && info.KDF != kdf {
+		return nil, fmt.Errorf("data is encrypted with a %s key, switch back or decrypt it first", keySourceName(info.KDF))
+	}
+
+	create := info == nil
+	if create {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		info = &KeyInfo{KDF: kdf, Salt: salt}
+		if kdf == kdfPassphrase {
+			info.Iterations = kdfIterations
+		}
+	}
+
+	key, err := deriveKey(info, source, create)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !create {
+		if check, err := c.Open(info.Check); err != nil || string(check) != keyCheckText {
+			return nil, ErrWrongKey
+		}
+		return c, nil
+	}
+
+	if info.Check, err = c.Seal([]byte(keyCheckText)); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAtomic(infoPath, data); err != nil {
+		return nil, fmt.Errorf("failed to write key info: %w", err)
+	}
+	return c, nil
+}
+
+// Converted reports whether the stored files were encrypted with tada
+// encrypt, see MarkConverted. It is false if the key info does not exist yet.
+func Converted(infoPath string) (bool, error) {
+	info, err := readKeyInfo(infoPath)
+	if err != nil || info == nil {
+		return false, err
+	}
+	return info.Converted, nil
+}
+
+// MarkConverted records in the key info whether all stored files are
+// encrypted. Once they are, ReadFile refuses plain text files when
+// SetRequireEncrypted is set from it.
+func MarkConverted(infoPath string, converted bool) error {
+	info, err := readKeyInfo(infoPath)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return fmt.Errorf("key info %s does not exist", infoPath)
+	}
+	info.Converted = converted
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(infoPath, data); err != nil {
+		return fmt.Errorf("failed to write key info: %w", err)
+	}
+	return nil
+}
+
+// readKeyInfo returns nil if the key info does not exist yet
+func readKeyInfo(path string) (*KeyInfo, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var info KeyInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid key info %s: %w", path, err)
+	}
+	return &info, nil
+}
+
+// deriveKey derives the storage key. A passphrase is stretched with PBKDF2, a
+// keyfile already holds enough entropy and only goes through HKDF.
+func deriveKey(info *KeyInfo, source KeySource, create bool) ([]byte, error) {
+	switch info.KDF {
+	case kdfPassphrase:
+		return pbkdf2.Key(sha256.New, source.Passphrase, info.Salt, info.Iterations, keySize)
+	case kdfKeyfile:
+		secret, err := readKeyfile(source.Keyfile, create)
+		if err != nil {
+			return nil, err
+		}
+		return hkdf.Key(sha256.New, secret, info.Salt, keyInfoLabel, keySize)
+	default:
+		return nil, fmt.Errorf("unknown key derivation %q", info.KDF)
+	}
+}
+
+// readKeyfile reads a keyfile. When the key is created a keyfile that does
+// not exist is generated with random content, later it must exist.
+func readKeyfile(path string, create bool) ([]byte, error) {
+	secret, err := os.ReadFile(path)
+	if os.IsNotExist(err) && create {
+		secret = make([]byte, keySize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		if err := writeAtomic(path, secret); err != nil {
+			return nil, fmt.Errorf("failed to create keyfile: %w", err)
+		}
+		return secret, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("keyfile %s is too short, use at least 16 bytes", path)
+	}
+	return secret, nil
+}
+
+func keySourceName(kdf string) string {
+	if kdf == kdfKeyfile {
+		return "keyfile"
+	}
+	return "passphrase"
+}
+
+var (
+	cipherMu         sync.RWMutex
+	defaultCipher    *Cipher
+	requireEncrypted bool
+)
+
+// SetCipher sets the Cipher used by ReadFile and the write functions. nil
+// disables encryption, encrypted files can then no longer be read.
+func SetCipher(c *Cipher) {
+	cipherMu.Lock()
+	defer cipherMu.Unlock()
+	defaultCipher = c
+}
+
+// DefaultCipher returns the Cipher used for stored data, nil if encryption is disabled
+func DefaultCipher() *Cipher {
+	cipherMu.RLock()
+	defer cipherMu.RUnlock()
+	return defaultCipher
+}
+
+// SetRequireEncrypted makes ReadFile refuse plain text files, set once every
+// stored file was encrypted. A plain text file could then only have been
+// planted by someone without the key, e.g. a forged memory or profile.
+func SetRequireEncrypted(require bool) {
+	cipherMu.Lock()
+	defer cipherMu.Unlock()
+	requireEncrypted = require
+}
+
+// plaintextAllowed reports whether ReadFile returns plain text files as they are
+func plaintextAllowed() bool {
+	cipherMu.RLock()
+	defer cipherMu.RUnlock()
+	return !requireEncrypted
+}
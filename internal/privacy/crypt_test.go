@@ -0,0 +1,183 @@
+package privacy
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testCipher(t *testing.T) *Cipher {
+	t.Helper()
+	c, err := NewCipher(bytes.Repeat([]byte{7}, keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCipher_SealOpen(t *testing.T) {
+	c := testCipher(t)
+	plain := []byte(`{"messages": []}`)
+
+	sealed, err := c.Seal(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(sealed) || bytes.Contains(sealed, plain) {
+		t.Fatalf("expected encrypted data, got %q", sealed)
+	}
+	again, _ := c.Seal(plain)
+	if bytes.Equal(sealed, again) {
+		t.Error("expected a new nonce for every encryption")
+	}
+
+	opened, err := c.Open(sealed)
+	if err != nil || !bytes.Equal(opened, plain) {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+
+	// Modified data fails to decrypt
+	sealed[len(sealed)-1] ^= 1
+	if _, err := c.Open(sealed); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("expected ErrWrongKey for modified data, got %v", err)
+	}
+	if _, err := c.Open(encryptedMagic); err == nil {
+		t.Error("expected error for truncated data")
+	}
+
+	if _, err := NewCipher([]byte("short")); err == nil {
+		t.Error("expected error for a short key")
+	}
+}
+
+func TestCipher_Strings(t *testing.T) {
+	c := testCipher(t)
+	encrypted, err := c.EncryptString("sk-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncryptedString(encrypted) {
+		t.Fatalf("expected enc: prefix, got %q", encrypted)
+	}
+	if got, err := c.DecryptString(encrypted); err != nil || got != "sk-secret" {
+		t.Errorf("DecryptString = %q, %v", got, err)
+	}
+	if got, _ := c.DecryptString("plain"); got != "plain" {
+		t.Errorf("expected plain values unchanged, got %q", got)
+	}
+
+	var none *Cipher
+	if _, err := none.DecryptString(encrypted); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked without a cipher, got %v", err)
+	}
+}
+
+func TestLoadCipher_Passphrase(t *testing.T) {
+	defer func(n int) { kdfIterations = n }(kdfIterations)
+	kdfIterations = 1000
+
+	infoPath := filepath.Join(t.TempDir(), "encryption.json")
+	c, err := LoadCipher(infoPath, KeySource{Passphrase: "correct horse"})
+	if err != nil {
+		t.Fatalf("LoadCipher failed: %v", err)
+	}
+	if info, err := os.Stat(infoPath); err != nil || info.Mode().Perm() != FileMode {
+		t.Fatalf("expected private key info, got %v", err)
+	}
+	sealed, _ := c.Seal([]byte("data"))
+
+	// The same passphrase derives the same key again
+	again, err := LoadCipher(infoPath, KeySource{Passphrase: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := again.Open(sealed); err != nil || string(plain) != "data" {
+		t.Errorf("Open = %q, %v", plain, err)
+	}
+
+	if _, err := LoadCipher(infoPath, KeySource{Passphrase: "wrong"}); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("expected ErrWrongKey, got %v", err)
+	}
+	if _, err := LoadCipher(infoPath, KeySource{Keyfile: filepath.Join(t.TempDir(), "key")}); err == nil {
+		t.Error("expected error when switching to a keyfile")
+	}
+	if _, err := LoadCipher(infoPath, KeySource{}); err == nil {
+		t.Error("expected error without a key source")
+	}
+}
+
+func TestLoadCipher_Keyfile(t *testing.T) {
+	dir := t.TempDir()
+	infoPath := filepath.Join(dir, "encryption.json")
+	keyfile := filepath.Join(dir, "tada.key")
+
+	c, err := LoadCipher(infoPath, KeySource{Keyfile: keyfile})
+	if err != nil {
+		t.Fatalf("LoadCipher failed: %v", err)
+	}
+	info, err := os.Stat(keyfile)
+	if err != nil || info.Size() != keySize || info.Mode().Perm() != FileMode {
+		t.Fatalf("expected a generated private keyfile, got %v", err)
+	}
+	sealed, _ := c.Seal([]byte("data"))
+
+	again, err := LoadCipher(infoPath, KeySource{Keyfile: keyfile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := again.Open(sealed); err != nil || string(plain) != "data" {
+		t.Errorf("Open = %q, %v", plain, err)
+	}
+
+	// A lost keyfile is not silently replaced
+	os.Remove(keyfile)
+	if _, err := LoadCipher(infoPath, KeySource{Keyfile: keyfile}); err == nil {
+		t.Error("expected error for a missing keyfile")
+	}
+	if _, err := os.Stat(keyfile); !os.IsNotExist(err) {
+		t.Error("expected no new keyfile to be generated")
+	}
+
+	if err := os.WriteFile(keyfile, bytes.Repeat([]byte{1}, keySize), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCipher(infoPath, KeySource{Keyfile: keyfile}); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("expected ErrWrongKey for another keyfile, got %v", err)
+	}
+}
+
+func TestMarkConverted(t *testing.T) {
+	defer func(n int) { kdfIterations = n }(kdfIterations)
+	kdfIterations = 1000
+	infoPath := filepath.Join(t.TempDir(), "encryption.json")
+
+	if err := MarkConverted(infoPath, true); err == nil {
+		t.Error("expected an error without key info")
+	}
+	if converted, err := Converted(infoPath); err != nil || converted {
+		t.Errorf("Converted = %v, %v, want false without key info", converted, err)
+	}
+
+	if _, err := LoadCipher(infoPath, KeySource{Passphrase: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarkConverted(infoPath, true); err != nil {
+		t.Fatalf("MarkConverted failed: %v", err)
+	}
+	if converted, _ := Converted(infoPath); !converted {
+		t.Error("expected the stores to be marked converted")
+	}
+	// The key still loads from the updated key info
+	if _, err := LoadCipher(infoPath, KeySource{Passphrase: "secret"}); err != nil {
+		t.Errorf("LoadCipher failed after MarkConverted: %v", err)
+	}
+
+	if err := MarkConverted(infoPath, false); err != nil {
+		t.Fatalf("MarkConverted failed: %v", err)
+	}
+	if converted, _ := Converted(infoPath); converted {
+		t.Error("expected the mark to be cleared")
+	}
+}
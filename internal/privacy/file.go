@@ -0,0 +1,109 @@
+package privacy
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// File and directory permissions of stored data, readable by the owner only
+const (
+	FileMode = 0600
+	DirMode  = 0700
+)
+
+// ReadFile reads a file written by WritePrivate, decrypting it
+// with the default Cipher. Plain text files, e.g. written before encryption
+// was enabled, are returned as they are until SetRequireEncrypted is set.
+// Errors from the file system are returned unwrapped, so os.IsNotExist works
+// on them.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncrypted(data) {
+		if !plaintextAllowed() {
+			return nil, &os.PathError{Op: "read", Path: path, Err: ErrPlaintext}
+		}
+		return data, nil
+	}
+	c := DefaultCipher()
+	if c == nil {
+		return nil, &os.PathError{Op: "decrypt", Path: path, Err: ErrLocked}
+	}
+	plain, err := c.Open(data)
+	if err != nil {
+		return nil, &os.PathError{Op: "decrypt", Path: path, Err: err}
+	}
+	return plain, nil
+}
+
+// WritePrivate writes data to path, readable by the owner only and encrypted
+// with the default Cipher if encryption is enabled. data is written as it is:
+// callers redact the values they store before marshaling them, redacting the
+// marshaled data could cut through escape sequences and corrupt the file.
+func WritePrivate(path string, data []byte) error {
+	return writeSealed(path, data, DefaultCipher())
+}
+
+// ConvertFile rewrites a stored file encrypted with to, or as plain text if
+// to is nil. It reports whether the file was changed, files already in the
+// requested form are left alone.
+func ConvertFile(path string, to *Cipher) (bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if IsEncrypted(raw) == (to != nil) {
+		return false, nil
+	}
+	// Plain text files are encrypted as they are, even if ReadFile refuses them
+	data := raw
+	if IsEncrypted(raw) {
+		if data, err = ReadFile(path); err != nil {
+			return false, err
+		}
+	}
+	return true, writeSealed(path, data, to)
+}
+
+// writeSealed encrypts data with c, if set, and writes it to path
+func writeSealed(path string, data []byte, c *Cipher) error {
+	if c != nil {
+		sealed, err := c.Seal(data)
+		if err != nil {
+			return err
+		}
+		data = sealed
+	}
+	return writeAtomic(path, data)
+}
+
+// writeAtomic writes data to a temporary file first and renames it over path,
+// so a crash never leaves a partial file and files written by older versions
+// with wider permissions are replaced.
+func writeAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, DirMode); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(FileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,66 @@
+package privacy
+
+import (
+	"context"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+// Provider redacts everything sent to the wrapped AI provider
+type Provider struct {
+	provider ai.AIProvider
+	redactor *Redactor
+}
+
+// WrapProvider returns provider with redaction in front of every call. With a
+// nil redactor the provider is returned unchanged.
+func WrapProvider(provider ai.AIProvider, redactor *Redactor) ai.AIProvider {
+	if provider == nil || redactor == nil {
+		return provider
+	}
+	return &Provider{provider: provider, redactor: redactor}
+}
+
+// ParseIntent implements ai.AIProvider. Placeholders in the returned commands
+// are restored, the commands run locally, but only those of secrets in input
+// itself. Secrets from the system prompt, e.g. memory, stay redacted.
+func (p *Provider) ParseIntent(ctx context.Context, input string, systemPrompt string) (*ai.Intent, error) {
+	redacted := p.redactor.Redact("ai.intent", input)
+	intent, err := p.provider.ParseIntent(ctx, redacted, p.redactor.Redact("ai.intent", systemPrompt))
+	if err != nil || intent == nil {
+		return intent, err
+	}
+
+	for i := range intent.Commands {
+		cmd := &intent.Commands[i]
+		cmd.Cmd = p.redactor.Restore(cmd.Cmd, redacted)
+		for j, arg := range cmd.Args {
+			cmd.Args[j] = p.redactor.Restore(arg, redacted)
+		}
+	}
+	return intent, nil
+}
+
+// AnalyzeOutput implements ai.AIProvider
+func (p *Provider) AnalyzeOutput(ctx context.Context, cmd string, output string) (string, error) {
+	return p.provider.AnalyzeOutput(ctx, p.redactor.Redact("ai.analyze", cmd), p.redactor.Redact("ai.analyze", output))
+}
+
+// Chat implements ai.AIProvider
+func (p *Provider) Chat(ctx context.Context, messages []ai.Message) (string, error) {
+	return p.provider.Chat(ctx, p.redactMessages(messages))
+}
+
+// ChatStream implements ai.AIProvider
+func (p *Provider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
+	return p.provider.ChatStream(ctx, p.redactMessages(messages))
+}
+
+// redactMessages returns a redacted copy of messages, the caller keeps the originals
+func (p *Provider) redactMessages(messages []ai.Message) []ai.Message {
+	redacted := make([]ai.Message, len(messages))
+	for i, msg := range messages {
+		redacted[i] = ai.Message{Role: msg.Role, Content: p.redactor.Redact("ai.chat", msg.Content)}
+	}
+	return redacted
+}
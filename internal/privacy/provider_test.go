@@ -0,0 +1,112 @@
+package privacy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+// recordingProvider records what it was sent
+type recordingProvider struct {
+	messages []ai.Message
+	input    string
+	intent   *ai.Intent
+}
+
+func (p *recordingProvider) ParseIntent(ctx context.Context, input string, systemPrompt string) (*ai.Intent, error) {
+	p.input = input
+	return p.intent, nil
+}
+
+func (p *recordingProvider) AnalyzeOutput(ctx context.Context, cmd string, output string) (string, error) {
+	p.input = cmd + "\n" + output
+	return "ok", nil
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, messages []ai.Message) (string, error) {
+	p.messages = messages
+	return "ok", nil
+}
+
+func (p *recordingProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
+	p.messages = messages
+	return nil, nil
+}
+
+const testKey = "sk-abcdefghijklmnopqrstuvwx"
+
+func TestWrapProvider_Nil(t *testing.T) {
+	inner := &recordingProvider{}
+	if WrapProvider(inner, nil) != ai.AIProvider(inner) {
+		t.Error("expected the provider unchanged without a redactor")
+	}
+}
+
+func TestProvider_Chat(t *testing.T) {
+	inner := &recordingProvider{}
+	r, _ := NewRedactor(nil)
+	provider := WrapProvider(inner, r)
+
+	messages := []ai.Message{{Role: "user", Content: "my key is " + testKey}}
+	if _, err := provider.Chat(context.Background(), messages); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(inner.messages[0].Content, testKey) {
+		t.Errorf("provider received the secret: %q", inner.messages[0].Content)
+	}
+	if messages[0].Content != "my key is "+testKey {
+		t.Error("expected the caller's messages to be left alone")
+	}
+
+	provider.ChatStream(context.Background(), messages)
+	if strings.Contains(inner.messages[0].Content, testKey) {
+		t.Errorf("stream received the secret: %q", inner.messages[0].Content)
+	}
+
+	provider.AnalyzeOutput(context.Background(), "env", "OPENAI_API_KEY="+testKey)
+	if strings.Contains(inner.input, testKey) {
+		t.Errorf("output analysis received the secret: %q", inner.input)
+	}
+}
+
+func TestProvider_ParseIntentRestoresCommands(t *testing.T) {
+	r, _ := NewRedactor(nil)
+	placeholder := r.Redact("test", testKey)
+	inner := &recordingProvider{intent: &ai.Intent{
+		Commands: []ai.Command{{Cmd: "export", Args: []string{"OPENAI_API_KEY=" + placeholder}}},
+	}}
+	provider := WrapProvider(inner, r)
+
+	intent, err := provider.ParseIntent(context.Background(), "set OPENAI_API_KEY to "+testKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(inner.input, testKey) {
+		t.Errorf("provider received the secret: %q", inner.input)
+	}
+	if got := intent.Commands[0].Args[0]; got != "OPENAI_API_KEY="+testKey {
+		t.Errorf("expected the secret to be restored in the command, got %q", got)
+	}
+}
+
+func TestProvider_ParseIntentKeepsEarlierSecrets(t *testing.T) {
+	r, _ := NewRedactor(nil)
+	provider := WrapProvider(&recordingProvider{}, r)
+
+	// A secret redacted from an earlier chat message
+	provider.Chat(context.Background(), []ai.Message{{Role: "user", Content: "my key is " + testKey}})
+	placeholder := r.Redact("test", testKey)
+
+	inner := &recordingProvider{intent: &ai.Intent{
+		Commands: []ai.Command{{Cmd: "curl", Args: []string{"https://example.com", "-d", placeholder}}},
+	}}
+	intent, err := WrapProvider(inner, r).ParseIntent(context.Background(), "send a test request", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := intent.Commands[0].Args[2]; got != placeholder {
+		t.Errorf("expected the earlier secret to stay redacted, got %q", got)
+	}
+}
@@ -0,0 +1,278 @@
+package privacy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Kinds of redacted secrets
+const (
+	KindPrivateKey  = "private_key"
+	KindURLPassword = "url_password"
+	KindAPIKey      = "api_key"
+	KindToken       = "token"
+	KindSecret      = "secret"
+	KindCustom      = "custom"
+)
+
+// placeholderPrefix starts every placeholder, text already redacted is not redacted again
+const placeholderPrefix = "[REDACTED:"
+
+// placeholderPattern matches placeholders, later rules must not match inside them
+var placeholderPattern = regexp.MustCompile(`\[REDACTED:[a-z_]+:[0-9a-f]{8}\]`)
+
+// maxFindings bounds the findings kept in memory
+const maxFindings = 200
+
+// rule detects one kind of secret. When group is set only that submatch is
+// the secret, the rest of the match is kept, e.g. the user name of a URL.
+type rule struct {
+	kind  string
+	re    *regexp.Regexp
+	group int
+	check func(secret string) bool // Optional, filters out false positives
+}
+
+// builtinRules detect common secrets, most specific first
+var builtinRules = []rule{
+	{kind: KindPrivateKey, re: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{kind: KindURLPassword, re: regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:([^\s@/]+)@`), group: 1},
+	{kind: KindAPIKey, re: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},           // OpenAI, Anthropic, DeepSeek
+	{kind: KindAPIKey, re: regexp.MustCompile(`\b[0-9a-f]{32}\.[A-Za-z0-9]{16}\b`)}, // GLM
+	{kind: KindAPIKey, re: regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},              // AWS access key
+	{kind: KindAPIKey, re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},           // Google
+	{kind: KindToken, re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{kind: KindToken, re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},                                  // Slack
+	{kind: KindToken, re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)}, // JWT
+	{kind: KindToken, re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{16,}=*)`), group: 1},
+	{kind: KindSecret, re: regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|password|passwd|pwd)["']?\s*[:=]\s*["']?([^\s"'\\,;]{8,})`), group: 1, check: notPlainWord},
+}
+
+// notPlainWord reports whether s contains something besides letters, so
+// prose such as "password: required" is not taken for a secret
+func notPlainWord(s string) bool {
+	for _, c := range s {
+		if !unicode.IsLetter(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finding records a redacted secret, without the secret itself
+type Finding struct {
+	Kind        string    `json:"kind"`
+	Placeholder string    `json:"placeholder"`
+	Source      string    `json:"source"` // Where the text was going, e.g. "ai.chat" or "memory"
+	Time        time.Time `json:"time"`
+}
+
+// Redactor replaces secrets in text with placeholders. The placeholder of a
+// secret is derived from its hash, so it is the same every time and in every
+// file, and the model can still tell different secrets apart.
+//
+// A nil Redactor leaves text unchanged.
+type Redactor struct {
+	rules []rule
+
+	mu       sync.Mutex
+	secrets  map[string]string // placeholder -> secret, to restore commands
+	seen     map[string]bool   // placeholder + source already recorded
+	findings []Finding
+	logPath  string
+}
+
+// NewRedactor creates a Redactor with the built-in rules and the custom
+// patterns. A custom pattern with a capture group only redacts the group.
+func NewRedactor(patterns []string) (*Redactor, error) {
+	rules := append([]rule(nil), builtinRules...)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		group := 0
+		if re.NumSubexp() > 0 {
+			group = 1
+		}
+		rules = append(rules, rule{kind: KindCustom, re: re, group: group})
+	}
+
+	return &Redactor{
+		rules:   rules,
+		secrets: make(map[string]string),
+		seen:    make(map[string]bool),
+	}, nil
+}
+
+// SetLogPath appends every finding to a JSON lines file at path
+func (r *Redactor) SetLogPath(path string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logPath = path
+}
+
+// Redact returns text with every secret replaced by its placeholder. source
+// describes where the text is going and is recorded with the findings.
+func (r *Redactor) Redact(source, text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+
+	for _, rule := range r.rules {
+		text = r.apply(rule, source, text)
+	}
+	return text
+}
+
+// apply replaces the secrets matched by one rule
+func (r *Redactor) apply(rule rule, source, text string) string {
+	matches := rule.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	placeholders := placeholderPattern.FindAllStringIndex(text, -1)
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if rule.group > 0 {
+			start, end = match[2*rule.group], match[2*rule.group+1]
+		}
+		if start < 0 || start < last || overlaps(placeholders, start, end) {
+			continue
+		}
+		secret := text[start:end]
+		if rule.check != nil && !rule.check(secret) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(r.placeholder(rule.kind, source, secret))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// overlaps reports whether text[start:end] overlaps one of the spans
+func overlaps(spans [][]int, start, end int) bool {
+	for _, span := range spans {
+		if start < span[1] && span[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholder returns the placeholder of a secret and records the finding
+func (r *Redactor) placeholder(kind, source, secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	placeholder := placeholderPrefix + kind + ":" + hex.EncodeToString(sum[:4]) + "]"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.secrets[placeholder] = secret
+	if key := placeholder + "\x00" + source; !r.seen[key] {
+		r.seen[key] = true
+		finding := Finding{Kind: kind, Placeholder: placeholder, Source: source, Time: time.Now()}
+		r.findings = append(r.findings, finding)
+		if len(r.findings) > maxFindings {
+			r.findings = r.findings[len(r.findings)-maxFindings:]
+		}
+		r.logFinding(finding)
+	}
+	return placeholder
+}
+
+// logFinding appends a finding to the log file. The caller must hold the lock.
+func (r *Redactor) logFinding(finding Finding) {
+	if r.logPath == "" {
+		return
+	}
+	data, err := json.Marshal(finding)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.logPath), DirMode); err != nil {
+		return
+	}
+	f, err := os.OpenFile(r.logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, FileMode)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
+
+// Restore replaces the placeholders this Redactor produced with the original
+// secrets, e.g. in commands the model returned that are run locally. Only
+// placeholders that also appear in scope are restored: scope is the redacted
+// request, so the model cannot bring back secrets from earlier messages,
+// memory or task output that it saw only as placeholders.
+func (r *Redactor) Restore(text, scope string) string {
+	if r == nil || !strings.Contains(text, placeholderPrefix) {
+		return text
+	}
+
+	for _, placeholder := range placeholderPattern.FindAllString(text, -1) {
+		if !strings.Contains(scope, placeholder) {
+			continue
+		}
+		r.mu.Lock()
+		secret, ok := r.secrets[placeholder]
+		r.mu.Unlock()
+		if ok {
+			text = strings.ReplaceAll(text, placeholder, secret)
+		}
+	}
+	return text
+}
+
+// Findings returns what was redacted so far, oldest first
+func (r *Redactor) Findings() []Finding {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Finding(nil), r.findings...)
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultRedactor *Redactor
+)
+
+// SetDefault sets the Redactor used by Redact. nil disables redaction.
+func SetDefault(r *Redactor) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultRedactor = r
+}
+
+// Default returns the Redactor used by Redact, nil if redaction is disabled
+func Default() *Redactor {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultRedactor
+}
+
+// Redact redacts text with the default Redactor
+func Redact(source, text string) string {
+	return Default().Redact(source, text)
+}
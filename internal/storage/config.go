@@ -5,7 +5,10 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/Lin-Jiong-HDU/tada/internal/core/notify"
+	"github.com/Lin-Jiong-HDU/tada/internal/core/queue"
 	"github.com/Lin-Jiong-HDU/tada/internal/core/security"
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 	"github.com/spf13/viper"
 )
 
@@ -13,26 +16,39 @@ const (
 	ConfigFileName = "config"
 	ConfigFileType = "yaml"
 	TadaDirName    = ".tada"
+
+	// RedactionLogName records what was redacted, never the secrets themselves
+	RedactionLogName = "redactions.log"
 )
 
 var config *Config
 
 // Config holds the application configuration
 type Config struct {
-	AI       AIConfig                `mapstructure:"ai"`
-	Security security.SecurityPolicy `mapstructure:"security"`
-	Chat     ChatConfig              `mapstructure:"chat"`
-	Memory   MemoryConfig            `mapstructure:"memory"`
+	AI         AIConfig                `mapstructure:"ai"`
+	Security   security.SecurityPolicy `mapstructure:"security"`
+	Chat       ChatConfig              `mapstructure:"chat"`
+	Memory     MemoryConfig            `mapstructure:"memory"`
+	Retention  RetentionConfig         `mapstructure:"retention"`
+	Approval   queue.ApprovalPolicy    `mapstructure:"approval"`
+	Notify     notify.Config           `mapstructure:"notifications"`
+	Privacy    PrivacyConfig           `mapstructure:"privacy"`
+	Encryption EncryptionConfig        `mapstructure:"encryption"`
 }
 
 // AIConfig holds AI-related configuration
 type AIConfig struct {
 	Provider  string `mapstructure:"provider"`
-	APIKey    string `mapstructure:"api_key"`
+	APIKey    string `mapstructure:"api_key"` // Plain, or encrypted with "enc:" prefix
 	Model     string `mapstructure:"model"`
 	BaseURL   string `mapstructure:"base_url"`
 	Timeout   int    `mapstructure:"timeout"`
 	MaxTokens int    `mapstructure:"max_tokens"`
+
+	// APIKeyEnv reads the API key from this environment variable instead
+	APIKeyEnv string `mapstructure:"api_key_env"`
+	// APIKeyCommand runs this shell command and uses its output as the API key, e.g. "pass show openai"
+	APIKeyCommand string `mapstructure:"api_key_command"`
 }
 
 // StreamingConfig 流式输出配置
@@ -49,6 +65,20 @@ type ChatConfig struct {
 	Stream         bool            `mapstructure:"stream"`
 	RenderMarkdown bool            `mapstructure:"render_markdown"`
 	Streaming      StreamingConfig `mapstructure:"streaming"`
+	// AllowCommands 允许在对话中通过 /run 和 ! 执行命令
+	AllowCommands bool `mapstructure:"allow_commands"`
+	// ProposeCommands 在助手回复包含 shell 代码块时提供执行
+	ProposeCommands bool `mapstructure:"propose_commands"`
+	// ContextTokens 发送给 AI 的历史 token 预算，0 表示模型上下文窗口减去 ai.max_tokens
+	ContextTokens int `mapstructure:"context_tokens"`
+	// SummarizeHistory 超出预算时用 AI 摘要较早的对话，关闭时直接丢弃
+	SummarizeHistory bool `mapstructure:"summarize_history"`
+	// AutoTitle 第一轮对话后由 AI 生成对话标题
+	AutoTitle bool `mapstructure:"auto_title"`
+	// InputHistory 保存在 ~/.tada/chat_history 中的输入历史条数，0 表示不保存
+	InputHistory int `mapstructure:"input_history"`
+	// ShowReasoning 显示推理模型的思考过程，对话中可用 /think on|off 切换
+	ShowReasoning bool `mapstructure:"show_reasoning"`
 }
 
 // MemoryConfig holds memory-related configuration
@@ -57,6 +87,54 @@ type MemoryConfig struct {
 	ShortTermMaxTokens int    `mapstructure:"short_term_max_tokens"`
 	EntityThreshold    int    `mapstructure:"entity_threshold"`
 	StoragePath        string `mapstructure:"storage_path"`
+	// RetrievalTopK 每次请求最多注入的相关摘要和实体数
+	RetrievalTopK int `mapstructure:"retrieval_top_k"`
+	// RetrievalTokens 注入的相关摘要的 token 预算
+	RetrievalTokens int `mapstructure:"retrieval_tokens"`
+	// CheckpointTurns 每隔多少轮把进行中的对话写入记忆，0 表示只在退出时写入
+	CheckpointTurns int `mapstructure:"checkpoint_turns"`
+	// EntityHalfLifeDays 实体热度减半所需的天数，长期不提到的实体会逐渐淡出用户画像
+	EntityHalfLifeDays int `mapstructure:"entity_half_life_days"`
+}
+
+// PrivacyConfig holds privacy-related configuration
+type PrivacyConfig struct {
+	// Redact 在发送给 AI 和写入磁盘前把 API key、令牌、私钥、URL 中的密码等替换为占位符
+	Redact bool `mapstructure:"redact"`
+	// Patterns 额外需要替换的正则表达式，有捕获组时只替换第一个捕获组
+	Patterns []string `mapstructure:"patterns"`
+}
+
+// EncryptionConfig holds encryption-at-rest configuration
+type EncryptionConfig struct {
+	// Enabled 加密 ~/.tada 下保存的对话、记忆、任务队列、会话和输入历史
+	Enabled bool `mapstructure:"enabled"`
+	// Keyfile 密钥文件路径，不存在时自动生成；为空时使用口令
+	Keyfile string `mapstructure:"keyfile"`
+	// PassphraseEnv 读取口令的环境变量，未设置时在终端中询问
+	PassphraseEnv string `mapstructure:"passphrase_env"`
+}
+
+// RetentionConfig holds task history retention configuration
+type RetentionConfig struct {
+	// MaxAgeDays archives sessions whose files have not changed for this many days, 0 disables archival
+	MaxAgeDays int `mapstructure:"max_age_days"`
+	// MaxCompletedTasks is the number of finished tasks kept per session, 0 means unlimited
+	MaxCompletedTasks int `mapstructure:"max_completed_tasks"`
+	// MaxOutputBytes caps the stored output of each task, 0 means unlimited
+	MaxOutputBytes int `mapstructure:"max_output_bytes"`
+	// AutoPrune runs garbage collection automatically, at most once a day
+	AutoPrune bool `mapstructure:"auto_prune"`
+}
+
+// DefaultRetentionConfig returns default retention configuration
+func DefaultRetentionConfig() RetentionConfig {
+	return RetentionConfig{
+		MaxAgeDays:        30,
+		MaxCompletedTasks: 100,
+		MaxOutputBytes:    64 * 1024,
+		AutoPrune:         true,
+	}
 }
 
 // DefaultChatConfig returns default chat configuration
@@ -70,6 +148,12 @@ func DefaultChatConfig() ChatConfig {
 		Streaming: StreamingConfig{
 			MaxDisplayLines: 10,
 		},
+		AllowCommands:    true,
+		ProposeCommands:  true,
+		SummarizeHistory: true,
+		AutoTitle:        true,
+		InputHistory:     1000,
+		ShowReasoning:    true,
 	}
 }
 
@@ -90,7 +174,7 @@ func InitConfig() (*Config, error) {
 	}
 
 	// Create config directory if not exists
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(configDir, privacy.DirMode); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
@@ -120,12 +204,49 @@ func InitConfig() (*Config, error) {
 	v.SetDefault("chat.stream", true)
 	v.SetDefault("chat.render_markdown", true)
 	v.SetDefault("chat.streaming.max_display_lines", 10)
+	v.SetDefault("chat.allow_commands", true)
+	v.SetDefault("chat.propose_commands", true)
+	v.SetDefault("chat.context_tokens", 0)
+	v.SetDefault("chat.summarize_history", true)
+	v.SetDefault("chat.auto_title", true)
+	v.SetDefault("chat.input_history", 1000)
+	v.SetDefault("chat.show_reasoning", true)
 
 	// Memory defaults
 	v.SetDefault("memory.enabled", true)
 	v.SetDefault("memory.short_term_max_tokens", 4000)
 	v.SetDefault("memory.entity_threshold", 5)
 	v.SetDefault("memory.storage_path", "~/.tada/memory")
+	v.SetDefault("memory.retrieval_top_k", 5)
+	v.SetDefault("memory.retrieval_tokens", 800)
+	v.SetDefault("memory.checkpoint_turns", 5)
+	v.SetDefault("memory.entity_half_life_days", 30)
+
+	// Retention defaults
+	retention := DefaultRetentionConfig()
+	v.SetDefault("retention.max_age_days", retention.MaxAgeDays)
+	v.SetDefault("retention.max_completed_tasks", retention.MaxCompletedTasks)
+	v.SetDefault("retention.max_output_bytes", retention.MaxOutputBytes)
+	v.SetDefault("retention.auto_prune", retention.AutoPrune)
+
+	// Approval defaults: every async task needs one manual approval
+	v.SetDefault("approval.auto_approve_safe", false)
+	v.SetDefault("approval.auto_approve_patterns", []string{})
+
+	// Notification defaults
+	notifications := notify.DefaultConfig()
+	v.SetDefault("notifications.bell.enabled", notifications.Bell.Enabled)
+	v.SetDefault("notifications.desktop.enabled", notifications.Desktop.Enabled)
+	v.SetDefault("notifications.webhook.enabled", notifications.Webhook.Enabled)
+	v.SetDefault("notifications.hook.enabled", notifications.Hook.Enabled)
+
+	// Privacy defaults
+	v.SetDefault("privacy.redact", true)
+	v.SetDefault("privacy.patterns", []string{})
+
+	// Encryption defaults
+	v.SetDefault("encryption.enabled", false)
+	v.SetDefault("encryption.passphrase_env", DefaultPassphraseEnv)
 
 	// Read config file (ignore if not exists)
 	if err := v.ReadInConfig(); err != nil {
@@ -145,10 +266,33 @@ func InitConfig() (*Config, error) {
 		cfg.Chat.Streaming.MaxDisplayLines = 10 // reset to default
 	}
 
+	if err := configureEncryption(cfg.Encryption, configDir); err != nil {
+		return nil, err
+	}
+	if err := configurePrivacy(cfg.Privacy, configDir); err != nil {
+		return nil, err
+	}
+
 	config = &cfg
 	return config, nil
 }
 
+// configurePrivacy sets up the redaction of everything sent to the AI
+// provider and written to disk
+func configurePrivacy(cfg PrivacyConfig, configDir string) error {
+	if !cfg.Redact {
+		privacy.SetDefault(nil)
+		return nil
+	}
+	redactor, err := privacy.NewRedactor(cfg.Patterns)
+	if err != nil {
+		return fmt.Errorf("invalid privacy config: %w", err)
+	}
+	redactor.SetLogPath(filepath.Join(configDir, RedactionLogName))
+	privacy.SetDefault(redactor)
+	return nil
+}
+
 // GetConfig returns the loaded config
 func GetConfig() *Config {
 	return config
@@ -162,7 +306,7 @@ func SaveConfig(cfg *Config) error {
 	}
 
 	// Create config directory if not exists
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(configDir, privacy.DirMode); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
@@ -171,8 +315,18 @@ func SaveConfig(cfg *Config) error {
 	v.SetConfigType(ConfigFileType)
 	v.AddConfigPath(configDir)
 
+	// Keep the API key encrypted when encryption is enabled
+	apiKey := cfg.AI.APIKey
+	if c := privacy.DefaultCipher(); c != nil && apiKey != "" && !privacy.IsEncryptedString(apiKey) {
+		if apiKey, err = c.EncryptString(apiKey); err != nil {
+			return fmt.Errorf("failed to encrypt api_key: %w", err)
+		}
+	}
+
 	v.Set("ai.provider", cfg.AI.Provider)
-	v.Set("ai.api_key", cfg.AI.APIKey)
+	v.Set("ai.api_key", apiKey)
+	v.Set("ai.api_key_env", cfg.AI.APIKeyEnv)
+	v.Set("ai.api_key_command", cfg.AI.APIKeyCommand)
 	v.Set("ai.model", cfg.AI.Model)
 	v.Set("ai.base_url", cfg.AI.BaseURL)
 	v.Set("ai.timeout", cfg.AI.Timeout)
@@ -192,13 +346,69 @@ func SaveConfig(cfg *Config) error {
 	v.Set("chat.stream", cfg.Chat.Stream)
 	v.Set("chat.render_markdown", cfg.Chat.RenderMarkdown)
 	v.Set("chat.streaming.max_display_lines", cfg.Chat.Streaming.MaxDisplayLines)
+	v.Set("chat.allow_commands", cfg.Chat.AllowCommands)
+	v.Set("chat.propose_commands", cfg.Chat.ProposeCommands)
+	v.Set("chat.context_tokens", cfg.Chat.ContextTokens)
+	v.Set("chat.summarize_history", cfg.Chat.SummarizeHistory)
+	v.Set("chat.auto_title", cfg.Chat.AutoTitle)
+	v.Set("chat.input_history", cfg.Chat.InputHistory)
+	v.Set("chat.show_reasoning", cfg.Chat.ShowReasoning)
 
 	// Save memory config
 	v.Set("memory.enabled", cfg.Memory.Enabled)
 	v.Set("memory.short_term_max_tokens", cfg.Memory.ShortTermMaxTokens)
 	v.Set("memory.entity_threshold", cfg.Memory.EntityThreshold)
 	v.Set("memory.storage_path", cfg.Memory.StoragePath)
+	v.Set("memory.retrieval_top_k", cfg.Memory.RetrievalTopK)
+	v.Set("memory.retrieval_tokens", cfg.Memory.RetrievalTokens)
+	v.Set("memory.checkpoint_turns", cfg.Memory.CheckpointTurns)
+	v.Set("memory.entity_half_life_days", cfg.Memory.EntityHalfLifeDays)
+
+	// Save retention config
+	v.Set("retention.max_age_days", cfg.Retention.MaxAgeDays)
+	v.Set("retention.max_completed_tasks", cfg.Retention.MaxCompletedTasks)
+	v.Set("retention.max_output_bytes", cfg.Retention.MaxOutputBytes)
+	v.Set("retention.auto_prune", cfg.Retention.AutoPrune)
+
+	// Save approval policy
+	v.Set("approval.auto_approve_safe", cfg.Approval.AutoApproveSafe)
+	v.Set("approval.auto_approve_patterns", cfg.Approval.AutoApprovePatterns)
+	rules := make([]map[string]interface{}, 0, len(cfg.Approval.Rules))
+	for _, rule := range cfg.Approval.Rules {
+		rules = append(rules, map[string]interface{}{
+			"pattern":            rule.Pattern,
+			"required_approvals": rule.RequiredApprovals,
+		})
+	}
+	v.Set("approval.rules", rules)
+
+	// Save notification config
+	v.Set("notifications.bell.enabled", cfg.Notify.Bell.Enabled)
+	v.Set("notifications.bell.on", cfg.Notify.Bell.On)
+	v.Set("notifications.desktop.enabled", cfg.Notify.Desktop.Enabled)
+	v.Set("notifications.desktop.on", cfg.Notify.Desktop.On)
+	v.Set("notifications.webhook.enabled", cfg.Notify.Webhook.Enabled)
+	v.Set("notifications.webhook.on", cfg.Notify.Webhook.On)
+	v.Set("notifications.webhook.url", cfg.Notify.Webhook.URL)
+	v.Set("notifications.webhook.timeout", cfg.Notify.Webhook.Timeout)
+	v.Set("notifications.hook.enabled", cfg.Notify.Hook.Enabled)
+	v.Set("notifications.hook.on", cfg.Notify.Hook.On)
+	v.Set("notifications.hook.command", cfg.Notify.Hook.Command)
 
+	// Save privacy config
+	v.Set("privacy.redact", cfg.Privacy.Redact)
+	v.Set("privacy.patterns", cfg.Privacy.Patterns)
+
+	// Save encryption config
+	v.Set("encryption.enabled", cfg.Encryption.Enabled)
+	v.Set("encryption.keyfile", cfg.Encryption.Keyfile)
+	v.Set("encryption.passphrase_env", cfg.Encryption.PassphraseEnv)
+
+	// The config holds the API key, keep it readable by the owner only
 	configPath := filepath.Join(configDir, ConfigFileName+"."+ConfigFileType)
-	return v.WriteConfigAs(configPath)
+	v.SetConfigPermissions(privacy.FileMode)
+	if err := v.WriteConfigAs(configPath); err != nil {
+		return err
+	}
+	return os.Chmod(configPath, privacy.FileMode)
 }
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 )
 
 func TestGetConfigDir(t *testing.T) {
@@ -77,8 +79,12 @@ func TestSaveConfig(t *testing.T) {
 	// Verify file exists
 	configDir, _ := GetConfigDir()
 	configPath := filepath.Join(configDir, ConfigFileName+"."+ConfigFileType)
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		t.Errorf("Config file was not created")
+	info, err := os.Stat(configPath)
+	if os.IsNotExist(err) {
+		t.Fatalf("Config file was not created")
+	}
+	if info.Mode().Perm() != privacy.FileMode {
+		t.Errorf("Expected config mode %o, got %o", privacy.FileMode, info.Mode().Perm())
 	}
 }
 
@@ -125,3 +131,126 @@ func TestDefaultChatConfig(t *testing.T) {
 		t.Errorf("Expected default MaxDisplayLines 10, got %d", cfg.Streaming.MaxDisplayLines)
 	}
 }
+
+func TestInitConfig_ApprovalPolicy(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	configDir := filepath.Join(tmpDir, TadaDirName)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `approval:
+  auto_approve_safe: true
+  auto_approve_patterns:
+    - "git status*"
+  rules:
+    - pattern: "rm *"
+      required_approvals: 2
+`
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+
+	if !cfg.Approval.AutoApproveSafe {
+		t.Error("Expected auto_approve_safe to be true")
+	}
+	if len(cfg.Approval.AutoApprovePatterns) != 1 || cfg.Approval.AutoApprovePatterns[0] != "git status*" {
+		t.Errorf("Unexpected auto approve patterns: %v", cfg.Approval.AutoApprovePatterns)
+	}
+	if len(cfg.Approval.Rules) != 1 || cfg.Approval.Rules[0].RequiredApprovals != 2 {
+		t.Errorf("Unexpected approval rules: %+v", cfg.Approval.Rules)
+	}
+}
+
+func TestInitConfig_Notifications(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	configDir := filepath.Join(tmpDir, TadaDirName)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `notifications:
+  webhook:
+    enabled: true
+    url: http://localhost:9000/hook
+    on: [failed]
+`
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+
+	if !cfg.Notify.Bell.Enabled {
+		t.Error("Expected bell to be enabled by default")
+	}
+	webhook := cfg.Notify.Webhook
+	if !webhook.Enabled || webhook.URL != "http://localhost:9000/hook" {
+		t.Errorf("Unexpected webhook config: %+v", webhook)
+	}
+	if len(webhook.On) != 1 || webhook.On[0] != "failed" {
+		t.Errorf("Expected webhook limited to failed, got %v", webhook.On)
+	}
+}
+
+func TestInitConfig_Privacy(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	defer privacy.SetDefault(nil)
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	if !cfg.Privacy.Redact || privacy.Default() == nil {
+		t.Error("Expected redaction to be enabled by default")
+	}
+
+	configDir := filepath.Join(tmpDir, TadaDirName)
+	content := `privacy:
+  redact: true
+  patterns: ["corp-[0-9]{6}"]
+`
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InitConfig(); err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	if got := privacy.Redact("test", "corp-123456"); got == "corp-123456" {
+		t.Error("Expected the custom pattern to be redacted")
+	}
+
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte("privacy:\n  patterns: [\"(\"]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InitConfig(); err == nil {
+		t.Error("Expected error for an invalid pattern")
+	}
+
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte("privacy:\n  redact: false\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InitConfig(); err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	if privacy.Default() != nil {
+		t.Error("Expected redaction to be disabled")
+	}
+}
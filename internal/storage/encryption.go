@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+	"golang.org/x/term"
+)
+
+const (
+	// KeyInfoName holds the salt of the storage key, see privacy.KeyInfo
+	KeyInfoName = "encryption.json"
+
+	// ConversationsDirName holds the chat conversations and their search index
+	ConversationsDirName = "conversations"
+
+	// HistoryFileName holds the chat input history
+	HistoryFileName = "chat_history"
+
+	// DefaultPassphraseEnv is the environment variable read for the passphrase
+	DefaultPassphraseEnv = "TADA_PASSPHRASE"
+
+	// apiKeyCommandTimeout bounds api_key_command, e.g. a password manager
+	apiKeyCommandTimeout = 30 * time.Second
+)
+
+// configureEncryption sets up the cipher used for everything stored under
+// the config directory. The key comes from the keyfile if one is configured,
+// otherwise from the passphrase environment variable or a terminal prompt.
+func configureEncryption(cfg EncryptionConfig, configDir string) error {
+	if !cfg.Enabled {
+		privacy.SetCipher(nil)
+		privacy.SetRequireEncrypted(false)
+		return nil
+	}
+
+	infoPath := filepath.Join(configDir, KeyInfoName)
+	source := privacy.KeySource{Keyfile: expandPath(cfg.Keyfile)}
+	if source.Keyfile == "" {
+		passphrase, err := readPassphrase(cfg.PassphraseEnv, infoPath)
+		if err != nil {
+			return err
+		}
+		source.Passphrase = passphrase
+	}
+
+	c, err := privacy.LoadCipher(infoPath, source)
+	if err != nil {
+		return fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	privacy.SetCipher(c)
+
+	// After tada encrypt every stored file is encrypted, refuse plain text ones
+	converted, err := privacy.Converted(infoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key info: %w", err)
+	}
+	privacy.SetRequireEncrypted(converted)
+	return nil
+}
+
+// readPassphrase reads the passphrase from the environment, or asks for it on
+// the terminal. A new passphrase is asked for twice.
+func readPassphrase(env, infoPath string) (string, error) {
+	if env == "" {
+		env = DefaultPassphraseEnv
+	}
+	if passphrase := os.Getenv(env); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("encryption is enabled but %s is not set", env)
+	}
+
+	_, err := os.Stat(infoPath)
+	create := os.IsNotExist(err)
+
+	fmt.Fprint(os.Stderr, "🔑 Passphrase for ~/.tada: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(passphrase) == 0 {
+		return "", errors.New("empty passphrase")
+	}
+	if create {
+		fmt.Fprint(os.Stderr, "🔑 Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(passphrase) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return string(passphrase), nil
+}
+
+// ResolveAPIKey returns the API key from the api_key_env environment
+// variable, the output of api_key_command, or api_key, in that order. An
+// api_key encrypted with `tada encrypt secret` is decrypted.
+func (c AIConfig) ResolveAPIKey() (string, error) {
+	if c.APIKeyEnv != "" {
+		if key := strings.TrimSpace(os.Getenv(c.APIKeyEnv)); key != "" {
+			return key, nil
+		}
+	}
+
+	if c.APIKeyCommand != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), apiKeyCommandTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", c.APIKeyCommand)
+		cmd.Stdin = os.Stdin // Password managers may ask for a passphrase
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("api_key_command failed: %w", err)
+		}
+		key := strings.TrimSpace(string(out))
+		if key == "" {
+			return "", errors.New("api_key_command printed no API key")
+		}
+		return key, nil
+	}
+
+	key, err := privacy.DefaultCipher().DecryptString(c.APIKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt api_key: %w", err)
+	}
+	return key, nil
+}
+
+// expandPath expands ~ to the home directory
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// StoreFiles returns the files holding stored data: conversations and their
+// search index, memory, sessions with their task queues, and the input
+// history. The config, prompts and archives are not included.
+func StoreFiles(configDir, memoryPath string) ([]string, error) {
+	var files []string
+	dirs := []string{
+		filepath.Join(configDir, ConversationsDirName),
+		filepath.Join(configDir, SessionDirName),
+		expandPath(memoryPath),
+	}
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			// Skip temporary files of interrupted writes
+			if d.Type().IsRegular() && !strings.HasPrefix(d.Name(), ".") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	history := filepath.Join(configDir, HistoryFileName)
+	if _, err := os.Stat(history); err == nil {
+		files = append(files, history)
+	}
+	return files, nil
+}
+
+// ConvertStores rewrites every stored file encrypted with to, or as plain
+// text if to is nil, and returns the number of files changed
+func ConvertStores(configDir, memoryPath string, to *privacy.Cipher) (int, error) {
+	files, err := StoreFiles(configDir, memoryPath)
+	if err != nil {
+		return 0, err
+	}
+	converted := 0
+	for _, path := range files {
+		changed, err := privacy.ConvertFile(path, to)
+		if err != nil {
+			return converted, err
+		}
+		if changed {
+			converted++
+		}
+	}
+	return converted, nil
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+)
+
+func TestResolveAPIKey(t *testing.T) {
+	defer privacy.SetCipher(nil)
+
+	t.Setenv("TADA_TEST_API_KEY", "sk-from-env")
+	cfg := AIConfig{APIKey: "sk-from-file", APIKeyEnv: "TADA_TEST_API_KEY", APIKeyCommand: "echo sk-from-command"}
+	if key, err := cfg.ResolveAPIKey(); err != nil || key != "sk-from-env" {
+		t.Errorf("Expected the environment to win, got %q, %v", key, err)
+	}
+
+	cfg.APIKeyEnv = "TADA_TEST_UNSET"
+	if key, err := cfg.ResolveAPIKey(); err != nil || key != "sk-from-command" {
+		t.Errorf("Expected the command output, got %q, %v", key, err)
+	}
+
+	cfg.APIKeyCommand = "exit 1"
+	if _, err := cfg.ResolveAPIKey(); err == nil {
+		t.Error("Expected error for a failing command")
+	}
+
+	cfg.APIKeyCommand = ""
+	if key, err := cfg.ResolveAPIKey(); err != nil || key != "sk-from-file" {
+		t.Errorf("Expected the config value, got %q, %v", key, err)
+	}
+
+	c, _ := privacy.NewCipher([]byte(strings.Repeat("k", 32)))
+	cfg.APIKey, _ = c.EncryptString("sk-encrypted")
+	if _, err := cfg.ResolveAPIKey(); err == nil {
+		t.Error("Expected error for an encrypted key without encryption enabled")
+	}
+	privacy.SetCipher(c)
+	if key, err := cfg.ResolveAPIKey(); err != nil || key != "sk-encrypted" {
+		t.Errorf("Expected the decrypted key, got %q, %v", key, err)
+	}
+}
+
+func TestInitConfig_Encryption(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	defer privacy.SetCipher(nil)
+
+	configDir := filepath.Join(tmpDir, TadaDirName)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	content := `encryption:
+  enabled: true
+  keyfile: ~/tada.key
+`
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	if privacy.DefaultCipher() == nil {
+		t.Fatal("Expected encryption to be enabled")
+	}
+	for _, path := range []string{filepath.Join(tmpDir, "tada.key"), filepath.Join(configDir, KeyInfoName)} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be created: %v", path, err)
+		}
+	}
+
+	// The API key is saved encrypted
+	cfg.AI.APIKey = "sk-plain"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(configDir, "config.yaml"))
+	if strings.Contains(string(data), "sk-plain") {
+		t.Errorf("Expected the API key to be encrypted, got:\n%s", data)
+	}
+	cfg, err = InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	if key, err := cfg.AI.ResolveAPIKey(); err != nil || key != "sk-plain" {
+		t.Errorf("Expected the API key to round trip, got %q, %v", key, err)
+	}
+
+	// Once tada encrypt converted the stores, plain text files are refused
+	defer privacy.SetRequireEncrypted(false)
+	if err := privacy.MarkConverted(filepath.Join(configDir, KeyInfoName), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InitConfig(); err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	planted := filepath.Join(configDir, "planted.json")
+	os.WriteFile(planted, []byte("{}"), 0600)
+	if _, err := privacy.ReadFile(planted); !errors.Is(err, privacy.ErrPlaintext) {
+		t.Errorf("Expected plain text to be refused, got %v", err)
+	}
+
+	// Without the passphrase a passphrase key cannot be loaded
+	os.Remove(filepath.Join(configDir, KeyInfoName))
+	os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte("encryption:\n  enabled: true\n  passphrase_env: TADA_TEST_UNSET\n"), 0600)
+	if _, err := InitConfig(); err == nil {
+		t.Error("Expected error without a passphrase")
+	}
+}
+
+func TestConvertStores(t *testing.T) {
+	defer privacy.SetCipher(nil)
+	configDir := t.TempDir()
+	memoryDir := filepath.Join(configDir, "memory")
+
+	files := []string{
+		filepath.Join(configDir, ConversationsDirName, "2026-01-01", "abc", "messages.json"),
+		filepath.Join(configDir, SessionDirName, "s1", "queue.json"),
+		filepath.Join(memoryDir, "profile.json"),
+		filepath.Join(configDir, HistoryFileName),
+	}
+	for _, path := range files {
+		os.MkdirAll(filepath.Dir(path), 0700)
+		os.WriteFile(path, []byte("{}"), 0644)
+	}
+	// Not stored data: left as it is
+	config := filepath.Join(configDir, "config.yaml")
+	os.WriteFile(config, []byte("ai: {}"), 0600)
+
+	c, _ := privacy.NewCipher([]byte(strings.Repeat("k", 32)))
+	privacy.SetCipher(c)
+
+	count, err := ConvertStores(configDir, memoryDir, c)
+	if err != nil || count != len(files) {
+		t.Fatalf("ConvertStores = %d, %v", count, err)
+	}
+	for _, path := range files {
+		raw, _ := os.ReadFile(path)
+		if !privacy.IsEncrypted(raw) {
+			t.Errorf("Expected %s to be encrypted", path)
+		}
+	}
+	if raw, _ := os.ReadFile(config); privacy.IsEncrypted(raw) {
+		t.Error("Expected the config to be left alone")
+	}
+
+	if count, _ := ConvertStores(configDir, memoryDir, c); count != 0 {
+		t.Errorf("Expected nothing left to encrypt, got %d", count)
+	}
+	if count, err := ConvertStores(configDir, memoryDir, nil); err != nil || count != len(files) {
+		t.Errorf("ConvertStores = %d, %v", count, err)
+	}
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+)
+
+const (
+	// ArchiveDirName is the directory (under the config dir) holding archived sessions
+	ArchiveDirName = "archive"
+
+	// gcMarkerFile records when automatic garbage collection last ran
+	gcMarkerFile = "gc.last"
+
+	// autoGCInterval is the minimum time between automatic garbage collections
+	autoGCInterval = 24 * time.Hour
+)
+
+// GCReport summarizes what a garbage collection run did (or would do in dry-run mode)
+type GCReport struct {
+	RemovedSessions  []string // Empty session directories removed
+	ArchivedSessions []string // Sessions moved into the archive
+}
+
+// SessionFunc is called for every session that is not empty before it is
+// considered for archival, e.g. to prune its task queue. It reports whether the
+// session is still in use, such sessions are never archived. With dryRun set it
+// must not change anything.
+type SessionFunc func(sessionID, sessionDir string, dryRun bool) (inUse bool, err error)
+
+// CollectGarbage applies the retention configuration to the session files under
+// sessionsDir: empty session directories are removed, and sessions idle for
+// longer than MaxAgeDays that visit does not report as in use are archived as
+// tar.gz files into archiveDir. visit may be nil. With dryRun set nothing is
+// modified.
+func CollectGarbage(sessionsDir, archiveDir string, cfg RetentionConfig, dryRun bool, now time.Time, visit SessionFunc) (*GCReport, error) {
+	report := &GCReport{}
+
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, nil
+		}
+		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		sessionID := entry.Name()
+		sessionDir := filepath.Join(sessionsDir, sessionID)
+
+		files, err := os.ReadDir(sessionDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session %s: %w", sessionID, err)
+		}
+		if len(files) == 0 {
+			report.RemovedSessions = append(report.RemovedSessions, sessionID)
+			if !dryRun {
+				if err := os.Remove(sessionDir); err != nil {
+					return nil, fmt.Errorf("failed to remove session %s: %w", sessionID, err)
+				}
+			}
+			continue
+		}
+
+		// Measure idle time before visiting, which may rewrite the session files
+		lastModified, err := latestModTime(sessionDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat session %s: %w", sessionID, err)
+		}
+
+		inUse := false
+		if visit != nil {
+			if inUse, err = visit(sessionID, sessionDir, dryRun); err != nil {
+				return nil, err
+			}
+		}
+
+		if cfg.MaxAgeDays <= 0 || inUse {
+			continue
+		}
+
+		if now.Sub(lastModified) < time.Duration(cfg.MaxAgeDays)*24*time.Hour {
+			continue
+		}
+
+		report.ArchivedSessions = append(report.ArchivedSessions, sessionID)
+		if dryRun {
+			continue
+		}
+		if err := archiveSession(sessionDir, filepath.Join(archiveDir, sessionID+".tar.gz")); err != nil {
+			return nil, fmt.Errorf("failed to archive session %s: %w", sessionID, err)
+		}
+		if err := os.RemoveAll(sessionDir); err != nil {
+			return nil, fmt.Errorf("failed to remove archived session %s: %w", sessionID, err)
+		}
+	}
+
+	return report, nil
+}
+
+// AutoCollectGarbage runs CollectGarbage on the default directories if auto pruning is
+// enabled and it has not run within the last day. Errors are returned but callers
+// usually treat them as non-fatal.
+func AutoCollectGarbage(cfg RetentionConfig, visit SessionFunc) (*GCReport, error) {
+	if !cfg.AutoPrune {
+		return nil, nil
+	}
+
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	markerPath := filepath.Join(configDir, gcMarkerFile)
+	if info, err := os.Stat(markerPath); err == nil && now.Sub(info.ModTime()) < autoGCInterval {
+		return nil, nil
+	}
+
+	report, err := CollectGarbage(
+		filepath.Join(configDir, SessionDirName),
+		filepath.Join(configDir, ArchiveDirName),
+		cfg, false, now, visit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := privacy.WritePrivate(markerPath, []byte(now.Format(time.RFC3339)+"\n")); err != nil {
+		return report, fmt.Errorf("failed to write gc marker: %w", err)
+	}
+	return report, nil
+}
+
+// latestModTime returns the newest modification time of the files in dir.
+// Directories are skipped since their mtime changes whenever entries are added or removed.
+func latestModTime(dir string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
+
+// archiveSession writes the contents of sessionDir into a gzip-compressed tarball
+func archiveSession(sessionDir, archivePath string) error {
+	if err := os.MkdirAll(filepath.Dir(archivePath), privacy.DirMode); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	out, err := os.OpenFile(archivePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, privacy.FileMode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	base := filepath.Base(sessionDir)
+	err = filepath.WalkDir(sessionDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(sessionDir, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSession writes a session file into sessionsDir/sessionID, last modified at modTime
+func writeSession(t *testing.T, sessionsDir, sessionID string, modTime time.Time) string {
+	t.Helper()
+	dir := filepath.Join(sessionsDir, sessionID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "session.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCollectGarbage(t *testing.T) {
+	root := t.TempDir()
+	sessionsDir := filepath.Join(root, "sessions")
+	archiveDir := filepath.Join(root, "archive")
+	now := time.Now()
+	old := now.Add(-60 * 24 * time.Hour)
+
+	// Old idle session -> archived
+	oldDir := writeSession(t, sessionsDir, "old", old)
+	// Old session still in use -> kept
+	busyDir := writeSession(t, sessionsDir, "busy", old)
+	// Recent session -> kept
+	recentDir := writeSession(t, sessionsDir, "recent", now)
+
+	// Empty session directory -> removed
+	if err := os.MkdirAll(filepath.Join(sessionsDir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var visited []string
+	visit := func(sessionID, sessionDir string, dryRun bool) (bool, error) {
+		visited = append(visited, sessionID)
+		if !dryRun {
+			// Rewriting a file must not make the session look recently used
+			os.WriteFile(filepath.Join(sessionDir, "session.json"), []byte("{}"), 0644)
+		}
+		return sessionID == "busy", nil
+	}
+
+	cfg := RetentionConfig{MaxAgeDays: 30}
+
+	// Dry run reports but changes nothing
+	report, err := CollectGarbage(sessionsDir, archiveDir, cfg, true, now, visit)
+	if err != nil {
+		t.Fatalf("CollectGarbage dry run failed: %v", err)
+	}
+	if len(report.ArchivedSessions) != 1 || len(report.RemovedSessions) != 1 {
+		t.Errorf("Unexpected dry-run report: %+v", report)
+	}
+	if len(visited) != 3 {
+		t.Errorf("Expected the non-empty sessions to be visited, got %v", visited)
+	}
+	if _, err := os.Stat(filepath.Join(sessionsDir, "empty")); err != nil {
+		t.Error("Expected dry run to keep empty session")
+	}
+
+	report, err = CollectGarbage(sessionsDir, archiveDir, cfg, false, now, visit)
+	if err != nil {
+		t.Fatalf("CollectGarbage failed: %v", err)
+	}
+	if len(report.ArchivedSessions) != 1 || report.ArchivedSessions[0] != "old" {
+		t.Errorf("Expected the old session to be archived, got %v", report.ArchivedSessions)
+	}
+
+	if _, err := os.Stat(filepath.Join(archiveDir, "old.tar.gz")); err != nil {
+		t.Errorf("Expected archive for old session: %v", err)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Error("Expected archived session directory to be removed")
+	}
+	if _, err := os.Stat(busyDir); err != nil {
+		t.Error("Expected session in use to be kept")
+	}
+	if _, err := os.Stat(recentDir); err != nil {
+		t.Error("Expected recent session to be kept")
+	}
+	if _, err := os.Stat(filepath.Join(sessionsDir, "empty")); !os.IsNotExist(err) {
+		t.Error("Expected empty session directory to be removed")
+	}
+}
+
+func TestAutoCollectGarbage_Throttled(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	cfg := DefaultRetentionConfig()
+
+	report, err := AutoCollectGarbage(cfg, nil)
+	if err != nil {
+		t.Fatalf("AutoCollectGarbage failed: %v", err)
+	}
+	if report == nil {
+		t.Fatal("Expected first run to collect garbage")
+	}
+
+	report, err = AutoCollectGarbage(cfg, nil)
+	if err != nil {
+		t.Fatalf("AutoCollectGarbage failed: %v", err)
+	}
+	if report != nil {
+		t.Error("Expected second run within a day to be skipped")
+	}
+
+	cfg.AutoPrune = false
+	if report, _ := AutoCollectGarbage(cfg, nil); report != nil {
+		t.Error("Expected disabled auto prune to do nothing")
+	}
+}
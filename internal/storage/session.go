@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
 )
 
 const (
@@ -55,6 +56,11 @@ func SaveSession() error {
 		return err
 	}
 
+	// Only create the directory when there is something to write
+	if err := os.MkdirAll(sessionDir, privacy.DirMode); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
 	sessionPath := filepath.Join(sessionDir, "session.json")
 
 	currentSession.UpdatedAt = time.Now()
@@ -64,12 +70,19 @@ func SaveSession() error {
 		currentSession.Messages = currentSession.Messages[len(currentSession.Messages)-MaxHistory:]
 	}
 
-	data, err := json.MarshalIndent(currentSession, "", "  ")
+	// Redact the messages, not the marshaled JSON, so the file stays valid
+	redacted := *currentSession
+	redacted.Messages = make([]ai.Message, len(currentSession.Messages))
+	for i, msg := range currentSession.Messages {
+		redacted.Messages[i] = ai.Message{Role: msg.Role, Content: privacy.Redact("session", msg.Content)}
+	}
+
+	data, err := json.MarshalIndent(redacted, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal session: %w", err)
 	}
 
-	if err := os.WriteFile(sessionPath, data, 0644); err != nil {
+	if err := privacy.WritePrivate(sessionPath, data); err != nil {
 		return fmt.Errorf("failed to write session: %w", err)
 	}
 
@@ -108,6 +121,9 @@ func ClearSession() error {
 		return err
 	}
 
+	// Remove the session directory if nothing else (e.g. a queue) is left in it
+	_ = removeIfEmpty(sessionDir)
+
 	currentSession = nil
 	return nil
 }
@@ -124,14 +140,26 @@ func generateSessionID() string {
 		now.Nanosecond())
 }
 
+// getSessionDir returns the session directory path without creating it
 func getSessionDir(sessionID string) (string, error) {
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return "", err
 	}
-	sessionDir := filepath.Join(configDir, SessionDirName, sessionID)
-	if err := os.MkdirAll(sessionDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create session directory: %w", err)
+	return filepath.Join(configDir, SessionDirName, sessionID), nil
+}
+
+// removeIfEmpty removes dir if it exists and contains no entries
+func removeIfEmpty(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(entries) > 0 {
+		return nil
 	}
-	return sessionDir, nil
+	return os.Remove(dir)
 }
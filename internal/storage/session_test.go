@@ -75,3 +75,24 @@ func TestClearSession(t *testing.T) {
 		t.Error("Expected nil session after clear")
 	}
 }
+
+func TestClearSession_RemovesEmptyDir(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	session, err := InitSession()
+	if err != nil {
+		t.Fatalf("InitSession failed: %v", err)
+	}
+	AddMessage("user", "hello")
+
+	sessionDir, _ := getSessionDir(session.ID)
+	if err := ClearSession(); err != nil {
+		t.Fatalf("ClearSession failed: %v", err)
+	}
+	if _, err := os.Stat(sessionDir); !os.IsNotExist(err) {
+		t.Error("Expected empty session directory to be removed")
+	}
+}
@@ -0,0 +1,383 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 附件大小限制
+const (
+	DefaultMaxFileBytes  = 64 * 1024  // 单个文件最多内联的字节数
+	DefaultMaxTotalBytes = 256 * 1024 // 一条消息中所有附件最多内联的字节数
+	DefaultMaxGlobFiles  = 20         // /attach 一次最多匹配的文件数
+)
+
+// StdinName 标准输入作为附件时显示的名称
+const StdinName = "stdin"
+
+// PathChecker 检查路径是否禁止访问，*security.PathAccessChecker 实现了该接口
+type PathChecker interface {
+	IsRestricted(path string) bool
+}
+
+// Attachment 内联到消息中的文件内容
+type Attachment struct {
+	Path      string // 用户输入的路径
+	Content   string
+	Size      int64 // 原始大小（字节）
+	Truncated bool
+}
+
+// Attacher 读取要附加到消息中的文件
+type Attacher struct {
+	checker       PathChecker // nil 表示不限制路径
+	MaxFileBytes  int
+	MaxTotalBytes int
+	MaxGlobFiles  int
+}
+
+// NewAttacher 创建附件读取器，checker 为 nil 时不检查受限路径
+func NewAttacher(checker PathChecker) *Attacher {
+	return &Attacher{
+		checker:       checker,
+		MaxFileBytes:  DefaultMaxFileBytes,
+		MaxTotalBytes: DefaultMaxTotalBytes,
+		MaxGlobFiles:  DefaultMaxGlobFiles,
+	}
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// checkPath 在读取之前检查路径是否受限
+func (a *Attacher) checkPath(path string) error {
+	if a.checker != nil && a.checker.IsRestricted(expandHome(path)) {
+		return fmt.Errorf("access to restricted path denied: %s", path)
+	}
+	return nil
+}
+
+// Load 读取文件，超过大小限制时截断
+func (a *Attacher) Load(path string) (Attachment, error) {
+	if err := a.checkPath(path); err != nil {
+		return Attachment{}, err
+	}
+
+	resolved := expandHome(path)
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if info.IsDir() {
+		return Attachment{}, fmt.Errorf("%s is a directory", path)
+	}
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer f.Close()
+
+	att, err := a.read(path, f)
+	if err != nil {
+		return Attachment{}, err
+	}
+	att.Size = info.Size()
+	att.Truncated = att.Truncated || int64(len(att.Content)) < info.Size()
+	return att, nil
+}
+
+// Read 从 reader 读取附件内容（例如管道输入的标准输入）
+func (a *Attacher) Read(name string, r io.Reader) (Attachment, error) {
+	att, err := a.read(name, r)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if att.Truncated {
+		// 读完剩余内容以得到原始大小
+		rest, _ := io.Copy(io.Discard, r)
+		att.Size += rest
+	}
+	return att, nil
+}
+
+// read 最多读取 MaxFileBytes 字节，拒绝二进制内容
+func (a *Attacher) read(name string, r io.Reader) (Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(a.MaxFileBytes)+1))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return Attachment{}, fmt.Errorf("%s looks like a binary file", name)
+	}
+
+	att := Attachment{Path: name, Size: int64(len(data))}
+	if len(data) > a.MaxFileBytes {
+		data = data[:a.MaxFileBytes]
+		att.Truncated = true
+
+		// 不在多字节字符中间截断
+		if r, _ := utf8.DecodeLastRune(data); r == utf8.RuneError {
+			for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+				if utf8.RuneStart(data[len(data)-i]) {
+					data = data[:len(data)-i]
+					break
+				}
+			}
+		}
+	}
+	att.Content = string(data)
+	return att, nil
+}
+
+// Glob 读取匹配 pattern 的所有文件，跳过目录
+//
+// 受限或无法读取的文件不会中断其他文件的读取，错误一并返回。
+func (a *Attacher) Glob(pattern string) ([]Attachment, []error) {
+	matches, err := filepath.Glob(expandHome(pattern))
+	if err != nil {
+		return nil, []error{err}
+	}
+	sort.Strings(matches)
+
+	var attachments []Attachment
+	var errs []error
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			continue
+		}
+		if len(attachments) >= a.MaxGlobFiles {
+			errs = append(errs, fmt.Errorf("too many files match %s, only the first %d are attached", pattern, a.MaxGlobFiles))
+			break
+		}
+		att, err := a.Load(match)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		attachments = append(attachments, att)
+	}
+	if len(attachments) == 0 && len(errs) == 0 {
+		errs = append(errs, fmt.Errorf("no files match %s", pattern))
+	}
+	return attachments, errs
+}
+
+// mentionPattern 匹配消息中的 @路径（@ 必须位于开头或空白之后）
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// mentionTrailing 路径后面可能跟着的标点
+const mentionTrailing = ",.;:!?)]}'\"，。；：！？）】"
+
+// Mentions 读取消息中 @路径 引用的文件
+//
+// 只处理存在的普通文件，像 @someone 这样的文本保持原样；
+// 受限或无法读取的文件作为错误返回。
+func (a *Attacher) Mentions(input string) ([]Attachment, []error) {
+	var attachments []Attachment
+	var errs []error
+	seen := make(map[string]bool)
+
+	for _, m := range mentionPattern.FindAllStringSubmatch(input, -1) {
+		path := m[1]
+		if !isFile(path) {
+			path = strings.TrimRight(path, mentionTrailing)
+			if !isFile(path) {
+				continue
+			}
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		att, err := a.Load(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		attachments = append(attachments, att)
+	}
+	return attachments, errs
+}
+
+// isFile 判断路径是否是存在的普通文件
+func isFile(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(expandHome(path))
+	return err == nil && !info.IsDir()
+}
+
+// Limit 按总大小限制保留附件，返回被丢弃的附件
+func (a *Attacher) Limit(attachments []Attachment) (kept, dropped []Attachment) {
+	total := 0
+	for _, att := range attachments {
+		if total+len(att.Content) > a.MaxTotalBytes {
+			dropped = append(dropped, att)
+			continue
+		}
+		total += len(att.Content)
+		kept = append(kept, att)
+	}
+	return kept, dropped
+}
+
+// FormatAttachments 将附件格式化为消息正文，每个文件一个代码块
+func FormatAttachments(attachments []Attachment) string {
+	var b strings.Builder
+	for i, att := range attachments {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "📎 %s\n", att.Path)
+
+		// 内容中含有 ``` 时使用更长的围栏
+		fence := "```"
+		for strings.Contains(att.Content, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "%s%s\n%s", fence, fenceLanguage(att.Path), att.Content)
+		if !strings.HasSuffix(att.Content, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString(fence)
+
+		if att.Truncated {
+			fmt.Fprintf(&b, "\n[已截断：共 %d 字节，只包含前 %d 字节]", att.Size, len(att.Content))
+		}
+	}
+	return b.String()
+}
+
+// fenceLanguage 根据扩展名返回代码块语言
+func fenceLanguage(path string) string {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" || strings.ContainsAny(ext, " `") {
+		return ""
+	}
+	return strings.ToLower(ext)
+}
+
+// AttachToMessage 将附件追加到消息正文之后
+func AttachToMessage(message string, attachments []Attachment) string {
+	if len(attachments) == 0 {
+		return message
+	}
+	if message == "" {
+		return FormatAttachments(attachments)
+	}
+	return message + "\n\n" + FormatAttachments(attachments)
+}
+
+// SetAttacher 设置附件读取器，用于检查受限路径和调整大小限制
+func (r *REPL) SetAttacher(attacher *Attacher) {
+	r.attacher = attacher
+}
+
+// Attach 添加附件，随下一条消息发送
+func (r *REPL) Attach(att Attachment) error {
+	total := len(att.Content)
+	for _, pending := range r.pending {
+		total += len(pending.Content)
+	}
+	if total > r.attacher.MaxTotalBytes {
+		return fmt.Errorf("attachments exceed %d bytes, %s not attached", r.attacher.MaxTotalBytes, att.Path)
+	}
+	r.pending = append(r.pending, att)
+	return nil
+}
+
+// attachFile 处理 /file <路径>
+func (r *REPL) attachFile(path string) {
+	if path == "" {
+		fmt.Println("用法: /file <路径>")
+		return
+	}
+	att, err := r.attacher.Load(path)
+	if err == nil {
+		err = r.Attach(att)
+	}
+	if err != nil {
+		fmt.Printf("附加文件失败: %v\n", err)
+		return
+	}
+	printAttached(att)
+}
+
+// attachGlob 处理 /attach <通配符>，不带参数时列出待发送的附件
+func (r *REPL) attachGlob(pattern string) {
+	if pattern == "" {
+		r.DisplayAttachments()
+		return
+	}
+	attachments, errs := r.attacher.Glob(pattern)
+	for _, err := range errs {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	for _, att := range attachments {
+		if err := r.Attach(att); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+			continue
+		}
+		printAttached(att)
+	}
+}
+
+// printAttached 显示已附加的文件
+func printAttached(att Attachment) {
+	note := ""
+	if att.Truncated {
+		note = fmt.Sprintf("，已截断为 %d 字节", len(att.Content))
+	}
+	fmt.Printf("📎 已附加 %s（%d 字节%s），将随下一条消息发送\n", att.Path, att.Size, note)
+}
+
+// DisplayAttachments 显示待发送的附件
+func (r *REPL) DisplayAttachments() {
+	if len(r.pending) == 0 {
+		fmt.Println("没有待发送的附件（/file <路径> 或 /attach <通配符> 添加）")
+		return
+	}
+	fmt.Println("待发送的附件:")
+	for _, att := range r.pending {
+		fmt.Printf("  📎 %s（%d 字节）\n", att.Path, len(att.Content))
+	}
+	fmt.Println("使用 /detach 清除")
+}
+
+// withAttachments 将待发送的附件和 @路径 引用的文件加入消息
+func (r *REPL) withAttachments(input string) string {
+	attachments := r.pending
+	r.pending = nil
+
+	mentioned, errs := r.attacher.Mentions(input)
+	for _, err := range errs {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	for _, att := range mentioned {
+		fmt.Printf("📎 %s（%d 字节）\n", att.Path, len(att.Content))
+	}
+	attachments = append(attachments, mentioned...)
+
+	kept, dropped := r.attacher.Limit(attachments)
+	for _, att := range dropped {
+		fmt.Printf("⚠️  附件总大小超过 %d 字节，已跳过 %s\n", r.attacher.MaxTotalBytes, att.Path)
+	}
+	return AttachToMessage(input, kept)
+}
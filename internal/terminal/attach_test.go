@@ -0,0 +1,241 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/conversation"
+)
+
+// restrictDir 将某个目录视为受限路径
+type restrictDir string
+
+func (d restrictDir) IsRestricted(path string) bool {
+	abs, _ := filepath.Abs(path)
+	return abs == string(d) || strings.HasPrefix(abs, string(d)+string(filepath.Separator))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAttacher_Load(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	writeFile(t, path, "package main\n")
+
+	att, err := NewAttacher(nil).Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if att.Content != "package main\n" || att.Truncated || att.Size != 13 {
+		t.Errorf("unexpected attachment: %+v", att)
+	}
+
+	if _, err := NewAttacher(nil).Load(dir); err == nil {
+		t.Error("expected error for directory")
+	}
+
+	binary := filepath.Join(dir, "data.bin")
+	writeFile(t, binary, "abc\x00def")
+	if _, err := NewAttacher(nil).Load(binary); err == nil {
+		t.Error("expected error for binary file")
+	}
+}
+
+func TestAttacher_Load_Truncates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	writeFile(t, path, strings.Repeat("日志", 10))
+
+	attacher := NewAttacher(nil)
+	attacher.MaxFileBytes = 10
+	att, err := attacher.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !att.Truncated || att.Size != 60 {
+		t.Errorf("expected truncated attachment of 60 bytes, got %+v", att)
+	}
+	// 不在多字节字符中间截断
+	if att.Content != "日志日" {
+		t.Errorf("Content = %q, want %q", att.Content, "日志日")
+	}
+
+	formatted := FormatAttachments([]Attachment{att})
+	if !strings.Contains(formatted, "[已截断：共 60 字节，只包含前 9 字节]") {
+		t.Errorf("missing truncation marker:\n%s", formatted)
+	}
+}
+
+func TestAttacher_Read(t *testing.T) {
+	attacher := NewAttacher(nil)
+	attacher.MaxFileBytes = 4
+	att, err := attacher.Read(StdinName, strings.NewReader("0123456789"))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if att.Content != "0123" || !att.Truncated || att.Size != 10 || att.Path != StdinName {
+		t.Errorf("unexpected attachment: %+v", att)
+	}
+}
+
+func TestAttacher_RestrictedPath(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret", "key.pem")
+	public := filepath.Join(dir, "public", "notes.md")
+	writeFile(t, secret, "PRIVATE KEY")
+	writeFile(t, public, "notes")
+
+	attacher := NewAttacher(restrictDir(filepath.Join(dir, "secret")))
+
+	if _, err := attacher.Load(secret); err == nil || !strings.Contains(err.Error(), "restricted") {
+		t.Errorf("expected restricted path error, got %v", err)
+	}
+
+	attachments, errs := attacher.Glob(filepath.Join(dir, "*", "*"))
+	if len(attachments) != 1 || attachments[0].Path != public {
+		t.Errorf("expected only the public file, got %+v", attachments)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected 1 error for the restricted file, got %v", errs)
+	}
+
+	mentioned, errs := attacher.Mentions("看看 @" + secret)
+	if len(mentioned) != 0 || len(errs) != 1 {
+		t.Errorf("restricted mention should not be attached: %+v %v", mentioned, errs)
+	}
+}
+
+func TestAttacher_Glob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.go", "a.go", "c.txt"} {
+		writeFile(t, filepath.Join(dir, name), name)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "d.go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	attachments, errs := NewAttacher(nil).Glob(filepath.Join(dir, "*.go"))
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(attachments) != 2 || attachments[0].Content != "a.go" || attachments[1].Content != "b.go" {
+		t.Errorf("expected a.go and b.go in order, got %+v", attachments)
+	}
+
+	attacher := NewAttacher(nil)
+	attacher.MaxGlobFiles = 1
+	attachments, errs = attacher.Glob(filepath.Join(dir, "*.go"))
+	if len(attachments) != 1 || len(errs) != 1 {
+		t.Errorf("expected 1 attachment and a limit error, got %d, %v", len(attachments), errs)
+	}
+
+	if _, errs := attacher.Glob(filepath.Join(dir, "*.rs")); len(errs) != 1 {
+		t.Errorf("expected no-match error, got %v", errs)
+	}
+}
+
+func TestAttacher_Mentions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, "key: value\n")
+
+	input := "请检查 @" + path + "，顺便问 @someone，邮件 a@b.com，重复 @" + path
+	attachments, errs := NewAttacher(nil).Mentions(input)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(attachments) != 1 || attachments[0].Path != path {
+		t.Errorf("expected one attachment for %s, got %+v", path, attachments)
+	}
+}
+
+func TestAttacher_Limit(t *testing.T) {
+	attacher := NewAttacher(nil)
+	attacher.MaxTotalBytes = 5
+	kept, dropped := attacher.Limit([]Attachment{
+		{Path: "a", Content: "123"},
+		{Path: "b", Content: "456"},
+		{Path: "c", Content: "78"},
+	})
+	if len(kept) != 2 || kept[1].Path != "c" || len(dropped) != 1 || dropped[0].Path != "b" {
+		t.Errorf("unexpected limit result: kept=%+v dropped=%+v", kept, dropped)
+	}
+}
+
+func TestFormatAttachments(t *testing.T) {
+	got := AttachToMessage("解释一下", []Attachment{
+		{Path: "main.go", Content: "package main"},
+		{Path: "README.md", Content: "```sh\nls\n```\n"},
+	})
+	want := "解释一下\n\n📎 main.go\n```go\npackage main\n```\n\n" +
+		"📎 README.md\n````md\n```sh\nls\n```\n````"
+	if got != want {
+		t.Errorf("AttachToMessage =\n%s\nwant\n%s", got, want)
+	}
+
+	if AttachToMessage("hi", nil) != "hi" {
+		t.Error("message without attachments should be unchanged")
+	}
+}
+
+func TestREPL_Attachments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "error.log")
+	writeFile(t, path, "panic: boom\n")
+
+	storage := conversation.NewFileStorage(t.TempDir())
+	manager := conversation.NewManager(storage, conversation.NewPromptLoader(dir), &mockREPLAIProvider{response: "ok"})
+	conv, _ := manager.Create("test", "default")
+	repl := NewREPL(manager, conv, false, 10)
+
+	if _, err := repl.HandleCommand("/file " + path); err != nil {
+		t.Fatalf("/file failed: %v", err)
+	}
+	if len(repl.pending) != 1 {
+		t.Fatalf("expected 1 pending attachment, got %d", len(repl.pending))
+	}
+
+	if err := repl.ProcessInput("为什么崩溃？"); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if len(repl.pending) != 0 {
+		t.Error("pending attachments should be sent with the message")
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	var user string
+	for _, msg := range loaded.ActiveMessages() {
+		if msg.Role == "user" {
+			user = msg.Content
+		}
+	}
+	if !strings.HasPrefix(user, "为什么崩溃？") || !strings.Contains(user, "panic: boom") {
+		t.Errorf("attachment not inlined into message: %q", user)
+	}
+
+	// @路径 引用的文件直接随消息发送
+	if err := repl.ProcessInput("再看 @" + path); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	loaded, _ = manager.Get(conv.ID)
+	messages := loaded.ActiveMessages()
+	if last := messages[len(messages)-2]; !strings.Contains(last.Content, "```log\npanic: boom\n```") {
+		t.Errorf("mention not inlined into message: %q", last.Content)
+	}
+
+	if _, err := repl.HandleCommand("/attach " + filepath.Join(dir, "*.log")); err != nil {
+		t.Fatalf("/attach failed: %v", err)
+	}
+	if _, err := repl.HandleCommand("/detach"); err != nil || len(repl.pending) != 0 {
+		t.Errorf("/detach should clear pending attachments, err=%v pending=%d", err, len(repl.pending))
+	}
+}
@@ -0,0 +1,150 @@
+package terminal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CommandRunner 在对话中执行命令
+//
+// 每个方法都经过安全检查和用户确认，返回的文本会追加到对话中，
+// 作为下一轮对话的上下文。返回空字符串表示没有可记录的内容。
+type CommandRunner interface {
+	// RunRequest 将自然语言请求转换为命令并执行
+	RunRequest(ctx context.Context, request string) (string, error)
+	// RunShell 执行用户输入的 shell 命令行
+	RunShell(ctx context.Context, line string) (string, error)
+	// RunProposed 执行助手建议的 shell 命令行，每条都需要确认
+	RunProposed(ctx context.Context, lines []string) (string, error)
+}
+
+// shellBlockLanguages 被视为可执行命令的代码块语言
+var shellBlockLanguages = map[string]bool{
+	"bash":    true,
+	"sh":      true,
+	"shell":   true,
+	"zsh":     true,
+	"console": true,
+}
+
+// ExtractShellCommands 从助手回复的 shell 代码块中提取命令行
+//
+// 跳过空行和注释，去掉 "$ " 提示符，并合并以 "\" 结尾的续行。
+func ExtractShellCommands(response string) []string {
+	var commands []string
+	inBlock := false
+	pending := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "```") {
+			if inBlock {
+				if pending != "" {
+					commands = append(commands, pending)
+					pending = ""
+				}
+				inBlock = false
+				continue
+			}
+			lang := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "```")))
+			inBlock = shellBlockLanguages[lang]
+			if !inBlock {
+				// 跳过非 shell 代码块直到结束标记
+				for scanner.Scan() {
+					if strings.HasPrefix(strings.TrimSpace(scanner.Text()), "```") {
+						break
+					}
+				}
+			}
+			continue
+		}
+
+		if !inBlock {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "$ ")
+		if pending == "" && (line == "" || strings.HasPrefix(line, "#")) {
+			continue
+		}
+
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSuffix(line, "\\")
+			continue
+		}
+		commands = append(commands, pending+line)
+		pending = ""
+	}
+
+	return commands
+}
+
+// SetCommandRunner 设置命令执行器，启用 /run 和 ! 命令
+func (r *REPL) SetCommandRunner(runner CommandRunner) {
+	r.runner = runner
+}
+
+// SetProposeCommands 设置是否提供执行助手回复中建议的命令
+func (r *REPL) SetProposeCommands(propose bool) {
+	r.proposeCommands = propose
+}
+
+// runShell 处理 !<shell> 输入
+func (r *REPL) runShell(line string) error {
+	if r.runner == nil {
+		fmt.Println("对话中执行命令未启用")
+		return nil
+	}
+	if line == "" {
+		fmt.Println("用法: !<shell 命令>")
+		return nil
+	}
+
+	transcript, err := r.runner.RunShell(context.Background(), line)
+	return r.recordTranscript(transcript, err)
+}
+
+// runRequest 处理 /run <请求>
+func (r *REPL) runRequest(request string) error {
+	if r.runner == nil {
+		fmt.Println("对话中执行命令未启用")
+		return nil
+	}
+	if request == "" {
+		fmt.Println("用法: /run <请求>")
+		return nil
+	}
+
+	transcript, err := r.runner.RunRequest(context.Background(), request)
+	return r.recordTranscript(transcript, err)
+}
+
+// offerProposedCommands 提供执行助手回复中 shell 代码块里的命令
+func (r *REPL) offerProposedCommands(response string) error {
+	if r.runner == nil || !r.proposeCommands {
+		return nil
+	}
+
+	commands := ExtractShellCommands(response)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n💡 助手建议了 %d 条命令，逐条确认是否执行:\n", len(commands))
+	transcript, err := r.runner.RunProposed(context.Background(), commands)
+	return r.recordTranscript(transcript, err)
+}
+
+// recordTranscript 将命令执行记录追加到对话
+func (r *REPL) recordTranscript(transcript string, runErr error) error {
+	if transcript != "" {
+		if err := r.manager.AppendMessage(r.conversation.ID, "user", transcript); err != nil {
+			return fmt.Errorf("保存命令输出失败: %w", err)
+		}
+	}
+	return runErr
+}
@@ -0,0 +1,102 @@
+package terminal
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/conversation"
+)
+
+// fakeRunner 记录调用并返回固定的执行记录
+type fakeRunner struct {
+	shell    []string
+	requests []string
+	proposed [][]string
+}
+
+func (f *fakeRunner) RunRequest(ctx context.Context, request string) (string, error) {
+	f.requests = append(f.requests, request)
+	return "[request]\n" + request, nil
+}
+
+func (f *fakeRunner) RunShell(ctx context.Context, line string) (string, error) {
+	f.shell = append(f.shell, line)
+	return "$ " + line, nil
+}
+
+func (f *fakeRunner) RunProposed(ctx context.Context, lines []string) (string, error) {
+	f.proposed = append(f.proposed, lines)
+	return "[proposed]", nil
+}
+
+func TestExtractShellCommands(t *testing.T) {
+	response := "试试这些:\n\n```bash\n# 列出文件\n$ ls -la\ngrep -r foo \\\n  src\n```\n\n```go\nfmt.Println(1)\n```\n\n```\nplain block\n```\n"
+
+	got := ExtractShellCommands(response)
+	want := []string{"ls -la", "grep -r foo src"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractShellCommands() = %q, want %q", got, want)
+	}
+}
+
+func TestREPL_ShellAndRunCommands(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "repl-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	manager := conversation.NewManager(conversation.NewFileStorage(tmpDir), conversation.NewPromptLoader(tmpDir), &mockREPLAIProvider{response: "ok"})
+	conv, _ := manager.Create("test", "default")
+
+	runner := &fakeRunner{}
+	repl := NewREPL(manager, conv, false, 10)
+	repl.SetCommandRunner(runner)
+
+	if err := repl.ProcessInput("!ls -la"); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+	if err := repl.ProcessInput("/run 查看磁盘空间"); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+
+	if len(runner.shell) != 1 || runner.shell[0] != "ls -la" {
+		t.Errorf("Expected shell command to be run, got %v", runner.shell)
+	}
+	if len(runner.requests) != 1 || runner.requests[0] != "查看磁盘空间" {
+		t.Errorf("Expected request to be run, got %v", runner.requests)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	// system + 两条命令记录
+	if len(loaded.Messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(loaded.Messages))
+	}
+	if loaded.Messages[1].Content != "$ ls -la" {
+		t.Errorf("Expected shell transcript in conversation, got %q", loaded.Messages[1].Content)
+	}
+}
+
+func TestREPL_OffersProposedCommands(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "repl-test-*")
+	defer os.RemoveAll(tmpDir)
+
+	response := "运行:\n```sh\nmake test\n```\n"
+	manager := conversation.NewManager(conversation.NewFileStorage(tmpDir), conversation.NewPromptLoader(tmpDir), &mockREPLAIProvider{response: response})
+	conv, _ := manager.Create("test", "default")
+
+	runner := &fakeRunner{}
+	repl := NewREPL(manager, conv, false, 10)
+	repl.SetCommandRunner(runner)
+
+	// 未启用建议时不执行
+	_ = repl.ProcessInput("怎么跑测试")
+	if len(runner.proposed) != 0 {
+		t.Error("Expected no proposals when disabled")
+	}
+
+	repl.SetProposeCommands(true)
+	_ = repl.ProcessInput("怎么跑测试")
+	if len(runner.proposed) != 1 || runner.proposed[0][0] != "make test" {
+		t.Errorf("Expected proposed command, got %v", runner.proposed)
+	}
+}
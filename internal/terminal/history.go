@@ -0,0 +1,153 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+)
+
+// DefaultHistorySize 默认保留的输入历史条数
+const DefaultHistorySize = 1000
+
+// History 输入历史，保存在文件中供之后的会话使用
+//
+// 每条记录占一行，多行输入中的换行和反斜杠会被转义。
+type History struct {
+	path    string // 空字符串表示只保存在内存中
+	max     int
+	entries []string
+}
+
+// LoadHistory 从文件加载输入历史，文件不存在时返回空历史
+//
+// 文件可能已加密，见 privacy.ReadFile。
+func LoadHistory(path string, max int) (*History, error) {
+	if max <= 0 {
+		max = DefaultHistorySize
+	}
+	h := &History{path: path, max: max}
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := privacy.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		// 读取失败（例如加密后未配置密钥）时只保存在内存中，避免破坏原文件
+		h.path = ""
+		return h, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, unescapeHistory(line))
+		}
+	}
+	if len(h.entries) > max {
+		h.entries = h.entries[len(h.entries)-max:]
+	}
+	return h, nil
+}
+
+// Len 返回历史条数
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// At 返回第 i 条历史（0 为最早）
+func (h *History) At(i int) string {
+	return h.entries[i]
+}
+
+// Add 添加一条历史并写入文件，跳过空输入和与上一条相同的输入
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return nil
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+		return h.rewrite()
+	}
+	// 加密的文件不能追加，整个重写
+	if privacy.DefaultCipher() != nil {
+		return h.rewrite()
+	}
+	return h.append(line)
+}
+
+// Search 从第 before 条之前向前查找包含 query 的历史，返回下标，找不到返回 -1
+func (h *History) Search(query string, before int) int {
+	if before > len(h.entries) {
+		before = len(h.entries)
+	}
+	for i := before - 1; i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// append 在历史文件末尾追加一条记录
+func (h *History) append(line string) error {
+	if h.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), privacy.DirMode); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, privacy.FileMode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, escapeHistory(privacy.Redact("history", line)))
+	return err
+}
+
+// rewrite 超出条数限制或启用加密时重写历史文件
+func (h *History) rewrite() error {
+	if h.path == "" {
+		return nil
+	}
+	var b strings.Builder
+	for _, entry := range h.entries {
+		b.WriteString(escapeHistory(privacy.Redact("history", entry)))
+		b.WriteByte('\n')
+	}
+	return privacy.WritePrivate(h.path, []byte(b.String()))
+}
+
+// escapeHistory 转义换行和反斜杠，使每条记录占一行
+func escapeHistory(line string) string {
+	line = strings.ReplaceAll(line, `\`, `\\`)
+	return strings.ReplaceAll(line, "\n", `\n`)
+}
+
+// unescapeHistory 还原 escapeHistory 转义的记录
+func unescapeHistory(line string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			i++
+			if line[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(line[i])
+			}
+			continue
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,114 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/privacy"
+)
+
+func TestHistory_PersistsAcrossSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat_history")
+
+	h, err := LoadHistory(path, 10)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	for _, line := range []string{"hello", "hello", "  ", "```go\nfmt.Println(`a\\b`)\n```", "/exit"} {
+		if err := h.Add(line); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if h.Len() != 3 {
+		t.Fatalf("expected 3 entries (duplicates and blanks skipped), got %d", h.Len())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("history file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadHistory(path, 10)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if loaded.Len() != 3 || loaded.At(1) != "```go\nfmt.Println(`a\\b`)\n```" {
+		t.Errorf("multi-line entry not restored: %q", loaded.At(1))
+	}
+}
+
+func TestHistory_Limit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat_history")
+	h, _ := LoadHistory(path, 2)
+	for _, line := range []string{"a", "b", "c"} {
+		if err := h.Add(line); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if h.Len() != 2 || h.At(0) != "b" {
+		t.Errorf("expected [b c], got %d entries starting with %q", h.Len(), h.At(0))
+	}
+
+	loaded, _ := LoadHistory(path, 2)
+	if loaded.Len() != 2 || loaded.At(0) != "b" || loaded.At(1) != "c" {
+		t.Errorf("history file not trimmed: %d entries", loaded.Len())
+	}
+}
+
+func TestHistory_Search(t *testing.T) {
+	h, _ := LoadHistory("", 0)
+	for _, line := range []string{"git status", "ls -la", "git log"} {
+		_ = h.Add(line)
+	}
+
+	if got := h.Search("git", h.Len()); got != 2 {
+		t.Errorf("Search(git) = %d, want 2", got)
+	}
+	if got := h.Search("git", 2); got != 0 {
+		t.Errorf("Search(git, 2) = %d, want 0", got)
+	}
+	if got := h.Search("docker", h.Len()); got != -1 {
+		t.Errorf("Search(docker) = %d, want -1", got)
+	}
+}
+
+func TestHistory_Encrypted(t *testing.T) {
+	c, _ := privacy.NewCipher([]byte(strings.Repeat("k", 32)))
+	privacy.SetCipher(c)
+	defer privacy.SetCipher(nil)
+
+	path := filepath.Join(t.TempDir(), "chat_history")
+	// 启用加密前写入的明文历史
+	os.WriteFile(path, []byte("old\n"), 0600)
+
+	h, err := LoadHistory(path, 10)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	h.Add("new")
+	raw, _ := os.ReadFile(path)
+	if !privacy.IsEncrypted(raw) {
+		t.Fatalf("expected the history to be encrypted, got %q", raw)
+	}
+
+	loaded, err := LoadHistory(path, 10)
+	if err != nil || loaded.Len() != 2 || loaded.At(1) != "new" {
+		t.Fatalf("unexpected history: %v, %v", loaded.entries, err)
+	}
+
+	// 没有密钥时读取失败，也不会写坏原文件
+	privacy.SetCipher(nil)
+	locked, err := LoadHistory(path, 10)
+	if err == nil {
+		t.Fatal("expected error without a key")
+	}
+	locked.Add("plain")
+	if after, _ := os.ReadFile(path); string(after) != string(raw) {
+		t.Error("expected the encrypted history to be left alone")
+	}
+}
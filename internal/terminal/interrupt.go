@@ -0,0 +1,87 @@
+package terminal
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// keyWatcher 生成回复期间监听终端按键，任意按键都会中断生成
+//
+// 按键从单独打开的 /dev/tty 以非阻塞方式读取，停止监听后不会吞掉之后的输入。
+// 监听期间终端处于 raw 模式，输出的换行需要经过 translate 转换。
+type keyWatcher struct {
+	tty   *os.File
+	state *term.State
+	done  chan struct{}
+}
+
+// watchKeys 开始监听按键，按下任意键时调用 cancel
+//
+// 没有可用的终端时返回 nil，nil 的 keyWatcher 可以安全使用。
+func watchKeys(cancel func()) *keyWatcher {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil
+	}
+	// 不支持超时的文件无法在停止时打断读取
+	if err := tty.SetReadDeadline(time.Time{}); err != nil {
+		tty.Close()
+		return nil
+	}
+
+	w := &keyWatcher{tty: tty, done: make(chan struct{})}
+	if err := w.control(func(fd int) error {
+		state, err := term.MakeRaw(fd)
+		w.state = state
+		return err
+	}); err != nil {
+		tty.Close()
+		return nil
+	}
+
+	go func() {
+		defer close(w.done)
+		buf := make([]byte, 16)
+		if n, err := tty.Read(buf); err == nil && n > 0 {
+			cancel()
+		}
+	}()
+	return w
+}
+
+// control 在 tty 的文件描述符上执行 fn，不影响其非阻塞模式
+func (w *keyWatcher) control(fn func(fd int) error) error {
+	raw, err := w.tty.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	if err := raw.Control(func(fd uintptr) { fnErr = fn(int(fd)) }); err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// Stop 停止监听并恢复终端，可以重复调用
+func (w *keyWatcher) Stop() {
+	if w == nil || w.tty == nil {
+		return
+	}
+	_ = w.tty.SetReadDeadline(time.Now())
+	<-w.done
+	_ = w.control(func(fd int) error { return term.Restore(fd, w.state) })
+	w.tty.Close()
+	w.tty = nil
+}
+
+// translate 监听期间把换行转换为 \r\n
+func (w *keyWatcher) translate(s string) string {
+	if w == nil || w.tty == nil {
+		return s
+	}
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
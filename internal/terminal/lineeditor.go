@@ -0,0 +1,702 @@
+package terminal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// ErrInterrupt 表示用户按 Ctrl-C 放弃了当前输入
+var ErrInterrupt = errors.New("interrupted")
+
+// Completer 返回输入的补全候选，每个候选是补全后的整行
+type Completer func(line string) []string
+
+// 括号粘贴模式：粘贴的内容包在 ESC[200~ 和 ESC[201~ 之间，其中的换行不会提交输入
+const (
+	bracketedPasteOn  = "\x1b[?2004h"
+	bracketedPasteOff = "\x1b[?2004l"
+	pasteEnd          = "\x1b[201~"
+)
+
+// LineEditor 交互式行编辑器
+//
+// 支持光标移动、输入历史（↑/↓）、反向搜索（Ctrl-R）、Tab 补全和多行输入：
+// Alt-Enter 或 Ctrl-J 插入换行，以 ``` 开头的输入在代码块闭合前 Enter 也只插入换行。
+// 标准输入不是终端时退化为按行读取。
+type LineEditor struct {
+	reader    *bufio.Reader
+	fd        int
+	out       io.Writer
+	history   *History
+	completer Completer
+
+	Prompt             string // 第一行的提示符
+	ContinuationPrompt string // 多行输入后续行的提示符
+
+	buf       []rune
+	pos       int
+	cursorRow int // 光标相对输入区域第一行的行数
+	endRow    int // 输入区域最后一行的行数
+	histIdx   int // 正在浏览的历史下标，等于 history.Len() 表示当前输入
+	draft     string
+}
+
+// NewLineEditor 创建行编辑器，reader 应包装 in，以便和其他读取标准输入的地方共用缓冲
+func NewLineEditor(reader *bufio.Reader, in *os.File, out io.Writer, history *History) *LineEditor {
+	fd := -1
+	if in != nil {
+		fd = int(in.Fd())
+	}
+	if history == nil {
+		history, _ = LoadHistory("", 0)
+	}
+	return &LineEditor{
+		reader:             reader,
+		fd:                 fd,
+		out:                out,
+		history:            history,
+		Prompt:             "👉 ",
+		ContinuationPrompt: "… ",
+	}
+}
+
+// SetCompleter 设置 Tab 补全
+func (e *LineEditor) SetCompleter(completer Completer) {
+	e.completer = completer
+}
+
+// ReadLine 读取一次输入，可能包含多行
+//
+// Ctrl-C 返回 ErrInterrupt，空行上的 Ctrl-D 返回 io.EOF。
+func (e *LineEditor) ReadLine() (string, error) {
+	if e.fd < 0 || !term.IsTerminal(e.fd) {
+		return e.readPlain()
+	}
+
+	state, err := term.MakeRaw(e.fd)
+	if err != nil {
+		return e.readPlain()
+	}
+	defer term.Restore(e.fd, state)
+
+	fmt.Fprint(e.out, bracketedPasteOn)
+	defer fmt.Fprint(e.out, bracketedPasteOff)
+
+	line, err := e.edit()
+	if err != nil {
+		return "", err
+	}
+	if err := e.history.Add(line); err != nil {
+		fmt.Fprintf(e.out, "⚠️  保存输入历史失败: %v\r\n", err)
+	}
+	return line, nil
+}
+
+// readPlain 标准输入不是终端时按行读取，未闭合的代码块会继续读取下一行
+func (e *LineEditor) readPlain() (string, error) {
+	fmt.Fprint(e.out, e.Prompt)
+	var lines []string
+	for {
+		line, err := e.reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if err != nil {
+			if len(lines) == 0 && line == "" {
+				return "", err
+			}
+			return strings.Join(append(lines, line), "\n"), nil
+		}
+		lines = append(lines, line)
+		text := strings.Join(lines, "\n")
+		if !inCodeFence(text) {
+			return text, nil
+		}
+		fmt.Fprint(e.out, e.ContinuationPrompt)
+	}
+}
+
+// inCodeFence 判断文本中是否有未闭合的 ``` 代码块
+func inCodeFence(text string) bool {
+	fences := 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fences++
+		}
+	}
+	return fences%2 == 1
+}
+
+// edit 编辑循环，直到提交、中断或读取失败
+func (e *LineEditor) edit() (string, error) {
+	e.buf, e.pos = nil, 0
+	e.cursorRow, e.endRow = 0, 0
+	e.histIdx, e.draft = e.history.Len(), ""
+	e.refresh()
+
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			e.finish("")
+			return "", err
+		}
+		done, err := e.handleKey(k)
+		if err != nil {
+			return "", err
+		}
+		if done {
+			e.finish("")
+			return string(e.buf), nil
+		}
+	}
+}
+
+// handleKey 处理一个按键，返回是否提交输入
+func (e *LineEditor) handleKey(k key) (bool, error) {
+	switch k.code {
+	case keyRune:
+		e.insert([]rune{k.r})
+	case keyPaste:
+		e.insert([]rune(k.text))
+	case keyNewline:
+		e.insert([]rune{'\n'})
+	case keyEnter:
+		if !inCodeFence(string(e.buf)) {
+			return true, nil
+		}
+		e.insert([]rune{'\n'})
+	case keyInterrupt:
+		e.finish("^C")
+		return false, ErrInterrupt
+	case keyEOF:
+		if len(e.buf) == 0 {
+			e.finish("")
+			return false, io.EOF
+		}
+		e.delete(e.pos, e.pos+1)
+	case keyBackspace:
+		e.delete(e.pos-1, e.pos)
+	case keyDelete:
+		e.delete(e.pos, e.pos+1)
+	case keyLeft:
+		e.pos = max(e.pos-1, 0)
+	case keyRight:
+		e.pos = min(e.pos+1, len(e.buf))
+	case keyWordLeft:
+		e.pos = e.wordStart()
+	case keyWordRight:
+		e.pos = e.wordEnd()
+	case keyHome:
+		e.pos = e.lineStart(e.pos)
+	case keyEnd:
+		e.pos = e.lineEnd(e.pos)
+	case keyUp:
+		if !e.moveLine(-1) {
+			e.historyPrev()
+		}
+	case keyDown:
+		if !e.moveLine(1) {
+			e.historyNext()
+		}
+	case keyKillEnd:
+		e.delete(e.pos, e.lineEnd(e.pos))
+	case keyKillStart:
+		e.delete(e.lineStart(e.pos), e.pos)
+	case keyKillWord:
+		e.delete(e.wordStart(), e.pos)
+	case keyClear:
+		fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		e.cursorRow, e.endRow = 0, 0
+	case keyTab:
+		e.complete()
+	case keySearch:
+		return e.reverseSearch()
+	}
+	e.refresh()
+	return false, nil
+}
+
+// insert 在光标处插入文本
+func (e *LineEditor) insert(text []rune) {
+	buf := make([]rune, 0, len(e.buf)+len(text))
+	buf = append(buf, e.buf[:e.pos]...)
+	buf = append(buf, text...)
+	buf = append(buf, e.buf[e.pos:]...)
+	e.buf = buf
+	e.pos += len(text)
+}
+
+// delete 删除 [from, to) 范围内的字符
+func (e *LineEditor) delete(from, to int) {
+	from = max(from, 0)
+	to = min(to, len(e.buf))
+	if from >= to {
+		return
+	}
+	e.buf = append(e.buf[:from], e.buf[to:]...)
+	if e.pos > to {
+		e.pos -= to - from
+	} else if e.pos > from {
+		e.pos = from
+	}
+}
+
+// setText 替换整个输入，光标移到末尾
+func (e *LineEditor) setText(text string) {
+	e.buf = []rune(text)
+	e.pos = len(e.buf)
+}
+
+// lineStart 返回 pos 所在行的开头
+func (e *LineEditor) lineStart(pos int) int {
+	for pos > 0 && e.buf[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+// lineEnd 返回 pos 所在行的末尾
+func (e *LineEditor) lineEnd(pos int) int {
+	for pos < len(e.buf) && e.buf[pos] != '\n' {
+		pos++
+	}
+	return pos
+}
+
+// wordStart 返回光标前一个单词的开头
+func (e *LineEditor) wordStart() int {
+	pos := e.pos
+	for pos > 0 && unicode.IsSpace(e.buf[pos-1]) {
+		pos--
+	}
+	for pos > 0 && !unicode.IsSpace(e.buf[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// wordEnd 返回光标后一个单词的末尾
+func (e *LineEditor) wordEnd() int {
+	pos := e.pos
+	for pos < len(e.buf) && unicode.IsSpace(e.buf[pos]) {
+		pos++
+	}
+	for pos < len(e.buf) && !unicode.IsSpace(e.buf[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// moveLine 在多行输入中把光标移到上一行或下一行，没有该行时返回 false
+func (e *LineEditor) moveLine(delta int) bool {
+	start := e.lineStart(e.pos)
+	col := e.pos - start
+
+	var target int
+	if delta < 0 {
+		if start == 0 {
+			return false
+		}
+		target = e.lineStart(start - 1)
+	} else {
+		end := e.lineEnd(e.pos)
+		if end == len(e.buf) {
+			return false
+		}
+		target = end + 1
+	}
+	e.pos = min(target+col, e.lineEnd(target))
+	return true
+}
+
+// historyPrev 显示上一条历史，保留正在编辑的输入
+func (e *LineEditor) historyPrev() {
+	if e.histIdx == 0 {
+		return
+	}
+	if e.histIdx == e.history.Len() {
+		e.draft = string(e.buf)
+	}
+	e.histIdx--
+	e.setText(e.history.At(e.histIdx))
+}
+
+// historyNext 显示下一条历史，到末尾时恢复正在编辑的输入
+func (e *LineEditor) historyNext() {
+	if e.histIdx >= e.history.Len() {
+		return
+	}
+	e.histIdx++
+	if e.histIdx == e.history.Len() {
+		e.setText(e.draft)
+		return
+	}
+	e.setText(e.history.At(e.histIdx))
+}
+
+// complete 处理 Tab：唯一候选直接补全，多个候选补全公共前缀或列出候选
+func (e *LineEditor) complete() {
+	if e.completer == nil || e.pos != len(e.buf) {
+		return
+	}
+	line := string(e.buf)
+	candidates := e.completer(line)
+
+	switch len(candidates) {
+	case 0:
+		fmt.Fprint(e.out, "\a")
+	case 1:
+		completed := candidates[0]
+		if !strings.HasSuffix(completed, " ") {
+			completed += " "
+		}
+		e.setText(completed)
+	default:
+		if prefix := commonPrefix(candidates); len(prefix) > len(line) {
+			e.setText(prefix)
+			return
+		}
+		e.finish("")
+		fmt.Fprint(e.out, strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+// commonPrefix 返回字符串的公共前缀
+func commonPrefix(values []string) string {
+	prefix := []rune(values[0])
+	for _, v := range values[1:] {
+		runes := []rune(v)
+		n := 0
+		for n < len(prefix) && n < len(runes) && prefix[n] == runes[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
+
+// reverseSearch 处理 Ctrl-R 反向搜索历史
+//
+// 输入字符缩小搜索范围，再按 Ctrl-R 查找更早的匹配，Enter 提交匹配的输入，
+// Ctrl-G 或 Ctrl-C 取消，其他按键接受匹配并继续编辑。
+func (e *LineEditor) reverseSearch() (bool, error) {
+	original, originalPos := e.buf, e.pos
+	var query []rune
+	match, failed := -1, false
+
+	search := func(before int) {
+		if len(query) == 0 {
+			match, failed = -1, false
+			return
+		}
+		if idx := e.history.Search(string(query), before); idx >= 0 {
+			match, failed = idx, false
+		} else {
+			failed = true
+		}
+	}
+
+	for {
+		label := "reverse-i-search"
+		if failed {
+			label = "failed reverse-i-search"
+		}
+		text, cursor := "", 0
+		if match >= 0 {
+			text = e.history.At(match)
+			if idx := strings.Index(text, string(query)); idx >= 0 {
+				cursor = len([]rune(text[:idx]))
+			}
+		}
+		e.render(fmt.Sprintf("(%s)`%s': ", label, string(query)), []rune(text), cursor)
+
+		k, err := e.readKey()
+		if err != nil {
+			e.finish("")
+			return false, err
+		}
+
+		switch k.code {
+		case keyRune:
+			query = append(query, k.r)
+			before := e.history.Len()
+			if match >= 0 {
+				before = match + 1
+			}
+			search(before)
+		case keyBackspace:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+			search(e.history.Len())
+		case keySearch:
+			if match >= 0 {
+				search(match)
+			}
+		case keyCancel, keyInterrupt:
+			e.buf, e.pos = original, originalPos
+			e.refresh()
+			return false, nil
+		default:
+			if match >= 0 {
+				e.histIdx = e.history.Len()
+				e.setText(e.history.At(match))
+			}
+			if k.code == keyEnter {
+				return true, nil
+			}
+			return e.handleKey(k)
+		}
+	}
+}
+
+// refresh 重新绘制输入
+func (e *LineEditor) refresh() {
+	e.render(e.Prompt, e.buf, e.pos)
+}
+
+// render 清除上次绘制的输入区域，绘制提示符和文本，并把光标移到 cursor 处
+func (e *LineEditor) render(prompt string, text []rune, cursor int) {
+	width := e.width()
+	var b strings.Builder
+
+	if e.cursorRow > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", e.cursorRow)
+	}
+	b.WriteString("\r\x1b[J")
+	b.WriteString(prompt)
+
+	row, col := 0, runewidth.StringWidth(prompt)
+	curRow, curCol := row, col
+	for i, r := range text {
+		if i == cursor {
+			curRow, curCol = row, col
+		}
+		if r == '\n' {
+			b.WriteString("\r\n")
+			b.WriteString(e.ContinuationPrompt)
+			row, col = row+1, runewidth.StringWidth(e.ContinuationPrompt)
+			continue
+		}
+		w := runewidth.RuneWidth(r)
+		if col+w > width {
+			row, col = row+1, 0
+		}
+		b.WriteRune(r)
+		col += w
+		// 正好写满一行时终端光标停在行尾，手动换到下一行
+		if col >= width {
+			b.WriteString("\r\n")
+			row, col = row+1, 0
+		}
+	}
+	if cursor >= len(text) {
+		curRow, curCol = row, col
+	}
+
+	if row > curRow {
+		fmt.Fprintf(&b, "\x1b[%dA", row-curRow)
+	}
+	b.WriteString("\r")
+	if curCol > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", curCol)
+	}
+
+	e.cursorRow, e.endRow = curRow, row
+	fmt.Fprint(e.out, b.String())
+}
+
+// finish 把光标移到输入区域之后，下一次绘制从新的一行开始
+func (e *LineEditor) finish(suffix string) {
+	if down := e.endRow - e.cursorRow; down > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dB", down)
+	}
+	fmt.Fprint(e.out, suffix+"\r\n")
+	e.cursorRow, e.endRow = 0, 0
+}
+
+// width 返回终端宽度
+func (e *LineEditor) width() int {
+	if e.fd >= 0 {
+		if w, _, err := term.GetSize(e.fd); err == nil && w > 0 {
+			return w
+		}
+	}
+	return 80
+}
+
+// keyCode 按键类型
+type keyCode int
+
+const (
+	keyUnknown keyCode = iota
+	keyRune
+	keyPaste
+	keyEnter
+	keyNewline
+	keyBackspace
+	keyDelete
+	keyLeft
+	keyRight
+	keyUp
+	keyDown
+	keyHome
+	keyEnd
+	keyWordLeft
+	keyWordRight
+	keyKillEnd
+	keyKillStart
+	keyKillWord
+	keyTab
+	keyClear
+	keySearch
+	keyCancel
+	keyInterrupt
+	keyEOF
+)
+
+// key 一次按键
+type key struct {
+	code keyCode
+	r    rune   // keyRune 的字符
+	text string // keyPaste 粘贴的内容
+}
+
+// controlKeys Ctrl 组合键对应的按键
+var controlKeys = map[rune]keyCode{
+	1:   keyHome,      // Ctrl-A
+	2:   keyLeft,      // Ctrl-B
+	3:   keyInterrupt, // Ctrl-C
+	4:   keyEOF,       // Ctrl-D
+	5:   keyEnd,       // Ctrl-E
+	6:   keyRight,     // Ctrl-F
+	7:   keyCancel,    // Ctrl-G
+	8:   keyBackspace, // Ctrl-H
+	9:   keyTab,
+	10:  keyNewline, // Ctrl-J
+	11:  keyKillEnd, // Ctrl-K
+	12:  keyClear,   // Ctrl-L
+	13:  keyEnter,
+	14:  keyDown,      // Ctrl-N
+	16:  keyUp,        // Ctrl-P
+	18:  keySearch,    // Ctrl-R
+	21:  keyKillStart, // Ctrl-U
+	23:  keyKillWord,  // Ctrl-W
+	127: keyBackspace,
+}
+
+// readKey 读取一次按键，解析转义序列
+func (e *LineEditor) readKey() (key, error) {
+	r, _, err := e.reader.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	if r == 27 {
+		return e.readEscape()
+	}
+	if code, ok := controlKeys[r]; ok {
+		return key{code: code}, nil
+	}
+	if r < 32 {
+		return key{code: keyUnknown}, nil
+	}
+	return key{code: keyRune, r: r}, nil
+}
+
+// readEscape 解析 ESC 开头的序列：Alt 组合键、方向键等
+func (e *LineEditor) readEscape() (key, error) {
+	r, _, err := e.reader.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	switch r {
+	case '\r', '\n':
+		return key{code: keyNewline}, nil // Alt-Enter
+	case 'b', 'B':
+		return key{code: keyWordLeft}, nil
+	case 'f', 'F':
+		return key{code: keyWordRight}, nil
+	case 'O':
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return key{}, err
+		}
+		return csiKey("", byte(r)), nil
+	case '[':
+		var params []byte
+		for {
+			b, err := e.reader.ReadByte()
+			if err != nil {
+				return key{}, err
+			}
+			if b >= 0x40 && b <= 0x7e {
+				if string(params) == "200" && b == '~' {
+					text, err := e.readPaste()
+					return key{code: keyPaste, text: text}, err
+				}
+				return csiKey(string(params), b), nil
+			}
+			params = append(params, b)
+		}
+	}
+	return key{code: keyUnknown}, nil
+}
+
+// csiKey 将 CSI/SS3 序列转换为按键，带 Alt 或 Ctrl 修饰的左右键按单词移动
+func csiKey(params string, final byte) key {
+	modified := strings.HasSuffix(params, ";3") || strings.HasSuffix(params, ";5")
+	switch final {
+	case 'A':
+		return key{code: keyUp}
+	case 'B':
+		return key{code: keyDown}
+	case 'C':
+		if modified {
+			return key{code: keyWordRight}
+		}
+		return key{code: keyRight}
+	case 'D':
+		if modified {
+			return key{code: keyWordLeft}
+		}
+		return key{code: keyLeft}
+	case 'H':
+		return key{code: keyHome}
+	case 'F':
+		return key{code: keyEnd}
+	case '~':
+		switch params {
+		case "1", "7":
+			return key{code: keyHome}
+		case "4", "8":
+			return key{code: keyEnd}
+		case "3":
+			return key{code: keyDelete}
+		}
+	}
+	return key{code: keyUnknown}
+}
+
+// readPaste 读取括号粘贴的内容，统一换行符
+func (e *LineEditor) readPaste() (string, error) {
+	var data []byte
+	for {
+		b, err := e.reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		data = append(data, b)
+		if b == '~' && strings.HasSuffix(string(data), pasteEnd) {
+			break
+		}
+	}
+	text := strings.TrimSuffix(string(data), pasteEnd)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n"), nil
+}
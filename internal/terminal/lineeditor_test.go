@@ -0,0 +1,156 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// newTestEditor 创建从字符串读取按键的编辑器
+func newTestEditor(keys string, history ...string) *LineEditor {
+	h, _ := LoadHistory("", 0)
+	for _, line := range history {
+		_ = h.Add(line)
+	}
+	return NewLineEditor(bufio.NewReader(strings.NewReader(keys)), nil, &bytes.Buffer{}, h)
+}
+
+func TestLineEditor_Editing(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"plain", "hello\r", "hello"},
+		{"backspace", "helo\x7f\x7fllo\r", "hello"},
+		{"cursor movement", "world\x01hello \r", "hello world"},
+		{"arrow keys", "ac\x1b[Db\x1b[C!\r", "abc!"},
+		{"kill to end", "hello world\x1b[D\x1b[D\x1b[D\x1b[D\x1b[D\x0b\r", "hello "},
+		{"kill word", "hello world\x17\r", "hello "},
+		{"kill to start", "hello world\x15bye\r", "bye"},
+		{"unicode", "你好世界\x7f\r", "你好世"},
+		{"alt-enter", "line 1\x1b\rline 2\r", "line 1\nline 2"},
+		{"ctrl-j", "line 1\nline 2\r", "line 1\nline 2"},
+		{"code fence", "```go\rfmt.Println()\r```\r", "```go\nfmt.Println()\n```"},
+		{"bracketed paste", "\x1b[200~a\r\nb\x1b[201~\r", "a\nb"},
+		{"move between lines", "ab\ncd\x1b[A\x1b[D!\r", "a!b\ncd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestEditor(tt.keys).edit()
+			if err != nil {
+				t.Fatalf("edit failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("edit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineEditor_InterruptAndEOF(t *testing.T) {
+	if _, err := newTestEditor("abc\x03").edit(); !errors.Is(err, ErrInterrupt) {
+		t.Errorf("Ctrl-C: expected ErrInterrupt, got %v", err)
+	}
+	if _, err := newTestEditor("\x04").edit(); !errors.Is(err, io.EOF) {
+		t.Errorf("Ctrl-D on empty line: expected io.EOF, got %v", err)
+	}
+	// 非空行上的 Ctrl-D 删除光标处的字符
+	got, err := newTestEditor("ab\x01\x04\r").edit()
+	if err != nil || got != "b" {
+		t.Errorf("Ctrl-D with text = %q, %v; want \"b\"", got, err)
+	}
+}
+
+func TestLineEditor_History(t *testing.T) {
+	history := []string{"first", "second"}
+
+	got, _ := newTestEditor("\x1b[A\x1b[A\r", history...).edit()
+	if got != "first" {
+		t.Errorf("up twice = %q, want first", got)
+	}
+
+	// 回到末尾时恢复正在编辑的输入
+	got, _ = newTestEditor("draft\x1b[A\x1b[B\r", history...).edit()
+	if got != "draft" {
+		t.Errorf("up then down = %q, want draft", got)
+	}
+}
+
+func TestLineEditor_ReverseSearch(t *testing.T) {
+	history := []string{"git status", "ls -la", "git log", "make test"}
+
+	got, _ := newTestEditor("\x12git\r", history...).edit()
+	if got != "git log" {
+		t.Errorf("search git = %q, want git log", got)
+	}
+
+	got, _ = newTestEditor("\x12git\x12\r", history...).edit()
+	if got != "git status" {
+		t.Errorf("search git twice = %q, want git status", got)
+	}
+
+	// 其他按键接受匹配并继续编辑
+	got, _ = newTestEditor("\x12ls\x05 /tmp\r", history...).edit()
+	if got != "ls -la /tmp" {
+		t.Errorf("accept and edit = %q, want \"ls -la /tmp\"", got)
+	}
+
+	// Ctrl-G 取消搜索，恢复原来的输入
+	got, _ = newTestEditor("draft\x12make\x07\r", history...).edit()
+	if got != "draft" {
+		t.Errorf("cancel search = %q, want draft", got)
+	}
+}
+
+func TestLineEditor_Complete(t *testing.T) {
+	completer := func(line string) []string {
+		var candidates []string
+		for _, c := range []string{"/branch", "/branches", "/help"} {
+			if strings.HasPrefix(c, line) {
+				candidates = append(candidates, c)
+			}
+		}
+		return candidates
+	}
+
+	tests := []struct {
+		keys string
+		want string
+	}{
+		{"/he\t\r", "/help "},
+		{"/b\t\r", "/branch"},
+		{"/x\t\r", "/x"},
+	}
+	for _, tt := range tests {
+		e := newTestEditor(tt.keys)
+		e.SetCompleter(completer)
+		got, err := e.edit()
+		if err != nil {
+			t.Fatalf("edit failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("complete %q = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestLineEditor_ReadPlain(t *testing.T) {
+	e := newTestEditor("hello\n```\ncode\n```\nlast")
+	for _, want := range []string{"hello", "```\ncode\n```", "last"} {
+		got, err := e.ReadLine()
+		if err != nil {
+			t.Fatalf("ReadLine failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("ReadLine() = %q, want %q", got, want)
+		}
+	}
+	if _, err := e.ReadLine(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at end of input, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/memory"
+)
+
+// handleRemember 处理 /remember：立即把一条事实写入记忆，不必等到对话结束
+//
+// 默认记为所有对话都可用的事实，--project 只用于当前项目，--chat 只用于当前对话。
+func (r *REPL) handleRemember(args string) {
+	mem := r.manager.GetMemoryManager()
+	if mem == nil {
+		fmt.Println("记忆未启用（memory.enabled）")
+		return
+	}
+
+	scope, fact := parseRememberArgs(args)
+	if fact == "" {
+		fmt.Println("用法: /remember [--project|--chat] <事实>")
+		return
+	}
+	if scope == memory.ScopeConversation && r.conversation.IsEphemeral() {
+		fmt.Println("临时对话不会保存，无法记住只属于本对话的内容")
+		return
+	}
+
+	note, err := mem.Remember(scope, r.conversation.ID, fact)
+	if err != nil {
+		fmt.Printf("记住失败: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ 已记住%s: %s\n", scopeLabel(mem, scope), note.Text)
+}
+
+// parseRememberArgs 解析 /remember 的作用范围选项和事实内容
+func parseRememberArgs(args string) (memory.Scope, string) {
+	scope := memory.ScopeGlobal
+	fields := strings.Fields(args)
+	for len(fields) > 0 {
+		switch fields[0] {
+		case "-p", "--project":
+			scope = memory.ScopeProject
+		case "-c", "--chat":
+			scope = memory.ScopeConversation
+		default:
+			return scope, strings.Join(fields, " ")
+		}
+		fields = fields[1:]
+	}
+	return scope, ""
+}
+
+// scopeLabel 返回作用范围的说明，用于提示
+func scopeLabel(mem *memory.Manager, scope memory.Scope) string {
+	switch scope {
+	case memory.ScopeProject:
+		if project := mem.Project(); project != nil {
+			return fmt.Sprintf("（项目 %s）", project.Name)
+		}
+		return "（当前项目）"
+	case memory.ScopeConversation:
+		return "（仅本对话）"
+	default:
+		return ""
+	}
+}
+
+// handleForget 处理 /forget：立即删除包含指定内容的记忆
+func (r *REPL) handleForget(fact string) {
+	mem := r.manager.GetMemoryManager()
+	if mem == nil {
+		fmt.Println("记忆未启用（memory.enabled）")
+		return
+	}
+	if fact == "" {
+		fmt.Println("用法: /forget <内容>")
+		return
+	}
+
+	removed, err := mem.ForgetFact(r.conversation.ID, fact)
+	if errors.Is(err, memory.ErrNoMatch) {
+		fmt.Printf("没有找到包含 %q 的记忆\n", fact)
+		return
+	}
+	if err != nil {
+		// 已经删除的部分也列出来，失败的原因可能是保存记忆文件出错
+		fmt.Printf("忘记失败: %v\n", err)
+	}
+	if len(removed) == 0 {
+		return
+	}
+	fmt.Printf("✓ 已忘记 %d 条:\n", len(removed))
+	for _, item := range removed {
+		fmt.Printf("  - %s\n", item)
+	}
+}
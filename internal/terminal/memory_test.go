@@ -0,0 +1,64 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/conversation"
+	"github.com/Lin-Jiong-HDU/tada/internal/memory"
+)
+
+func TestParseRememberArgs(t *testing.T) {
+	tests := []struct {
+		args  string
+		scope memory.Scope
+		fact  string
+	}{
+		{"I use fish shell", memory.ScopeGlobal, "I use fish shell"},
+		{"--project tests run with make test", memory.ScopeProject, "tests run with make test"},
+		{"-c answer briefly", memory.ScopeConversation, "answer briefly"},
+		{"--chat", memory.ScopeConversation, ""},
+		{"", memory.ScopeGlobal, ""},
+	}
+	for _, tt := range tests {
+		scope, fact := parseRememberArgs(tt.args)
+		if scope != tt.scope || fact != tt.fact {
+			t.Errorf("parseRememberArgs(%q) = %s, %q, want %s, %q", tt.args, scope, fact, tt.scope, tt.fact)
+		}
+	}
+}
+
+func TestREPL_RememberAndForget(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager := conversation.NewManager(conversation.NewFileStorage(tmpDir), conversation.NewPromptLoader(tmpDir), &mockREPLAIProvider{})
+	config := memory.DefaultConfig()
+	config.StoragePath = t.TempDir()
+	mem, err := memory.NewManager(config, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem.SetProject(memory.Project{Root: tmpDir, Name: "app"})
+	manager.SetMemoryManager(mem)
+
+	conv, _ := manager.Create("test", "default")
+	repl := NewREPL(manager, conv, false, 10)
+
+	for _, cmd := range []string{"/remember I use fish shell", "/remember --project uses PostgreSQL", "/remember --chat answer briefly"} {
+		if _, err := repl.HandleCommand(cmd); err != nil {
+			t.Fatalf("%s failed: %v", cmd, err)
+		}
+	}
+	if len(mem.Notes().GetNotes()) != 1 || len(mem.ProjectNotes().GetNotes()) != 1 || len(mem.ConversationNotes(conv.ID).GetNotes()) != 1 {
+		t.Fatal("expected one note in each scope")
+	}
+
+	if _, err := repl.HandleCommand("/forget postgresql"); err != nil {
+		t.Fatalf("/forget failed: %v", err)
+	}
+	if len(mem.ProjectNotes().GetNotes()) != 0 {
+		t.Error("expected the project note to be forgotten")
+	}
+	if len(mem.Notes().GetNotes()) != 1 {
+		t.Error("expected other notes to be kept")
+	}
+}
@@ -0,0 +1,111 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// 回复渲染之后思考过程默认折叠，只显示开头几行
+const (
+	reasoningPreviewLines = 3
+	reasoningPreviewWidth = 100 // 折叠时每行最多显示的宽度
+)
+
+// 暗色显示思考过程的 ANSI 序列
+const (
+	ansiDim   = "\033[2m"
+	ansiReset = "\033[0m"
+)
+
+// SetShowReasoning 设置是否显示推理模型的思考过程
+func (r *REPL) SetShowReasoning(enabled bool) {
+	r.showReasoning = enabled
+}
+
+// handleThink 处理 /think [on|off|show]
+func (r *REPL) handleThink(arg string) {
+	switch arg {
+	case "on":
+		r.showReasoning = true
+		fmt.Println("✓ 已开启思考过程显示")
+	case "off":
+		r.showReasoning = false
+		fmt.Println("✓ 已关闭思考过程显示")
+	case "show":
+		if r.lastReasoning == "" {
+			fmt.Println("上一条回复没有思考过程")
+			return
+		}
+		fmt.Print(formatReasoning(r.lastReasoning, 0))
+	case "":
+		if r.showReasoning {
+			fmt.Println("思考过程显示: 开启（/think off 关闭）")
+		} else {
+			fmt.Println("思考过程显示: 关闭（/think on 开启）")
+		}
+	default:
+		fmt.Println("用法: /think [on|off|show]")
+	}
+}
+
+// reasoningDisplay 包装流式输出的回调
+//
+// 关闭思考过程显示时丢弃思考内容；开启时在思考过程前加上标题，
+// 并在回复内容开始前换行，把两者分开。
+func (r *REPL) reasoningDisplay(show func(text string, reasoning bool)) func(text string, reasoning bool) {
+	inReasoning := false
+	return func(text string, reasoning bool) {
+		if reasoning {
+			if !r.showReasoning {
+				return
+			}
+			if !inReasoning {
+				show("💭 ", false)
+				inReasoning = true
+			}
+			show(text, true)
+			return
+		}
+		if inReasoning {
+			show("\n\n", false)
+			inReasoning = false
+		}
+		show(text, false)
+	}
+}
+
+// styleReasoning 思考过程以暗色显示
+func styleReasoning(text string, reasoning bool) string {
+	if !reasoning || text == "" {
+		return text
+	}
+	return ansiDim + text + ansiReset
+}
+
+// formatReasoning 将思考过程格式化为暗色的块
+//
+// maxLines 大于 0 时折叠显示，只保留前 maxLines 行并截断过长的行。
+func formatReasoning(reasoning string, maxLines int) string {
+	lines := strings.Split(strings.TrimSpace(reasoning), "\n")
+	shown := lines
+	if maxLines > 0 && len(lines) > maxLines {
+		shown = lines[:maxLines]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "💭 思考过程（%d 行）\n", len(lines))
+	truncated := len(shown) < len(lines)
+	for _, line := range shown {
+		if maxLines > 0 && runewidth.StringWidth(line) > reasoningPreviewWidth {
+			line = runewidth.Truncate(line, reasoningPreviewWidth, "…")
+			truncated = true
+		}
+		b.WriteString(ansiDim + "│ " + line + ansiReset + "\n")
+	}
+	if truncated {
+		b.WriteString(ansiDim + "│ …（/think show 展开）" + ansiReset + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package terminal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
+)
+
+func TestReadStream_Reasoning(t *testing.T) {
+	stream := make(chan ai.StreamEvent, 3)
+	stream <- ai.StreamEvent{Reasoning: "think"}
+	stream <- ai.StreamEvent{Reasoning: "ing"}
+	stream <- ai.StreamEvent{Content: "answer", FinishReason: ai.FinishStop}
+	close(stream)
+
+	var shown []string
+	repl := &REPL{showReasoning: true}
+	result := readStream(context.Background(), stream, repl.reasoningDisplay(func(text string, reasoning bool) {
+		shown = append(shown, styleReasoning(text, reasoning))
+	}))
+	if result.reasoning != "thinking" || result.content != "answer" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	want := []string{"💭 ", ansiDim + "think" + ansiReset, ansiDim + "ing" + ansiReset, "\n\n", "answer"}
+	if strings.Join(shown, "|") != strings.Join(want, "|") {
+		t.Errorf("displayed %q, want %q", shown, want)
+	}
+
+	// 关闭显示时不输出思考过程，但仍然保留在结果中
+	shown = nil
+	repl.handleThink("off")
+	display := repl.reasoningDisplay(func(text string, reasoning bool) {
+		shown = append(shown, text)
+	})
+	display("hidden", true)
+	display("answer", false)
+	if strings.Join(shown, "|") != "answer" {
+		t.Errorf("reasoning shown while disabled: %q", shown)
+	}
+}
+
+func TestFormatReasoning(t *testing.T) {
+	reasoning := "first\nsecond\nthird\nfourth\n"
+
+	collapsed := formatReasoning(reasoning, 3)
+	if !strings.Contains(collapsed, "思考过程（4 行）") || strings.Contains(collapsed, "fourth") {
+		t.Errorf("collapsed block should show the first 3 of 4 lines:\n%s", collapsed)
+	}
+	if !strings.Contains(collapsed, "/think show") {
+		t.Errorf("collapsed block should tell how to expand:\n%s", collapsed)
+	}
+
+	full := formatReasoning(reasoning, 0)
+	if !strings.Contains(full, "fourth") || strings.Contains(full, "/think show") {
+		t.Errorf("expanded block should show all lines:\n%s", full)
+	}
+
+	long := formatReasoning(strings.Repeat("很长", 100), 3)
+	if !strings.Contains(long, "…") || !strings.Contains(long, "/think show") {
+		t.Errorf("long lines should be cut when collapsed:\n%s", long)
+	}
+}
+
+func TestREPL_Think(t *testing.T) {
+	repl := &REPL{showReasoning: true}
+	repl.handleThink("off")
+	if repl.showReasoning {
+		t.Error("/think off should disable reasoning display")
+	}
+	repl.handleThink("on")
+	if !repl.showReasoning {
+		t.Error("/think on should enable reasoning display")
+	}
+	repl.handleThink("show")
+	repl.handleThink("bogus")
+	if !repl.showReasoning {
+		t.Error("unknown argument should not change the setting")
+	}
+}
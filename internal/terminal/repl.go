@@ -1,10 +1,16 @@
 package terminal
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/Lin-Jiong-HDU/tada/internal/ai"
 	"github.com/Lin-Jiong-HDU/tada/internal/conversation"
 )
 
@@ -19,6 +25,17 @@ type REPL struct {
 	stream          bool
 	showThinking    bool
 	maxDisplayLines int // 流式输出最大显示行数
+
+	runner          CommandRunner // 对话中执行命令，nil 表示未启用
+	proposeCommands bool          // 是否提供执行助手建议的命令
+
+	attacher *Attacher    // 读取 /file、/attach 和 @路径 附加的文件
+	pending  []Attachment // 随下一条消息发送的附件
+
+	keyInterrupt bool // 生成回复时任意按键中断
+
+	showReasoning bool   // 显示推理模型的思考过程，/think on|off 切换
+	lastReasoning string // 上一条回复的思考过程，/think show 展开
 }
 
 // NewREPL 创建 REPL
@@ -29,6 +46,8 @@ func NewREPL(manager *conversation.Manager, conv *conversation.Conversation, str
 		stream:          stream,
 		showThinking:    true,
 		maxDisplayLines: maxDisplayLines,
+		attacher:        NewAttacher(nil),
+		showReasoning:   true,
 	}
 }
 
@@ -37,10 +56,20 @@ func (r *REPL) SetRenderer(renderer *conversation.Renderer) {
 	r.renderer = renderer
 }
 
+// Conversation 返回当前对话，/fork 之后是分叉出的新对话
+func (r *REPL) Conversation() *conversation.Conversation {
+	return r.conversation
+}
+
 // ProcessInput 处理用户输入
 func (r *REPL) ProcessInput(input string) error {
 	input = strings.TrimSpace(input)
 
+	// ! 开头直接执行 shell 命令
+	if strings.HasPrefix(input, "!") {
+		return r.runShell(strings.TrimSpace(input[1:]))
+	}
+
 	// 检查是否是命令
 	if strings.HasPrefix(input, "/") {
 		shouldExit, err := r.HandleCommand(input)
@@ -53,7 +82,8 @@ func (r *REPL) ProcessInput(input string) error {
 		return nil
 	}
 
-	// 普通对话
+	// 普通对话，附上待发送的附件和 @路径 引用的文件
+	input = r.withAttachments(input)
 	if r.stream {
 		return r.processStreamChat(input)
 	}
@@ -61,9 +91,29 @@ func (r *REPL) ProcessInput(input string) error {
 	return r.processChat(input)
 }
 
+// SetKeyInterrupt 设置生成回复时是否监听按键，任意按键都会中断生成
+func (r *REPL) SetKeyInterrupt(enabled bool) {
+	r.keyInterrupt = enabled
+}
+
+// interruptContext 返回按 Ctrl-C 时取消的 context，用于一次回复的生成
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
 // processChat 处理普通对话
 func (r *REPL) processChat(input string) error {
-	response, err := r.manager.Chat(r.conversation.ID, input)
+	ctx, stop := interruptContext()
+	defer stop()
+	return r.printReply(r.manager.Chat(ctx, r.conversation.ID, input))
+}
+
+// printReply 显示非流式回复
+func (r *REPL) printReply(response string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("⏹ 已中断")
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -76,20 +126,55 @@ func (r *REPL) processChat(input string) error {
 		fmt.Println(response)
 	}
 
-	return nil
+	return r.offerProposedCommands(response)
 }
 
 // processStreamChat 处理流式对话
 func (r *REPL) processStreamChat(input string) error {
+	return r.streamReply(func(ctx context.Context) (<-chan ai.StreamEvent, error) {
+		return r.manager.ChatStream(ctx, r.conversation.ID, input)
+	})
+}
+
+// regenerate 为当前分支最后一条用户消息生成新的回复
+func (r *REPL) regenerate() error {
+	if r.stream {
+		return r.streamReply(func(ctx context.Context) (<-chan ai.StreamEvent, error) {
+			return r.manager.ReplyStream(ctx, r.conversation.ID)
+		})
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	return r.printReply(r.manager.Reply(ctx, r.conversation.ID))
+}
+
+// streamReply 显示流式回复，start 发起请求
+//
+// Ctrl-C 或任意按键（启用 SetKeyInterrupt 时）中断生成，已生成的部分会被保存。
+func (r *REPL) streamReply(start func(ctx context.Context) (<-chan ai.StreamEvent, error)) error {
+	ctx, cancel := interruptContext()
+	defer cancel()
+
 	if r.showThinking {
 		fmt.Print("🤠 思考中...")
 	}
 
-	stream, err := r.manager.ChatStream(r.conversation.ID, input)
+	var keys *keyWatcher
+	if r.keyInterrupt {
+		keys = watchKeys(cancel)
+	}
+	defer keys.Stop()
+
+	stream, err := start(ctx)
 	if err != nil {
 		if r.showThinking {
 			fmt.Print("\r\033[K")
 		}
+		if errors.Is(err, context.Canceled) {
+			keys.Stop()
+			fmt.Println("⏹ 已中断")
+			return nil
+		}
 		return err
 	}
 
@@ -98,63 +183,124 @@ func (r *REPL) processStreamChat(input string) error {
 		fmt.Println()
 	}
 
-	var fullResponse strings.Builder
-
 	tracker, err := NewLineTracker(r.maxDisplayLines)
 	if err != nil {
-		return r.processStreamChatFallback(input, stream)
+		return r.processStreamChatFallback(ctx, stream, keys)
 	}
 
-	for chunk := range stream {
-		fullResponse.WriteString(chunk)
-		displayText, overflow := tracker.Track(chunk)
+	result := readStream(ctx, stream, r.reasoningDisplay(func(text string, reasoning bool) {
+		displayText, overflow := tracker.Track(text)
 		if displayText != "" {
-			fmt.Print(displayText)
+			fmt.Print(styleReasoning(keys.translate(displayText), reasoning))
 		}
 		if overflow {
 			fmt.Print("...")
 		}
-	}
+	}))
+	keys.Stop()
 
 	if tracker.LineCount() > 0 {
 		fmt.Printf("\033[%dA\r\033[J", tracker.LineCount())
 	}
 
-	fmt.Print("\n🤖\n")
-	if r.renderer != nil {
-		rendered, _ := r.renderer.Render(fullResponse.String())
-		fmt.Print(rendered)
-	} else {
-		fmt.Println(fullResponse.String())
-	}
-
-	return nil
+	return r.showStreamed(result)
 }
 
 // processStreamChatFallback 降级处理
-func (r *REPL) processStreamChatFallback(input string, stream <-chan string) error {
-	var fullResponse strings.Builder
+func (r *REPL) processStreamChatFallback(ctx context.Context, stream <-chan ai.StreamEvent, keys *keyWatcher) error {
 	lineCount := 1
 
-	for chunk := range stream {
-		fmt.Print(chunk)
-		lineCount += strings.Count(chunk, "\n")
-		fullResponse.WriteString(chunk)
-	}
+	result := readStream(ctx, stream, r.reasoningDisplay(func(text string, reasoning bool) {
+		fmt.Print(styleReasoning(keys.translate(text), reasoning))
+		lineCount += strings.Count(text, "\n")
+	}))
+	keys.Stop()
 
 	if lineCount > 0 {
 		fmt.Printf("\033[%dA\r\033[J", lineCount)
 	}
 
-	fmt.Print("\n🤖\n")
-	if r.renderer != nil {
-		rendered, _ := r.renderer.Render(fullResponse.String())
-		fmt.Print(rendered)
-	} else {
-		fmt.Println(fullResponse.String())
+	return r.showStreamed(result)
+}
+
+// showStreamed 渲染流式回复的完整内容，回复不完整时说明原因
+func (r *REPL) showStreamed(result streamResult) error {
+	r.lastReasoning = result.reasoning
+	if r.showReasoning && result.reasoning != "" {
+		fmt.Print("\n" + formatReasoning(result.reasoning, reasoningPreviewLines))
 	}
 
-	return nil
+	if result.content != "" || result.err == nil {
+		fmt.Print("\n🤖\n")
+		if r.renderer != nil {
+			rendered, _ := r.renderer.Render(result.content)
+			fmt.Print(rendered)
+		} else {
+			fmt.Println(result.content)
+		}
+	}
+
+	switch {
+	case result.interrupted:
+		if result.content != "" {
+			fmt.Println("⏹ 已中断，部分回复已保存（/retry 重新生成）")
+		} else {
+			fmt.Println("⏹ 已中断")
+		}
+		return nil
+	case result.err != nil:
+		if result.content != "" {
+			fmt.Printf("❌ 回复中途出错: %v\n部分回复已保存（/retry 重新生成）\n", result.err)
+		} else {
+			fmt.Printf("❌ 回复失败: %v（/retry 重试）\n", result.err)
+		}
+		return nil
+	case result.finishReason == ai.FinishLength:
+		fmt.Println("✂️  [truncated: length] 回复达到长度限制被截断（/retry 重新生成）")
+		return nil
+	}
+	return r.offerProposedCommands(result.content)
+}
+
+// streamResult 一次流式回复的结果
+type streamResult struct {
+	content      string
+	reasoning    string // 推理模型的思考过程
+	interrupted  bool   // 被 Ctrl-C 或按键中断
+	finishReason string // AI 返回的结束原因
+	err          error  // 流中途出错
+}
+
+// readStream 读取流式回复，onChunk 依次收到思考过程和回复内容的增量
+//
+// ctx 取消后不再显示新的内容，但继续读到 channel 关闭，
+// 确保部分回复保存之后才进行下一次操作。
+func readStream(ctx context.Context, stream <-chan ai.StreamEvent, onChunk func(text string, reasoning bool)) streamResult {
+	var result streamResult
+	var fullResponse, reasoning strings.Builder
+	for event := range stream {
+		if ctx.Err() != nil {
+			continue
+		}
+		if event.FinishReason != "" {
+			result.finishReason = event.FinishReason
+		}
+		if event.Err != nil {
+			result.err = event.Err
+		}
+		if event.Reasoning != "" {
+			reasoning.WriteString(event.Reasoning)
+			onChunk(event.Reasoning, true)
+		}
+		if event.Content != "" {
+			fullResponse.WriteString(event.Content)
+			onChunk(event.Content, false)
+		}
+	}
+	result.content = fullResponse.String()
+	result.reasoning = reasoning.String()
+	result.interrupted = ctx.Err() != nil
+	return result
 }
 
 // HandleCommand 处理命令
@@ -173,10 +319,140 @@ func (r *REPL) HandleCommand(cmd string) (bool, error) {
 		r.DisplayHelp()
 		return false, nil
 
+	case "/run":
+		request := strings.TrimSpace(strings.TrimPrefix(cmd, "/run"))
+		return false, r.runRequest(request)
+
+	case "/file":
+		r.attachFile(strings.TrimSpace(strings.TrimPrefix(cmd, "/file")))
+		return false, nil
+
+	case "/attach":
+		r.attachGlob(strings.TrimSpace(strings.TrimPrefix(cmd, "/attach")))
+		return false, nil
+
+	case "/detach":
+		r.pending = nil
+		fmt.Println("✓ 已清除待发送的附件")
+		return false, nil
+
+	case "/think":
+		r.handleThink(strings.TrimSpace(strings.TrimPrefix(cmd, "/think")))
+		return false, nil
+
+	case "/remember":
+		r.handleRemember(strings.TrimSpace(strings.TrimPrefix(cmd, "/remember")))
+		return false, nil
+
+	case "/forget":
+		r.handleForget(strings.TrimSpace(strings.TrimPrefix(cmd, "/forget")))
+		return false, nil
+
 	case "/clear":
 		fmt.Print("\033[H\033[2J") // ANSI 清屏
 		return false, nil
 
+	case "/retry":
+		if err := r.manager.PrepareRetry(r.conversation.ID); err != nil {
+			fmt.Printf("无法重新生成: %v\n", err)
+			return false, nil
+		}
+		return false, r.regenerate()
+
+	case "/edit":
+		return false, r.editMessage(strings.TrimSpace(strings.TrimPrefix(cmd, "/edit")))
+
+	case "/undo":
+		count, err := r.manager.Undo(r.conversation.ID)
+		if err != nil {
+			fmt.Printf("无法撤销: %v\n", err)
+			return false, nil
+		}
+		fmt.Printf("✓ 已撤销 %d 条消息（/branches 可找回）\n", count)
+		return false, nil
+
+	case "/fork":
+		name := strings.TrimSpace(strings.TrimPrefix(cmd, "/fork"))
+		fork, err := r.manager.Fork(r.conversation.ID, name)
+		if err != nil {
+			fmt.Printf("分叉失败: %v\n", err)
+			return false, nil
+		}
+		fmt.Printf("✓ 已分叉为新对话: %s (原对话: %s)\n", fork.ID, r.conversation.ID)
+		r.conversation = fork
+		return false, nil
+
+	case "/branches":
+		r.DisplayBranches()
+		return false, nil
+
+	case "/branch":
+		if len(parts) < 2 {
+			r.DisplayBranches()
+			return false, nil
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			fmt.Printf("无效的分支编号: %s\n", parts[1])
+			return false, nil
+		}
+		if err := r.manager.SwitchBranch(r.conversation.ID, n); err != nil {
+			fmt.Printf("切换分支失败: %v\n", err)
+			return false, nil
+		}
+		fmt.Printf("✓ 已切换到分支 %d\n", n)
+		return false, nil
+
+	case "/rename":
+		name := strings.TrimSpace(strings.TrimPrefix(cmd, "/rename"))
+		if name == "" {
+			fmt.Println("用法: /rename <标题>")
+			return false, nil
+		}
+		if err := r.manager.Rename(r.conversation.ID, name); err != nil {
+			fmt.Printf("重命名失败: %v\n", err)
+			return false, nil
+		}
+		fmt.Printf("✓ 对话标题: %s\n", name)
+		return false, nil
+
+	case "/tag":
+		var add, remove []string
+		for _, tag := range parts[1:] {
+			if strings.HasPrefix(tag, "-") {
+				remove = append(remove, strings.TrimPrefix(tag, "-"))
+			} else {
+				add = append(add, strings.TrimPrefix(tag, "+"))
+			}
+		}
+		tags, err := r.manager.UpdateTags(r.conversation.ID, add, remove)
+		if err != nil {
+			fmt.Printf("更新标签失败: %v\n", err)
+			return false, nil
+		}
+		if len(tags) == 0 {
+			fmt.Println("没有标签（/tag <标签> 添加，/tag -<标签> 移除）")
+		} else {
+			fmt.Printf("🏷  标签: %s\n", formatTags(tags))
+		}
+		return false, nil
+
+	case "/compact":
+		ctx, stop := interruptContext()
+		compaction, err := r.manager.Compact(ctx, r.conversation.ID)
+		stop()
+		if err != nil {
+			fmt.Printf("压缩失败: %v\n", err)
+			return false, nil
+		}
+		if compaction == nil {
+			fmt.Println("没有可压缩的消息")
+			return false, nil
+		}
+		fmt.Printf("✓ 已压缩 %d 条消息 (约 %d → %d tokens)\n",
+			compaction.MessageCount, compaction.TokensBefore, compaction.TokensAfter)
+		return false, nil
+
 	case "/prompt":
 		if len(parts) < 2 {
 			// 没有参数，列出可用的 prompts
@@ -199,6 +475,45 @@ func (r *REPL) HandleCommand(cmd string) (bool, error) {
 	}
 }
 
+// commandNames REPL 支持的命令，用于 Tab 补全
+var commandNames = []string{
+	"/help", "/clear", "/prompt", "/compact", "/retry", "/edit", "/undo",
+	"/branches", "/branch", "/fork", "/rename", "/tag", "/file", "/attach",
+	"/detach", "/run", "/think", "/remember", "/forget", "/exit", "/quit",
+}
+
+// Complete 补全命令名和 /prompt 的模板名称
+func (r *REPL) Complete(line string) []string {
+	if !strings.HasPrefix(line, "/") {
+		return nil
+	}
+
+	var candidates []string
+	if name, ok := strings.CutPrefix(line, "/prompt "); ok {
+		prompts, err := r.manager.ListPrompts()
+		if err != nil {
+			return nil
+		}
+		for _, p := range prompts {
+			if strings.HasPrefix(p.Name, name) {
+				candidates = append(candidates, "/prompt "+p.Name)
+			}
+		}
+		sort.Strings(candidates)
+		return candidates
+	}
+
+	if strings.Contains(line, " ") {
+		return nil
+	}
+	for _, name := range commandNames {
+		if strings.HasPrefix(name, line) {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
 // DisplayHelp 显示帮助
 func (r *REPL) DisplayHelp() {
 	help := `
@@ -206,11 +521,112 @@ func (r *REPL) DisplayHelp() {
   /help              显示此帮助
   /clear             清屏
   /prompt [name]     切换/列出 prompt 模板
+  /compact           将较早的对话压缩成摘要，节省上下文
+  /retry             重新生成最后一条回复
+  /edit [n] [内容]   修改第 n 条用户消息并重新生成（不带参数列出消息）
+  /undo              撤销最后一轮对话
+  /branches          列出分支，/branch <n> 切换分支
+  /fork [名称]       将当前分支复制为新对话并切换过去
+  /rename <标题>     修改对话标题
+  /tag [标签...]     添加标签，-标签 移除，不带参数显示标签
+  /file <路径>       附加文件，随下一条消息发送（也可在消息中写 @路径）
+  /attach [通配符]   附加匹配的所有文件，不带参数列出待发送的附件
+  /detach            清除待发送的附件
+  /run <请求>        让 AI 生成命令并执行，输出会加入对话
+  /think [on|off]    开关推理模型思考过程的显示，/think show 展开上一条的思考过程
+  /remember <事实>   立即记住，所有对话可用；--project 只用于当前项目，--chat 只用于本对话
+  /forget <内容>     删除包含该内容的记忆
+  !<命令>            直接执行 shell 命令，输出会加入对话
   /exit, /quit       退出并保存
+
+编辑:
+  ↑/↓                浏览输入历史，Ctrl-R 搜索历史
+  Tab                补全命令和 prompt 名称
+  Alt-Enter, Ctrl-J  换行；以 ` + "```" + ` 开头的输入在代码块闭合前 Enter 也只换行
+  Ctrl-C             清空输入；生成回复时 Ctrl-C 或任意键中断，保留部分回复
+  Ctrl-D             退出
 `
 	fmt.Println(help)
 }
 
+// editMessage 处理 /edit：修改第 n 条用户消息后重新生成回复
+func (r *REPL) editMessage(args string) error {
+	conv, err := r.manager.Get(r.conversation.ID)
+	if err != nil {
+		return err
+	}
+	users := conv.UserMessages()
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		if len(users) == 0 {
+			fmt.Println("没有可修改的消息")
+			return nil
+		}
+		fmt.Println("用户消息:")
+		for i, idx := range users {
+			fmt.Printf("  %d. %s\n", i+1, previewText(conv.Messages[idx].Content, 60))
+		}
+		fmt.Println("使用 /edit <n> <新内容> 修改")
+		return nil
+	}
+
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 || n > len(users) {
+		fmt.Printf("无效的消息编号: %s（共 %d 条用户消息）\n", fields[0], len(users))
+		return nil
+	}
+
+	content := strings.TrimSpace(strings.TrimPrefix(args, fields[0]))
+	if content == "" {
+		fmt.Printf("第 %d 条消息:\n%s\n", n, conv.Messages[users[n-1]].Content)
+		fmt.Println("使用 /edit <n> <新内容> 修改")
+		return nil
+	}
+
+	if err := r.manager.EditMessage(r.conversation.ID, n, content); err != nil {
+		return err
+	}
+	return r.regenerate()
+}
+
+// DisplayBranches 显示对话的分支
+func (r *REPL) DisplayBranches() {
+	conv, err := r.manager.Get(r.conversation.ID)
+	if err != nil {
+		fmt.Printf("获取分支失败: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n分支:")
+	for _, b := range conv.Branches() {
+		marker := " "
+		if b.Active {
+			marker = "*"
+		}
+		fmt.Printf(" %s %d. %d 条消息  %s\n", marker, b.Number, b.Length, b.Preview)
+	}
+	fmt.Println()
+}
+
+// formatTags 格式化标签用于显示
+func formatTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = "#" + tag
+	}
+	return strings.Join(formatted, " ")
+}
+
+// previewText 截取文本开头用于显示
+func previewText(text string, maxRunes int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > maxRunes {
+		return string(runes[:maxRunes]) + "..."
+	}
+	return string(runes)
+}
+
 // DisplayAvailablePrompts 显示可用的 prompt 模板
 func (r *REPL) DisplayAvailablePrompts() {
 	prompts, err := r.manager.ListPrompts()
@@ -242,7 +658,13 @@ func (r *REPL) DisplayAvailablePrompts() {
 func (r *REPL) DisplayExitSummary() {
 	fmt.Println("📝 对话已保存")
 	fmt.Printf("   ID: %s\n", r.conversation.ID)
-	fmt.Printf("   消息: %d 条\n", len(r.conversation.Messages))
+	if conv, err := r.manager.Get(r.conversation.ID); err == nil {
+		r.conversation = conv
+	}
+	if title := r.conversation.Title(); title != "" {
+		fmt.Printf("   标题: %s\n", title)
+	}
+	fmt.Printf("   消息: %d 条\n", len(r.conversation.ActiveMessages()))
 	fmt.Printf("   恢复: tada chat --continue %s\n", r.conversation.ID)
 }
 
@@ -2,7 +2,9 @@ package terminal
 
 import (
 	"context"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/Lin-Jiong-HDU/tada/internal/ai"
@@ -26,11 +28,11 @@ func (m *mockREPLAIProvider) Chat(ctx context.Context, messages []ai.Message) (s
 	return m.response, nil
 }
 
-func (m *mockREPLAIProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan string, error) {
-	ch := make(chan string)
+func (m *mockREPLAIProvider) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
+	ch := make(chan ai.StreamEvent)
 	go func() {
 		defer close(ch)
-		ch <- m.response
+		ch <- ai.StreamEvent{Content: m.response, FinishReason: ai.FinishStop}
 	}()
 	return ch, nil
 }
@@ -96,3 +98,157 @@ func TestREPL_HandleCommand(t *testing.T) {
 		t.Error("Expected shouldExit=true for /exit")
 	}
 }
+
+func TestREPL_HandleCommand_Compact(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager := conversation.NewManager(conversation.NewFileStorage(tmpDir), conversation.NewPromptLoader(tmpDir), &mockREPLAIProvider{response: "summary"})
+	conv, _ := manager.Create("test", "default")
+	repl := NewREPL(manager, conv, false, 10)
+
+	for i := 0; i < 3; i++ {
+		if _, err := manager.Chat(context.Background(), conv.ID, "hi"); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+
+	shouldExit, err := repl.HandleCommand("/compact")
+	if err != nil || shouldExit {
+		t.Fatalf("Expected /compact to succeed without exiting, got %v, %v", shouldExit, err)
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	if len(loaded.Compactions) != 1 {
+		t.Errorf("Expected 1 compaction, got %d", len(loaded.Compactions))
+	}
+}
+
+func TestREPL_HandleCommand_Branching(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager := conversation.NewManager(conversation.NewFileStorage(tmpDir), conversation.NewPromptLoader(tmpDir), &mockREPLAIProvider{response: "answer"})
+	conv, _ := manager.Create("test", "default")
+	repl := NewREPL(manager, conv, false, 10)
+
+	if err := repl.ProcessInput("question"); err != nil {
+		t.Fatalf("ProcessInput failed: %v", err)
+	}
+
+	for _, cmd := range []string{"/retry", "/edit 1 better question", "/edit", "/branches", "/undo", "/branch 1"} {
+		if _, err := repl.HandleCommand(cmd); err != nil {
+			t.Fatalf("%s failed: %v", cmd, err)
+		}
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	if got := len(loaded.Branches()); got != 3 {
+		t.Errorf("Expected 3 branches, got %d", got)
+	}
+
+	if _, err := repl.HandleCommand("/fork copy"); err != nil {
+		t.Fatalf("/fork failed: %v", err)
+	}
+	if repl.conversation.ID == conv.ID || repl.conversation.ForkedFrom != conv.ID {
+		t.Error("Expected REPL to switch to the forked conversation")
+	}
+}
+
+func TestREPL_HandleCommand_RenameAndTag(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	manager := conversation.NewManager(conversation.NewFileStorage(tmpDir), conversation.NewPromptLoader(tmpDir), &mockREPLAIProvider{})
+	conv, _ := manager.Create("", "default")
+	repl := NewREPL(manager, conv, false, 10)
+
+	for _, cmd := range []string{"/rename Deploy notes", "/tag work +k8s", "/tag -work", "/tag"} {
+		if _, err := repl.HandleCommand(cmd); err != nil {
+			t.Fatalf("%s failed: %v", cmd, err)
+		}
+	}
+
+	loaded, _ := manager.Get(conv.ID)
+	if loaded.Name != "Deploy notes" {
+		t.Errorf("Expected title 'Deploy notes', got %q", loaded.Name)
+	}
+	if len(loaded.Tags) != 1 || loaded.Tags[0] != "k8s" {
+		t.Errorf("Expected tags [k8s], got %v", loaded.Tags)
+	}
+}
+
+func TestREPL_Complete(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := conversation.EnsureDefaultPrompts(tmpDir); err != nil {
+		t.Fatalf("EnsureDefaultPrompts failed: %v", err)
+	}
+
+	storage := conversation.NewFileStorage(t.TempDir())
+	manager := conversation.NewManager(storage, conversation.NewPromptLoader(tmpDir), &mockREPLAIProvider{})
+	conv, _ := manager.Create("test", "default")
+	repl := NewREPL(manager, conv, false, 10)
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"/he", []string{"/help"}},
+		{"/bra", []string{"/branches", "/branch"}},
+		{"/prompt co", []string{"/prompt coder"}},
+		{"/edit 1", nil},
+		{"hello", nil},
+	}
+	for _, tt := range tests {
+		got := repl.Complete(tt.line)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Complete(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestReadStream_Interrupted(t *testing.T) {
+	stream := make(chan ai.StreamEvent)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer close(stream)
+		stream <- ai.StreamEvent{Content: "shown"}
+		stream <- ai.StreamEvent{Content: "hidden"}
+	}()
+
+	// 显示第一块后中断
+	var displayed []string
+	result := readStream(ctx, stream, func(chunk string, reasoning bool) {
+		displayed = append(displayed, chunk)
+		cancel()
+	})
+	if !result.interrupted || result.content != "shown" || len(displayed) != 1 {
+		t.Errorf("readStream = %+v, displayed %v", result, displayed)
+	}
+}
+
+func TestReadStream_FinishReasonAndError(t *testing.T) {
+	events := func(events ...ai.StreamEvent) <-chan ai.StreamEvent {
+		ch := make(chan ai.StreamEvent, len(events))
+		for _, event := range events {
+			ch <- event
+		}
+		close(ch)
+		return ch
+	}
+	noop := func(string, bool) {}
+
+	result := readStream(context.Background(), events(
+		ai.StreamEvent{Content: "long "},
+		ai.StreamEvent{Content: "answer", FinishReason: ai.FinishLength},
+		ai.StreamEvent{Usage: &ai.Usage{TotalTokens: 10}},
+	), noop)
+	if result.content != "long answer" || result.finishReason != ai.FinishLength || result.err != nil {
+		t.Errorf("unexpected result for truncated reply: %+v", result)
+	}
+
+	result = readStream(context.Background(), events(
+		ai.StreamEvent{Content: "half"},
+		ai.StreamEvent{Err: ai.ErrStreamIncomplete},
+	), noop)
+	if result.content != "half" || !errors.Is(result.err, ai.ErrStreamIncomplete) || result.interrupted {
+		t.Errorf("unexpected result for failed stream: %+v", result)
+	}
+}
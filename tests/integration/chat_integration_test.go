@@ -46,7 +46,7 @@ func TestChat_FullWorkflow(t *testing.T) {
 	}
 
 	// Send message
-	response, err := manager.Chat(conv.ID, "hello")
+	response, err := manager.Chat(context.Background(), conv.ID, "hello")
 	if err != nil {
 		t.Fatalf("Chat failed: %v", err)
 	}
@@ -96,14 +96,17 @@ func TestChat_StreamWorkflow(t *testing.T) {
 	}
 
 	// Test streaming chat
-	stream, err := manager.ChatStream(conv.ID, "stream")
+	stream, err := manager.ChatStream(context.Background(), conv.ID, "stream")
 	if err != nil {
 		t.Fatalf("ChatStream failed: %v", err)
 	}
 
 	var fullResponse string
-	for chunk := range stream {
-		fullResponse += chunk
+	for event := range stream {
+		if event.Err != nil {
+			t.Fatalf("stream error: %v", event.Err)
+		}
+		fullResponse += event.Content
 	}
 
 	if fullResponse != "Stream response" {
@@ -136,7 +139,7 @@ func TestChat_Persistence(t *testing.T) {
 	}
 
 	// Send a message
-	_, err = manager.Chat(conv1.ID, "test")
+	_, err = manager.Chat(context.Background(), conv1.ID, "test")
 	if err != nil {
 		t.Fatalf("Chat failed: %v", err)
 	}
@@ -231,12 +234,12 @@ func (m *mockChatAI) Chat(ctx context.Context, messages []ai.Message) (string, e
 	return "Default response", nil
 }
 
-func (m *mockChatAI) ChatStream(ctx context.Context, messages []ai.Message) (<-chan string, error) {
+func (m *mockChatAI) ChatStream(ctx context.Context, messages []ai.Message) (<-chan ai.StreamEvent, error) {
 	resp, _ := m.Chat(ctx, messages)
-	ch := make(chan string)
+	ch := make(chan ai.StreamEvent)
 	go func() {
 		defer close(ch)
-		ch <- resp
+		ch <- ai.StreamEvent{Content: resp, FinishReason: ai.FinishStop}
 	}()
 	return ch, nil
 }